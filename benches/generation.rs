@@ -0,0 +1,285 @@
+//! Benchmarks of on-the-fly generation: the time and the peak heap memory of single and concurrent builds,
+//! streamed to a writer that discards the bytes (like a client download).
+//!
+//! Run with `cargo bench --bench generation` (add `--features async` for the asynchronous builds).
+
+use std::{
+    alloc::{GlobalAlloc, Layout, System},
+    io::Write,
+    path::PathBuf,
+    sync::{
+        OnceLock,
+        atomic::{AtomicUsize, Ordering},
+    },
+    thread,
+    time::{Duration, Instant},
+};
+
+use liber::{
+    ZipCompression,
+    epub::{
+        ContentBuilder, EpubBuilder, ImageType, MemoryFileSystem, MetadataBuilder, ReferenceType,
+        Resource, SizeBudget,
+    },
+};
+
+/// The number of builds averaged by every benchmark.
+const ITERATIONS: u32 = 5;
+/// The number of concurrent builds of the concurrency benchmark.
+const CONCURRENT_BUILDS: usize = 8;
+/// The size of every image resource of the books with images.
+const IMAGE_SIZE: usize = 256 * 1024;
+
+/// The paths of the image resources, shared by every book.
+static IMAGE_PATHS: OnceLock<Vec<PathBuf>> = OnceLock::new();
+
+/// The bytes currently allocated on the heap.
+static CURRENT: AtomicUsize = AtomicUsize::new(0);
+/// The peak of the bytes allocated on the heap since the last reset.
+static PEAK: AtomicUsize = AtomicUsize::new(0);
+
+/// The system allocator, tracking the current and peak allocated bytes.
+struct CountingAllocator;
+
+unsafe impl GlobalAlloc for CountingAllocator {
+    unsafe fn alloc(&self, layout: Layout) -> *mut u8 {
+        let ptr = unsafe { System.alloc(layout) };
+        if !ptr.is_null() {
+            grow(layout.size());
+        }
+        ptr
+    }
+
+    unsafe fn dealloc(&self, ptr: *mut u8, layout: Layout) {
+        unsafe { System.dealloc(ptr, layout) };
+        CURRENT.fetch_sub(layout.size(), Ordering::Relaxed);
+    }
+
+    unsafe fn realloc(&self, ptr: *mut u8, layout: Layout, new_size: usize) -> *mut u8 {
+        let new_ptr = unsafe { System.realloc(ptr, layout, new_size) };
+        if !new_ptr.is_null() {
+            CURRENT.fetch_sub(layout.size(), Ordering::Relaxed);
+            grow(new_size);
+        }
+        new_ptr
+    }
+}
+
+#[global_allocator]
+static ALLOCATOR: CountingAllocator = CountingAllocator;
+
+/// Records an allocation of `size` bytes, updating the peak.
+fn grow(size: usize) {
+    let current = CURRENT.fetch_add(size, Ordering::Relaxed) + size;
+    PEAK.fetch_max(current, Ordering::Relaxed);
+}
+
+/// A writer discarding the bytes, counting them.
+#[derive(Default)]
+struct Counter(u64);
+
+impl Write for Counter {
+    fn write(&mut self, buf: &[u8]) -> std::io::Result<usize> {
+        self.0 += buf.len() as u64;
+        Ok(buf.len())
+    }
+
+    fn flush(&mut self) -> std::io::Result<()> {
+        Ok(())
+    }
+}
+
+#[cfg(feature = "async")]
+impl tokio::io::AsyncWrite for Counter {
+    fn poll_write(
+        self: std::pin::Pin<&mut Self>,
+        _: &mut std::task::Context<'_>,
+        buf: &[u8],
+    ) -> std::task::Poll<std::io::Result<usize>> {
+        self.get_mut().0 += buf.len() as u64;
+        std::task::Poll::Ready(Ok(buf.len()))
+    }
+
+    fn poll_flush(
+        self: std::pin::Pin<&mut Self>,
+        _: &mut std::task::Context<'_>,
+    ) -> std::task::Poll<std::io::Result<()>> {
+        std::task::Poll::Ready(Ok(()))
+    }
+
+    fn poll_shutdown(
+        self: std::pin::Pin<&mut Self>,
+        _: &mut std::task::Context<'_>,
+    ) -> std::task::Poll<std::io::Result<()>> {
+        std::task::Poll::Ready(Ok(()))
+    }
+}
+
+/// Builds a book with the given number of chapters, each with the given number of paragraphs, and
+/// the given number of image resources (read from memory).
+fn book(
+    chapters: usize,
+    paragraphs: usize,
+    images: usize,
+    size_budget: bool,
+) -> EpubBuilder<'static> {
+    let paragraph = "<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor \
+                     incididunt ut labore et dolore magna aliqua.</p>";
+
+    let mut builder = EpubBuilder::new(MetadataBuilder::title("Benchmark").build());
+    for chapter in 1..=chapters {
+        builder = builder.add_content(
+            ContentBuilder::new_owned(
+                format!(
+                    "<body><h1>Chapter {chapter}</h1>{}</body>",
+                    paragraph.repeat(paragraphs)
+                ),
+                ReferenceType::Text(format!("Chapter {chapter}")),
+            )
+            .build(),
+        );
+    }
+    if images > 0 {
+        let paths = IMAGE_PATHS.get_or_init(|| {
+            (1..=images)
+                .map(|image| PathBuf::from(format!("images/{image:03}.jpg")))
+                .collect()
+        });
+        let mut file_system = MemoryFileSystem::new();
+        for path in &paths[..images] {
+            file_system = file_system.file(path.clone(), vec![0; IMAGE_SIZE]);
+            builder = builder.add_resource(Resource::Image(path, ImageType::Jpg));
+        }
+        builder = builder.file_system(file_system);
+    }
+    if size_budget {
+        builder = builder.size_budget(SizeBudget::new().total(u64::MAX));
+    }
+    builder
+}
+
+/// Generates the book returned by `book` in every thread, returning the archive size, the mean time of
+/// every build and the peak heap memory beyond the books themselves.
+fn measure<F>(threads: usize, book: F) -> (u64, Duration, usize)
+where
+    F: Fn() -> EpubBuilder<'static> + Sync,
+{
+    let mut size = 0;
+    let mut elapsed = Duration::ZERO;
+    let mut peak = 0;
+
+    for _ in 0..ITERATIONS {
+        let books = (0..threads).map(|_| book()).collect::<Vec<_>>();
+        let baseline = CURRENT.load(Ordering::Relaxed);
+        PEAK.store(baseline, Ordering::Relaxed);
+
+        let start = Instant::now();
+        let sizes = thread::scope(|scope| {
+            let handles = books
+                .into_iter()
+                .map(|book| {
+                    scope.spawn(move || {
+                        let mut counter = Counter::default();
+                        book.create_with_compression(&mut counter, ZipCompression::Deflated)
+                            .expect("the benchmark book is valid");
+                        counter.0
+                    })
+                })
+                .collect::<Vec<_>>();
+            handles
+                .into_iter()
+                .map(|handle| handle.join().expect("the build does not panic"))
+                .collect::<Vec<_>>()
+        });
+        elapsed += start.elapsed();
+
+        size = sizes[0];
+        peak = peak.max(PEAK.load(Ordering::Relaxed).saturating_sub(baseline));
+    }
+
+    (size, elapsed / ITERATIONS, peak)
+}
+
+/// Generates the book returned by `book` asynchronously, returning the archive size, the mean time of
+/// every build and the peak heap memory beyond the book itself.
+#[cfg(feature = "async")]
+fn measure_async<F>(book: F) -> (u64, Duration, usize)
+where
+    F: Fn() -> EpubBuilder<'static>,
+{
+    let runtime = tokio::runtime::Builder::new_current_thread()
+        .enable_all()
+        .build()
+        .expect("the runtime is created");
+
+    let mut size = 0;
+    let mut elapsed = Duration::ZERO;
+    let mut peak = 0;
+
+    for _ in 0..ITERATIONS {
+        let book = book();
+        let baseline = CURRENT.load(Ordering::Relaxed);
+        PEAK.store(baseline, Ordering::Relaxed);
+
+        let start = Instant::now();
+        let mut counter = Counter::default();
+        runtime
+            .block_on(book.async_create_with_compression(&mut counter, ZipCompression::Deflated))
+            .expect("the benchmark book is valid");
+        elapsed += start.elapsed();
+
+        size = counter.0;
+        peak = peak.max(PEAK.load(Ordering::Relaxed).saturating_sub(baseline));
+    }
+
+    (size, elapsed / ITERATIONS, peak)
+}
+
+/// Prints a row of the results table.
+fn print(name: &str, builds: usize, (size, mean, peak): (u64, Duration, usize)) {
+    println!(
+        "{name:<34} {builds:>8} {:>12} {:>12.1} {:>14}",
+        size / 1024,
+        mean.as_secs_f64() * 1000.0,
+        peak / 1024
+    );
+}
+
+fn main() {
+    println!(
+        "{:<34} {:>8} {:>12} {:>12} {:>14}",
+        "benchmark", "builds", "archive KiB", "mean ms", "peak heap KiB"
+    );
+
+    let benchmarks: [(&str, usize, usize, usize, usize, bool); 7] = [
+        ("10 chapters", 1, 10, 50, 0, false),
+        ("200 chapters", 1, 200, 50, 0, false),
+        ("1000 chapters", 1, 1000, 50, 0, false),
+        ("1000 chapters, size budget", 1, 1000, 50, 0, true),
+        ("10 chapters of 2000 paragraphs", 1, 10, 2000, 0, false),
+        ("200 chapters, 64 images", 1, 200, 50, 64, false),
+        (
+            "200 chapters, concurrent",
+            CONCURRENT_BUILDS,
+            200,
+            50,
+            0,
+            false,
+        ),
+    ];
+
+    for (name, threads, chapters, paragraphs, images, size_budget) in benchmarks {
+        print(
+            name,
+            threads,
+            measure(threads, || book(chapters, paragraphs, images, size_budget)),
+        );
+    }
+
+    #[cfg(feature = "async")]
+    print(
+        "200 chapters, 64 images, async",
+        1,
+        measure_async(|| book(200, 50, 64, false)),
+    );
+}
@@ -16,7 +16,6 @@ fn create() -> Result<(), Box<dyn std::error::Error>> {
     let style = std::fs::read("./files/style.css")?;
     let chapter1 = std::fs::read("./files/chapter1.xhtml")?;
 
-    let mut file = std::fs::File::create("book.epub")?;
     let title = "My Book";
 
     let contents = vec![
@@ -59,7 +58,7 @@ fn create() -> Result<(), Box<dyn std::error::Error>> {
                 .build(),
         );
 
-    epub_builder.create(&mut file)?;
+    epub_builder.create_file("book.epub")?;
 
     Ok(())
 }
@@ -0,0 +1,217 @@
+use quick_xml::escape::escape;
+
+use crate::epub::{ContentBuilder, Epub, EpubBuilder, ReferenceType, Resource};
+
+/// The filename of the generated "Also by" page.
+pub const ALSO_BY_FILENAME: &str = "also-by.xhtml";
+
+/// Another title by the same author, listed in the generated **"Also by" page** (see [`EpubBuilder::also_by`]).
+///
+/// ```rust
+/// use std::path::Path;
+/// use liber::epub::{AlsoBy, EpubBuilder, ImageType, MetadataBuilder, Resource};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("The Sea").build()).also_by(
+///     "Also by Jane Doe",
+///     vec![
+///         AlsoBy::new("The River")
+///             .cover(Resource::Image(Path::new("covers/river.jpg"), ImageType::Jpg))
+///             .link("https://example.com/store/the-river"),
+///         AlsoBy::new("The Lake").description("A mystery on frozen waters."),
+///     ],
+/// );
+/// ```
+#[derive(Debug, Clone)]
+pub struct AlsoBy<'a> {
+    /// The title of the book.
+    title: String,
+    /// Optional short description (e.g., a tagline or the series).
+    description: Option<String>,
+    /// Optional cover thumbnail, added to the resources of the book.
+    cover: Option<Resource<'a>>,
+    /// Optional URL of the book in a store.
+    link: Option<String>,
+}
+
+impl<'a> AlsoBy<'a> {
+    /// Creates an entry with the given **title**.
+    pub fn new<S: Into<String>>(title: S) -> Self {
+        Self {
+            title: title.into(),
+            description: None,
+            cover: None,
+            link: None,
+        }
+    }
+
+    /// Sets a **short description** of the book, shown under its title.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn description<S: Into<String>>(mut self, description: S) -> Self {
+        self.description = Some(description.into());
+        self
+    }
+
+    /// Sets the **cover thumbnail** of the book, added to the resources when the book is generated
+    /// (unless a resource with the same filename is already added).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn cover(mut self, cover: Resource<'a>) -> Self {
+        self.cover = Some(cover);
+        self
+    }
+
+    /// Sets the **store link** of the book, linked from its title and cover (with the tracking parameters
+    /// of the book, see [`EpubBuilder::link_tracking`]).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn link<S: Into<String>>(mut self, link: S) -> Self {
+        self.link = Some(link.into());
+        self
+    }
+
+    /// Builds the list item of the book, with the link tracking parameters of the `epub`.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::FilenameNotFound`] if the cover path has no filename.
+    fn as_list_item(&self, epub: &Epub<'_>) -> crate::Result<String> {
+        let title = escape(&self.title);
+        let linked = |inner: &str| match self.link {
+            Some(ref link) => format!(
+                r#"<a href="{}">{inner}</a>"#,
+                escape(epub.tracked_link(link))
+            ),
+            None => inner.to_string(),
+        };
+
+        let mut item = String::from("<li>");
+        if let Some(ref cover) = self.cover {
+            item.push_str(&linked(&format!(
+                r#"<img class="also-by-cover" src="{}" alt="{title}"/>"#,
+                escape(&cover.filename()?)
+            )));
+        }
+        item.push_str(&format!(
+            r#"<p class="also-by-title">{}</p>"#,
+            linked(&title)
+        ));
+        if let Some(ref description) = self.description {
+            item.push_str(&format!(
+                r#"<p class="also-by-description">{}</p>"#,
+                escape(description)
+            ));
+        }
+        item.push_str("</li>");
+        Ok(item)
+    }
+}
+
+impl<'a> Epub<'a> {
+    /// Adds the "Also by" page at the end of the book, if set, and the cover thumbnails of its titles
+    /// to the resources.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::FilenameNotFound`] if a cover path has no filename.
+    pub(crate) fn insert_also_by(&mut self) -> crate::Result {
+        let Some((title, also_by)) = self.also_by.clone() else {
+            return Ok(());
+        };
+
+        let items = also_by
+            .iter()
+            .map(|also_by| also_by.as_list_item(self))
+            .collect::<crate::Result<String>>()?;
+
+        for cover in also_by.into_iter().filter_map(|also_by| also_by.cover) {
+            let filename = cover.filename()?;
+            let resources = self.resources.get_or_insert_with(Vec::new);
+            if !resources
+                .iter()
+                .any(|resource| resource.filename().is_ok_and(|added| added == filename))
+            {
+                resources.push(cover);
+            }
+        }
+
+        let body = format!(
+            r#"<body><h1>{}</h1><ul class="also-by">{items}</ul></body>"#,
+            escape(&title)
+        );
+        self.contents.get_or_insert_with(Vec::new).push(
+            ContentBuilder::new_owned(body, ReferenceType::Text(title))
+                .filename(ALSO_BY_FILENAME)
+                .build(),
+        );
+        Ok(())
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds a generated **"Also by" page** with the given title (e.g., `Also by Jane Doe`): the promotional
+    /// back matter listing other titles of the author, with their cover thumbnails and store links.
+    ///
+    /// The page is added at the end of the book as a [`ReferenceType::Text`].
+    pub fn also_by<S: Into<String>>(mut self, title: S, also_by: Vec<AlsoBy<'a>>) -> Self {
+        self.0.also_by = Some((title.into(), also_by));
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::epub::{ImageType, LinkTracking, MetadataBuilder};
+
+    #[test]
+    fn test_also_by() {
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("The Sea").build())
+            .add_resource(Resource::Image(Path::new("river.jpg"), ImageType::Jpg))
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .also_by(
+                "Also by Jane Doe",
+                vec![
+                    AlsoBy::new("The River")
+                        .cover(Resource::Image(
+                            Path::new("covers/river.jpg"),
+                            ImageType::Jpg,
+                        ))
+                        .link("https://example.com/store?id=1&format=epub"),
+                    AlsoBy::new("The Lake")
+                        .cover(Resource::Image(
+                            Path::new("covers/lake.png"),
+                            ImageType::Png,
+                        ))
+                        .description("A mystery on frozen waters."),
+                ],
+            )
+            .link_tracking(LinkTracking::new().parameter("ref", "sea"))
+            .0;
+        epub.insert_also_by().unwrap();
+
+        assert_eq!(epub.resources.as_ref().unwrap().len(), 2);
+
+        let contents = epub.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 2);
+        assert_eq!(contents[1].title(), "Also by Jane Doe");
+        assert_eq!(contents[1].filename(2), ALSO_BY_FILENAME);
+
+        let files = contents[1].file_content(&mut 1, "").unwrap();
+        let bytes = &files[0].bytes;
+        assert_eq!(
+            bytes
+                .matches(r#"<a href="https://example.com/store?id=1&amp;format=epub&amp;ref=sea">"#)
+                .count(),
+            2
+        );
+        assert!(bytes.contains(r#"<img class="also-by-cover" src="river.jpg" alt="The River"/>"#));
+        assert!(bytes.contains(r#"<img class="also-by-cover" src="lake.png" alt="The Lake"/>"#));
+        assert!(bytes.contains(r#"<p class="also-by-title">The Lake</p>"#));
+        assert!(
+            bytes.contains(r#"<p class="also-by-description">A mystery on frozen waters.</p>"#)
+        );
+    }
+}
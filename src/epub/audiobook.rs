@@ -0,0 +1,189 @@
+use std::{path::Path, time::Duration};
+
+use quick_xml::escape::escape;
+
+use crate::epub::{ContentBuilder, EpubBuilder, ImageType, Metadata, ReferenceType, Resource};
+
+/// The CSS rules used by the pages generated by [`AudiobookBuilder`].
+pub const AUDIOBOOK_CSS: &str = "audio.track { display: block; width: 100%; margin: 1em 0; }
+p.duration { font-style: italic; }";
+
+/// A single **track** of an audiobook: one chapter backed by one audio file.
+#[derive(Debug, Clone)]
+pub struct Track<'a> {
+    /// The title of the chapter, used in the navigation and its page.
+    title: String,
+    /// The path of the audio file.
+    path: &'a Path,
+    /// The playing time of the audio file.
+    duration: Duration,
+}
+
+impl<'a> Track<'a> {
+    /// Creates a track with the given chapter title, audio file and playing time.
+    pub fn new<S: Into<String>>(title: S, path: &'a Path, duration: Duration) -> Self {
+        Self {
+            title: title.into(),
+            path,
+            duration,
+        }
+    }
+
+    /// Builds the minimal page of the track: its title, duration and an audio element.
+    fn body(&self) -> String {
+        let src = Resource::Audio(self.path).filename().unwrap_or_default();
+        format!(
+            r#"<body><h1>{}</h1><p class="duration">{}</p><audio class="track" controls="controls" src="{}">{}</audio></body>"#,
+            escape(&self.title),
+            clock_value(self.duration),
+            escape(&src),
+            escape(&self.title)
+        )
+    }
+}
+
+/// A higher-level builder for **audio-first** books, where every chapter is an audio file.
+///
+/// Every [`Track`] becomes a generated page with its title and an audio element, and its audio file is
+/// added as a resource. The playing time of every track is shown on its page (the EPUB 2 package has no
+/// `media:duration`). Their `<audio>` elements are EPUB 3 markup, reported by the [`crate::epub::Linter`].
+/// Style the generated pages with [`AUDIOBOOK_CSS`].
+///
+/// ```rust
+/// use std::{path::Path, time::Duration};
+/// use liber::epub::{AudiobookBuilder, MetadataBuilder, Track};
+///
+/// let epub_builder = AudiobookBuilder::new(MetadataBuilder::title("The Novel").build())
+///     .add_track(Track::new("Chapter 1", Path::new("01.mp3"), Duration::from_secs(1520)))
+///     .add_track(Track::new("Chapter 2", Path::new("02.mp3"), Duration::from_secs(1735)))
+///     .build();
+/// ```
+#[derive(Debug, Clone)]
+pub struct AudiobookBuilder<'a> {
+    /// The metadata of the book.
+    metadata: Metadata,
+    /// Optional cover image of the book.
+    cover_image: Option<(&'a Path, ImageType)>,
+    /// The tracks of the book, in playing order.
+    tracks: Vec<Track<'a>>,
+}
+
+impl<'a> AudiobookBuilder<'a> {
+    /// Creates a builder for an audiobook with the given metadata.
+    pub fn new(metadata: Metadata) -> Self {
+        Self {
+            metadata,
+            cover_image: None,
+            tracks: Vec::new(),
+        }
+    }
+
+    /// Sets the **cover image** of the book.
+    pub fn cover_image(mut self, path: &'a Path, image_type: ImageType) -> Self {
+        self.cover_image = Some((path, image_type));
+        self
+    }
+
+    /// Adds a **track** to the book.
+    pub fn add_track(mut self, track: Track<'a>) -> Self {
+        self.tracks.push(track);
+        self
+    }
+
+    /// Adds several **tracks** to the book.
+    pub fn add_tracks(mut self, tracks: Vec<Track<'a>>) -> Self {
+        self.tracks.extend(tracks);
+        self
+    }
+
+    /// Gets the **total playing time** of the tracks.
+    pub fn duration(&self) -> Duration {
+        self.tracks.iter().map(|track| track.duration).sum()
+    }
+
+    /// Consumes the builder and returns an [`EpubBuilder`] with the generated pages and audio files,
+    /// which can be further configured (e.g., with a stylesheet) before creating the file.
+    pub fn build(self) -> EpubBuilder<'a> {
+        let mut epub_builder = EpubBuilder::new(self.metadata);
+
+        for track in self.tracks {
+            epub_builder = epub_builder
+                .add_content(
+                    ContentBuilder::new_owned(track.body(), ReferenceType::Text(track.title))
+                        .structure()
+                        .build(),
+                )
+                .add_resource(Resource::Audio(track.path));
+        }
+
+        if let Some((path, image_type)) = self.cover_image {
+            epub_builder = epub_builder.cover_image(path, image_type);
+        }
+
+        epub_builder
+    }
+}
+
+/// Formats a duration as a **SMIL clock value** (`h:mm:ss.fff`), as shown on the track pages.
+fn clock_value(duration: Duration) -> String {
+    let seconds = duration.as_secs();
+    format!(
+        "{}:{:02}:{:02}.{:03}",
+        seconds / 3600,
+        seconds / 60 % 60,
+        seconds % 60,
+        duration.subsec_millis()
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{MemoryFileSystem, MetadataBuilder};
+
+    #[test]
+    fn test_clock_value() {
+        assert_eq!(clock_value(Duration::ZERO), "0:00:00.000");
+        assert_eq!(clock_value(Duration::from_millis(3_723_450)), "1:02:03.450");
+    }
+
+    #[test]
+    fn test_audiobook_builder() {
+        let audiobook = AudiobookBuilder::new(MetadataBuilder::title("Novel").build())
+            .add_track(Track::new(
+                "Chapter 1",
+                Path::new("audio/01.mp3"),
+                Duration::from_secs(1800),
+            ))
+            .add_track(Track::new(
+                "Chapter 2",
+                Path::new("audio/02.mp3"),
+                Duration::from_secs(1830),
+            ));
+        assert_eq!(audiobook.duration(), Duration::from_secs(3630));
+
+        let epub_builder = audiobook.build().file_system(
+            MemoryFileSystem::new()
+                .file("audio/01.mp3", vec![0; 16])
+                .file("audio/02.mp3", vec![0; 16]),
+        );
+
+        let epub = &epub_builder.0;
+        assert_eq!(epub.contents.as_ref().unwrap().len(), 2);
+        assert_eq!(epub.resources.as_ref().unwrap().len(), 2);
+
+        let files = epub.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<p class="duration">0:30:00.000</p>"#)
+        );
+        assert!(files[0].bytes.contains(
+            r#"<audio class="track" controls="controls" src="01.mp3">Chapter 1</audio>"#
+        ));
+
+        assert!(epub_builder.create(&mut Vec::new()).is_ok());
+    }
+}
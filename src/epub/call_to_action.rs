@@ -0,0 +1,251 @@
+use std::fmt::Write;
+
+use quick_xml::escape::escape;
+
+use crate::epub::{Content, ContentBuilder, Epub, EpubBuilder, ReferenceType};
+
+/// The filename of the generated call-to-action page.
+pub const CALL_TO_ACTION_FILENAME: &str = "call-to-action.xhtml";
+
+/// A **call-to-action page** (e.g., a newsletter sign-up or a review request), generated at the end of the
+/// book (see [`EpubBuilder::call_to_action`]).
+///
+/// Its links carry the [`LinkTracking`] parameters of the book, so per-store variants of the same book
+/// (see [`crate::epub::Overrides::link_tracking`]) can be told apart in the analytics.
+///
+/// ```rust
+/// use liber::epub::{CallToAction, EpubBuilder, LinkTracking, MetadataBuilder};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("The Sea").build())
+///     .call_to_action(
+///         CallToAction::new("Join the newsletter")
+///             .text("Get a free short story and news about upcoming books.")
+///             .link("Sign up", "https://example.com/newsletter"),
+///     )
+///     .link_tracking(LinkTracking::utm("kobo", "ebook", "the-sea"));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CallToAction {
+    /// The title of the page.
+    title: String,
+    /// The paragraphs of the page.
+    paragraphs: Vec<String>,
+    /// The `(label, URL)` of the links.
+    links: Vec<(String, String)>,
+}
+
+impl CallToAction {
+    /// Creates a call-to-action page with the given **title**.
+    pub fn new<S: Into<String>>(title: S) -> Self {
+        Self {
+            title: title.into(),
+            paragraphs: Vec::new(),
+            links: Vec::new(),
+        }
+    }
+
+    /// Adds a **paragraph** of text, shown before the links.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn text<S: Into<String>>(mut self, text: S) -> Self {
+        self.paragraphs.push(text.into());
+        self
+    }
+
+    /// Adds a **link** with its label and URL.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn link<L: Into<String>, U: Into<String>>(mut self, label: L, url: U) -> Self {
+        self.links.push((label.into(), url.into()));
+        self
+    }
+}
+
+/// The **tracking parameters** (e.g., UTM parameters) appended to the query of the links of the generated
+/// call-to-action and "Also by" pages (see [`EpubBuilder::link_tracking`]).
+///
+/// ```rust
+/// use liber::epub::LinkTracking;
+///
+/// let link_tracking = LinkTracking::utm("apple books", "ebook", "the-sea").parameter("utm_content", "back");
+/// assert_eq!(
+///     link_tracking.apply("https://example.com/news?lang=en#signup"),
+///     "https://example.com/news?lang=en&utm_source=apple%20books&utm_medium=ebook&utm_campaign=the-sea&utm_content=back#signup"
+/// );
+/// ```
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct LinkTracking(Vec<(String, String)>);
+
+impl LinkTracking {
+    /// Creates empty tracking parameters.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Creates the **UTM parameters** `utm_source` (e.g., the store), `utm_medium` and `utm_campaign`.
+    pub fn utm<S: Into<String>, M: Into<String>, C: Into<String>>(
+        source: S,
+        medium: M,
+        campaign: C,
+    ) -> Self {
+        Self::new()
+            .parameter("utm_source", source)
+            .parameter("utm_medium", medium)
+            .parameter("utm_campaign", campaign)
+    }
+
+    /// Adds a **query parameter**, replacing any previous one with the same name.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn parameter<N: Into<String>, V: Into<String>>(mut self, name: N, value: V) -> Self {
+        let name = name.into();
+        self.0.retain(|(defined, _)| *defined != name);
+        self.0.push((name, value.into()));
+        self
+    }
+
+    /// Appends the parameters (percent-encoded) to the query of a URL, before its fragment.
+    #[must_use]
+    pub fn apply(&self, url: &str) -> String {
+        if self.0.is_empty() {
+            return url.to_string();
+        }
+
+        let (url, fragment) = match url.find('#') {
+            Some(position) => url.split_at(position),
+            None => (url, ""),
+        };
+        let mut result = url.to_string();
+        for (name, value) in &self.0 {
+            result.push(if result.contains('?') { '&' } else { '?' });
+            result.push_str(&percent_encode(name));
+            result.push('=');
+            result.push_str(&percent_encode(value));
+        }
+        result.push_str(fragment);
+        result
+    }
+}
+
+/// Percent-encodes a query component, keeping only the unreserved characters.
+fn percent_encode(component: &str) -> String {
+    let mut encoded = String::with_capacity(component.len());
+    for byte in component.bytes() {
+        if byte.is_ascii_alphanumeric() || matches!(byte, b'-' | b'.' | b'_' | b'~') {
+            encoded.push(char::from(byte));
+        } else {
+            let _ = write!(encoded, "%{byte:02X}");
+        }
+    }
+    encoded
+}
+
+impl<'a> Epub<'a> {
+    /// Gets a URL of a generated page with the tracking parameters of the book, if set.
+    pub(crate) fn tracked_link(&self, url: &str) -> String {
+        match self.link_tracking {
+            Some(ref link_tracking) => link_tracking.apply(url),
+            None => url.to_string(),
+        }
+    }
+
+    /// Generates the call-to-action page, if set.
+    pub(crate) fn call_to_action_content(&self) -> Option<Content<'a>> {
+        let call_to_action = self.call_to_action.as_ref()?;
+
+        let paragraphs = call_to_action
+            .paragraphs
+            .iter()
+            .map(|paragraph| format!("<p>{}</p>", escape(paragraph)))
+            .collect::<String>();
+        let links = call_to_action
+            .links
+            .iter()
+            .map(|(label, url)| {
+                format!(
+                    r#"<p class="call-to-action-link"><a href="{}">{}</a></p>"#,
+                    escape(self.tracked_link(url)),
+                    escape(label)
+                )
+            })
+            .collect::<String>();
+        let body = format!(
+            "<body><h1>{}</h1>{paragraphs}{links}</body>",
+            escape(&call_to_action.title)
+        );
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Text(call_to_action.title.clone()))
+                .filename(CALL_TO_ACTION_FILENAME)
+                .build(),
+        )
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds a generated **call-to-action page** (see [`CallToAction`]), at the end of the book as a
+    /// [`ReferenceType::Text`].
+    pub fn call_to_action(mut self, call_to_action: CallToAction) -> Self {
+        self.0.call_to_action = Some(call_to_action);
+        self
+    }
+
+    /// Sets the **tracking parameters** (see [`LinkTracking`]) appended to the links of the generated
+    /// call-to-action and "Also by" pages when the book is generated (e.g., the UTM parameters of a store).
+    pub fn link_tracking(mut self, link_tracking: LinkTracking) -> Self {
+        self.0.link_tracking = Some(link_tracking);
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::MetadataBuilder;
+
+    #[test]
+    fn test_link_tracking() {
+        let link_tracking = LinkTracking::utm("kobo", "ebook", "spring sale");
+        assert_eq!(
+            link_tracking.apply("https://example.com"),
+            "https://example.com?utm_source=kobo&utm_medium=ebook&utm_campaign=spring%20sale"
+        );
+        assert_eq!(
+            link_tracking
+                .parameter("utm_source", "B&N")
+                .apply("https://example.com/?a=1#top"),
+            "https://example.com/?a=1&utm_medium=ebook&utm_campaign=spring%20sale&utm_source=B%26N#top"
+        );
+        assert_eq!(
+            LinkTracking::new().apply("https://example.com"),
+            "https://example.com"
+        );
+    }
+
+    #[test]
+    fn test_call_to_action_content() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build());
+        assert!(builder.0.call_to_action_content().is_none());
+
+        let builder = builder
+            .call_to_action(
+                CallToAction::new("Stay in touch")
+                    .text("News & free stories.")
+                    .link("Sign up", "https://example.com/news"),
+            )
+            .link_tracking(LinkTracking::utm("kobo", "ebook", "title"));
+        let files = builder
+            .0
+            .call_to_action_content()
+            .unwrap()
+            .file_content(&mut 0, "")
+            .unwrap();
+
+        assert_eq!(files[0].filepath, "OEBPS/call-to-action.xhtml");
+        assert!(files[0].bytes.contains("<p>News &amp; free stories.</p>"));
+        assert!(files[0].bytes.contains(
+            r#"<a href="https://example.com/news?utm_source=kobo&amp;utm_medium=ebook&amp;utm_campaign=title">Sign up</a>"#
+        ));
+    }
+}
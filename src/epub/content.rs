@@ -1,14 +1,21 @@
 use std::borrow::Cow;
 
+#[cfg(any(test, feature = "async"))]
+use crate::output::file_content::FileContent;
 use crate::{
-    epub::ContentReference,
-    output::{file_content::FileContent, xml},
+    epub::{ContentReference, IdGenerator, Language, Transcript, slug::Slugs, slugify, transcript},
+    markup::{self, Footnotes},
+    output::xml,
 };
 
 /// Defines the **semantically meaningful type** and **display title** for a piece of content.
 ///
 /// Each variant carries a `String` which serves as the **display title** (e.g., "Chapter 1", "Glossary").
 /// The variant name itself maps to a machine-readable type string (e.g., `toc`, `foreword`).
+///
+/// Every content is listed with its type in the `<guide>` of the `content.opf`, which is the EPUB 2
+/// (the only version generated) equivalent of the EPUB 3 landmarks: reading systems find the cover,
+/// the table of contents or the start of reading there, without a `<nav epub:type="landmarks">`.
 #[derive(Debug, Clone)]
 pub enum ReferenceType {
     /// Content preceding the main text, like a thank you section.
@@ -41,6 +48,8 @@ pub enum ReferenceType {
     Preface(String),
     /// The main, continuous textual content of the book.
     Text(String),
+    /// A list of the characters of a work of fiction or drama.
+    DramatisPersonae(String),
     /// The dedicated title page content.
     TitlePage(String),
     /// The Table of Contents (TOC).
@@ -68,10 +77,78 @@ impl ReferenceType {
             Self::Notes(s) => ("notes", s),
             Self::Preface(s) => ("preface", s),
             Self::Text(s) => ("text", s),
+            Self::DramatisPersonae(s) => ("other.dramatis-personae", s),
             Self::TitlePage(s) => ("title-page", s),
             Self::Toc(s) => ("toc", s),
         }
     }
+
+    /// Retrieves the **structural semantics** of the generated pages of this type: the `epub:type` and the
+    /// DPUB-ARIA `role` (e.g., `("index", "doc-index")`).
+    ///
+    /// Returns `None` for the types without a DPUB-ARIA role.
+    pub(crate) fn structure(&self) -> Option<(&'static str, &'static str)> {
+        match self {
+            Self::Acknowledgements(_) => Some(("acknowledgments", "doc-acknowledgments")),
+            Self::Bibliography(_) => Some(("bibliography", "doc-bibliography")),
+            Self::Colophon(_) => Some(("colophon", "doc-colophon")),
+            Self::Dedication(_) => Some(("dedication", "doc-dedication")),
+            Self::Epigraph(_) => Some(("epigraph", "doc-epigraph")),
+            Self::Foreword(_) => Some(("foreword", "doc-foreword")),
+            Self::Glossary(_) => Some(("glossary", "doc-glossary")),
+            Self::Index(_) => Some(("index", "doc-index")),
+            Self::Notes(_) => Some(("endnotes", "doc-endnotes")),
+            Self::Preface(_) => Some(("preface", "doc-preface")),
+            Self::Text(_) => Some(("chapter", "doc-chapter")),
+            Self::Toc(_) => Some(("toc", "doc-toc")),
+            _ => None,
+        }
+    }
+
+    /// Gets a mutable reference to the **display title**.
+    pub(crate) fn title_mut(&mut self) -> &mut String {
+        match self {
+            Self::Acknowledgements(s)
+            | Self::Bibliography(s)
+            | Self::Colophon(s)
+            | Self::Copyright(s)
+            | Self::Cover(s)
+            | Self::Dedication(s)
+            | Self::Epigraph(s)
+            | Self::Foreword(s)
+            | Self::Glossary(s)
+            | Self::Index(s)
+            | Self::Loi(s)
+            | Self::Lot(s)
+            | Self::Notes(s)
+            | Self::Preface(s)
+            | Self::Text(s)
+            | Self::DramatisPersonae(s)
+            | Self::TitlePage(s)
+            | Self::Toc(s) => s,
+        }
+    }
+}
+
+/// The **division of the book** a content belongs to, in conventional reading order.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Matter {
+    /// Front matter (e.g., title page, dedication, preface).
+    Front,
+    /// Body matter: the main text.
+    Body,
+    /// Back matter (e.g., appendices, glossary, index).
+    Back,
+}
+
+impl AsRef<str> for Matter {
+    fn as_ref(&self) -> &str {
+        match self {
+            Self::Front => "frontmatter",
+            Self::Body => "bodymatter",
+            Self::Back => "backmatter",
+        }
+    }
 }
 
 /// Represents a single hierarchical content unit within a document structure.
@@ -80,8 +157,8 @@ impl ReferenceType {
 /// and reference other content units via `content_references`.
 #[derive(Debug, Clone)]
 pub struct Content<'a> {
-    /// A byte slice containing the raw body of the content (assumed to be XHTML fragments).
-    body: &'a [u8],
+    /// The raw body of the content (assumed to be XHTML fragments), borrowed or owned (e.g., generated pages).
+    body: Cow<'a, [u8]>,
     /// The semantic type and display title of this content unit.
     pub(crate) reference_type: ReferenceType,
     /// An optional vector of children, enabling hierarchical (chapter/section) nesting.
@@ -90,20 +167,150 @@ pub struct Content<'a> {
     pub(crate) content_references: Option<Vec<ContentReference>>,
     /// An optional, user-defined filename. If `None`, a sequential name is generated.
     filename: Option<String>,
+    /// Whether the display title is taken from the first `<h1>`/`<h2>` of the body.
+    title_from_heading: bool,
+    /// Optional attributes (e.g., `class`, `xml:lang`, `dir`) set on the `<body>` element.
+    body_attributes: Option<Vec<(String, String)>>,
+    /// Optional notes registered for the generated endnotes page.
+    pub(crate) notes: Option<Footnotes>,
+    /// Optional language of the content, set as the `xml:lang` of the `<body>` element.
+    language: Option<Language>,
+    /// Whether the language is detected from the body when it is not set.
+    detect_language: bool,
+    /// Whether this content is where reading starts (the `text` guide reference).
+    pub(crate) start_reading: bool,
+    /// Whether this content is the preview excerpt of the book (the `other.preview` guide reference).
+    pub(crate) preview: bool,
+    /// Whether this content is part of the linear reading order. Defaults to `true`.
+    pub(crate) linear: bool,
+    /// Optional transcripts of the audio and video embedded in the body.
+    pub(crate) transcripts: Option<Vec<Transcript<'a>>>,
+    /// Optional key placing this content (and its subcontents) in the spine, independently of the tree.
+    pub(crate) spine_order: Option<i32>,
+    /// Whether this content is only a navigation entry pointing into another content (its filename,
+    /// or its first subcontent if it has no filename).
+    pub(crate) link: bool,
+    /// Optional division of the book this content (and its subcontents) belongs to.
+    pub(crate) matter: Option<Matter>,
 }
 
 impl<'a> Content<'a> {
     /// Creates a new `Content` instance with mandatory fields and uninitialized optional fields.
-    fn new(body: &'a [u8], reference_type: ReferenceType) -> Self {
+    fn new(body: Cow<'a, [u8]>, reference_type: ReferenceType) -> Self {
         Self {
             body,
             reference_type,
             subcontents: None,
             content_references: None,
             filename: None,
+            title_from_heading: false,
+            body_attributes: None,
+            notes: None,
+            language: None,
+            detect_language: false,
+            start_reading: false,
+            preview: false,
+            linear: true,
+            transcripts: None,
+            spine_order: None,
+            link: false,
+            matter: None,
         }
     }
 
+    /// Recursively checks this content unit and all subcontents for obvious mistakes:
+    /// an empty title, a custom filename not ending with `.xhtml`, a body that is not valid UTF-8
+    /// (or with malformed ruby markup or an invalid remote media URL) or a content reference without title
+    /// or pointing to an id missing from the body (generated `idNN` ids included).
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] describing the first invalid content found.
+    pub(crate) fn validate(&self) -> crate::Result {
+        let title = self.title();
+        if title.trim().is_empty() {
+            return Err(crate::Error::Validation(
+                "content title must not be empty".to_string(),
+            ));
+        }
+
+        if self.link
+            && self.filename.is_none()
+            && self.subcontents.as_ref().is_none_or(Vec::is_empty)
+        {
+            return Err(crate::Error::Validation(format!(
+                "heading '{title}' has no subcontent to point to"
+            )));
+        }
+
+        if let Some(ref filename) = self.filename
+            && !self.link
+            && (!filename.ends_with(".xhtml") || filename.contains('/'))
+        {
+            return Err(crate::Error::Validation(format!(
+                "content '{title}' has an invalid filename '{filename}'"
+            )));
+        }
+
+        let Ok(body) = std::str::from_utf8(&self.body) else {
+            return Err(crate::Error::Validation(format!(
+                "content '{title}' has a body that is not valid UTF-8"
+            )));
+        };
+
+        if let Err(crate::Error::Validation(message)) = markup::validate_ruby(body) {
+            return Err(crate::Error::Validation(format!(
+                "content '{title}' has invalid {message}"
+            )));
+        }
+
+        if let Some(url) = xml::remote_media(body)
+            .into_iter()
+            .find(|url| !is_valid_url(url))
+        {
+            return Err(crate::Error::Validation(format!(
+                "content '{title}' has an invalid remote media URL '{url}'"
+            )));
+        }
+
+        if self
+            .content_references
+            .iter()
+            .flatten()
+            .any(|content_reference| !content_reference.has_titles())
+        {
+            return Err(crate::Error::Validation(format!(
+                "content '{title}' has a content reference without title"
+            )));
+        }
+
+        if !self.link {
+            let ids = xml::ids(body);
+            let mut link_number = 0;
+            if let Some((reference, anchor_id)) =
+                self.content_references
+                    .iter()
+                    .flatten()
+                    .find_map(|content_reference| {
+                        content_reference.missing_anchor(&mut link_number, &ids)
+                    })
+            {
+                return Err(crate::Error::Validation(format!(
+                    "content '{title}' has no element with id '{anchor_id}' for the content reference '{reference}'"
+                )));
+            }
+        }
+
+        self.subcontents
+            .iter()
+            .flatten()
+            .try_for_each(Content::validate)
+    }
+
+    /// Checks whether the body of this content has an element named `name` (e.g., `iframe`).
+    pub(crate) fn has_element(&self, name: &str) -> bool {
+        std::str::from_utf8(&self.body).is_ok_and(|body| xml::has_element(body, name))
+    }
+
     /// Recursively calculates the maximum nesting depth of **subcontents**.
     ///
     /// Returns `0` for leaf nodes.
@@ -113,6 +320,21 @@ impl<'a> Content<'a> {
             .map_or(0, |subcontents| 1 + subcontents[0].level())
     }
 
+    /// Recursively calculates the maximum nesting depth of the **content references** of this content and
+    /// its subcontents, for a flattened navigation.
+    pub(crate) fn level_references_flat(&self) -> usize {
+        let content_references_level = self
+            .content_references
+            .as_ref()
+            .map_or(0, |content_references| 1 + content_references[0].level());
+
+        self.subcontents
+            .iter()
+            .flatten()
+            .map(Content::level_references_flat)
+            .fold(content_references_level, usize::max)
+    }
+
     /// Recursively calculates the maximum nesting depth considering both **subcontents** and **content references**.
     ///
     /// This is typically used for determining the necessary depth of the final document structure (e.g., NCX/TOC).
@@ -129,33 +351,123 @@ impl<'a> Content<'a> {
         content_references_level.max(subcontents_cont_ref_level)
     }
 
+    /// Recursively checks this content unit and all subcontents, collecting **every** problem
+    /// (invalid filename, invalid UTF-8 body, malformed XHTML) instead of stopping at the first one.
+    ///
+    /// # Arguments
+    /// * `number`: A mutable counter to generate sequential filenames.
+    /// * `buffer`: A buffer reused to format every XHTML file.
+    /// * `errors`: The vector receiving the errors found.
+    pub(crate) fn collect_errors(
+        &self,
+        number: &mut usize,
+        buffer: &mut Vec<u8>,
+        errors: &mut Vec<crate::Error>,
+    ) {
+        *number += 1;
+
+        let filename = self.filename(*number);
+        if !filename.ends_with(".xhtml") {
+            errors.push(crate::Error::ContentFilename(filename.into_owned()));
+        }
+
+        if let Err(e) = std::str::from_utf8(&self.body)
+            .map_err(crate::Error::from)
+            .and_then(|body| xml::format_into(&self.xhtml(body, ""), buffer))
+        {
+            errors.push(self.context_error(*number, e));
+        }
+
+        if let Some(ref subcontents) = self.subcontents {
+            for content in subcontents {
+                content.collect_errors(number, buffer, errors);
+            }
+        }
+    }
+
+    /// Gets a **size hint** in bytes of the bodies of this content unit and all its subcontents.
+    pub(crate) fn size_hint(&self) -> u64 {
+        self.body.len() as u64
+            + self.subcontents.as_ref().map_or(0, |subcontents| {
+                subcontents.iter().map(Content::size_hint).sum()
+            })
+    }
+
+    /// Recursively serializes this content unit and all subcontents, handing every XHTML file to `write`.
+    ///
+    /// Each file is formatted into the reusable `buffer` and written right away, so only one
+    /// formatted file is held in memory at a time, regardless of the size of the book.
+    ///
+    /// # Arguments
+    /// * `number`: A mutable counter to generate sequential filenames.
+    /// * `head`: The markup added to the `<head>` of every XHTML file (e.g., the stylesheet link).
+    /// * `buffer`: A buffer reused to format every XHTML file.
+    /// * `write`: A callback receiving the file path and the formatted bytes of each file.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Content`] naming the content if the body is not valid UTF-8 or if XML
+    /// formatting fails, or the error returned by `write`.
+    pub(crate) fn write_files<F>(
+        &self,
+        number: &mut usize,
+        head: &str,
+        buffer: &mut Vec<u8>,
+        write: &mut F,
+    ) -> crate::Result
+    where
+        F: FnMut(String, &[u8]) -> crate::Result,
+    {
+        *number += 1;
+        if !self.link {
+            let filepath = format!("OEBPS/{}", self.filename(*number));
+
+            std::str::from_utf8(&self.body)
+                .map_err(crate::Error::from)
+                .and_then(|body| xml::format_into(&self.xhtml(body, head), buffer))
+                .map_err(|e| self.context_error(*number, e))?;
+            write(filepath, buffer)?;
+        }
+
+        if let Some(ref subcontents) = self.subcontents {
+            for content in subcontents {
+                content.write_files(number, head, buffer, write)?;
+            }
+        }
+        Ok(())
+    }
+
     /// Recursively converts this content unit and all subcontents into a vector of [`FileContent`] structs.
     ///
     /// This handles serialization to final XHTML files and assigns sequential filenames.
     ///
     /// # Arguments
     /// * `number`: A mutable counter to generate sequential filenames.
-    /// * `add_stylesheet`: Flag to include a CSS link in the generated XHTML header.
+    /// * `head`: The markup added to the `<head>` of every XHTML file (e.g., the stylesheet link).
     ///
     /// # Errors
     /// Returns a [`crate::Result`] if the body is not valid UTF-8 or if XML formatting fails.
+    #[cfg(any(test, feature = "async"))]
     pub(crate) fn file_content(
         &self,
         number: &mut usize,
-        add_stylesheet: bool,
+        head: &str,
     ) -> crate::Result<Vec<FileContent<String, String>>> {
         *number += 1;
         let filepath = format!("OEBPS/{}", self.filename(*number));
         let mut file_contents = Vec::new();
 
-        let xhtml_content =
-            xml::format(&self.xhtml(std::str::from_utf8(self.body)?, add_stylesheet))?;
+        if !self.link {
+            let xhtml_content = std::str::from_utf8(&self.body)
+                .map_err(crate::Error::from)
+                .and_then(|body| xml::format(&self.xhtml(body, head)))
+                .map_err(|e| self.context_error(*number, e))?;
 
-        file_contents.push(FileContent::new(filepath, xhtml_content));
+            file_contents.push(FileContent::new(filepath, xhtml_content));
+        }
 
         if let Some(ref subcontents) = self.subcontents {
             for content in subcontents {
-                let contents = content.file_content(number, add_stylesheet)?;
+                let contents = content.file_content(number, head)?;
                 file_contents.extend(contents);
             }
         }
@@ -169,23 +481,25 @@ impl<'a> Content<'a> {
     pub(crate) async fn async_file_content(
         &self,
         number: &mut usize,
-        add_stylesheet: bool,
+        head: &str,
     ) -> crate::Result<Vec<FileContent<String, String>>> {
         *number += 1;
         let filepath = format!("OEBPS/{}", self.filename(*number));
         let mut file_contents = Vec::new();
 
-        let xhtml_content = xml::async_format(
-            self.xhtml(std::str::from_utf8(self.body)?, add_stylesheet)
-                .into_owned(),
-        )
-        .await?;
+        if !self.link {
+            let body = std::str::from_utf8(&self.body)
+                .map_err(|e| self.context_error(*number, e.into()))?;
+            let xhtml_content = xml::async_format(self.xhtml(body, head).into_owned())
+                .await
+                .map_err(|e| self.context_error(*number, e))?;
 
-        file_contents.push(FileContent::new(filepath.to_string(), xhtml_content));
+            file_contents.push(FileContent::new(filepath.to_string(), xhtml_content));
+        }
 
         if let Some(ref subcontents) = self.subcontents {
             for content in subcontents {
-                let contents = content.file_content(number, add_stylesheet)?;
+                let contents = content.file_content(number, head)?;
                 file_contents.extend(contents);
             }
         }
@@ -198,49 +512,385 @@ impl<'a> Content<'a> {
     pub(crate) fn filename(&self, number: usize) -> Cow<'_, str> {
         if let Some(ref filename) = self.filename {
             Cow::Borrowed(filename)
+        } else if let Some(first) = self
+            .subcontents
+            .as_deref()
+            .and_then(<[Content]>::first)
+            .filter(|_| self.link)
+        {
+            first.filename(number + 1)
         } else {
             Cow::Owned(format!("c{number:02}.xhtml"))
         }
     }
 
+    /// Wraps an error with the title and filename of this content unit, so the failing content can be identified.
+    fn context_error(&self, number: usize, error: crate::Error) -> crate::Error {
+        crate::Error::Content(
+            self.title().to_string(),
+            self.filename(number).into_owned(),
+            Box::new(error),
+        )
+    }
+
     /// Gets the display title of this content unit from its `ReferenceType`.
-    pub(crate) fn title(&self) -> &str {
-        self.reference_type.type_and_title().1
+    ///
+    /// If the title is taken from the heading, the text of the first `<h1>`/`<h2>` of the body
+    /// is used instead, falling back to the `ReferenceType` title if there is no heading.
+    pub(crate) fn title(&self) -> Cow<'_, str> {
+        let heading = if self.title_from_heading {
+            std::str::from_utf8(&self.body)
+                .ok()
+                .and_then(xml::first_heading)
+        } else {
+            None
+        };
+
+        heading.map_or_else(
+            || Cow::Borrowed(self.reference_type.type_and_title().1),
+            Cow::Owned,
+        )
     }
 
-    /// Wraps the content body and necessary boilerplate into a complete XHTML 1.1 document string.
-    fn xhtml(&self, text: &'a str, add_stylesheet: bool) -> Cow<'a, str> {
-        if !text.starts_with(r#"<?xml version="1.0" encoding="utf-8"?>"#) {
-            let stylesheet = if add_stylesheet {
-                r#"<link href="style.css" rel="stylesheet" type="text/css"/>"#
-            } else {
-                ""
-            };
+    /// Prefixes the display title (e.g., with its number), freezing a title taken from the heading.
+    pub(crate) fn prefix_title(&mut self, prefix: &str) {
+        let title = format!("{prefix}{}", self.title());
+        *self.reference_type.title_mut() = title;
+        self.title_from_heading = false;
+    }
+
+    /// Recursively calls `f` with the filename and body of this content and all subcontents, in reading order.
+    ///
+    /// Bodies that are not valid UTF-8 are skipped.
+    pub(crate) fn for_each_body<F>(&self, number: &mut usize, f: &mut F)
+    where
+        F: FnMut(&str, &str),
+    {
+        *number += 1;
+        if let Ok(body) = std::str::from_utf8(&self.body) {
+            f(&self.filename(*number), body);
+        }
+
+        for content in self.subcontents.iter().flatten() {
+            content.for_each_body(number, f);
+        }
+    }
+
+    /// Recursively rewrites the body of this content and all subcontents, in reading order.
+    ///
+    /// `rewrite` receives the filename, title and body of every content, returning the new body
+    /// or `None` to keep it.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Content`] naming the content if a body is not valid UTF-8
+    /// or `rewrite` fails.
+    pub(crate) fn rewrite_bodies<F>(&mut self, number: &mut usize, rewrite: &mut F) -> crate::Result
+    where
+        F: FnMut(&str, &str, &str) -> crate::Result<Option<String>>,
+    {
+        *number += 1;
+
+        let filename = self.filename(*number).into_owned();
+        let rewritten = std::str::from_utf8(&self.body)
+            .map_err(crate::Error::from)
+            .and_then(|body| rewrite(&filename, &self.title(), body))
+            .map_err(|e| self.context_error(*number, e))?;
+        if let Some(rewritten) = rewritten {
+            self.body = Cow::Owned(rewritten.into_bytes());
+        }
+
+        if let Some(ref mut subcontents) = self.subcontents {
+            for content in subcontents {
+                content.rewrite_bodies(number, rewrite)?;
+            }
+        }
+        Ok(())
+    }
+
+    /// Recursively registers the explicit filenames of this content and all subcontents in `slugs`.
+    pub(crate) fn reserve_filenames(&self, slugs: &mut Slugs) {
+        if let Some(ref filename) = self.filename
+            && !self.link
+        {
+            slugs.reserve(filename.clone());
+        }
+
+        for content in self.subcontents.iter().flatten() {
+            content.reserve_filenames(slugs);
+        }
+    }
+
+    /// Recursively sets the filename of this content and all subcontents without an explicit filename
+    /// to the unique slug of their title, numbering the files with `number`.
+    ///
+    /// Link contents and contents whose title has no slug keep their filename.
+    pub(crate) fn assign_slug_filenames(&mut self, number: &mut usize, slugs: &mut Slugs) {
+        *number += 1;
+
+        if self.filename.is_none() && !self.link {
+            let slug = slugify(&self.title());
+            if !slug.is_empty() {
+                self.filename = Some(slugs.unique(&slug, ".xhtml"));
+            }
+        }
+
+        for content in self.subcontents.iter_mut().flatten() {
+            content.assign_slug_filenames(number, slugs);
+        }
+    }
+
+    /// Recursively sets the anchor ID of every content reference without a custom `id` (see [`IdGenerator`]),
+    /// in this content and all subcontents, numbering the files with `number`.
+    pub(crate) fn assign_anchor_ids(&mut self, number: &mut usize, ids: &dyn IdGenerator) {
+        *number += 1;
+
+        let filename = self.filename(*number).into_owned();
+        let mut slugs = Slugs::default();
+        for content_reference in self.content_references.iter().flatten() {
+            content_reference.reserve_ids(&mut slugs);
+        }
+
+        let mut link_number = 0;
+        for content_reference in self.content_references.iter_mut().flatten() {
+            content_reference.assign_ids(&filename, &mut link_number, ids, &mut slugs);
+        }
+
+        for content in self.subcontents.iter_mut().flatten() {
+            content.assign_anchor_ids(number, ids);
+        }
+    }
+
+    /// Recursively appends the `footer` markup to the body of this content and all subcontents of type
+    /// [`ReferenceType::Copyright`], before the closing `</body>` tag.
+    ///
+    /// Returns `true` if any body was stamped.
+    pub(crate) fn stamp_copyright_footer(&mut self, footer: &str) -> bool {
+        let mut stamped = false;
+        if matches!(self.reference_type, ReferenceType::Copyright(_))
+            && let Ok(body) = std::str::from_utf8(&self.body)
+        {
+            let position = body.rfind("</body>").unwrap_or(body.len());
+            self.body = Cow::Owned(
+                format!("{}{footer}{}", &body[..position], &body[position..]).into_bytes(),
+            );
+            stamped = true;
+        }
+
+        for content in self.subcontents.iter_mut().flatten() {
+            stamped |= content.stamp_copyright_footer(footer);
+        }
+        stamped
+    }
+
+    /// Recursively links the media of this content and all subcontents to their transcripts, appending
+    /// the generated transcript pages (numbered by `transcript_number`) after the subcontents.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Content`] naming the content if a body is not valid UTF-8
+    /// or has no media element for one of its transcripts.
+    pub(crate) fn link_transcripts(
+        &mut self,
+        number: &mut usize,
+        transcript_number: &mut usize,
+    ) -> crate::Result {
+        *number += 1;
+        let own_number = *number;
+
+        if let Some(ref mut subcontents) = self.subcontents {
+            for content in subcontents {
+                content.link_transcripts(number, transcript_number)?;
+            }
+        }
 
+        let Some(ref transcripts) = self.transcripts else {
+            return Ok(());
+        };
+
+        let filename = self.filename(own_number).into_owned();
+        let mut body = std::str::from_utf8(&self.body)
+            .map_err(|e| self.context_error(own_number, e.into()))?
+            .to_string();
+        let mut pages = Vec::new();
+        for transcript in transcripts {
+            *transcript_number += 1;
+            let transcript_filename = transcript::transcript_filename(*transcript_number);
+            body = transcript
+                .link(&body, *transcript_number, &transcript_filename)
+                .map_err(|e| self.context_error(own_number, e))?;
+            pages.push(transcript.content(transcript_filename, &filename));
+        }
+
+        *number += pages.len();
+        self.body = Cow::Owned(body.into_bytes());
+        self.subcontents.get_or_insert_with(Vec::new).extend(pages);
+        Ok(())
+    }
+
+    /// Returns `true` if this content or any subcontent satisfies the predicate.
+    pub(crate) fn any<F: Fn(&Content<'_>) -> bool>(&self, predicate: &F) -> bool {
+        predicate(self)
+            || self
+                .subcontents
+                .iter()
+                .flatten()
+                .any(|content| content.any(predicate))
+    }
+
+    /// Recursively counts the contents (this one and all subcontents) marked as the start of reading.
+    pub(crate) fn start_reading_count(&self) -> usize {
+        usize::from(self.start_reading)
+            + self
+                .subcontents
+                .iter()
+                .flatten()
+                .map(Content::start_reading_count)
+                .sum::<usize>()
+    }
+
+    /// Recursively counts the contents (this one and all subcontents) marked as the preview excerpt.
+    pub(crate) fn preview_count(&self) -> usize {
+        usize::from(self.preview)
+            + self
+                .subcontents
+                .iter()
+                .flatten()
+                .map(Content::preview_count)
+                .sum::<usize>()
+    }
+
+    /// Recursively finds the filename of the first content (this one or any subcontent) satisfying the predicate.
+    pub(crate) fn find_filename<F: Fn(&Content<'_>) -> bool>(
+        &self,
+        file_number: &mut usize,
+        predicate: &F,
+    ) -> Option<String> {
+        *file_number += 1;
+        if predicate(self) {
+            return Some(self.filename(*file_number).into_owned());
+        }
+        self.subcontents
+            .iter()
+            .flatten()
+            .find_map(|content| content.find_filename(file_number, predicate))
+    }
+
+    /// Recursively collects the `(title, word count)` of this content and all subcontents, in reading order.
+    pub(crate) fn collect_word_counts(&self, word_counts: &mut Vec<(String, usize)>) {
+        let words = std::str::from_utf8(&self.body)
+            .map(xml::word_count)
+            .unwrap_or_default();
+        word_counts.push((self.title().into_owned(), words));
+
+        for content in self.subcontents.iter().flatten() {
+            content.collect_word_counts(word_counts);
+        }
+    }
+
+    /// Gets the `(level, text)` of the headings of the body, in document order.
+    pub(crate) fn headings(&self) -> Vec<(u8, String)> {
+        std::str::from_utf8(&self.body)
+            .map(xml::headings)
+            .unwrap_or_default()
+    }
+
+    /// Gets the `(id, label)` of the print page markers of the body, in document order.
+    ///
+    /// See [`crate::markup::PageMarker`].
+    pub(crate) fn page_markers(&self) -> Vec<(String, String)> {
+        std::str::from_utf8(&self.body)
+            .map(xml::page_markers)
+            .unwrap_or_default()
+    }
+
+    /// Gets the byte position of the element with the given `id` in the body, if any.
+    pub(crate) fn anchor_position(&self, id: &str) -> Option<usize> {
+        let id = format!(r#"id="{id}""#);
+        self.body
+            .windows(id.len())
+            .position(|window| window == id.as_bytes())
+    }
+
+    /// Wraps the content body and necessary boilerplate into a complete XHTML 1.1 document string.
+    ///
+    /// The `head` markup is added to the `<head>` element and the body attributes, if any, to the `<body>` element.
+    fn xhtml<'t>(&self, text: &'t str, head: &str) -> Cow<'t, str> {
+        let xhtml = if !text.starts_with(r#"<?xml version="1.0" encoding="utf-8"?>"#) {
             Cow::Owned(format!(
                 r#"<?xml version="1.0" encoding="utf-8"?><!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
             <html xmlns="http://www.w3.org/1999/xhtml"><head><title>{}</title>{}</head>{}</html>"#,
                 self.title(),
-                stylesheet,
+                head,
                 text
             ))
         } else {
             Cow::Borrowed(text)
+        };
+
+        let mut attributes = Vec::new();
+        if let Some(language) = self.language(text) {
+            attributes.push(("xml:lang".to_string(), language.as_ref().to_string()));
         }
+        attributes.extend(self.body_attributes.iter().flatten().cloned());
+
+        match xml::add_attributes(&xhtml, "body", &attributes) {
+            Cow::Owned(with_attributes) => Cow::Owned(with_attributes),
+            Cow::Borrowed(_) => xhtml,
+        }
+    }
+
+    /// Gets the language of this content unit: the one set or, if enabled, the one detected from the body.
+    fn language(&self, body: &str) -> Option<Language> {
+        self.language.clone().or_else(|| {
+            self.detect_language
+                .then(|| Language::detect(&xml::strip_tags(body)))
+                .flatten()
+        })
     }
 }
 
 /// A builder for creating and configuring hierarchical [`Content`] structures.
 ///
 /// This provides a **fluent interface** to manage children and references.
-#[derive(Debug)]
+/// The builder can be cloned to reuse a partially configured content as a template.
+#[derive(Debug, Clone)]
 pub struct ContentBuilder<'a>(Content<'a>);
 
 impl<'a> ContentBuilder<'a> {
     /// Creates a new builder instance, initializing the content with the raw body and required type.
     #[must_use]
     pub fn new(body: &'a [u8], reference_type: ReferenceType) -> Self {
-        Self(Content::new(body, reference_type))
+        Self(Content::new(Cow::Borrowed(body), reference_type))
+    }
+
+    /// Creates a **navigation-only entry** pointing into another content, given its filename and an
+    /// optional fragment (e.g., `maps.xhtml` or `maps.xhtml#north`).
+    ///
+    /// The entry appears in the TOC (and the guide) where it is placed in the tree, while the target file
+    /// is written and added to the spine only once, so the same file can be reached from different places
+    /// of the hierarchy (e.g., "Maps" under both the front matter and an appendix).
+    #[must_use]
+    pub fn link<S: Into<String>>(reference_type: ReferenceType, target: S) -> Self {
+        let mut content = Content::new(Cow::Borrowed(b""), reference_type);
+        content.filename = Some(target.into());
+        content.link = true;
+        Self(content)
+    }
+
+    /// Creates a **heading-only entry**, a purely structural level of the TOC (e.g., "Part One")
+    /// without its own file, pointing to its first subcontent.
+    ///
+    /// Its children are added as usual, with [`ContentBuilder::add_child`] or [`ContentBuilder::add_children`].
+    #[must_use]
+    pub fn heading(reference_type: ReferenceType) -> Self {
+        let mut content = Content::new(Cow::Borrowed(b""), reference_type);
+        content.link = true;
+        Self(content)
+    }
+
+    /// Creates a new builder instance from an **owned** body, such as a generated or downloaded page.
+    #[must_use]
+    pub fn new_owned<B: Into<Vec<u8>>>(body: B, reference_type: ReferenceType) -> Self {
+        Self(Content::new(Cow::Owned(body.into()), reference_type))
     }
 
     /// Adds a single [`Content`] unit as a **child** (subcontent) of the current unit.
@@ -291,10 +941,155 @@ impl<'a> ContentBuilder<'a> {
         self
     }
 
+    /// Sets an **attribute on the generated `<body>` element** (e.g., `class`, `id`, `epub:type`, `xml:lang` or `dir`),
+    /// as needed by CSS theming and accessibility.
+    ///
+    /// The `epub` namespace is declared automatically when an `epub:` attribute is set.
+    pub fn body_attribute<N: Into<String>, V: Into<String>>(mut self, name: N, value: V) -> Self {
+        let attribute = (name.into(), value.into());
+        if let Some(ref mut body_attributes) = self.0.body_attributes {
+            body_attributes.push(attribute);
+        } else {
+            self.0.body_attributes = Some(vec![attribute]);
+        }
+        self
+    }
+
+    /// Stamps the **structural semantics** of the reference type (see [`ReferenceType::structure`]) on the
+    /// `<body>` element: its `epub:type` and DPUB-ARIA `role`. Used by the generated pages.
+    pub(crate) fn structure(self) -> Self {
+        match self.0.reference_type.structure() {
+            Some((epub_type, role)) => self
+                .body_attribute("epub:type", epub_type)
+                .body_attribute("role", role),
+            None => self,
+        }
+    }
+
+    /// Registers the **notes** of this content (created with [`Footnotes::endnotes`]), to be collected
+    /// into the endnotes page enabled with [`crate::epub::EpubBuilder::endnotes`].
+    pub fn notes(mut self, notes: Footnotes) -> Self {
+        self.0.notes = Some(notes);
+        self
+    }
+
+    /// Sets the **language** of this content when it differs from the book's primary language
+    /// (e.g., in multilingual anthologies), as the `xml:lang` of the `<body>` element.
+    ///
+    /// Screen readers and hyphenation engines use it to pronounce and break the text correctly.
+    pub fn language(mut self, language: Language) -> Self {
+        self.0.language = Some(language);
+        self
+    }
+
+    /// **Detects** the language of this content from its body (see [`Language::detect`]) when it is not set
+    /// with [`ContentBuilder::language`]. Nothing is set if the language cannot be told.
+    pub fn detect_language(mut self) -> Self {
+        self.0.detect_language = true;
+        self
+    }
+
+    /// Marks this content as the **start of reading** (e.g., the first chapter), where reading systems open the book.
+    ///
+    /// It becomes the only `text` reference of the guide, instead of every `ReferenceType::Text` content.
+    pub fn start_reading(mut self) -> Self {
+        self.0.start_reading = true;
+        self
+    }
+
+    /// Marks this content as the **preview excerpt** of the book (e.g., the first chapter), the sample
+    /// that retail samplers and "look inside" features show.
+    ///
+    /// It is referenced in the guide as `other.preview` and declared as a `<meta name="preview">`
+    /// with its manifest item id. Only one content can be the preview excerpt.
+    pub fn preview(mut self) -> Self {
+        self.0.preview = true;
+        self
+    }
+
+    /// Sets whether this content is part of the **linear reading order**. Defaults to `true`.
+    ///
+    /// Non-linear contents (e.g., a cover page, which some stores want out of the reading order)
+    /// are marked `linear="no"` in the spine, so readers skip them when paging through the book.
+    pub fn linear(mut self, linear: bool) -> Self {
+        self.0.linear = linear;
+        self
+    }
+
+    /// Sets the **spine order key** of this content, so the reading order can differ from the navigation
+    /// hierarchy (e.g., appendices nested under parts in the TOC but read at the end of the book).
+    ///
+    /// The spine is sorted by this key (stably, so contents with the same key keep the depth-first order
+    /// of the tree). Subcontents inherit the key of their parent unless they set their own. Defaults to `0`.
+    pub fn spine_order(mut self, spine_order: i32) -> Self {
+        self.0.spine_order = Some(spine_order);
+        self
+    }
+
+    /// Tags this content (a top-level one) as **front, body or back matter**.
+    ///
+    /// Tagged contents must follow the conventional order (front, body, back), checked by
+    /// [`crate::epub::EpubBuilder::validate`]. Generated front matter pages are placed before the first body matter content,
+    /// the first body matter content is the start of reading (unless another is marked with
+    /// [`ContentBuilder::start_reading`]) and front matter is excluded from chapter numbering
+    /// (see [`crate::epub::EpubBuilder::add_parts`]).
+    pub fn matter(mut self, matter: Matter) -> Self {
+        self.0.matter = Some(matter);
+        self
+    }
+
+    /// Adds a **transcript** of an audio or video embedded in the body, generating its page and linking it
+    /// from the media element (see [`Transcript`]).
+    pub fn add_transcript(mut self, transcript: Transcript<'a>) -> Self {
+        if let Some(ref mut transcripts) = self.0.transcripts {
+            transcripts.push(transcript);
+        } else {
+            self.0.transcripts = Some(vec![transcript]);
+        }
+        self
+    }
+
+    /// Takes the **display title** (used in the TOC and guide) from the first `<h1>`/`<h2>` of the body,
+    /// keeping the navigation in sync with the actual chapter headings.
+    ///
+    /// The `ReferenceType` title is used as a fallback if the body has no heading.
+    pub fn title_from_heading(mut self) -> Self {
+        self.0.title_from_heading = true;
+        self
+    }
+
     /// Consumes the builder and returns the final [`Content`] instance.
     pub fn build(self) -> Content<'a> {
         self.0
     }
+
+    /// Consumes the builder and returns the final [`Content`] instance, checking it (and its children) first.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the title is empty, the filename does not end
+    /// with `.xhtml`, the body is not valid UTF-8 or a content reference has no title.
+    pub fn build_validated(self) -> crate::Result<Content<'a>> {
+        self.0.validate()?;
+        Ok(self.0)
+    }
+}
+
+/// Checks that a remote media URL is an `http` or `https` URL with a host, and without spaces or quotes.
+fn is_valid_url(url: &str) -> bool {
+    let Some(rest) = url
+        .strip_prefix("https://")
+        .or_else(|| url.strip_prefix("http://"))
+    else {
+        return false;
+    };
+    let host = rest.split(['/', '?', '#']).next().unwrap_or_default();
+    !host.is_empty()
+        && host
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || matches!(c, '.' | '-' | ':' | '[' | ']'))
+        && !url
+            .chars()
+            .any(|c| c.is_whitespace() || matches!(c, '"' | '<' | '>'))
 }
 
 #[cfg(test)]
@@ -321,7 +1116,7 @@ mod tests {
 
         let subs = parent_content.subcontents.unwrap();
         assert_eq!(subs.len(), 1);
-        assert_eq!(subs[0].body, b"child");
+        assert_eq!(subs[0].body.as_ref(), b"child");
     }
 
     #[test]
@@ -430,12 +1225,168 @@ mod tests {
         assert_eq!(parent.level_reference_content(), 3);
     }
 
+    #[test]
+    fn test_content_remote_media() {
+        let content = ContentBuilder::new(
+            br#"<body><audio src="https://cdn.example.com/talk.mp3"/></body>"#,
+            ReferenceType::Text("T".to_string()),
+        )
+        .build();
+        assert!(content.validate().is_ok());
+
+        let content = ContentBuilder::new(
+            br#"<body><iframe src="https://example.com/widget"/><script src="app.js"/></body>"#,
+            ReferenceType::Text("T".to_string()),
+        )
+        .build();
+        assert!(content.has_element("iframe"));
+        assert!(content.validate().is_ok());
+
+        assert!(is_valid_url("http://localhost:8080/a.mp4"));
+        assert!(!is_valid_url("ftp://example.com/a.mp4"));
+        assert!(!is_valid_url("https:///a.mp4"));
+        assert!(!is_valid_url("https://example.com/my talk.mp3"));
+
+        let result = ContentBuilder::new(
+            br#"<body><video><source src="ftp://example.com/v.mp4"/></video></body>"#,
+            ReferenceType::Text("T".to_string()),
+        )
+        .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+    }
+
+    #[test]
+    fn test_content_build_validated() {
+        let result = ContentBuilder::new(
+            br#"<body><h2 id="id01">R1</h2><h3 id="id02">R1.1</h3></body>"#,
+            ReferenceType::Text("T".to_string()),
+        )
+        .filename("chapter.xhtml")
+        .add_content_reference(make_cr("R1").add_child(make_cr("R1.1")))
+        .build_validated();
+        assert!(result.is_ok());
+
+        let result = ContentBuilder::new(
+            br#"<body><h2 id="id01">R1</h2></body>"#,
+            ReferenceType::Text("T".to_string()),
+        )
+        .add_content_reference(make_cr("R1").add_child(make_cr("R1.1")))
+        .build_validated();
+        assert_eq!(
+            result.unwrap_err().to_string(),
+            "Validation error: content 'T' has no element with id 'id02' for the content reference 'R1.1'"
+        );
+
+        let result =
+            ContentBuilder::new(b"", ReferenceType::Text(" ".to_string())).build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = ContentBuilder::new(b"", ReferenceType::Text("T".to_string()))
+            .filename("chapter.html")
+            .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = ContentBuilder::new(b"", ReferenceType::Text("T".to_string()))
+            .add_child(make_content("\u{0}", ""))
+            .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = ContentBuilder::new(&[0xff, 0xfe], ReferenceType::Text("T".to_string()))
+            .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = ContentBuilder::new(b"", ReferenceType::Text("T".to_string()))
+            .add_content_reference(make_cr("R1").add_child(make_cr("")))
+            .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = ContentBuilder::new(
+            "<body><ruby>漢</ruby></body>".as_bytes(),
+            ReferenceType::Text("T".to_string()),
+        )
+        .build_validated();
+        assert_eq!(
+            result.unwrap_err().to_string(),
+            "Validation error: content 'T' has invalid ruby markup: <ruby> without <rt>"
+        );
+    }
+
+    #[test]
+    fn test_content_file_content_error_context() {
+        let child = ContentBuilder::new(
+            b"<body><p>Unclosed</body>",
+            ReferenceType::Text("Section 1.1".to_string()),
+        )
+        .build();
+        let parent = ContentBuilder::new(b"<body/>", ReferenceType::Text("Chapter 1".to_string()))
+            .add_child(child)
+            .build();
+
+        match parent.file_content(&mut 0, "") {
+            Err(crate::Error::Content(title, filename, e)) => {
+                assert_eq!(title, "Section 1.1");
+                assert_eq!(filename, "c02.xhtml");
+                assert!(matches!(*e, crate::Error::XmlParser(..)));
+            }
+            _ => panic!("Expected Content error for malformed body"),
+        }
+    }
+
+    #[test]
+    fn test_content_collect_errors() {
+        let parent = ContentBuilder::new(b"<body/>", ReferenceType::Text("Chapter 1".to_string()))
+            .filename("chapter1.html")
+            .add_child(make_content("<body><p>Unclosed</body>", "Section 1.1"))
+            .add_child(make_content("<body/>", "Section 1.2"))
+            .add_child(make_content("<body><p>Unclosed</body>", "Section 1.3"))
+            .build();
+
+        let mut errors = Vec::new();
+        parent.collect_errors(&mut 0, &mut Vec::new(), &mut errors);
+
+        assert_eq!(errors.len(), 3);
+        assert!(matches!(errors[0], crate::Error::ContentFilename(_)));
+        assert!(
+            matches!(errors[1], crate::Error::Content(ref title, ..) if title == "Section 1.1")
+        );
+        assert!(
+            matches!(errors[2], crate::Error::Content(ref title, ..) if title == "Section 1.3")
+        );
+    }
+
+    #[test]
+    fn test_content_title_from_heading() {
+        let content = ContentBuilder::new(
+            b"<body><h1>The <em>Sea</em></h1></body>",
+            ReferenceType::Text("Chapter 1".to_string()),
+        );
+        assert_eq!(content.clone().build().title(), "Chapter 1");
+        assert_eq!(content.title_from_heading().build().title(), "The Sea");
+
+        let content = ContentBuilder::new(
+            b"<body><p>No heading</p></body>",
+            ReferenceType::Text("Chapter 2".to_string()),
+        )
+        .title_from_heading()
+        .build();
+        assert_eq!(content.title(), "Chapter 2");
+    }
+
+    #[test]
+    fn test_content_size_hint() {
+        let parent = ContentBuilder::new(b"parent", ReferenceType::Text("P".to_string()))
+            .add_child(make_content("child", "C"))
+            .build();
+
+        assert_eq!(parent.size_hint(), 11);
+    }
+
     #[test]
     fn test_content_xhtml_no_stylesheet() {
         let content = make_content("<body>Content</body>", "Test");
         let expected = r#"<?xml version="1.0" encoding="utf-8"?><!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
             <html xmlns="http://www.w3.org/1999/xhtml"><head><title>Test</title></head><body>Content</body></html>"#;
-        assert_eq!(content.xhtml("<body>Content</body>", false), expected);
+        assert_eq!(content.xhtml("<body>Content</body>", ""), expected);
     }
 
     #[test]
@@ -443,14 +1394,74 @@ mod tests {
         let content = make_content("<body>Content</body>", "Test");
         let expected = r#"<?xml version="1.0" encoding="utf-8"?><!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
             <html xmlns="http://www.w3.org/1999/xhtml"><head><title>Test</title><link href="style.css" rel="stylesheet" type="text/css"/></head><body>Content</body></html>"#;
-        assert_eq!(content.xhtml("<body>Content</body>", true), expected);
+        assert_eq!(
+            content.xhtml(
+                "<body>Content</body>",
+                r#"<link href="style.css" rel="stylesheet" type="text/css"/>"#
+            ),
+            expected
+        );
+    }
+
+    #[test]
+    fn test_reference_type_structure() {
+        assert_eq!(
+            ReferenceType::Toc("Contents".to_string()).structure(),
+            Some(("toc", "doc-toc"))
+        );
+        assert_eq!(
+            ReferenceType::Text("One".to_string()).structure(),
+            Some(("chapter", "doc-chapter"))
+        );
+        assert_eq!(ReferenceType::Cover("Cover".to_string()).structure(), None);
+    }
+
+    #[test]
+    fn test_content_xhtml_with_body_attributes() {
+        let content = ContentBuilder::new(b"", ReferenceType::Text("Test".to_string()))
+            .body_attribute("class", "chapter")
+            .body_attribute("xml:lang", "fr")
+            .build();
+
+        assert!(
+            content
+                .xhtml("<body>Contenu</body>", "")
+                .ends_with(r#"<body class="chapter" xml:lang="fr">Contenu</body></html>"#)
+        );
+    }
+
+    #[test]
+    fn test_content_xhtml_with_language() {
+        let content = ContentBuilder::new(b"", ReferenceType::Text("Test".to_string()))
+            .language(Language::French)
+            .body_attribute("class", "poem")
+            .build();
+        assert!(
+            content
+                .xhtml("<body>Texte</body>", "")
+                .ends_with(r#"<body xml:lang="fr" class="poem">Texte</body></html>"#)
+        );
+
+        let content = ContentBuilder::new(b"", ReferenceType::Text("Test".to_string()))
+            .detect_language()
+            .build();
+        assert!(
+            content
+                .xhtml("<body><p>Der Hund und die Katze</p></body>", "")
+                .contains(r#"<body xml:lang="de">"#)
+        );
+        assert!(
+            content
+                .xhtml("<body><p>???</p></body>", "")
+                .contains("<body>")
+        );
     }
 
     #[test]
     fn test_content_file_content_no_subcontents() {
         let content = make_content("body text", "Chapter 1");
         let mut number = 0;
-        let files = content.file_content(&mut number, false).unwrap();
+        let files = content.file_content(&mut number, "").unwrap();
 
         assert_eq!(number, 1);
         assert_eq!(files.len(), 1);
@@ -470,7 +1481,7 @@ mod tests {
             .build();
 
         let mut number = 0;
-        let files = parent.file_content(&mut number, false).unwrap();
+        let files = parent.file_content(&mut number, "").unwrap();
 
         assert_eq!(number, 3);
         assert_eq!(files.len(), 3);
@@ -483,4 +1494,32 @@ mod tests {
         assert!(files[1].bytes.contains("<title>Section 1.1</title>"));
         assert!(files[2].bytes.contains("<title>Section 1.2</title>"));
     }
+
+    #[test]
+    fn test_content_write_files_reuses_buffer() {
+        let child = make_content("<body>c1</body>", "Section 1.1");
+        let parent = ContentBuilder::new(
+            b"<body>p</body>",
+            ReferenceType::Text("Chapter 1".to_string()),
+        )
+        .add_child(child)
+        .build();
+
+        let mut number = 0;
+        let mut buffer = Vec::new();
+        let mut written = Vec::new();
+        parent
+            .write_files(&mut number, "", &mut buffer, &mut |filepath, bytes| {
+                written.push((filepath, String::from_utf8(bytes.to_vec())?));
+                Ok(())
+            })
+            .unwrap();
+
+        assert_eq!(number, 2);
+        assert_eq!(written.len(), 2);
+        assert_eq!(written[0].0, "OEBPS/c01.xhtml");
+        assert_eq!(written[1].0, "OEBPS/c02.xhtml");
+        assert!(written[1].1.contains("<title>Section 1.1</title>"));
+        assert_eq!(written[1].1.as_bytes(), buffer);
+    }
 }
@@ -1,3 +1,7 @@
+use std::borrow::Cow;
+
+use crate::epub::{IdGenerator, slug::Slugs};
+
 /// Represents a single entry in a hierarchical list of references (e.g., a Table of Contents entry).
 ///
 /// This structure links a title to a specific location (via `id`) and supports nested sub-references.
@@ -68,6 +72,16 @@ impl ContentReference {
             })
     }
 
+    /// Checks that this reference and all its sub-references have a non-empty title.
+    pub(crate) fn has_titles(&self) -> bool {
+        !self.title.trim().is_empty()
+            && self
+                .subcontent_references
+                .iter()
+                .flatten()
+                .all(ContentReference::has_titles)
+    }
+
     /// Generates the full file-path anchor string for this reference.
     ///
     /// It combines the provided XHTML filename with either the custom `id` or a sequential one.
@@ -76,10 +90,66 @@ impl ContentReference {
     /// * `xhtml`: The base filename (e.g., `c01.xhtml`) this reference points to.
     /// * `number`: A sequential number used for generating a default anchor ID if `self.id` is `None`.
     pub(crate) fn reference_name(&self, xhtml: &str, number: usize) -> String {
+        format!("{xhtml}#{}", self.anchor_id(number))
+    }
+
+    /// Gets the anchor ID this reference points to: the custom `id`, or `idNN` generated from `number`.
+    pub(crate) fn anchor_id(&self, number: usize) -> Cow<'_, str> {
         self.id
-            .as_ref()
-            .map(|id| format!("{xhtml}#{id}"))
-            .unwrap_or(format!("{xhtml}#id{number:02}"))
+            .as_deref()
+            .map_or_else(|| Cow::Owned(format!("id{number:02}")), Cow::Borrowed)
+    }
+
+    /// Recursively registers the custom `id` of this reference and all its sub-references in `slugs`.
+    pub(crate) fn reserve_ids(&self, slugs: &mut Slugs) {
+        if let Some(ref id) = self.id {
+            slugs.reserve(id.clone());
+        }
+
+        for content_reference in self.subcontent_references.iter().flatten() {
+            content_reference.reserve_ids(slugs);
+        }
+    }
+
+    /// Recursively sets the anchor ID of this reference and all its sub-references without a custom `id`,
+    /// numbering them depth-first with `link_number` as the navigation does and deduplicating them with `slugs`.
+    pub(crate) fn assign_ids(
+        &mut self,
+        filename: &str,
+        link_number: &mut usize,
+        ids: &dyn IdGenerator,
+        slugs: &mut Slugs,
+    ) {
+        *link_number += 1;
+        if self.id.is_none() {
+            let id = ids.anchor_id(filename, &self.title, *link_number);
+            self.id = Some(slugs.unique(&id, ""));
+        }
+
+        for content_reference in self.subcontent_references.iter_mut().flatten() {
+            content_reference.assign_ids(filename, link_number, ids, slugs);
+        }
+    }
+
+    /// Recursively finds the first reference (this one or a sub-reference) whose anchor ID is not in `ids`,
+    /// numbering the references depth-first with `link_number` as the navigation does.
+    ///
+    /// Returns the `(title, anchor ID)` of the reference, or `None` if every anchor exists.
+    pub(crate) fn missing_anchor(
+        &self,
+        link_number: &mut usize,
+        ids: &[&str],
+    ) -> Option<(String, String)> {
+        *link_number += 1;
+        let anchor_id = self.anchor_id(*link_number);
+        if !ids.contains(&anchor_id.as_ref()) {
+            return Some((self.title.clone(), anchor_id.into_owned()));
+        }
+
+        self.subcontent_references
+            .iter()
+            .flatten()
+            .find_map(|content_reference| content_reference.missing_anchor(link_number, ids))
     }
 }
 
@@ -0,0 +1,323 @@
+use quick_xml::escape::escape;
+
+use crate::epub::{Content, ContentBuilder, CreativeCommons, Epub, EpubBuilder, ReferenceType};
+
+/// The filename of the generated image credits page.
+pub const CREDITS_FILENAME: &str = "credits.xhtml";
+
+/// The filename of the generated license page.
+pub const LICENSE_FILENAME: &str = "license.xhtml";
+
+/// The **rights and credit** of a resource (e.g., a photo under a Creative Commons license or stock imagery),
+/// registered once by filename (see [`EpubBuilder::credit`]) and listed in the generated image credits page.
+///
+/// ```rust
+/// use liber::epub::{Credit, EpubBuilder, MetadataBuilder};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+///     .credit(
+///         "harbor.jpg",
+///         Credit::new("Photo by Jane Doe")
+///             .license("CC BY 4.0", "https://creativecommons.org/licenses/by/4.0/")
+///             .source("https://example.com/photos/harbor"),
+///     )
+///     .credit("skyline.jpg", Credit::new("Stock image, used under license"))
+///     .image_credits("Image credits")
+///     .license_links(true);
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Credit {
+    /// The credit line (e.g., `Photo by Jane Doe`).
+    credit: String,
+    /// Optional name and URL of the license (e.g., `CC BY 4.0`).
+    license: Option<(String, String)>,
+    /// Optional URL of the original work.
+    source: Option<String>,
+}
+
+impl Credit {
+    /// Creates a credit with the given **credit line** (e.g., `Photo by Jane Doe`).
+    pub fn new<S: Into<String>>(credit: S) -> Self {
+        Self {
+            credit: credit.into(),
+            license: None,
+            source: None,
+        }
+    }
+
+    /// Sets the **license** of the resource: its name (e.g., `CC BY 4.0`) and the URL of its legal text.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn license<N: Into<String>, U: Into<String>>(mut self, name: N, url: U) -> Self {
+        self.license = Some((name.into(), url.into()));
+        self
+    }
+
+    /// Sets a **Creative Commons license** (see [`CreativeCommons`]) as the license of the resource.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn creative_commons(self, license: CreativeCommons) -> Self {
+        self.license(license.to_string(), license.url())
+    }
+
+    /// Sets the **URL of the original work**, linked from the credits page.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn source<S: Into<String>>(mut self, source: S) -> Self {
+        self.source = Some(source.into());
+        self
+    }
+
+    /// Builds the definition list entries of the credit for the resource `filename`.
+    fn as_list_entries(&self, filename: &str) -> String {
+        let mut entries = format!(
+            r#"<dt>{}</dt><dd role="doc-credit">{}</dd>"#,
+            escape(filename),
+            escape(&self.credit)
+        );
+        if let Some((ref name, ref url)) = self.license {
+            entries.push_str(&format!(
+                r#"<dd class="credit-license"><a rel="license" href="{}">{}</a></dd>"#,
+                escape(url),
+                escape(name)
+            ));
+        }
+        if let Some(ref source) = self.source {
+            entries.push_str(&format!(
+                r#"<dd class="credit-source"><a href="{}">Source</a></dd>"#,
+                escape(source)
+            ));
+        }
+        entries
+    }
+}
+
+impl<'a> Epub<'a> {
+    /// Generates the image credits page, if enabled and any credit is registered.
+    pub(crate) fn credits_content(&self) -> Option<Content<'a>> {
+        let title = self.image_credits.as_ref()?;
+        let credits = self
+            .credits
+            .as_ref()
+            .filter(|credits| !credits.is_empty())?;
+
+        let entries = credits
+            .iter()
+            .map(|(filename, credit)| credit.as_list_entries(filename))
+            .collect::<String>();
+        let body = format!(
+            r#"<body><h1>{}</h1><dl class="credits">{entries}</dl></body>"#,
+            escape(title)
+        );
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Text(title.clone()))
+                .filename(CREDITS_FILENAME)
+                .body_attribute("role", "doc-credits")
+                .build(),
+        )
+    }
+
+    /// Generates the license page, if enabled and a Creative Commons license is set in the metadata:
+    /// the license notice, the freedoms and terms of its deed and a link to it.
+    pub(crate) fn license_content(&self) -> Option<Content<'a>> {
+        let title = self.license_page.as_ref()?;
+        let license = self.metadata.license?;
+
+        let definitions = |class: &str, heading: &str, entries: Vec<(&str, &str)>| {
+            if entries.is_empty() {
+                return String::new();
+            }
+            let entries = entries
+                .into_iter()
+                .map(|(name, description)| format!("<dt>{name}</dt><dd>{description}</dd>"))
+                .collect::<String>();
+            format!(r#"<h2>{heading}</h2><dl class="{class}">{entries}</dl>"#)
+        };
+
+        let body = format!(
+            r#"<body><h1>{}</h1><p class="license-notice">{}</p>{}{}<p class="license-link"><a rel="license" href="{}">{}</a></p></body>"#,
+            escape(title),
+            license.notice(),
+            definitions("license-freedoms", "You are free to:", license.freedoms()),
+            definitions(
+                "license-terms",
+                "Under the following terms:",
+                license.terms()
+            ),
+            license.url(),
+            license.title()
+        );
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Copyright(title.clone()))
+                .filename(LICENSE_FILENAME)
+                .build(),
+        )
+    }
+
+    /// Generates the XML `<link rel="cc:license">` tags of the licensed resources (if enabled), refining
+    /// their manifest items, used in the content package metadata.
+    ///
+    /// Returns `None` if not enabled.
+    pub fn credits_as_metadata_xml(&self) -> Option<String> {
+        if !self.license_links {
+            return None;
+        }
+
+        Some(
+            self.credits
+                .iter()
+                .flatten()
+                .filter_map(|(filename, credit)| {
+                    let (_, url) = credit.license.as_ref()?;
+                    Some(format!(
+                        r##"<link rel="cc:license" refines="#{}" href="{}"/>"##,
+                        escape(self.ids().item_id(filename)),
+                        escape(url)
+                    ))
+                })
+                .collect(),
+        )
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Registers the **rights and credit** (see [`Credit`]) of the resource with the given filename
+    /// (e.g., `harbor.jpg`).
+    pub fn credit<S: Into<String>>(mut self, filename: S, credit: Credit) -> Self {
+        let credit = (filename.into(), credit);
+        if let Some(ref mut credits) = self.0.credits {
+            credits.push(credit);
+        } else {
+            self.0.credits = Some(vec![credit]);
+        }
+        self
+    }
+
+    /// Adds a generated **image credits page** with the given title, listing the credit and license of every
+    /// resource registered with [`EpubBuilder::credit`].
+    ///
+    /// The page is added at the end of the book.
+    pub fn image_credits<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.image_credits = Some(title.into());
+        self
+    }
+
+    /// Adds a generated **license page** with the given title, with the notice, freedoms and terms of the
+    /// Creative Commons license set in the metadata (see [`crate::epub::MetadataBuilder::creative_commons`]).
+    ///
+    /// The page is added at the end of the book, and only if a license is set.
+    pub fn license_page<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.license_page = Some(title.into());
+        self
+    }
+
+    /// Declares the license of every credited resource in the package metadata, as a `<link rel="cc:license">`
+    /// refining its manifest item.
+    pub fn license_links(mut self, license_links: bool) -> Self {
+        self.0.license_links = license_links;
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::MetadataBuilder;
+
+    #[test]
+    fn test_credits_content() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .credit(
+                "harbor.jpg",
+                Credit::new("Photo by Jane Doe")
+                    .license("CC BY 4.0", "https://creativecommons.org/licenses/by/4.0/")
+                    .source("https://example.com/harbor"),
+            )
+            .credit("sky.jpg", Credit::new("Stock & co."));
+        assert!(builder.0.credits_content().is_none());
+
+        let builder = builder.image_credits("Credits");
+        let files = builder
+            .0
+            .credits_content()
+            .unwrap()
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/credits.xhtml");
+        assert!(files[0].bytes.contains(r#"role="doc-credits""#));
+        assert!(files[0].bytes.contains(
+            r#"<a rel="license" href="https://creativecommons.org/licenses/by/4.0/">CC BY 4.0</a>"#
+        ));
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<dd role="doc-credit">Stock &amp; co.</dd>"#)
+        );
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="https://example.com/harbor">Source</a>"#)
+        );
+    }
+
+    #[test]
+    fn test_license_content() {
+        let builder =
+            EpubBuilder::new(MetadataBuilder::title("Title").build()).license_page("License");
+        assert!(builder.0.license_content().is_none());
+
+        let builder = EpubBuilder::new(
+            MetadataBuilder::title("Title")
+                .creative_commons(CreativeCommons::ByNcNd4)
+                .build(),
+        )
+        .license_page("License");
+        let files = builder
+            .0
+            .license_content()
+            .unwrap()
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/license.xhtml");
+
+        let page = &files[0].bytes;
+        assert!(page.contains("This work is licensed under Creative Commons Attribution-NonCommercial-NoDerivatives 4.0 International. To view a copy of this license, visit https://creativecommons.org/licenses/by-nc-nd/4.0/"));
+        assert!(page.contains("<dt>Share</dt>"));
+        assert!(!page.contains("<dt>Adapt</dt>"));
+        assert!(page.contains("<dt>NonCommercial</dt>"));
+        assert!(page.contains("<dt>NoDerivatives</dt>"));
+        assert!(!page.contains("<dt>ShareAlike</dt>"));
+    }
+
+    #[test]
+    fn test_credit_creative_commons() {
+        assert_eq!(
+            Credit::new("Photo").creative_commons(CreativeCommons::By4),
+            Credit::new("Photo")
+                .license("CC BY 4.0", "https://creativecommons.org/licenses/by/4.0/")
+        );
+    }
+
+    #[test]
+    fn test_credits_as_metadata_xml() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .credit(
+                "harbor.jpg",
+                Credit::new("Photo")
+                    .license("CC0", "https://creativecommons.org/publicdomain/zero/1.0/"),
+            )
+            .credit("sky.jpg", Credit::new("Stock"));
+        assert!(builder.0.credits_as_metadata_xml().is_none());
+
+        assert_eq!(
+            builder
+                .license_links(true)
+                .0
+                .credits_as_metadata_xml()
+                .unwrap(),
+            r##"<link rel="cc:license" refines="#harbor.jpg" href="https://creativecommons.org/publicdomain/zero/1.0/"/>"##
+        );
+    }
+}
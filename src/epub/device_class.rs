@@ -0,0 +1,494 @@
+use std::{
+    fmt::{Debug, Formatter},
+    io,
+    sync::Arc,
+};
+
+use crate::{
+    epub::{Epub, EpubBuilder, ImageType, Resource},
+    output::file_content::FileContent,
+};
+
+/// A **target device class**, whose screen bounds the useful resolution of the embedded images
+/// (see [`EpubBuilder::device_class`]).
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DeviceClass {
+    /// E-ink readers (e.g., Kindle Paperwhite or Kobo Clara), with a 758×1024 screen.
+    EInk,
+    /// Tablets (e.g., iPad), with a 1536×2048 screen.
+    Tablet,
+    /// A custom maximum `(width, height)` in pixels.
+    Custom(u32, u32),
+}
+
+impl DeviceClass {
+    /// Gets the maximum useful `(width, height)` of an image, in pixels.
+    pub fn max_size(self) -> (u32, u32) {
+        match self {
+            Self::EInk => (758, 1024),
+            Self::Tablet => (1536, 2048),
+            Self::Custom(width, height) => (width, height),
+        }
+    }
+
+    /// Gets the `(width, height)` an image of the given size is shrunk to, keeping its aspect ratio,
+    /// or `None` if it already fits.
+    pub fn fit(self, width: u32, height: u32) -> Option<(u32, u32)> {
+        let (max_width, max_height) = self.max_size();
+        if width <= max_width && height <= max_height {
+            return None;
+        }
+
+        let scale = (f64::from(max_width) / f64::from(width))
+            .min(f64::from(max_height) / f64::from(height));
+        let shrink =
+            |size: u32, max: u32| ((f64::from(size) * scale).round() as u32).clamp(1, max.max(1));
+        Some((shrink(width, max_width), shrink(height, max_height)))
+    }
+}
+
+/// The codec used to **resize the embedded images** to a [`DeviceClass`] (see [`EpubBuilder::device_class`]),
+/// e.g., backed by `image` or `fast_image_resize`.
+pub trait ImageResizer {
+    /// Resizes the `image` (encoded as `image_type`) to `width`×`height` pixels, returning it encoded
+    /// in the same format.
+    fn resize(
+        &self,
+        image: &[u8],
+        image_type: &ImageType,
+        width: u32,
+        height: u32,
+    ) -> io::Result<Vec<u8>>;
+}
+
+impl<F> ImageResizer for F
+where
+    F: Fn(&[u8], &ImageType, u32, u32) -> io::Result<Vec<u8>>,
+{
+    fn resize(
+        &self,
+        image: &[u8],
+        image_type: &ImageType,
+        width: u32,
+        height: u32,
+    ) -> io::Result<Vec<u8>> {
+        self(image, image_type, width, height)
+    }
+}
+
+/// The codec used to **convert the embedded images to grayscale** (see [`EpubBuilder::grayscale_images`]).
+pub trait GrayscaleConverter {
+    /// Converts the `image` (encoded as `image_type`) to grayscale, returning it encoded in the same format
+    /// (ideally with a single gray channel, e.g., an 8-bit gray PNG or a one-component JPEG).
+    fn grayscale(&self, image: &[u8], image_type: &ImageType) -> io::Result<Vec<u8>>;
+}
+
+impl<F> GrayscaleConverter for F
+where
+    F: Fn(&[u8], &ImageType) -> io::Result<Vec<u8>>,
+{
+    fn grayscale(&self, image: &[u8], image_type: &ImageType) -> io::Result<Vec<u8>> {
+        self(image, image_type)
+    }
+}
+
+/// The processing of the embedded images of a book, applied as they are written.
+#[derive(Clone, Default)]
+pub(crate) struct ImageProcessing {
+    /// Optional target device class and resizer of the images.
+    resizing: Option<(DeviceClass, Arc<dyn ImageResizer + Send + Sync>)>,
+    /// Optional converter of the color images to grayscale.
+    grayscale: Option<Arc<dyn GrayscaleConverter + Send + Sync>>,
+}
+
+impl ImageProcessing {
+    /// Sets the target device class and the resizer of the images.
+    pub(crate) fn resize<R>(&mut self, device_class: DeviceClass, resizer: R)
+    where
+        R: ImageResizer + Send + Sync + 'static,
+    {
+        self.resizing = Some((device_class, Arc::new(resizer)));
+    }
+
+    /// Sets the converter of the color images to grayscale.
+    pub(crate) fn grayscale<C>(&mut self, converter: C)
+    where
+        C: GrayscaleConverter + Send + Sync + 'static,
+    {
+        self.grayscale = Some(Arc::new(converter));
+    }
+
+    /// Merges the settings of `other`, replacing the ones it sets.
+    pub(crate) fn merge(&mut self, other: ImageProcessing) {
+        if other.resizing.is_some() {
+            self.resizing = other.resizing;
+        }
+        if other.grayscale.is_some() {
+            self.grayscale = other.grayscale;
+        }
+    }
+}
+
+impl Debug for ImageProcessing {
+    fn fmt(&self, f: &mut Formatter<'_>) -> std::fmt::Result {
+        f.debug_struct("ImageProcessing")
+            .field(
+                "device_class",
+                &self.resizing.as_ref().map(|(device_class, _)| device_class),
+            )
+            .field("grayscale", &self.grayscale.is_some())
+            .finish()
+    }
+}
+
+/// The properties of a JPEG, PNG or GIF image read from its header.
+#[derive(Debug)]
+struct ImageHeader {
+    /// The format of the image.
+    image_type: ImageType,
+    /// The width in pixels.
+    width: u32,
+    /// The height in pixels.
+    height: u32,
+    /// Whether the image is already grayscale (a gray PNG or a one-component JPEG).
+    grayscale: bool,
+}
+
+/// Reads the header of a JPEG, PNG or GIF image.
+fn image_header(bytes: &[u8]) -> Option<ImageHeader> {
+    let be16 = |at: usize| {
+        Some(u32::from(u16::from_be_bytes(
+            bytes.get(at..at + 2)?.try_into().ok()?,
+        )))
+    };
+
+    if bytes.starts_with(b"\x89PNG\r\n\x1a\n") && bytes.get(12..16) == Some(b"IHDR") {
+        let be32 = |at: usize| Some(u32::from_be_bytes(bytes.get(at..at + 4)?.try_into().ok()?));
+        return Some(ImageHeader {
+            image_type: ImageType::Png,
+            width: be32(16)?,
+            height: be32(20)?,
+            // The color types 0 (gray) and 4 (gray with alpha)
+            grayscale: matches!(bytes.get(25)?, 0 | 4),
+        });
+    }
+
+    if bytes.starts_with(b"GIF87a") || bytes.starts_with(b"GIF89a") {
+        let le16 = |at: usize| {
+            Some(u32::from(u16::from_le_bytes(
+                bytes.get(at..at + 2)?.try_into().ok()?,
+            )))
+        };
+        return Some(ImageHeader {
+            image_type: ImageType::Gif,
+            width: le16(6)?,
+            height: le16(8)?,
+            grayscale: false,
+        });
+    }
+
+    if bytes.starts_with(&[0xFF, 0xD8]) {
+        let mut position = 2;
+        while bytes.get(position) == Some(&0xFF) {
+            let marker = *bytes.get(position + 1)?;
+            match marker {
+                // Fill bytes preceding a marker
+                0xFF => position += 1,
+                // Standalone markers, without a length
+                0x01 | 0xD0..=0xD8 => position += 2,
+                // Start of frame (except DHT, JPG and DAC), holding the size and the number of components
+                0xC0..=0xCF if !matches!(marker, 0xC4 | 0xC8 | 0xCC) => {
+                    return Some(ImageHeader {
+                        image_type: ImageType::Jpg,
+                        width: be16(position + 7)?,
+                        height: be16(position + 5)?,
+                        grayscale: *bytes.get(position + 9)? == 1,
+                    });
+                }
+                // Start of scan: the frame header was not found
+                0xDA => return None,
+                _ => position += 2 + usize::try_from(be16(position + 2)?).ok()?,
+            }
+        }
+    }
+
+    None
+}
+
+impl Epub<'_> {
+    /// Processes the image file (JPEG, PNG or GIF) with the given bytes: shrinks it to the target device
+    /// class, if set and larger than the device screen, then converts it to grayscale, if set and in color.
+    ///
+    /// Returns `None` if the bytes are left as they are (not an image, or nothing to do).
+    ///
+    /// # Errors
+    /// Returns any I/O error of the resizer or the grayscale converter.
+    pub(crate) fn process_image(&self, bytes: &[u8]) -> crate::Result<Option<Vec<u8>>> {
+        let Some(ref image_processing) = self.image_processing else {
+            return Ok(None);
+        };
+        let Some(header) = image_header(bytes) else {
+            return Ok(None);
+        };
+
+        let mut processed = None;
+        if let Some((device_class, ref resizer)) = image_processing.resizing
+            && let Some((width, height)) = device_class.fit(header.width, header.height)
+        {
+            processed = Some(resizer.resize(bytes, &header.image_type, width, height)?);
+        }
+        if let Some(ref converter) = image_processing.grayscale
+            && !header.grayscale
+        {
+            let image = processed.as_deref().unwrap_or(bytes);
+            processed = Some(converter.grayscale(image, &header.image_type)?);
+        }
+
+        Ok(processed)
+    }
+
+    /// Processes the file content of `resource` in place (see [`Epub::process_image`]) if it is an image:
+    /// the cover and the image resources are the only files processed, while the raw files, the iTunes
+    /// artwork and the generated files are written as they are.
+    ///
+    /// # Errors
+    /// Returns any I/O error of the resizer or the grayscale converter.
+    pub(crate) fn process_resource(
+        &self,
+        resource: &Resource<'_>,
+        file_content: &mut FileContent<String, Vec<u8>>,
+    ) -> crate::Result {
+        if matches!(resource, Resource::Image(..))
+            && let Some(processed) = self.process_image(&file_content.bytes)?
+        {
+            file_content.bytes = processed;
+        }
+        Ok(())
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Sets the **target device class** (see [`DeviceClass`]): the JPEG, PNG and GIF cover and image resources
+    /// larger than its screen are shrunk by the `resizer` (keeping their aspect ratio) as they are written,
+    /// shrinking the file (e.g., for e-ink distributions) without touching the source assets. Raw files and
+    /// the iTunes artwork are written as they are.
+    ///
+    /// ```rust
+    /// use liber::epub::{DeviceClass, EpubBuilder, ImageType, MetadataBuilder};
+    ///
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).device_class(
+    ///     DeviceClass::EInk,
+    ///     |image: &[u8], image_type: &ImageType, width: u32, height: u32| {
+    ///         // Decode, resize to `width`×`height` and encode again with an image crate
+    ///         Ok(image.to_vec())
+    ///     },
+    /// );
+    /// ```
+    ///
+    /// Returning an error from the resizer aborts the creation of the file with that error.
+    pub fn device_class<R>(mut self, device_class: DeviceClass, resizer: R) -> Self
+    where
+        R: ImageResizer + Send + Sync + 'static,
+    {
+        self.0
+            .image_processing
+            .get_or_insert_with(ImageProcessing::default)
+            .resize(device_class, resizer);
+        self
+    }
+
+    /// **Converts the cover and image resources to grayscale** with the `converter` as they are written (after
+    /// resizing them to the target device class, if any), for e-ink builds: gray images are smaller and avoid
+    /// the dithering artifacts of the device. The images already grayscale (gray PNG or JPEG), the raw files
+    /// and the iTunes artwork are left as they are.
+    ///
+    /// ```rust
+    /// use liber::epub::{EpubBuilder, ImageType, MetadataBuilder};
+    ///
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+    ///     .grayscale_images(|image: &[u8], image_type: &ImageType| {
+    ///         // Decode, convert to 8-bit gray and encode again with an image crate
+    ///         Ok(image.to_vec())
+    ///     });
+    /// ```
+    ///
+    /// Returning an error from the converter aborts the creation of the file with that error.
+    pub fn grayscale_images<C>(mut self, converter: C) -> Self
+    where
+        C: GrayscaleConverter + Send + Sync + 'static,
+    {
+        self.0
+            .image_processing
+            .get_or_insert_with(ImageProcessing::default)
+            .grayscale(converter);
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::{
+        path::Path,
+        sync::{Arc, Mutex},
+    };
+
+    use super::*;
+    use crate::epub::{ContentBuilder, MemoryFileSystem, MetadataBuilder, RawFile, ReferenceType};
+
+    fn png(width: u32, height: u32, color_type: u8) -> Vec<u8> {
+        let mut png = b"\x89PNG\r\n\x1a\n\0\0\0\x0dIHDR".to_vec();
+        png.extend(width.to_be_bytes());
+        png.extend(height.to_be_bytes());
+        png.extend([8, color_type]);
+        png
+    }
+
+    #[test]
+    fn test_device_class_fit() {
+        assert_eq!(DeviceClass::EInk.fit(758, 1024), None);
+        assert_eq!(DeviceClass::EInk.fit(1516, 1024), Some((758, 512)));
+        assert_eq!(DeviceClass::EInk.fit(3000, 4000), Some((758, 1011)));
+        assert_eq!(DeviceClass::Tablet.fit(3000, 4000), Some((1536, 2048)));
+        assert_eq!(DeviceClass::Custom(100, 100).fit(10000, 1), Some((100, 1)));
+    }
+
+    #[test]
+    fn test_image_header() {
+        assert!(matches!(
+            image_header(&png(640, 480, 2)),
+            Some(ImageHeader {
+                image_type: ImageType::Png,
+                width: 640,
+                height: 480,
+                grayscale: false
+            })
+        ));
+        assert!(image_header(&png(640, 480, 4)).unwrap().grayscale);
+        assert!(matches!(
+            image_header(b"GIF89a\x80\x02\xe0\x01"),
+            Some(ImageHeader {
+                image_type: ImageType::Gif,
+                width: 640,
+                height: 480,
+                grayscale: false
+            })
+        ));
+
+        let mut jpeg = vec![
+            0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 0x4A, 0x46, 0xFF, 0xC0, 0x00, 0x11, 0x08, 0x01,
+            0xE0, 0x02, 0x80, 0x03,
+        ];
+        assert!(matches!(
+            image_header(&jpeg),
+            Some(ImageHeader {
+                image_type: ImageType::Jpg,
+                width: 640,
+                height: 480,
+                grayscale: false
+            })
+        ));
+        jpeg[17] = 1;
+        assert!(image_header(&jpeg).unwrap().grayscale);
+
+        assert!(image_header(b"<svg/>").is_none());
+        assert!(image_header(&[0xFF, 0xD8, 0xFF, 0xDA]).is_none());
+        assert!(image_header(&png(1, 1, 0)[..20]).is_none());
+    }
+
+    #[test]
+    fn test_process_image() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build()).0;
+        assert!(epub.process_image(&png(4000, 3000, 2)).unwrap().is_none());
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).device_class(
+            DeviceClass::EInk,
+            |_: &[u8], image_type: &ImageType, width: u32, height: u32| {
+                assert!(matches!(image_type, ImageType::Png));
+                Ok(png(width, height, 2))
+            },
+        );
+        assert_eq!(
+            builder.0.process_image(&png(4000, 2000, 2)).unwrap(),
+            Some(png(758, 379, 2))
+        );
+        assert!(
+            builder
+                .0
+                .process_image(&png(700, 1000, 2))
+                .unwrap()
+                .is_none()
+        );
+        assert!(builder.0.process_image(b"<body/>").unwrap().is_none());
+
+        let epub = builder
+            .grayscale_images(|image: &[u8], _: &ImageType| {
+                let mut image = image.to_vec();
+                image[25] = 0;
+                Ok(image)
+            })
+            .0;
+        assert_eq!(
+            epub.process_image(&png(4000, 2000, 2)).unwrap(),
+            Some(png(758, 379, 0))
+        );
+        assert_eq!(
+            epub.process_image(&png(700, 1000, 6)).unwrap(),
+            Some(png(700, 1000, 0))
+        );
+        assert!(epub.process_image(&png(700, 1000, 0)).unwrap().is_none());
+    }
+
+    #[test]
+    fn test_process_resources_only() {
+        let large = png(4000, 2000, 2);
+        let written = Arc::new(Mutex::new(Vec::new()));
+        let recorder = Arc::clone(&written);
+
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .cover_image(Path::new("cover.png"), ImageType::Png)
+            .itunes_artwork(true)
+            .add_resource(Resource::Image(Path::new("map.png"), ImageType::Png))
+            .add_raw_file(RawFile::new("extras/map.png", &large))
+            .file_system(
+                MemoryFileSystem::new()
+                    .file("cover.png", large.clone())
+                    .file("map.png", large.clone()),
+            )
+            .device_class(
+                DeviceClass::EInk,
+                |_: &[u8], _: &ImageType, width, height| Ok(png(width, height, 2)),
+            )
+            .grayscale_images(|image: &[u8], _: &ImageType| {
+                let mut image = image.to_vec();
+                image[25] = 0;
+                Ok(image)
+            })
+            .add_file_hook(move |filepath, bytes| {
+                if let Some(header) = image_header(&bytes) {
+                    recorder.lock().unwrap().push((
+                        filepath.to_string(),
+                        header.width,
+                        header.grayscale,
+                    ));
+                }
+                Ok(bytes)
+            })
+            .create(&mut Vec::new())
+            .unwrap();
+
+        let mut written = written.lock().unwrap().clone();
+        written.sort();
+        assert_eq!(
+            written,
+            vec![
+                ("OEBPS/cover.png".to_string(), 758, true),
+                ("OEBPS/map.png".to_string(), 758, true),
+                ("extras/map.png".to_string(), 4000, false),
+                ("iTunesArtwork".to_string(), 4000, false),
+            ]
+        );
+    }
+}
@@ -0,0 +1,259 @@
+use std::collections::HashSet;
+
+use chrono::{DateTime, Utc};
+
+use crate::epub::{
+    Article, Content, ContentBuilder, EpubBuilder, MagazineBuilder, Metadata, ReferenceType,
+    Resource, Section,
+};
+
+/// The CSS rules used by the pages generated by [`DigestBuilder`], along with [`crate::epub::PERIODICAL_CSS`].
+pub const DIGEST_CSS: &str = "div.masthead { text-align: center; margin-top: 20%; }
+p.masthead-date { font-variant: small-caps; }
+p.masthead-sections { font-size: 0.9em; }
+p.dateline { font-size: 0.8em; color: #555; }";
+
+/// The filename of the generated masthead page.
+pub const MASTHEAD_FILENAME: &str = "masthead.xhtml";
+
+/// A dated **news item** of a digest, such as an article fetched from a feed.
+#[derive(Debug, Clone)]
+pub struct DigestItem<'a> {
+    /// The title of the item.
+    title: String,
+    /// The source or category the item is grouped under (e.g., the feed name).
+    category: String,
+    /// The publication date of the item.
+    date: DateTime<Utc>,
+    /// The XHTML fragment with the text of the item, without `<body>`.
+    html: String,
+    /// Optional author of the item.
+    author: Option<String>,
+    /// The images referenced by the item.
+    images: Vec<Resource<'a>>,
+}
+
+impl<'a> DigestItem<'a> {
+    /// Creates an item with its title, source or category, date and XHTML fragment (the inner markup of the body).
+    pub fn new<T, C, H>(title: T, category: C, date: DateTime<Utc>, html: H) -> Self
+    where
+        T: Into<String>,
+        C: Into<String>,
+        H: Into<String>,
+    {
+        Self {
+            title: title.into(),
+            category: category.into(),
+            date,
+            html: html.into(),
+            author: None,
+            images: Vec::new(),
+        }
+    }
+
+    /// Sets the **author** of the item.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn author<S: Into<String>>(mut self, author: S) -> Self {
+        self.author = Some(author.into());
+        self
+    }
+
+    /// Adds an **image** referenced by the item. Images shared by several items are only included once.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_image(mut self, image: Resource<'a>) -> Self {
+        self.images.push(image);
+        self
+    }
+
+    /// Converts the item into an article, with its title and date on top of the text.
+    fn article(self) -> Article<'a> {
+        let body = format!(
+            r#"<body><h1>{}</h1><p class="dateline">{}</p>{}</body>"#,
+            self.title,
+            self.date.format("%Y-%m-%d %H:%M UTC"),
+            self.html
+        );
+
+        let article = Article::new_owned(self.title, body);
+        match self.author {
+            Some(author) => article.author(author),
+            None => article,
+        }
+    }
+}
+
+/// A convenience assembler of **news digests**, built on top of [`MagazineBuilder`].
+///
+/// The items are grouped into a section per source or category (in order of first appearance),
+/// sorted from newest to oldest within each section. A masthead page with the title and date of
+/// the digest is generated first, and the images shared by several items are included only once.
+///
+/// ```rust
+/// use chrono::Utc;
+/// use liber::epub::{DigestBuilder, DigestItem, MetadataBuilder};
+///
+/// let epub_builder = DigestBuilder::new(MetadataBuilder::title("Morning Digest").build())
+///     .add_item(DigestItem::new("Headline", "World", Utc::now(), "<p>Text</p>").author("Jane Doe"))
+///     .build();
+///
+/// assert!(epub_builder.create(&mut Vec::new()).is_ok());
+/// ```
+#[derive(Debug, Clone)]
+pub struct DigestBuilder<'a> {
+    /// The metadata of the digest, whose title and date are shown on the masthead.
+    metadata: Metadata,
+    /// The news items of the digest.
+    items: Vec<DigestItem<'a>>,
+}
+
+impl<'a> DigestBuilder<'a> {
+    /// Creates a builder for a digest with the given metadata.
+    pub fn new(metadata: Metadata) -> Self {
+        Self {
+            metadata,
+            items: Vec::new(),
+        }
+    }
+
+    /// Adds a news **item** to the digest.
+    pub fn add_item(mut self, item: DigestItem<'a>) -> Self {
+        self.items.push(item);
+        self
+    }
+
+    /// Adds several news **items** to the digest.
+    pub fn add_items(mut self, items: Vec<DigestItem<'a>>) -> Self {
+        self.items.extend(items);
+        self
+    }
+
+    /// Consumes the builder and returns an [`EpubBuilder`] with the masthead, the sections and their items,
+    /// which can be further configured (e.g., with a stylesheet) before creating the file.
+    pub fn build(self) -> EpubBuilder<'a> {
+        let mut categories: Vec<(String, Vec<DigestItem<'a>>)> = Vec::new();
+        let mut images = Vec::new();
+        let mut image_filenames = HashSet::new();
+
+        for mut item in self.items {
+            for image in item.images.drain(..) {
+                if image_filenames.insert(image.filename().unwrap_or_else(|_| image.to_string())) {
+                    images.push(image);
+                }
+            }
+
+            match categories
+                .iter_mut()
+                .find(|(category, _)| *category == item.category)
+            {
+                Some((_, items)) => items.push(item),
+                None => categories.push((item.category.clone(), vec![item])),
+            }
+        }
+
+        let masthead = masthead(&self.metadata, &categories);
+
+        let mut magazine_builder = MagazineBuilder::new(self.metadata);
+        for (category, mut items) in categories {
+            items.sort_by(|a, b| b.date.cmp(&a.date));
+            magazine_builder = magazine_builder.add_section(
+                Section::new(category)
+                    .add_articles(items.into_iter().map(DigestItem::article).collect()),
+            );
+        }
+
+        let mut epub_builder = magazine_builder.build();
+        if let Some(ref mut contents) = epub_builder.0.contents {
+            contents.insert(0, masthead);
+        }
+
+        if images.is_empty() {
+            epub_builder
+        } else {
+            epub_builder.add_resources(images)
+        }
+    }
+}
+
+/// Generates the masthead page, with the title and date of the digest and a summary of its sections.
+fn masthead<'a>(metadata: &Metadata, categories: &[(String, Vec<DigestItem<'_>>)]) -> Content<'a> {
+    let count = categories
+        .iter()
+        .map(|(_, items)| items.len())
+        .sum::<usize>();
+    let sections = categories
+        .iter()
+        .map(|(category, _)| category.as_str())
+        .collect::<Vec<_>>()
+        .join(", ");
+
+    let body = format!(
+        r#"<body><div class="masthead"><h1>{title}</h1><p class="masthead-date">{date}</p><p class="masthead-sections">{count} {articles}: {sections}</p></div></body>"#,
+        title = metadata.title,
+        date = metadata
+            .date
+            .unwrap_or_else(Utc::now)
+            .format("%A, %B %-d, %Y"),
+        articles = if count == 1 { "article" } else { "articles" },
+    );
+
+    ContentBuilder::new_owned(body, ReferenceType::TitlePage(metadata.title.clone()))
+        .filename(MASTHEAD_FILENAME)
+        .build()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use chrono::TimeZone;
+
+    use super::*;
+    use crate::epub::{ImageType, MetadataBuilder};
+
+    #[test]
+    fn test_digest_builder() {
+        let morning = Utc.with_ymd_and_hms(2024, 5, 1, 8, 0, 0).unwrap();
+        let noon = Utc.with_ymd_and_hms(2024, 5, 1, 12, 0, 0).unwrap();
+        let logo = Path::new("logo.jpg");
+
+        let epub_builder = DigestBuilder::new(
+            MetadataBuilder::title("Daily")
+                .date(Utc.with_ymd_and_hms(2024, 5, 1, 0, 0, 0).unwrap())
+                .build(),
+        )
+        .add_item(
+            DigestItem::new("Old", "World", morning, "<p>One</p>")
+                .add_image(Resource::Image(logo, ImageType::Jpg)),
+        )
+        .add_item(DigestItem::new("Sports", "Sports", morning, "<p>Two</p>"))
+        .add_item(
+            DigestItem::new("New", "World", noon, "<p>Three</p>")
+                .author("Jane Doe")
+                .add_image(Resource::Image(logo, ImageType::Jpg)),
+        )
+        .build();
+
+        assert_eq!(epub_builder.0.resources.as_ref().unwrap().len(), 1);
+
+        let contents = epub_builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 4);
+
+        let masthead = contents[0].file_content(&mut 0, "").unwrap().remove(0);
+        assert_eq!(masthead.filepath, "OEBPS/masthead.xhtml");
+        assert!(masthead.bytes.contains("Wednesday, May 1, 2024"));
+        assert!(masthead.bytes.contains("3 articles: World, Sports"));
+
+        let world = contents[2].file_content(&mut 2, "").unwrap();
+        assert_eq!(world.len(), 3);
+        assert!(world[1].bytes.contains("New"));
+        assert!(
+            world[1]
+                .bytes
+                .contains(r#"<p class="byline">By Jane Doe</p>"#)
+        );
+        assert!(world[1].bytes.contains("2024-05-01 12:00 UTC"));
+        assert!(world[2].bytes.contains("Old"));
+    }
+}
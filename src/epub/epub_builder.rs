@@ -1,11 +1,43 @@
-use std::{io::Write, path::Path};
+use std::{
+    borrow::Cow,
+    io::Write,
+    path::Path,
+    sync::{
+        Arc,
+        atomic::{AtomicUsize, Ordering},
+    },
+};
 
 use crate::ZipCompression;
 use crate::{
-    epub::{Content, ImageType, Resource, metadata::Metadata},
-    output::creator::EpubFile,
+    epub::{
+        AlsoBy, CallToAction, Content, ContentBuilder, Credit, FileSystem, FontLicense,
+        IdGenerator, ImageDescription, ImageType, LinkTracking, Lint, Linter, Matter, Profile,
+        RawFile, ReferenceType, Resource, SizeBudget, StdFileSystem, Video, Watermark,
+        device_class::ImageProcessing,
+        file_hook::FileHook,
+        image_description::{self, ImageDescriber},
+        metadata::Metadata,
+        script::{self, Script},
+        transformer::Transformer,
+    },
+    markup::{
+        self, ABBREVIATIONS_FILENAME, Abbreviations, BIBLIOGRAPHY_FILENAME, Bibliography,
+        CHARACTERS_FILENAME, Characters, ENDNOTES_FILENAME, FigureList, INDEX_FILENAME,
+        LOI_FILENAME, ReadingTime, TermIndex,
+    },
+    output::{
+        creator::{self, EpubFile},
+        xml,
+    },
 };
 
+/// The filename of the Adobe page template.
+pub(crate) const PAGE_TEMPLATE_FILENAME: &str = "page-template.xpgt";
+
+/// The media type of the Adobe page template.
+pub(crate) const PAGE_TEMPLATE_MEDIA_TYPE: &str = "application/vnd.adobe-page-template+xml";
+
 /// The main structure representing a complete EPUB document ready for generation.
 ///
 /// It holds all the necessary components: metadata, styling, resources, and ordered content.
@@ -16,12 +48,94 @@ pub(crate) struct Epub<'a> {
     pub metadata: Metadata,
     /// Optional stylesheet content (CSS bytes) to be included in the EPUB.
     pub stylesheet: Option<&'a [u8]>,
+    /// Optional Adobe page template (`.xpgt` bytes), linked from every content for ADE-based readers.
+    pub page_template: Option<&'a [u8]>,
+    /// Optional script resources (JavaScript), referenced from the content bodies.
+    pub scripts: Option<Vec<Script<'a>>>,
+    /// Whether the scripts are joined into a single bundle, updating the references of the bodies.
+    pub bundle_scripts: bool,
+    /// Whether the scripts are minified.
+    pub minify_scripts: bool,
+    /// Whether the heading levels of every body are normalized.
+    pub normalize_headings: bool,
+    /// Optional descriptions of the images, by filename, applied to every `<img>` of the bodies.
+    pub image_descriptions: Option<Vec<(String, ImageDescription)>>,
+    /// Whether every image must have an alternative text.
+    pub require_alt_text: bool,
     /// Optional resource designated as the cover image.
     pub cover_image: Option<Resource<'a>>,
+    /// Optional small version of the cover image, used by library software to list the book.
+    pub cover_thumbnail: Option<Resource<'a>>,
+    /// Whether the thumbnail (or the cover image) is also written as the root `iTunesArtwork` file.
+    pub itunes_artwork: bool,
     /// Optional list of external resources (images, fonts, audio) used by the content.
     pub resources: Option<Vec<Resource<'a>>>,
+    /// Optional rights and credits of the resources, by filename.
+    pub credits: Option<Vec<(String, Credit)>>,
+    /// Optional title of the generated image credits page, listing the credits of the resources.
+    pub image_credits: Option<String>,
+    /// Whether the licenses of the credited resources are declared in the package metadata.
+    pub license_links: bool,
+    /// Optional title of the generated license page, with the Creative Commons license of the metadata.
+    pub license_page: Option<String>,
+    /// Optional buyer watermark, stamped on a personalized copy.
+    pub watermark: Option<Watermark>,
+    /// Optional licenses of the font resources, by path.
+    pub font_licenses: Option<Vec<(&'a Path, FontLicense)>>,
+    /// Whether the fonts are obfuscated, declaring them in `META-INF/encryption.xml`.
+    pub obfuscate_fonts: bool,
+    /// Optional list of SVG images paired with their raster fallback images.
+    pub fallbacks: Option<Vec<(Resource<'a>, Resource<'a>)>>,
     /// Optional, ordered list of main content units (chapters, sections, appendices).
     pub contents: Option<Vec<Content<'a>>>,
+    /// Whether a SHA-256 manifest of every generated file is embedded in `META-INF`.
+    pub integrity_manifest: bool,
+    /// Whether every problem is collected and reported at once before generating the file.
+    pub collect_errors: bool,
+    /// Optional directory against which relative resource paths are resolved.
+    pub base_dir: Option<&'a Path>,
+    /// Optional file system used for every file read. Defaults to the local disk.
+    pub file_system: Option<Arc<dyn FileSystem>>,
+    /// Optional post-generation hooks, transforming every generated file before it is written.
+    pub file_hooks: Option<Vec<FileHook>>,
+    /// Optional generator of the manifest item and anchor ids. Defaults to the filenames and `idNN`.
+    pub id_generator: Option<Arc<dyn IdGenerator>>,
+    /// Whether the filenames of the contents are derived from their titles instead of `cNN.xhtml`.
+    pub slug_filenames: bool,
+    /// Whether the nested contents are listed at a single level of the navigation, after their parent.
+    pub flat_toc: bool,
+    /// Optional `(content directory, package filename)` of the rootfile. Defaults to `OEBPS/content.opf`.
+    pub rootfile: Option<(String, String)>,
+    /// Optional files written as is into the archive.
+    pub raw_files: Option<Vec<RawFile<'a>>>,
+    /// Optional content transformers, rewriting every content body before the book is generated.
+    pub transformers: Option<Vec<Transformer>>,
+    /// Optional title of the generated endnotes page, collecting the notes of every content.
+    pub endnotes: Option<String>,
+    /// Optional title and references of the generated bibliography page.
+    pub bibliography: Option<(String, Bibliography)>,
+    /// Optional title of the generated index page, listing the index terms tagged in the contents.
+    pub index: Option<String>,
+    /// Optional title of the generated list of illustrations, listing the figures of the contents.
+    pub list_of_illustrations: Option<String>,
+    /// Optional title and registry of the generated list of abbreviations.
+    pub abbreviations: Option<(String, Abbreviations)>,
+    /// Optional title and registry of the generated list of characters.
+    pub characters: Option<(String, Characters)>,
+    /// Optional title and other titles of the author of the generated "Also by" page.
+    pub also_by: Option<(String, Vec<AlsoBy<'a>>)>,
+    /// Optional generated call-to-action page.
+    pub call_to_action: Option<CallToAction>,
+    /// Optional tracking parameters appended to the links of the generated pages.
+    pub link_tracking: Option<LinkTracking>,
+    /// Optional settings of the estimated reading time.
+    pub reading_time: Option<ReadingTime>,
+    /// Optional target reading system, adjusting the generation and validating its requirements.
+    pub profile: Option<Profile>,
+    /// Optional size budgets, checked while the file is created.
+    pub size_budget: Option<SizeBudget>,
+    /// Optional processing of the embedded images (resizing to a device class, grayscale conversion).
+    pub image_processing: Option<ImageProcessing>,
 }
 
 impl<'a> Epub<'a> {
@@ -30,10 +144,470 @@ impl<'a> Epub<'a> {
         Self {
             metadata,
             stylesheet: None,
+            page_template: None,
+            scripts: None,
+            bundle_scripts: false,
+            normalize_headings: false,
+            image_descriptions: None,
+            require_alt_text: false,
+            minify_scripts: false,
             cover_image: None,
+            cover_thumbnail: None,
+            itunes_artwork: false,
             resources: None,
+            credits: None,
+            image_credits: None,
+            license_links: false,
+            license_page: None,
+            watermark: None,
+            font_licenses: None,
+            obfuscate_fonts: false,
+            fallbacks: None,
             contents: None,
+            integrity_manifest: false,
+            collect_errors: false,
+            base_dir: None,
+            file_system: None,
+            file_hooks: None,
+            id_generator: None,
+            slug_filenames: false,
+            flat_toc: false,
+            rootfile: None,
+            raw_files: None,
+            transformers: None,
+            endnotes: None,
+            bibliography: None,
+            index: None,
+            list_of_illustrations: None,
+            abbreviations: None,
+            characters: None,
+            also_by: None,
+            call_to_action: None,
+            link_tracking: None,
+            reading_time: None,
+            profile: None,
+            size_budget: None,
+            image_processing: None,
+        }
+    }
+
+    /// Prepares the contents right before generating the file. It must be called only once.
+    ///
+    /// The process involves:
+    /// 1. Checking the rootfile and the raw files.
+    /// 2. Running the content transformers and assigning the slug filenames (if enabled).
+    /// 3. Rewriting the bodies: normalizing the heading levels (if enabled), resolving the citations, index
+    ///    terms, abbreviations and character names, and inserting the reading time badges (if enabled).
+    /// 4. Numbering the automatic page markers and marking the first body matter content as the start of
+    ///    reading (if none is marked).
+    /// 5. Inserting the generated front matter (the lists of characters, abbreviations and illustrations)
+    ///    and applying the registered image descriptions.
+    /// 6. Appending the generated back matter: the endnotes, bibliography, index, image credits, license
+    ///    and extended image descriptions pages, the "Also by" page and the call-to-action page.
+    /// 7. Stamping the watermark footer (if any), minifying and bundling the scripts (if enabled) and linking
+    ///    the media to their transcripts.
+    /// 8. Checking the alternative text of every image (if required) and the license of every font.
+    /// 9. Applying and validating the target profile, if any, and checking the resources against the size
+    ///    budget, if any.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Content`] naming the content if a transformer fails on it, or if it cites
+    /// an unknown key (or any key, if no bibliography is set), has a transcript without its media element,
+    /// an image without alternative text (if required) or a body that is not valid UTF-8.
+    ///
+    /// Returns a [`crate::Error::Validation`] if the rootfile or a raw file is invalid, a font license
+    /// disallows raw embedding without font obfuscation, a watermark footer has no copyright page, or
+    /// a requirement of the target profile or the size budget is not met.
+    ///
+    /// Returns a [`crate::Error::FilenameNotFound`] if a cover of the "Also by" page has no filename.
+    pub fn prepare(&mut self) -> crate::Result {
+        self.check_rootfile()?;
+        self.check_raw_files()?;
+        self.transform_bodies()?;
+        self.assign_slug_filenames();
+
+        if self.normalize_headings {
+            self.rewrite_bodies(|_, _, body| Ok(xml::normalize_headings(body)))?;
+        }
+
+        let bibliography = self
+            .bibliography
+            .as_ref()
+            .map(|(_, bibliography)| bibliography.clone())
+            .unwrap_or_default();
+        self.rewrite_bodies(|_, _, body| bibliography.resolve(body))?;
+
+        let mut term_index = TermIndex::default();
+        self.rewrite_bodies(|filename, title, body| Ok(term_index.resolve(filename, title, body)))?;
+
+        if let Some((_, ref abbreviations)) = self.abbreviations.clone() {
+            self.rewrite_bodies(|_, _, body| Ok(abbreviations.mark_up(body)))?;
+        }
+
+        if let Some((_, ref characters)) = self.characters.clone() {
+            if let Some(mut linker) = characters.linker() {
+                self.rewrite_bodies(|_, _, body| Ok(linker.link(body)))?;
+            }
+        }
+
+        if let Some(reading_time) = self.reading_time.filter(|reading_time| reading_time.badges) {
+            self.rewrite_bodies(|_, _, body| {
+                let minutes = reading_time.minutes(xml::word_count(body));
+                Ok((minutes > 0)
+                    .then(|| xml::insert_after_heading(body, &reading_time.badge(minutes)))
+                    .flatten())
+            })?;
+        }
+
+        self.number_pages()?;
+
+        if !self
+            .contents
+            .iter()
+            .flatten()
+            .any(|content| content.start_reading_count() > 0)
+            && let Some(content) = self
+                .contents
+                .iter_mut()
+                .flatten()
+                .find(|content| content.matter == Some(Matter::Body))
+        {
+            content.start_reading = true;
+        }
+
+        let front_matter = [self.characters_content(), self.abbreviations_content()];
+        for content in front_matter.into_iter().flatten() {
+            self.insert_front_matter(content);
+        }
+        self.insert_list_of_illustrations()?;
+
+        let mut describer = ImageDescriber::default();
+        if let Some(descriptions) = self.image_descriptions.clone() {
+            self.rewrite_bodies(|filename, _, body| {
+                Ok(describer.describe(filename, body, &descriptions))
+            })?;
+        }
+
+        let generated = [
+            self.endnotes_content(),
+            self.bibliography_content(),
+            self.index_content(&term_index),
+            self.credits_content(),
+            self.license_content(),
+        ];
+        for content in generated.into_iter().flatten() {
+            self.contents.get_or_insert_with(Vec::new).push(content);
+        }
+        if let Some(content) = describer.content("Image descriptions") {
+            self.contents.get_or_insert_with(Vec::new).push(content);
+        }
+        self.insert_also_by()?;
+        if let Some(content) = self.call_to_action_content() {
+            self.contents.get_or_insert_with(Vec::new).push(content);
+        }
+        self.stamp_watermark()?;
+
+        self.prepare_scripts()?;
+
+        let (mut number, mut transcript_number) = (0, 0);
+        for content in self.contents.iter_mut().flatten() {
+            content.link_transcripts(&mut number, &mut transcript_number)?;
+        }
+
+        if self.require_alt_text {
+            self.rewrite_bodies(
+                |_, _, body| match image_description::missing_alt_text(body) {
+                    Some(src) => Err(crate::Error::Validation(format!(
+                        "image '{src}' has no alt text"
+                    ))),
+                    None => Ok(None),
+                },
+            )?;
+        }
+
+        self.assign_anchor_ids();
+        self.check_font_licenses()?;
+
+        if let Some(profile) = self.profile {
+            profile.apply(self);
+            profile.validate(self)?;
+        }
+
+        if let Some(size_budget) = self.size_budget {
+            size_budget.check_resources(self)?;
+        }
+        Ok(())
+    }
+
+    /// Gets the `(title, minutes)` estimated reading time of every content, in reading order.
+    pub fn reading_times(&self, reading_time: &ReadingTime) -> Vec<(String, u32)> {
+        let mut word_counts = Vec::new();
+        for content in self.contents.iter().flatten() {
+            content.collect_word_counts(&mut word_counts);
+        }
+
+        word_counts
+            .into_iter()
+            .map(|(title, words)| (title, reading_time.minutes(words)))
+            .collect()
+    }
+
+    /// Generates the XML `<meta>` tag with the total estimated reading time (in minutes), if enabled.
+    pub fn reading_time_as_metadata_xml(&self) -> Option<String> {
+        let reading_time = self.reading_time.filter(|reading_time| reading_time.meta)?;
+        let minutes: u32 = self
+            .reading_times(&reading_time)
+            .into_iter()
+            .map(|(_, minutes)| minutes)
+            .sum();
+
+        Some(format!(
+            r#"<meta name="reading-time" content="{minutes}"/>"#
+        ))
+    }
+
+    /// Generates the XML `<meta>` tag with the manifest item id of the preview excerpt, if any
+    /// (see [`ContentBuilder::preview`]).
+    pub fn preview_as_metadata_xml(&self) -> Option<String> {
+        let mut file_number = 0;
+        let filename = self.contents.iter().flatten().find_map(|content| {
+            content.find_filename(&mut file_number, &|content| content.preview)
+        })?;
+
+        Some(format!(
+            r#"<meta name="preview" content="{}"/>"#,
+            self.ids().item_id(&filename)
+        ))
+    }
+
+    /// Minifies the scripts and joins them into a single bundle (if enabled), pointing the
+    /// references of the bodies to the bundle.
+    fn prepare_scripts(&mut self) -> crate::Result {
+        let Some(mut scripts) = self.scripts.take() else {
+            return Ok(());
+        };
+
+        if self.minify_scripts {
+            for script in &mut scripts {
+                let minified = std::str::from_utf8(&script.source)
+                    .map_err(|e| {
+                        crate::Error::Resource(script.filename.clone(), Box::new(e.into()))
+                    })
+                    .map(script::minify)?;
+                script.source = Cow::Owned(minified.into_bytes());
+            }
+        }
+
+        if self.bundle_scripts {
+            let filenames = scripts
+                .iter()
+                .map(|script| script.filename.clone())
+                .collect::<Vec<_>>();
+            self.rewrite_bodies(|_, _, body| Ok(script::reference_bundle(body, &filenames)))?;
+            scripts = vec![script::bundle(&scripts)];
+        }
+
+        self.scripts = Some(scripts);
+        Ok(())
+    }
+
+    /// Generates the XML `<meta>` tags declaring the **accessibility features** of the book
+    /// (e.g., `transcript` when a content has transcripts).
+    ///
+    /// Returns `None` if no feature is detected.
+    pub fn accessibility_as_metadata_xml(&self) -> Option<String> {
+        let has = |predicate: &dyn Fn(&Content<'_>) -> bool| {
+            self.contents
+                .iter()
+                .flatten()
+                .any(|content| content.any(&|content| predicate(content)))
+        };
+
+        let features = [("transcript", has(&|content| content.transcripts.is_some()))];
+        let xml = features
+            .into_iter()
+            .filter(|(_, detected)| *detected)
+            .map(|(feature, _)| {
+                format!(r#"<meta name="schema:accessibilityFeature" content="{feature}"/>"#)
+            })
+            .collect::<String>();
+
+        (!xml.is_empty()).then_some(xml)
+    }
+
+    /// Inserts a generated front matter content right before the first body matter content
+    /// (see [`ContentBuilder::matter`]) or, if there are no tagged contents, the first [`ReferenceType::Text`] content.
+    ///
+    /// Returns the position where it was inserted.
+    fn insert_front_matter(&mut self, content: Content<'a>) -> usize {
+        let contents = self.contents.get_or_insert_with(Vec::new);
+        let tagged = contents.iter().any(|content| content.matter.is_some());
+        let position = contents
+            .iter()
+            .position(|content| {
+                if tagged {
+                    content.matter.is_some_and(|matter| matter >= Matter::Body)
+                } else {
+                    matches!(content.reference_type, ReferenceType::Text(_))
+                }
+            })
+            .unwrap_or(contents.len());
+        contents.insert(position, content);
+        position
+    }
+
+    /// Labels the automatically numbered page markers (see [`crate::markup::PageMarker::auto`]): with lowercase roman numerals
+    /// in the front matter and arabic numbers from the body matter on.
+    fn number_pages(&mut self) -> crate::Result {
+        let (mut number, mut front_page, mut page) = (0, 0, 0);
+        for content in self.contents.iter_mut().flatten() {
+            let front = content.matter == Some(Matter::Front);
+            content.rewrite_bodies(&mut number, &mut |_, _, body| {
+                Ok(markup::number_pages(body, || {
+                    if front {
+                        front_page += 1;
+                        markup::roman_numeral(front_page)
+                    } else {
+                        page += 1;
+                        page.to_string()
+                    }
+                }))
+            })?;
+        }
+        Ok(())
+    }
+
+    /// Rewrites the body of every content, in reading order (see [`Content::rewrite_bodies`]).
+    pub(crate) fn rewrite_bodies<F>(&mut self, mut rewrite: F) -> crate::Result
+    where
+        F: FnMut(&str, &str, &str) -> crate::Result<Option<String>>,
+    {
+        let mut number = 0;
+        for content in self.contents.iter_mut().flatten() {
+            content.rewrite_bodies(&mut number, &mut rewrite)?;
+        }
+        Ok(())
+    }
+
+    /// Generates the list of characters, if set.
+    fn characters_content(&self) -> Option<Content<'a>> {
+        let (title, characters) = self.characters.as_ref()?;
+        let body = format!("<body><h1>{title}</h1>{}</body>", characters.build());
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::DramatisPersonae(title.clone()))
+                .filename(CHARACTERS_FILENAME)
+                .build(),
+        )
+    }
+
+    /// Generates the list of abbreviations, if set.
+    fn abbreviations_content(&self) -> Option<Content<'a>> {
+        let (title, abbreviations) = self.abbreviations.as_ref()?;
+        let body = format!("<body><h1>{title}</h1>{}</body>", abbreviations.build());
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Glossary(title.clone()))
+                .filename(ABBREVIATIONS_FILENAME)
+                .structure()
+                .build(),
+        )
+    }
+
+    /// Generates the index page, if enabled and any index term was found.
+    fn index_content(&self, term_index: &TermIndex) -> Option<Content<'a>> {
+        let title = self.index.as_ref().filter(|_| !term_index.is_empty())?;
+        let body = format!("<body><h1>{title}</h1>{}</body>", term_index.build());
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Index(title.clone()))
+                .filename(INDEX_FILENAME)
+                .structure()
+                .build(),
+        )
+    }
+
+    /// Inserts the list of illustrations as front matter, if enabled and any figure is found.
+    ///
+    /// The page is inserted before collecting the figures, so their links use the final filenames
+    /// of the contents that follow it.
+    fn insert_list_of_illustrations(&mut self) -> crate::Result {
+        let Some(title) = self.list_of_illustrations.clone() else {
+            return Ok(());
+        };
+        let loi = |body: String| {
+            ContentBuilder::new_owned(body, ReferenceType::Loi(title.clone()))
+                .filename(LOI_FILENAME)
+                .build()
+        };
+        let position = self.insert_front_matter(loi(String::new()));
+
+        let mut figure_list = FigureList::default();
+        self.rewrite_bodies(|filename, _, body| Ok(figure_list.resolve(filename, body)))?;
+
+        let contents = self.contents.get_or_insert_with(Vec::new);
+        if figure_list.is_empty() {
+            contents.remove(position);
+        } else {
+            contents[position] = loi(format!(
+                "<body><h1>{title}</h1>{}</body>",
+                figure_list.build()
+            ));
+        }
+        Ok(())
+    }
+
+    /// Generates the bibliography page, if set.
+    fn bibliography_content(&self) -> Option<Content<'a>> {
+        let (title, bibliography) = self.bibliography.as_ref()?;
+        let body = format!("<body><h1>{title}</h1>{}</body>", bibliography.build());
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Bibliography(title.clone()))
+                .filename(BIBLIOGRAPHY_FILENAME)
+                .structure()
+                .build(),
+        )
+    }
+
+    /// Generates the endnotes page, grouping the notes by content and linking back to their references.
+    ///
+    /// Returns `None` if the page is not enabled or no content has notes.
+    fn endnotes_content(&self) -> Option<Content<'a>> {
+        let title = self.endnotes.as_ref()?;
+
+        let mut groups = String::new();
+        collect_endnotes(
+            &mut 0,
+            self.contents.as_deref().unwrap_or_default(),
+            &mut groups,
+        );
+        if groups.is_empty() {
+            return None;
+        }
+
+        let body = format!("<body><h1>{title}</h1>{groups}</body>");
+
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Notes(title.clone()))
+                .filename(ENDNOTES_FILENAME)
+                .structure()
+                .build(),
+        )
+    }
+
+    /// Gets the markup linking the stylesheet and the page template, added to the `<head>` of every content.
+    pub fn head_links(&self) -> String {
+        let mut head = String::new();
+        if self.stylesheet.is_some() {
+            head.push_str(r#"<link href="style.css" rel="stylesheet" type="text/css"/>"#);
         }
+        if self.page_template.is_some() {
+            head.push_str(&format!(
+                r#"<link href="{PAGE_TEMPLATE_FILENAME}" rel="stylesheet" type="{PAGE_TEMPLATE_MEDIA_TYPE}"/>"#
+            ));
+        }
+        head
     }
 
     /// Generates the XML `<meta>` tag for the EPUB's NCX file, specifying the maximum **navigation depth**.
@@ -47,7 +621,8 @@ impl<'a> Epub<'a> {
     pub fn cover_image_as_metadata_xml(&self) -> Option<String> {
         Some(format!(
             r#"<meta name="cover" content="{}"/>"#,
-            self.cover_image.as_ref()?.filename().ok()?
+            self.ids()
+                .item_id(&self.cover_image.as_ref()?.filename().ok()?)
         ))
     }
 
@@ -55,7 +630,95 @@ impl<'a> Epub<'a> {
     ///
     /// Returns `None` if no cover image is set.
     pub fn cover_image_as_manifest_xml(&self) -> Option<String> {
-        self.cover_image.as_ref()?.as_manifest_xml()
+        self.cover_image.as_ref()?.as_manifest_xml(self.ids())
+    }
+
+    /// Gets the file system used to read resources, defaulting to [`StdFileSystem`].
+    pub fn file_system(&self) -> &dyn FileSystem {
+        self.file_system.as_deref().unwrap_or(&StdFileSystem)
+    }
+
+    /// Gets the image to be written as the `iTunesArtwork` file, preferring the cover thumbnail.
+    ///
+    /// Returns `None` if the option is disabled or there is no cover image at all.
+    pub fn itunes_artwork(&self) -> Option<&Resource<'a>> {
+        if self.itunes_artwork {
+            self.cover_thumbnail.as_ref().or(self.cover_image.as_ref())
+        } else {
+            None
+        }
+    }
+
+    /// Gets a **size hint** in bytes of the uncompressed book: stylesheet, page template, images, resources and content bodies.
+    ///
+    /// Resources are measured through their file metadata, so nothing is read from disk.
+    /// Resources whose metadata cannot be read are not counted.
+    pub fn size_hint(&self) -> u64 {
+        let resources = self
+            .cover_image
+            .iter()
+            .chain(self.cover_thumbnail.iter())
+            .chain(self.resources.iter().flatten())
+            .chain(
+                self.fallbacks
+                    .iter()
+                    .flatten()
+                    .flat_map(|(image, fallback)| [image, fallback]),
+            )
+            .filter_map(|resource| resource.size_hint(self.base_dir, self.file_system()))
+            .sum::<u64>();
+
+        let contents = self
+            .contents
+            .iter()
+            .flatten()
+            .map(Content::size_hint)
+            .sum::<u64>();
+
+        let scripts = self
+            .scripts
+            .iter()
+            .flatten()
+            .map(|script| script.source.len() as u64)
+            .sum::<u64>();
+
+        self.stylesheet
+            .map_or(0, |stylesheet| stylesheet.len() as u64)
+            + scripts
+            + self
+                .page_template
+                .map_or(0, |page_template| page_template.len() as u64)
+            + resources
+            + contents
+    }
+
+    /// Checks every resource and content, collecting **all** the problems found
+    /// (missing files, bad filenames, invalid bodies) instead of stopping at the first one.
+    ///
+    /// # Errors
+    /// Returns the single error found, or a [`crate::Error::Multiple`] aggregating all of them.
+    pub fn check(&self) -> crate::Result {
+        let mut errors = self
+            .cover_image
+            .iter()
+            .chain(self.cover_thumbnail.iter())
+            .chain(self.resources.iter().flatten())
+            .chain(
+                self.fallbacks
+                    .iter()
+                    .flatten()
+                    .flat_map(|(image, fallback)| [image, fallback]),
+            )
+            .filter_map(|resource| resource.check(self.base_dir, self.file_system()).err())
+            .collect::<Vec<_>>();
+
+        let mut file_number = 0;
+        let mut buffer = Vec::new();
+        for content in self.contents.iter().flatten() {
+            content.collect_errors(&mut file_number, &mut buffer, &mut errors);
+        }
+
+        crate::Error::aggregate(errors)
     }
 
     /// Calculates the maximum nesting level based on all content and content references.
@@ -63,6 +726,14 @@ impl<'a> Epub<'a> {
     /// This value is used to set the `dtb:depth` property in the TOC/NCX file.
     fn level(&self) -> usize {
         if let Some(ref contents) = self.contents {
+            if self.flat_toc {
+                return contents
+                    .iter()
+                    .map(|content| content.level_references_flat() + 1)
+                    .max()
+                    .unwrap_or(1);
+            }
+
             let level_subcontents = contents
                 .iter()
                 .map(|content| content.level() + 1)
@@ -85,7 +756,11 @@ impl<'a> Epub<'a> {
 /// A fluent builder for creating and configuring an Epub.
 ///
 /// Use the `create()` method to serialize the EPUB to a file.
-#[derive(Debug)]
+///
+/// The builder is `Send` and `Sync` and can be cloned to be used as a **template**:
+/// every clone owns its own metadata and content tree, so extending one of them
+/// (e.g., from different threads generating many similar books) never affects the others.
+#[derive(Debug, Clone)]
 pub struct EpubBuilder<'a>(pub(crate) Epub<'a>);
 
 impl<'a> EpubBuilder<'a> {
@@ -95,12 +770,98 @@ impl<'a> EpubBuilder<'a> {
         Self(Epub::new(metadata))
     }
 
+    /// Sets the **base directory** against which relative resource paths (cover image, fonts, images, etc.) are resolved.
+    ///
+    /// Without it, relative paths are resolved against the current working directory of the process,
+    /// so builds invoked from different directories may not find the files.
+    pub fn base_dir(mut self, base_dir: &'a Path) -> Self {
+        self.0.base_dir = Some(base_dir);
+        self
+    }
+
+    /// Sets the [`FileSystem`] used for **every file read** (cover image, thumbnail and resources).
+    ///
+    /// Defaults to [`StdFileSystem`], which reads from the local disk. A custom implementation
+    /// enables in-memory tests, sandboxed builds or sourcing files from archives or remote locations.
+    pub fn file_system<F: FileSystem + 'static>(mut self, file_system: F) -> Self {
+        self.0.file_system = Some(Arc::new(file_system));
+        self
+    }
+
     /// Sets the raw byte content for the required stylesheet (`style.css`).
     pub fn stylesheet(mut self, stylesheet: &'a [u8]) -> Self {
         self.0.stylesheet = Some(stylesheet);
         self
     }
 
+    /// Adds a **script resource** (JavaScript) with the given filename (e.g., `quiz.js`), to be referenced
+    /// from the content bodies with `<script src="quiz.js"></script>`.
+    pub fn add_script<S: Into<String>>(mut self, filename: S, source: &'a [u8]) -> Self {
+        let script = Script {
+            filename: filename.into(),
+            source: Cow::Borrowed(source),
+        };
+        if let Some(ref mut scripts) = self.0.scripts {
+            scripts.push(script);
+        } else {
+            self.0.scripts = Some(vec![script]);
+        }
+        self
+    }
+
+    /// Joins every script into a single bundle ([`crate::epub::SCRIPT_BUNDLE_FILENAME`]) when the file is created,
+    /// replacing the references of the bodies by a single reference to the bundle.
+    pub fn bundle_scripts(mut self, bundle_scripts: bool) -> Self {
+        self.0.bundle_scripts = bundle_scripts;
+        self
+    }
+
+    /// **Minifies** every script when the file is created, removing comments, indentation and empty lines.
+    pub fn minify_scripts(mut self, minify_scripts: bool) -> Self {
+        self.0.minify_scripts = minify_scripts;
+        self
+    }
+
+    /// Registers the **description** (alternative text and optional long description) of an image by its filename,
+    /// applied when the file is created to every `<img>` of the bodies (including the ones emitted by helpers like
+    /// [`crate::markup::Figure`]) whose `src` has the same filename and an empty or missing `alt`.
+    pub fn describe_image<S: Into<String>>(
+        mut self,
+        filename: S,
+        description: ImageDescription,
+    ) -> Self {
+        let description = (filename.into(), description);
+        if let Some(ref mut image_descriptions) = self.0.image_descriptions {
+            image_descriptions.push(description);
+        } else {
+            self.0.image_descriptions = Some(vec![description]);
+        }
+        self
+    }
+
+    /// Requires an **alternative text** on every image (an empty one marks a decorative image) once the registered
+    /// descriptions are applied (see [`EpubBuilder::describe_image`]): creating the file fails otherwise.
+    pub fn require_alt_text(mut self, require_alt_text: bool) -> Self {
+        self.0.require_alt_text = require_alt_text;
+        self
+    }
+
+    /// **Normalizes the heading levels** of every body when the file is created, so each content starts at `<h1>`
+    /// and no level is skipped (e.g., in imported HTML), improving accessibility and the extraction of titles
+    /// (see [`ContentBuilder::title_from_heading`]).
+    pub fn normalize_headings(mut self, normalize_headings: bool) -> Self {
+        self.0.normalize_headings = normalize_headings;
+        self
+    }
+
+    /// Sets an **Adobe page template** (`.xpgt`), linked from the head of every content.
+    ///
+    /// Legacy ADE-based readers rely on it for margins and columns, and some library distributors still require it.
+    pub fn page_template(mut self, page_template: &'a [u8]) -> Self {
+        self.0.page_template = Some(page_template);
+        self
+    }
+
     /// Sets the primary **cover image** for the EPUB.
     ///
     /// The cover image is automatically registered as a resource.
@@ -109,6 +870,25 @@ impl<'a> EpubBuilder<'a> {
         self
     }
 
+    /// Sets a small **cover thumbnail** image, so library software can show the cover
+    /// without rendering the full-size image.
+    ///
+    /// The thumbnail is registered as a resource. The image is not resized by this crate,
+    /// so it must be provided already scaled down.
+    pub fn cover_thumbnail(mut self, path: &'a Path, image_type: ImageType) -> Self {
+        self.0.cover_thumbnail = Some(Resource::Image(path, image_type));
+        self
+    }
+
+    /// Also writes the cover thumbnail as an **`iTunesArtwork`** file at the root of the archive,
+    /// following the iTunes/Apple Books convention.
+    ///
+    /// If no thumbnail is set, the cover image is used instead.
+    pub fn itunes_artwork(mut self, itunes_artwork: bool) -> Self {
+        self.0.itunes_artwork = itunes_artwork;
+        self
+    }
+
     /// Adds a single external [`Resource`] (e.g., a font or extra image) to the EPUB package.
     pub fn add_resource(mut self, resource: Resource<'a>) -> Self {
         if let Some(ref mut resources) = self.0.resources {
@@ -119,6 +899,13 @@ impl<'a> EpubBuilder<'a> {
         self
     }
 
+    /// Adds an embedded [`Video`] to the EPUB package: the video file and its poster image, if any.
+    ///
+    /// The `<video>` markup itself is embedded in a content body through the `Display` of the video.
+    pub fn add_video(self, video: &Video<'a>) -> Self {
+        self.add_resources(video.resources())
+    }
+
     /// Adds a collection of external [`Resource`] items to the EPUB package.
     pub fn add_resources(mut self, resources: Vec<Resource<'a>>) -> Self {
         if let Some(ref mut self_resources) = self.0.resources {
@@ -129,6 +916,30 @@ impl<'a> EpubBuilder<'a> {
         self
     }
 
+    /// Adds an **SVG image** together with a raster fallback image (e.g., a PNG rendering of the same image).
+    ///
+    /// Both images are registered as resources and the SVG manifest item declares the raster image
+    /// as its `fallback`, so reading systems without SVG support display the latter instead.
+    /// The fallback image must be provided already rasterized.
+    pub fn add_svg_with_fallback(
+        mut self,
+        svg: &'a Path,
+        fallback: &'a Path,
+        fallback_type: ImageType,
+    ) -> Self {
+        let pair = (
+            Resource::Image(svg, ImageType::Svg),
+            Resource::Image(fallback, fallback_type),
+        );
+
+        if let Some(ref mut fallbacks) = self.0.fallbacks {
+            fallbacks.push(pair);
+        } else {
+            self.0.fallbacks = Some(vec![pair]);
+        }
+        self
+    }
+
     /// Adds a single [`Content`] unit (like a chapter or section) to the main book flow.
     pub fn add_content(mut self, content: Content<'a>) -> Self {
         if let Some(ref mut contents) = self.0.contents {
@@ -149,74 +960,544 @@ impl<'a> EpubBuilder<'a> {
         self
     }
 
-    /// Finalizes the builder and **synchronously** generates the EPUB file, writing the contents to the provided writer.
-    ///
-    /// Uses the default zip compression method.
+    /// Collects the notes registered in every content (see [`crate::markup::Footnotes::endnotes`])
+    /// into a single generated **endnotes page** with the given title, grouped by content and with backlinks.
     ///
-    /// # Errors
-    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
-    pub fn create<W>(self, writer: &mut W) -> crate::Result
-    where
-        W: Write + Send,
-    {
-        self.create_with_compression(writer, ZipCompression::default())
+    /// The page is added at the end of the book as a [`ReferenceType::Notes`], so it is listed in the TOC and guide.
+    pub fn endnotes<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.endnotes = Some(title.into());
+        self
     }
 
-    /// Finalizes the builder and **synchronously** generates the EPUB file, using a specified zip compression method.
+    /// Adds a generated **bibliography page** with the given title, listing the references
+    /// of the [`Bibliography`] formatted in its style.
     ///
-    /// # Errors
-    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
-    pub fn create_with_compression<W>(
-        self,
-        writer: &mut W,
-        compression: ZipCompression,
-    ) -> crate::Result
-    where
-        W: Write + Send,
-    {
-        EpubFile::new(self.0, writer, compression).create()
+    /// The page is added at the end of the book as a [`ReferenceType::Bibliography`]. Use
+    /// [`crate::markup::CitationRef`] markers (resolved when the book is generated) or
+    /// [`Bibliography::link`] to cite its entries inline.
+    pub fn bibliography<S: Into<String>>(mut self, title: S, bibliography: Bibliography) -> Self {
+        self.0.bibliography = Some((title.into(), bibliography));
+        self
     }
 
-    /// **Asynchronously** generates the EPUB file, writing the contents to the provided `tokio::io::AsyncWrite` writer.
+    /// Adds a generated **list of illustrations** with the given title, linking to every figure of the contents
+    /// (a [`crate::markup::Figure`] or a `<figure>` element) labeled with its caption. Figures without an id get a generated one.
     ///
-    /// This method is only available when the **`async` feature** is enabled.
-    #[cfg(feature = "async")]
-    pub async fn async_create<W>(self, writer: &mut W) -> crate::Result
-    where
-        W: tokio::io::AsyncWrite + Unpin + Send,
-    {
-        self.async_create_with_compression(writer, ZipCompression::default())
-            .await
+    /// The list is added as front matter, as a [`ReferenceType::Loi`], if any figure is found.
+    pub fn list_of_illustrations<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.list_of_illustrations = Some(title.into());
+        self
     }
 
-    /// **Asynchronously** generates the EPUB file with a specified zip compression method.
+    /// Adds a generated **index page** with the given title, listing (alphabetically) every
+    /// [`crate::markup::IndexTerm`] tagged in the contents with links to its locations.
     ///
-    /// This method is only available when the **`async` feature** is enabled.
-    #[cfg(feature = "async")]
-    pub async fn async_create_with_compression<W>(
-        self,
-        writer: &mut W,
-        compression: ZipCompression,
-    ) -> crate::Result
-    where
-        W: tokio::io::AsyncWrite + Unpin + Send,
-    {
-        use crate::output::creator_async::EpubFile;
+    /// The page is added at the end of the book as a [`ReferenceType::Index`].
+    pub fn index<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.index = Some(title.into());
+        self
+    }
 
-        EpubFile::new(self.0, writer, compression).create().await
+    /// Marks up every occurrence of the [`Abbreviations`] in the contents with `<abbr title>` and adds
+    /// a generated **list of abbreviations** with the given title.
+    ///
+    /// The list is added as front matter (right before the first [`ReferenceType::Text`] content)
+    /// as a [`ReferenceType::Glossary`].
+    pub fn abbreviations<S: Into<String>>(
+        mut self,
+        title: S,
+        abbreviations: Abbreviations,
+    ) -> Self {
+        self.0.abbreviations = Some((title.into(), abbreviations));
+        self
     }
-}
 
-#[cfg(test)]
-mod tests {
-    use std::fs::File;
+    /// Adds a generated **list of characters** (*dramatis personae*) with the given title, optionally
+    /// linked from the first occurrence of every name in the contents (see [`Characters::link_first_occurrences`]).
+    ///
+    /// The list is added as front matter (right before the first [`ReferenceType::Text`] content)
+    /// as a [`ReferenceType::DramatisPersonae`].
+    pub fn characters<S: Into<String>>(mut self, title: S, characters: Characters) -> Self {
+        self.0.characters = Some((title.into(), characters));
+        self
+    }
 
-    use tempfile::tempdir;
+    /// Computes the **estimated reading time** of every content, emitting the total as metadata and/or
+    /// injecting "X min read" badges into the contents, as set in [`ReadingTime`].
+    pub fn reading_time(mut self, reading_time: ReadingTime) -> Self {
+        self.0.reading_time = Some(reading_time);
+        self
+    }
 
-    use super::*;
-    use crate::epub::{ContentBuilder, ContentReference, ReferenceType, metadata::MetadataBuilder};
+    /// Gets the `(title, minutes)` **estimated reading time** of every content (in reading order)
+    /// at the given reading speed. The total is the sum of all of them.
+    pub fn reading_times(&self, reading_time: &ReadingTime) -> Vec<(String, u32)> {
+        self.0.reading_times(reading_time)
+    }
 
-    #[test]
+    /// **Flattens the navigation**: the nested contents are listed at the same level as their parent, right
+    /// after it, for readers whose menus handle deep nesting poorly.
+    ///
+    /// Only the `navMap` of the `toc.ncx` changes: the content tree, its files and the spine stay the same.
+    /// The content references of every file are still nested under it.
+    pub fn flat_toc(mut self, flat_toc: bool) -> Self {
+        self.0.flat_toc = flat_toc;
+        self
+    }
+
+    /// Embeds a **SHA-256 manifest** (`META-INF/checksums.sha256`) listing the digest of every file in the archive.
+    ///
+    /// The manifest follows the `sha256sum` format, so distribution pipelines can verify the
+    /// integrity of the extracted files and detect tampering between build and delivery.
+    pub fn integrity_manifest(mut self, integrity_manifest: bool) -> Self {
+        self.0.integrity_manifest = integrity_manifest;
+        self
+    }
+
+    /// Checks the whole book for obvious mistakes before generating it: invalid metadata,
+    /// invalid contents, several starts of reading, duplicated content filenames, resources without a filename
+    /// and the requirements of the target profile.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] describing the first problem found.
+    pub fn validate(&self) -> crate::Result {
+        self.0.metadata.validate()?;
+
+        let contents = self.0.contents.as_deref().unwrap_or_default();
+        contents.iter().try_for_each(Content::validate)?;
+
+        if contents
+            .iter()
+            .map(Content::start_reading_count)
+            .sum::<usize>()
+            > 1
+        {
+            return Err(crate::Error::Validation(
+                "only one content can be the start of reading".to_string(),
+            ));
+        }
+
+        if contents.iter().map(Content::preview_count).sum::<usize>() > 1 {
+            return Err(crate::Error::Validation(
+                "only one content can be the preview excerpt".to_string(),
+            ));
+        }
+
+        let mut tagged = contents
+            .iter()
+            .filter_map(|content| content.matter.map(|matter| (content, matter)));
+        if let Some(mut previous) = tagged.next() {
+            for (content, matter) in tagged {
+                if matter < previous.1 {
+                    return Err(crate::Error::Validation(format!(
+                        "content '{}' ({}) must not follow content '{}' ({})",
+                        content.title(),
+                        matter.as_ref(),
+                        previous.0.title(),
+                        previous.1.as_ref()
+                    )));
+                }
+                previous = (content, matter);
+            }
+        }
+
+        let scripts = self.0.scripts.as_deref().unwrap_or_default();
+        for (index, script) in scripts.iter().enumerate() {
+            if !script.filename.ends_with(".js") || script.filename.contains('/') {
+                return Err(crate::Error::Validation(format!(
+                    "script '{}' has an invalid filename",
+                    script.filename
+                )));
+            }
+            if scripts[..index]
+                .iter()
+                .any(|other| other.filename == script.filename)
+            {
+                return Err(crate::Error::Validation(format!(
+                    "script filename '{}' is used more than once",
+                    script.filename
+                )));
+            }
+        }
+
+        let (mut filenames, mut links) = (Vec::new(), Vec::new());
+        collect_filenames(&mut 0, contents, &mut filenames, &mut links);
+        for (index, filename) in filenames.iter().enumerate() {
+            if filenames[..index].contains(filename) {
+                return Err(crate::Error::Validation(format!(
+                    "content filename '{filename}' is used more than once"
+                )));
+            }
+        }
+        if let Some(link) = links.iter().find(|link| {
+            let target = link
+                .split_once('#')
+                .map_or(link.as_str(), |(target, _)| target);
+            !filenames.iter().any(|filename| filename == target)
+        }) {
+            return Err(crate::Error::Validation(format!(
+                "link '{link}' does not point to a content"
+            )));
+        }
+
+        self.0
+            .cover_image
+            .iter()
+            .chain(self.0.cover_thumbnail.iter())
+            .chain(self.0.resources.iter().flatten())
+            .try_for_each(|resource| {
+                resource.filename().map(|_| ()).map_err(|_| {
+                    crate::Error::Validation(format!("resource '{resource}' has no filename"))
+                })
+            })?;
+
+        self.0
+            .profile
+            .map_or(Ok(()), |profile| profile.validate(&self.0))
+    }
+
+    /// Sets the **target reading system** (see [`Profile`]), adjusting the generation to its requirements
+    /// and checking them when the file is created.
+    pub fn profile(mut self, profile: Profile) -> Self {
+        self.0.profile = Some(profile);
+        self
+    }
+
+    /// Sets the **size budgets** (total, per spine item and per resource, e.g., retailer limits), checked while the file is created.
+    pub fn size_budget(mut self, size_budget: SizeBudget) -> Self {
+        self.0.size_budget = Some(size_budget);
+        self
+    }
+
+    /// Runs a **lint pass** over the book, reporting the constructs known to break specific readers
+    /// (see [`Linter`]). Nothing is changed nor rejected.
+    pub fn lint(&self, linter: &Linter) -> Vec<Lint> {
+        linter.lint(&self.0)
+    }
+
+    /// Checks every resource and content up front when generating the file, reporting
+    /// **all** missing files, bad filenames and invalid bodies at once instead of stopping at the first one.
+    pub fn collect_errors(mut self, collect_errors: bool) -> Self {
+        self.0.collect_errors = collect_errors;
+        self
+    }
+
+    /// Checks every resource and content, collecting all the problems found
+    /// (missing files, bad filenames, invalid bodies) without generating the file.
+    ///
+    /// # Errors
+    /// Returns the single error found, or a [`crate::Error::Multiple`] aggregating all of them.
+    pub fn check(&self) -> crate::Result {
+        self.0.check()
+    }
+
+    /// Gets an approximate **size in bytes** of the uncompressed book, without reading any file.
+    ///
+    /// Useful to preallocate buffers or to report progress while the EPUB is generated.
+    pub fn size_hint(&self) -> u64 {
+        self.0.size_hint()
+    }
+
+    /// **Estimates the size** in bytes of the final file, using the default zip compression method,
+    /// so oversized builds can be rejected early.
+    ///
+    /// See [`EpubBuilder::estimate_size_with_compression`].
+    ///
+    /// # Errors
+    /// Returns any error produced while generating the files (e.g., an unknown citation key).
+    pub fn estimate_size(&self) -> crate::Result<u64> {
+        self.estimate_size_with_compression(ZipCompression::default())
+    }
+
+    /// **Estimates the size** in bytes of the final file, using a specified zip compression method,
+    /// without writing the archive nor reading any resource.
+    ///
+    /// The generated files (contents, package and navigation files) are compressed in memory, while the
+    /// resources are measured through their file metadata, so the estimate is close to the actual size.
+    ///
+    /// # Errors
+    /// Returns any error produced while generating the files (e.g., an unknown citation key).
+    pub fn estimate_size_with_compression(
+        &self,
+        compression: ZipCompression,
+    ) -> crate::Result<u64> {
+        creator::estimate_size(&self.0, compression)
+    }
+
+    /// Finalizes the builder and **synchronously** generates the EPUB file, writing the contents to the provided writer.
+    ///
+    /// Uses the default zip compression method.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    pub fn create<W>(self, writer: &mut W) -> crate::Result
+    where
+        W: Write + Send,
+    {
+        self.create_with_compression(writer, ZipCompression::default())
+    }
+
+    /// Finalizes the builder and **synchronously** generates the EPUB file, using a specified zip compression method.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    pub fn create_with_compression<W>(
+        self,
+        writer: &mut W,
+        compression: ZipCompression,
+    ) -> crate::Result
+    where
+        W: Write + Send,
+    {
+        EpubFile::new(self.0, writer, compression).create()
+    }
+
+    /// **Asynchronously** generates the EPUB file, writing the contents to the provided `tokio::io::AsyncWrite` writer.
+    ///
+    /// This method is only available when the **`async` feature** is enabled.
+    #[cfg(feature = "async")]
+    pub async fn async_create<W>(self, writer: &mut W) -> crate::Result
+    where
+        W: tokio::io::AsyncWrite + Unpin + Send,
+    {
+        self.async_create_with_compression(writer, ZipCompression::default())
+            .await
+    }
+
+    /// **Asynchronously** generates the EPUB file with a specified zip compression method.
+    ///
+    /// This method is only available when the **`async` feature** is enabled.
+    #[cfg(feature = "async")]
+    pub async fn async_create_with_compression<W>(
+        self,
+        writer: &mut W,
+        compression: ZipCompression,
+    ) -> crate::Result
+    where
+        W: tokio::io::AsyncWrite + Unpin + Send,
+    {
+        use crate::output::creator_async::EpubFile;
+
+        EpubFile::new(self.0, writer, compression).create().await
+    }
+
+    /// Finalizes the builder and **synchronously** generates the EPUB file at `path`, **crash-safe**: the book
+    /// is written to a temporary file next to it, which is renamed to `path` only on success.
+    ///
+    /// On any error the temporary file is removed, so a failed build never leaves a partial `.epub` behind
+    /// (nor overwrites an existing one).
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    pub fn create_file<P: AsRef<Path>>(self, path: P) -> crate::Result {
+        let path = path.as_ref();
+        let temp_path = temp_path(path);
+
+        let result = std::fs::OpenOptions::new()
+            .write(true)
+            .create_new(true)
+            .open(&temp_path)
+            .map_err(crate::Error::from)
+            .and_then(|mut file| {
+                self.create(&mut file)?;
+                file.sync_all()?;
+                std::fs::rename(&temp_path, path)?;
+                Ok(sync_parent_dir(path)?)
+            });
+
+        if result.is_err() {
+            let _ = std::fs::remove_file(&temp_path);
+        }
+        result
+    }
+
+    /// **Asynchronously** generates the EPUB file at `path`, crash-safe (see [`EpubBuilder::create_file`]).
+    ///
+    /// This method is only available when the **`async` feature** is enabled.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    #[cfg(feature = "async")]
+    pub async fn async_create_file<P: AsRef<Path>>(self, path: P) -> crate::Result {
+        use tokio::io::AsyncWriteExt;
+
+        let path = path.as_ref();
+        let temp_path = temp_path(path);
+
+        let result = async {
+            let mut file = tokio::fs::OpenOptions::new()
+                .write(true)
+                .create_new(true)
+                .open(&temp_path)
+                .await?;
+            self.async_create(&mut file).await?;
+            file.flush().await?;
+            file.sync_all().await?;
+            tokio::fs::rename(&temp_path, path).await?;
+            #[cfg(unix)]
+            tokio::fs::File::open(parent_dir(path))
+                .await?
+                .sync_all()
+                .await?;
+            Ok::<_, crate::Error>(())
+        }
+        .await;
+
+        if result.is_err() {
+            let _ = tokio::fs::remove_file(&temp_path).await;
+        }
+        result
+    }
+}
+
+/// Gets the path of the temporary file of a crash-safe output: a hidden file in the same directory,
+/// so the final rename never crosses file systems (e.g., `.book.epub.1234.0.tmp` for `book.epub`).
+///
+/// Besides the process id, the name carries a per-call counter and the current nanoseconds, so concurrent
+/// builds of the same path never share it and a stale file left by a reused process id never blocks one.
+fn temp_path(path: &Path) -> std::path::PathBuf {
+    static COUNTER: AtomicUsize = AtomicUsize::new(0);
+
+    let filename = path
+        .file_name()
+        .map(|filename| filename.to_string_lossy().into_owned())
+        .unwrap_or_default();
+    let count = COUNTER.fetch_add(1, Ordering::Relaxed);
+    let nanos = std::time::SystemTime::now()
+        .duration_since(std::time::UNIX_EPOCH)
+        .map(|duration| duration.subsec_nanos())
+        .unwrap_or_default();
+    path.with_file_name(format!(
+        ".{filename}.{}.{count}.{nanos}.tmp",
+        std::process::id()
+    ))
+}
+
+/// Gets the directory of `path` (the current one for a bare filename).
+fn parent_dir(path: &Path) -> &Path {
+    match path.parent() {
+        Some(parent) if !parent.as_os_str().is_empty() => parent,
+        _ => Path::new("."),
+    }
+}
+
+/// Syncs the directory of `path` after a rename, so the new entry survives a crash. Directories cannot
+/// be opened as files outside Unix, where this does nothing.
+fn sync_parent_dir(path: &Path) -> std::io::Result<()> {
+    #[cfg(unix)]
+    std::fs::File::open(parent_dir(path))?.sync_all()?;
+    #[cfg(not(unix))]
+    let _ = path;
+    Ok(())
+}
+
+/// Recursively collects the final filenames of the contents, in the same order they are written,
+/// and the targets of the navigation-only entries.
+fn collect_filenames(
+    file_number: &mut usize,
+    contents: &[Content<'_>],
+    filenames: &mut Vec<String>,
+    links: &mut Vec<String>,
+) {
+    for content in contents {
+        *file_number += 1;
+        let filename = content.filename(*file_number).into_owned();
+        if content.link {
+            links.push(filename);
+        } else {
+            filenames.push(filename);
+        }
+        collect_filenames(
+            file_number,
+            content.subcontents.as_deref().unwrap_or_default(),
+            filenames,
+            links,
+        );
+    }
+}
+
+/// Recursively renders the notes of the contents, grouped under the title of their content.
+fn collect_endnotes(file_number: &mut usize, contents: &[Content<'_>], groups: &mut String) {
+    for content in contents {
+        *file_number += 1;
+        if let Some(notes) = content.notes.as_ref().filter(|notes| !notes.is_empty()) {
+            groups.push_str(&format!(
+                "<h2>{}</h2>{}",
+                content.title(),
+                notes.endnotes_xml(&content.filename(*file_number))
+            ));
+        }
+        collect_endnotes(
+            file_number,
+            content.subcontents.as_deref().unwrap_or_default(),
+            groups,
+        );
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::fs::File;
+
+    use tempfile::tempdir;
+
+    use super::*;
+    use crate::{
+        epub::{
+            ContentReference, DESCRIPTIONS_FILENAME, MemoryFileSystem, SCRIPT_BUNDLE_FILENAME,
+            Transcript, metadata::MetadataBuilder,
+        },
+        markup::{
+            Abbreviations, Characters, CitationBuilder, CitationRef, Figure, Footnotes, IndexTerm,
+            PageMarker, Question, Quiz, ReadingTime,
+        },
+    };
+
+    #[test]
+    fn test_epub_builder_create_file() {
+        let temp_dir = tempdir().unwrap();
+        let path = temp_dir.path().join("book.epub");
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+        );
+
+        builder.clone().create_file(&path).unwrap();
+        assert!(path.metadata().unwrap().len() > 0);
+        assert_eq!(std::fs::read_dir(temp_dir.path()).unwrap().count(), 1);
+
+        let failing_path = temp_dir.path().join("failing.epub");
+        let result = builder
+            .cover_image(Path::new("missing.jpg"), ImageType::Jpg)
+            .create_file(&failing_path);
+        assert!(result.is_err());
+        assert!(!failing_path.exists());
+        assert_eq!(std::fs::read_dir(temp_dir.path()).unwrap().count(), 1);
+    }
+
+    #[test]
+    fn test_epub_builder_create_file_concurrently() {
+        let temp_dir = tempdir().unwrap();
+        let path = temp_dir.path().join("book.epub");
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+        );
+
+        assert_ne!(temp_path(&path), temp_path(&path));
+        assert_eq!(parent_dir(Path::new("book.epub")), Path::new("."));
+
+        std::thread::scope(|scope| {
+            let handles = (0..4)
+                .map(|_| scope.spawn(|| builder.clone().create_file(&path)))
+                .collect::<Vec<_>>();
+            for handle in handles {
+                handle.join().unwrap().unwrap();
+            }
+        });
+        assert!(path.metadata().unwrap().len() > 0);
+        assert_eq!(std::fs::read_dir(temp_dir.path()).unwrap().count(), 1);
+    }
+
+    #[test]
     fn test_epub_builder_new() {
         let metadata = MetadataBuilder::title("Title").build();
         let builder = EpubBuilder::new(metadata);
@@ -224,6 +1505,859 @@ mod tests {
         assert!(builder.0.stylesheet.is_none());
     }
 
+    #[test]
+    fn test_epub_builder_is_send_and_sync() {
+        fn assert_send_sync<T: Send + Sync>() {}
+        assert_send_sync::<EpubBuilder<'_>>();
+    }
+
+    #[test]
+    fn test_epub_builder_clone_as_template() {
+        let template = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(
+                "<body><h1>Intro</h1></body>".as_bytes(),
+                ReferenceType::Text("Intro".to_string()),
+            )
+            .build(),
+        );
+
+        let handles = (0..4)
+            .map(|number| {
+                let builder = template.clone();
+                std::thread::spawn(move || {
+                    builder
+                        .add_content(
+                            ContentBuilder::new(
+                                "<body><h1>Chapter</h1></body>".as_bytes(),
+                                ReferenceType::Text(format!("Chapter {number}")),
+                            )
+                            .build(),
+                        )
+                        .0
+                        .contents
+                        .map(|contents| contents.len())
+                })
+            })
+            .collect::<Vec<_>>();
+
+        for handle in handles {
+            assert_eq!(handle.join().unwrap(), Some(2));
+        }
+        assert_eq!(template.0.contents.map(|contents| contents.len()), Some(1));
+    }
+
+    #[test]
+    fn test_epub_builder_add_svg_with_fallback() {
+        let metadata = MetadataBuilder::title("Title").build();
+        let builder = EpubBuilder::new(metadata)
+            .add_svg_with_fallback(Path::new("map.svg"), Path::new("map.png"), ImageType::Png)
+            .add_svg_with_fallback(Path::new("plan.svg"), Path::new("plan.jpg"), ImageType::Jpg);
+
+        let fallbacks = builder.0.fallbacks.expect("Fallbacks were not set");
+        assert_eq!(fallbacks.len(), 2);
+        assert_eq!(fallbacks[0].0.media_type(), "image/svg+xml");
+        assert_eq!(fallbacks[1].1.media_type(), "image/jpeg");
+    }
+
+    #[test]
+    fn test_epub_builder_itunes_artwork() {
+        let metadata = MetadataBuilder::title("Title").build();
+        let cover = Path::new("cover.jpg");
+        let thumbnail = Path::new("thumbnail.jpg");
+
+        let builder = EpubBuilder::new(metadata.clone())
+            .cover_image(cover, ImageType::Jpg)
+            .itunes_artwork(true);
+        assert_eq!(
+            builder.0.itunes_artwork().unwrap().filename().unwrap(),
+            "cover.jpg"
+        );
+
+        let builder = builder.cover_thumbnail(thumbnail, ImageType::Jpg);
+        assert_eq!(
+            builder.0.itunes_artwork().unwrap().filename().unwrap(),
+            "thumbnail.jpg"
+        );
+
+        let builder = EpubBuilder::new(metadata)
+            .cover_image(cover, ImageType::Jpg)
+            .cover_thumbnail(thumbnail, ImageType::Jpg);
+        assert!(builder.0.itunes_artwork().is_none());
+    }
+
+    #[test]
+    fn test_epub_builder_size_hint() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        let font = temp_dir.path().join("SomeFont.ttf");
+        std::fs::write(&font, [0; 100]).expect("Error writing mock font");
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .stylesheet(b"body {}")
+            .add_resource(Resource::Font(&font))
+            .add_resource(Resource::Font(Path::new("missing.ttf")))
+            .add_content(
+                ContentBuilder::new(
+                    "<body></body>".as_bytes(),
+                    ReferenceType::Text("Chapter 1".to_string()),
+                )
+                .build(),
+            );
+
+        assert_eq!(builder.size_hint(), 7 + 100 + 13);
+    }
+
+    #[test]
+    fn test_epub_builder_validate() {
+        let chapter = |filename: &str| {
+            ContentBuilder::new(
+                "<body></body>".as_bytes(),
+                ReferenceType::Text("Chapter".to_string()),
+            )
+            .filename(filename)
+            .build()
+        };
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("chapter1.xhtml"))
+            .add_content(chapter("chapter2.xhtml"));
+        assert!(builder.validate().is_ok());
+
+        let builder = builder.add_content(chapter("chapter1.xhtml"));
+        assert!(matches!(
+            builder.validate(),
+            Err(crate::Error::Validation(_))
+        ));
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("").build());
+        assert!(matches!(
+            builder.validate(),
+            Err(crate::Error::Validation(_))
+        ));
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_resource(Resource::Font(Path::new("/")));
+        assert!(matches!(
+            builder.validate(),
+            Err(crate::Error::Validation(_))
+        ));
+    }
+
+    #[test]
+    fn test_epub_builder_collect_errors() {
+        let result = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .cover_image(Path::new("missing_cover.jpg"), ImageType::Jpg)
+            .add_resource(Resource::Font(Path::new("missing_font.otf")))
+            .add_content(
+                ContentBuilder::new(
+                    "<body><p>Unclosed</body>".as_bytes(),
+                    ReferenceType::Text("Chapter 1".to_string()),
+                )
+                .filename("chapter1.html")
+                .build(),
+            )
+            .collect_errors(true)
+            .create(&mut Vec::new());
+
+        match result {
+            Err(crate::Error::Multiple(errors)) => {
+                assert_eq!(errors.len(), 4);
+                let message = crate::Error::Multiple(errors).to_string();
+                assert!(message.starts_with("4 errors found:"));
+                assert!(message.contains("missing_cover.jpg"));
+                assert!(message.contains("missing_font.otf"));
+                assert!(message.contains("chapter1.html"));
+            }
+            _ => panic!("Expected multiple errors"),
+        }
+    }
+
+    #[test]
+    fn test_epub_builder_base_dir() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        std::fs::create_dir(temp_dir.path().join("img")).expect("Error creating mock dir");
+        std::fs::write(temp_dir.path().join("img/cover.png"), b"dummy image data")
+            .expect("Error writing mock cover image");
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .cover_image(Path::new("img/cover.png"), ImageType::Png);
+        assert!(builder.check().is_err());
+
+        let builder = builder.base_dir(temp_dir.path());
+        assert!(builder.check().is_ok());
+        assert!(builder.create(&mut Vec::new()).is_ok());
+    }
+
+    #[test]
+    fn test_epub_builder_file_system() {
+        let file_system = MemoryFileSystem::new()
+            .file("cover.png", b"dummy image data".to_vec())
+            .file("fonts/font.otf", b"dummy font data".to_vec());
+
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .cover_image(Path::new("cover.png"), ImageType::Png)
+            .add_resource(Resource::Font(Path::new("fonts/font.otf")))
+            .file_system(file_system);
+
+        assert!(builder.check().is_ok());
+        assert_eq!(builder.size_hint(), 16 + 15);
+        assert!(builder.create(&mut Vec::new()).is_ok());
+    }
+
+    #[test]
+    fn test_epub_builder_estimate_size() {
+        let body = format!(
+            "<body>{}</body>",
+            "<p>Lorem ipsum dolor sit amet.</p>".repeat(100)
+        );
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .file_system(MemoryFileSystem::new().file("image.png", vec![7; 4096]))
+            .add_resource(Resource::Image(Path::new("image.png"), ImageType::Png))
+            .add_content(
+                ContentBuilder::new(body.as_bytes(), ReferenceType::Text("One".to_string()))
+                    .build(),
+            );
+
+        for compression in [ZipCompression::Stored, ZipCompression::Deflated] {
+            let estimate = builder
+                .estimate_size_with_compression(compression.clone())
+                .unwrap();
+
+            let mut actual = Vec::new();
+            builder
+                .clone()
+                .create_with_compression(&mut actual, compression)
+                .unwrap();
+
+            assert!(estimate.abs_diff(actual.len() as u64) < 64);
+        }
+    }
+
+    #[test]
+    fn test_epub_builder_links() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Preface("Front".to_string()))
+                    .add_child(
+                        ContentBuilder::new(b"<body/>", ReferenceType::Text("Maps".to_string()))
+                            .filename("maps.xhtml")
+                            .build(),
+                    )
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Appendix".to_string()))
+                    .add_child(
+                        ContentBuilder::link(
+                            ReferenceType::Text("Maps".to_string()),
+                            "maps.xhtml#north",
+                        )
+                        .build(),
+                    )
+                    .build(),
+            );
+        assert!(builder.validate().is_ok());
+
+        let content_opf = crate::output::file_content::content_opf(&builder.0)
+            .unwrap()
+            .bytes;
+        assert_eq!(content_opf.matches(r#"idref="maps.xhtml""#).count(), 1);
+        assert_eq!(content_opf.matches(r#"<item id="maps.xhtml""#).count(), 1);
+        assert!(content_opf.contains(r#"href="maps.xhtml#north""#));
+
+        let toc_ncx = crate::output::file_content::toc_ncx(&builder.0)
+            .unwrap()
+            .bytes;
+        assert!(toc_ncx.contains(r#"<content src="maps.xhtml"/>"#));
+        assert!(toc_ncx.contains(r#"<content src="maps.xhtml#north"/>"#));
+
+        let files = builder.0.contents.as_ref().unwrap()[1]
+            .file_content(&mut 2, "")
+            .unwrap();
+        assert_eq!(files.len(), 1);
+        assert!(builder.clone().create(&mut Vec::new()).is_ok());
+
+        let builder = builder.add_content(
+            ContentBuilder::link(ReferenceType::Text("Lost".to_string()), "lost.xhtml").build(),
+        );
+        assert!(builder.validate().is_err());
+    }
+
+    #[test]
+    fn test_epub_builder_matter() {
+        let content = |title: &str, matter| {
+            ContentBuilder::new(b"<body/>", ReferenceType::Text(title.to_string()))
+                .matter(matter)
+                .build()
+        };
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(content("Preface", Matter::Front))
+            .add_content(content("Chapter", Matter::Body))
+            .add_content(content("Appendix", Matter::Back))
+            .abbreviations(
+                "Abbreviations",
+                Abbreviations::new().define("EPUB", "Electronic Publication"),
+            );
+        assert!(builder.validate().is_ok());
+
+        let mut epub = builder.clone().0;
+        epub.prepare().unwrap();
+        let contents = epub.contents.as_ref().unwrap();
+        assert_eq!(contents[1].title(), "Abbreviations");
+        assert!(contents[2].start_reading);
+
+        let content_opf = crate::output::file_content::content_opf(&epub)
+            .unwrap()
+            .bytes;
+        assert_eq!(content_opf.matches(r#"<reference type="text""#).count(), 1);
+
+        let result = builder
+            .add_content(content("Foreword", Matter::Front))
+            .validate();
+        assert_eq!(
+            result.unwrap_err().to_string(),
+            "Validation error: content 'Foreword' (frontmatter) must not follow content 'Appendix' (backmatter)"
+        );
+    }
+
+    #[test]
+    fn test_epub_builder_page_numbering() {
+        let body = format!("<body><p>A</p>{}<p>B</p></body>", PageMarker::auto());
+        let content = |title: &str, matter| {
+            ContentBuilder::new_owned(body.clone(), ReferenceType::Text(title.to_string()))
+                .matter(matter)
+                .build()
+        };
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(content("Foreword", Matter::Front))
+            .add_content(content("Preface", Matter::Front))
+            .add_content(content("Chapter", Matter::Body))
+            .0;
+        epub.prepare().unwrap();
+
+        let toc_ncx = crate::output::file_content::toc_ncx(&epub).unwrap().bytes;
+        assert!(toc_ncx.contains(r#"<content src="c02.xhtml#page-ii"/>"#));
+        assert!(toc_ncx.contains(r#"<content src="c03.xhtml#page-1"/>"#));
+        assert!(toc_ncx.contains(r#"type="front""#));
+    }
+
+    #[test]
+    fn test_epub_builder_list_of_illustrations() {
+        let body = format!(
+            "<body><h1>One</h1>{}</body>",
+            Figure::new("map.png", "Map").caption("The island")
+        );
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .list_of_illustrations("Illustrations")
+            .add_content(
+                ContentBuilder::new_owned(body, ReferenceType::Text("One".to_string())).build(),
+            )
+            .0;
+        epub.prepare().unwrap();
+
+        let contents = epub.contents.as_ref().unwrap();
+        assert_eq!(contents[0].filename(1), LOI_FILENAME);
+        let files = contents[0].file_content(&mut 0, "").unwrap();
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="c02.xhtml#figure-1">The island</a>"#)
+        );
+
+        let content_opf = crate::output::file_content::content_opf(&epub)
+            .unwrap()
+            .bytes;
+        assert!(content_opf.contains(r#"<reference type="loi" title="Illustrations""#));
+    }
+
+    #[test]
+    fn test_epub_builder_image_descriptions() {
+        let body = format!(
+            r#"<body>{}<p><img src="images/logo.png"/></p></body>"#,
+            Figure::new("chart.png", "").caption("Sales")
+        );
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .describe_image("chart.png", ImageDescription::new("Bar chart of sales"))
+            .require_alt_text(true)
+            .add_content(
+                ContentBuilder::new_owned(body, ReferenceType::Text("One".to_string())).build(),
+            );
+
+        let mut epub = builder.clone().0;
+        assert_eq!(
+            epub.prepare().unwrap_err().to_string(),
+            "Error in content 'One' (c01.xhtml): Validation error: image 'images/logo.png' has no alt text"
+        );
+
+        let mut epub = builder
+            .describe_image("logo.png", ImageDescription::new(""))
+            .0;
+        epub.prepare().unwrap();
+        let files = epub.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(files[0].bytes.contains(r#"alt="Bar chart of sales""#));
+
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .describe_image(
+                "flow.png",
+                ImageDescription::new("Flow").extended_description("<p>Steps</p>"),
+            )
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><p><img src="flow.png"/></p></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            )
+            .0;
+        epub.prepare().unwrap();
+        let contents = epub.contents.as_ref().unwrap();
+        assert_eq!(contents[1].filename(2), DESCRIPTIONS_FILENAME);
+        assert!(!contents[1].linear);
+    }
+
+    #[test]
+    fn test_epub_builder_heading() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::heading(ReferenceType::Text("Part One".to_string()))
+                .add_child(
+                    ContentBuilder::heading(ReferenceType::Text("Book A".to_string()))
+                        .add_child(
+                            ContentBuilder::new(
+                                b"<body/>",
+                                ReferenceType::Text("Chapter 1".to_string()),
+                            )
+                            .build(),
+                        )
+                        .build(),
+                )
+                .build(),
+        );
+        assert!(builder.validate().is_ok());
+
+        let toc_ncx = crate::output::file_content::toc_ncx(&builder.0)
+            .unwrap()
+            .bytes;
+        assert_eq!(toc_ncx.matches(r#"<content src="c03.xhtml"/>"#).count(), 3);
+        assert!(toc_ncx.contains("<text>Part One</text>"));
+
+        let content_opf = crate::output::file_content::content_opf(&builder.0)
+            .unwrap()
+            .bytes;
+        assert_eq!(content_opf.matches("<itemref").count(), 1);
+        assert!(builder.clone().create(&mut Vec::new()).is_ok());
+
+        let builder = builder
+            .add_content(ContentBuilder::heading(ReferenceType::Text("Empty".to_string())).build());
+        assert!(builder.validate().is_err());
+    }
+
+    #[test]
+    fn test_epub_builder_scripts() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_script("a.js", b"// first\nvar a = 1;\n")
+            .add_script("b.js", b"var b = 2;")
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><p>Text</p><script src="a.js"></script><script src="b.js"></script></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            );
+
+        let content_opf = crate::output::file_content::content_opf(&builder.0)
+            .unwrap()
+            .bytes;
+        assert!(
+            content_opf
+                .contains(r#"<item id="a.js" href="a.js" media-type="application/javascript"/>"#)
+        );
+        assert!(!content_opf.contains("properties="));
+
+        let mut epub = builder.clone().bundle_scripts(true).minify_scripts(true).0;
+        epub.prepare().unwrap();
+        let scripts = epub.scripts.as_ref().unwrap();
+        assert_eq!(scripts.len(), 1);
+        assert_eq!(scripts[0].filename, SCRIPT_BUNDLE_FILENAME);
+        assert!(!String::from_utf8_lossy(&scripts[0].source).contains("first"));
+
+        let files = epub.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(files[0].bytes.contains(r#"src="scripts.js""#));
+        assert!(!files[0].bytes.contains(r#"src="a.js""#));
+
+        assert!(
+            builder
+                .clone()
+                .bundle_scripts(true)
+                .create(&mut Vec::new())
+                .is_ok()
+        );
+        assert!(builder.add_script("c.txt", b"").validate().is_err());
+    }
+
+    #[test]
+    fn test_epub_builder_quiz() {
+        let quiz = Quiz::new("quiz-1").add_question(
+            Question::new("Is 1 < 2?")
+                .correct_choice("Yes")
+                .choice("No"),
+        );
+        let body = format!("<body>{quiz}</body>");
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(body.as_bytes(), ReferenceType::Text("Quiz".to_string())).build(),
+        );
+
+        let content_opf = crate::output::file_content::content_opf(&builder.0)
+            .unwrap()
+            .bytes;
+        assert!(!content_opf.contains("properties="));
+
+        let files = builder.0.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(files[0].bytes.contains("i < questions.length"));
+        assert!(builder.create(&mut Vec::new()).is_ok());
+    }
+
+    #[test]
+    fn test_epub_builder_add_video() {
+        let video = Video::new(Path::new("clip.mp4")).poster(Path::new("clip.jpg"), ImageType::Jpg);
+        let body = format!("<body>{video}</body>");
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .file_system(
+                MemoryFileSystem::new()
+                    .file("clip.mp4", vec![0; 16])
+                    .file("clip.jpg", vec![0; 16]),
+            )
+            .add_content(
+                ContentBuilder::new(body.as_bytes(), ReferenceType::Text("Clip".to_string()))
+                    .build(),
+            )
+            .add_video(&video);
+
+        let content_opf = crate::output::file_content::content_opf(&builder.0)
+            .unwrap()
+            .bytes;
+        assert!(
+            content_opf.contains(r#"<item id="clip.mp4" href="clip.mp4" media-type="video/mp4"/>"#)
+        );
+        assert!(
+            content_opf
+                .contains(r#"<item id="clip.jpg" href="clip.jpg" media-type="image/jpeg"/>"#)
+        );
+        assert!(builder.create(&mut Vec::new()).is_ok());
+    }
+
+    #[test]
+    fn test_epub_builder_transcripts() {
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><audio src="talk.mp3"/></body>"#,
+                    ReferenceType::Text("Talk".to_string()),
+                )
+                .add_transcript(Transcript::new("talk.mp3", b"<body><p>Hi</p></body>"))
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Next".to_string())).build(),
+            )
+            .0;
+        assert_eq!(
+            epub.accessibility_as_metadata_xml().unwrap(),
+            r#"<meta name="schema:accessibilityFeature" content="transcript"/>"#
+        );
+
+        epub.prepare().unwrap();
+        let contents = epub.contents.as_ref().unwrap();
+        let files = contents[0].file_content(&mut 0, "").unwrap();
+        assert_eq!(files.len(), 2);
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<audio aria-describedby="transcript-link-1" src="talk.mp3"/>"#)
+        );
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="transcript01.xhtml">Transcript</a>"#)
+        );
+        assert_eq!(files[1].filepath, "OEBPS/transcript01.xhtml");
+        assert!(!contents[0].subcontents.as_ref().unwrap()[0].linear);
+
+        let files = contents[1].file_content(&mut 2, "").unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/c03.xhtml");
+
+        let mut epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Talk".to_string()))
+                    .add_transcript(Transcript::new("talk.mp3", b"<body/>"))
+                    .build(),
+            )
+            .0;
+        assert!(epub.prepare().is_err());
+    }
+
+    #[test]
+    fn test_epub_builder_size_budget() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>Two</p></body>",
+                    ReferenceType::Text("Two".to_string()),
+                )
+                .build(),
+            );
+
+        assert!(
+            builder
+                .clone()
+                .size_budget(SizeBudget::new().total(1_000_000).per_item(100_000))
+                .create(&mut Vec::new())
+                .is_ok()
+        );
+
+        let error = builder
+            .clone()
+            .size_budget(SizeBudget::new().per_item(100))
+            .create(&mut Vec::new())
+            .unwrap_err()
+            .to_string();
+        assert!(error.contains("'OEBPS/c01.xhtml'"));
+        assert!(error.contains("'OEBPS/c02.xhtml'"));
+
+        let temp_dir = tempdir().unwrap();
+        let path = temp_dir.path().join("book.epub");
+        let result = builder
+            .clone()
+            .size_budget(SizeBudget::new().total(100))
+            .create_file(&path);
+        assert!(result.is_err());
+        assert!(!path.exists());
+
+        let mut buffer = Vec::new();
+        assert!(
+            builder
+                .size_budget(SizeBudget::new().total(100).warn(|_| {}))
+                .create(&mut buffer)
+                .is_ok()
+        );
+        assert!(!buffer.is_empty());
+    }
+
+    #[test]
+    fn test_epub_builder_endnotes() {
+        let mut notes = Footnotes::endnotes("c2");
+        let body = format!("<body><p>Claim{}</p></body>", notes.note_ref("Source."));
+
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .add_content(
+                ContentBuilder::new(body.as_bytes(), ReferenceType::Text("Two".to_string()))
+                    .notes(notes)
+                    .build(),
+            );
+
+        builder.0.prepare().unwrap();
+        assert_eq!(builder.0.contents.as_ref().unwrap().len(), 2);
+
+        builder = builder.endnotes("Notes");
+        builder.0.prepare().unwrap();
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 3);
+
+        let file_content = contents[2].file_content(&mut 2, "").unwrap().remove(0);
+        assert_eq!(file_content.filepath, "OEBPS/notes.xhtml");
+        assert!(file_content.bytes.contains("<h1>Notes</h1>"));
+        assert!(
+            file_content
+                .bytes
+                .contains(r#"epub:type="endnotes" role="doc-endnotes""#)
+        );
+        assert!(file_content.bytes.contains("<h2>Two</h2>"));
+        assert!(
+            file_content
+                .bytes
+                .contains(r##"<a href="c02.xhtml#c2-fnref1">1.</a>"##)
+        );
+
+        assert!(builder.create(&mut Vec::new()).is_ok());
+    }
+
+    #[test]
+    fn test_epub_builder_bibliography() {
+        let bibliography = Bibliography::default().add_citation(
+            CitationBuilder::new("knuth1968", "The Art of Computer Programming")
+                .author("Donald Knuth")
+                .year(1968)
+                .build(),
+        );
+
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .bibliography("References", bibliography);
+        assert!(builder.0.prepare().is_ok());
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 1);
+        assert_eq!(contents[0].filename(1), "bibliography.xhtml");
+        assert_eq!(
+            contents[0].reference_type.type_and_title(),
+            ("bibliography", "References")
+        );
+    }
+
+    #[test]
+    fn test_epub_builder_index() {
+        let body = format!(
+            "<body><p>{}Ownership</p></body>",
+            IndexTerm::new("ownership")
+        );
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(body.as_bytes(), ReferenceType::Text("One".to_string()))
+                    .build(),
+            )
+            .index("Index");
+        builder.0.prepare().unwrap();
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 2);
+        assert_eq!(contents[1].filename(2), "index.xhtml");
+
+        let file_contents = contents[0].file_content(&mut 0, "").unwrap();
+        assert!(file_contents[0].bytes.contains(r#"<a id="index-term-1">"#));
+        assert!(!file_contents[0].bytes.contains("data-term"));
+
+        let index = contents[1].file_content(&mut 1, "").unwrap().remove(0);
+        assert!(index.bytes.contains(
+            r#"<body xmlns:epub="http://www.idpf.org/2007/ops" epub:type="index" role="doc-index">"#
+        ));
+        assert!(
+            index
+                .bytes
+                .contains(r##"<a href="c01.xhtml#index-term-1">One</a>"##)
+        );
+    }
+
+    #[test]
+    fn test_epub_builder_abbreviations() {
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>The CPU</p></body>",
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            )
+            .abbreviations(
+                "Abbreviations",
+                Abbreviations::new().define("CPU", "Central Processing Unit"),
+            );
+        builder.0.prepare().unwrap();
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 2);
+        assert_eq!(contents[0].filename(1), "abbreviations.xhtml");
+
+        let file_contents = contents[1].file_content(&mut 1, "").unwrap();
+        assert!(
+            file_contents[0]
+                .bytes
+                .contains(r#"<abbr title="Central Processing Unit">CPU</abbr>"#)
+        );
+    }
+
+    #[test]
+    fn test_epub_builder_characters() {
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::TitlePage("Title".to_string()))
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>Enter Hamlet.</p></body>",
+                    ReferenceType::Text("Act I".to_string()),
+                )
+                .build(),
+            )
+            .characters(
+                "Characters",
+                Characters::new()
+                    .add_character("Hamlet", "Prince of Denmark")
+                    .link_first_occurrences(true),
+            );
+        builder.0.prepare().unwrap();
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 3);
+        assert_eq!(contents[1].filename(2), "characters.xhtml");
+
+        let file_contents = contents[2].file_content(&mut 2, "").unwrap();
+        assert!(file_contents[0].bytes.contains(
+            r##"<a class="character" href="characters.xhtml#character-hamlet">Hamlet</a>"##
+        ));
+    }
+
+    #[test]
+    fn test_epub_builder_reading_time() {
+        let long_body = format!("<body><h1>Two</h1><p>{}</p></body>", "word ".repeat(460));
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>Short</p></body>",
+                    ReferenceType::Text("One".to_string()),
+                )
+                .add_child(
+                    ContentBuilder::new(
+                        long_body.as_bytes(),
+                        ReferenceType::Text("Two".to_string()),
+                    )
+                    .build(),
+                )
+                .build(),
+            )
+            .reading_time(ReadingTime::new().badges(true));
+
+        assert_eq!(
+            builder.reading_times(&ReadingTime::new()),
+            vec![("One".to_string(), 1), ("Two".to_string(), 3)]
+        );
+        assert_eq!(
+            builder.0.reading_time_as_metadata_xml().unwrap(),
+            r#"<meta name="reading-time" content="4"/>"#
+        );
+
+        builder.0.prepare().unwrap();
+        let file_contents = builder.0.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(!file_contents[0].bytes.contains("min read"));
+        assert!(file_contents[1].bytes.contains("3 min read"));
+    }
+
+    #[test]
+    fn test_epub_builder_unknown_citation() {
+        let body = format!("<body><p>{}</p></body>", CitationRef::new("nobody2000"));
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(body.as_bytes(), ReferenceType::Text("One".to_string())).build(),
+        );
+
+        let error = builder.create(&mut Vec::new()).unwrap_err();
+        assert_eq!(
+            error.to_string(),
+            "Error in content 'One' (c01.xhtml): Validation error: unknown citation key 'nobody2000'"
+        );
+    }
+
     #[test]
     fn test_epub_builder_stylesheet_file() {
         let metadata = MetadataBuilder::title("Title").build();
@@ -342,4 +2476,24 @@ mod tests {
 
         assert!(epub_result.is_ok());
     }
+
+    #[tokio::test]
+    #[cfg(feature = "async")]
+    async fn test_async_epub_builder_reproducible() {
+        let builder = EpubBuilder::new(
+            MetadataBuilder::title("Title")
+                .identifier(crate::epub::Identifier::UUID("1".to_string()))
+                .date(chrono::DateTime::UNIX_EPOCH)
+                .build(),
+        )
+        .add_content(
+            ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+        );
+
+        let mut first = Vec::new();
+        builder.clone().async_create(&mut first).await.unwrap();
+        let mut second = Vec::new();
+        builder.async_create(&mut second).await.unwrap();
+        assert_eq!(first, second);
+    }
 }
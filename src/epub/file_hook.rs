@@ -0,0 +1,158 @@
+use std::{
+    fmt::{Debug, Formatter},
+    sync::Arc,
+};
+
+use crate::epub::{Epub, EpubBuilder};
+
+/// The signature of a post-generation hook: receives the path of a file in the archive and its bytes,
+/// returning the bytes actually written.
+type HookFn = dyn Fn(&str, Vec<u8>) -> crate::Result<Vec<u8>> + Send + Sync;
+
+/// A **post-generation hook**, transforming every generated file right before it is written to the archive
+/// (see [`EpubBuilder::add_file_hook`]).
+#[derive(Clone)]
+pub(crate) struct FileHook(Arc<HookFn>);
+
+impl Debug for FileHook {
+    fn fmt(&self, f: &mut Formatter<'_>) -> std::fmt::Result {
+        f.write_str("FileHook")
+    }
+}
+
+impl Epub<'_> {
+    /// Runs the post-generation hooks, in order, on the file at `filepath`.
+    ///
+    /// Returns `None` if there is no hook to run (the `mimetype` file is never hooked).
+    ///
+    /// # Errors
+    /// Returns the first error returned by a hook.
+    pub(crate) fn hook_file(&self, filepath: &str, bytes: &[u8]) -> crate::Result<Option<Vec<u8>>> {
+        let Some(ref file_hooks) = self.file_hooks else {
+            return Ok(None);
+        };
+        if filepath == "mimetype" {
+            return Ok(None);
+        }
+
+        file_hooks
+            .iter()
+            .try_fold(bytes.to_vec(), |bytes, FileHook(hook)| {
+                hook(filepath, bytes)
+            })
+            .map(Some)
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds a **post-generation hook**, invoked with the path (e.g., `OEBPS/c01.xhtml`) and the bytes of
+    /// every generated file right before it is written to the archive, returning the bytes to write instead.
+    ///
+    /// Hooks enable custom transformations (extra checks, string substitutions, vendor tweaks) and run in the
+    /// order they are added. The `mimetype` file is never passed to the hooks.
+    ///
+    /// ```rust
+    /// use liber::epub::{EpubBuilder, MetadataBuilder};
+    ///
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_file_hook(
+    ///     |filepath, bytes| {
+    ///         if filepath.ends_with(".xhtml") {
+    ///             Ok(String::from_utf8_lossy(&bytes).replace("{{year}}", "2025").into_bytes())
+    ///         } else {
+    ///             Ok(bytes)
+    ///         }
+    ///     },
+    /// );
+    /// ```
+    ///
+    /// Returning an error from the hook aborts the creation of the file with that error.
+    pub fn add_file_hook<F>(mut self, hook: F) -> Self
+    where
+        F: Fn(&str, Vec<u8>) -> crate::Result<Vec<u8>> + Send + Sync + 'static,
+    {
+        let hook = FileHook(Arc::new(hook));
+        if let Some(ref mut file_hooks) = self.0.file_hooks {
+            file_hooks.push(hook);
+        } else {
+            self.0.file_hooks = Some(vec![hook]);
+        }
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_hook_file() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build());
+        assert_eq!(builder.0.hook_file("OEBPS/a.xhtml", b"a").unwrap(), None);
+
+        let builder = builder
+            .add_file_hook(|_, mut bytes| {
+                bytes.push(b'b');
+                Ok(bytes)
+            })
+            .add_file_hook(|_, mut bytes| {
+                bytes.push(b'c');
+                Ok(bytes)
+            });
+        assert_eq!(
+            builder.0.hook_file("OEBPS/a.xhtml", b"a").unwrap(),
+            Some(b"abc".to_vec())
+        );
+        assert_eq!(builder.0.hook_file("mimetype", b"a").unwrap(), None);
+    }
+
+    #[test]
+    fn test_file_hooks_on_create() {
+        let mut output = Vec::new();
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>Copyright {{year}}</p></body>",
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            )
+            .add_file_hook(|filepath, bytes| {
+                if filepath.ends_with(".xhtml") {
+                    Ok(String::from_utf8_lossy(&bytes)
+                        .replace("{{year}}", "2025")
+                        .into_bytes())
+                } else {
+                    Ok(bytes)
+                }
+            })
+            .create(&mut output)
+            .unwrap();
+
+        let mut archive = zip::ZipArchive::new(std::io::Cursor::new(output)).unwrap();
+        let mut chapter = String::new();
+        std::io::Read::read_to_string(
+            &mut archive.by_name("OEBPS/c01.xhtml").unwrap(),
+            &mut chapter,
+        )
+        .unwrap();
+        assert!(chapter.contains("Copyright 2025"));
+    }
+
+    #[test]
+    fn test_file_hook_error() {
+        let result = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_file_hook(|filepath, bytes| {
+                if filepath.ends_with("content.opf") {
+                    Err(crate::Error::Validation("rejected".to_string()))
+                } else {
+                    Ok(bytes)
+                }
+            })
+            .create(&mut Vec::new());
+        assert_eq!(
+            result.unwrap_err().to_string(),
+            "Validation error: rejected"
+        );
+    }
+}
@@ -0,0 +1,101 @@
+use std::{
+    collections::HashMap,
+    fmt::Debug,
+    fs, io,
+    path::{Path, PathBuf},
+};
+
+/// Abstraction over the source of every file read while generating the EPUB (cover image, resources, etc.).
+///
+/// Implement this trait to source files from memory, from a sandbox, from an archive or from
+/// a remote location. Use [`crate::epub::EpubBuilder::file_system`] to set it.
+pub trait FileSystem: Debug + Send + Sync {
+    /// Reads the whole content of the file at `path`.
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>>;
+
+    /// Gets the size in bytes of the file at `path` without reading it.
+    fn size(&self, path: &Path) -> io::Result<u64>;
+}
+
+/// The default [`FileSystem`], reading from the local disk through `std::fs`.
+#[derive(Debug, Default, Clone, Copy)]
+pub struct StdFileSystem;
+
+impl FileSystem for StdFileSystem {
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>> {
+        fs::read(path)
+    }
+
+    fn size(&self, path: &Path) -> io::Result<u64> {
+        fs::metadata(path).map(|metadata| metadata.len())
+    }
+}
+
+/// An in-memory [`FileSystem`], useful for tests and sandboxed builds.
+#[derive(Debug, Default, Clone)]
+pub struct MemoryFileSystem(HashMap<PathBuf, Vec<u8>>);
+
+impl MemoryFileSystem {
+    /// Creates an empty in-memory file system.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a file with the given content at `path`.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn file<P: Into<PathBuf>, B: Into<Vec<u8>>>(mut self, path: P, bytes: B) -> Self {
+        self.0.insert(path.into(), bytes.into());
+        self
+    }
+
+    /// Gets the file stored at `path`, or a `NotFound` error naming the path.
+    fn get(&self, path: &Path) -> io::Result<&Vec<u8>> {
+        self.0.get(path).ok_or_else(|| {
+            io::Error::new(
+                io::ErrorKind::NotFound,
+                format!("file not found: {}", path.display()),
+            )
+        })
+    }
+}
+
+impl FileSystem for MemoryFileSystem {
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>> {
+        self.get(path).cloned()
+    }
+
+    fn size(&self, path: &Path) -> io::Result<u64> {
+        self.get(path).map(|bytes| bytes.len() as u64)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_memory_file_system() {
+        let file_system = MemoryFileSystem::new().file("img/cover.png", vec![1, 2, 3]);
+
+        assert_eq!(
+            file_system.read(Path::new("img/cover.png")).unwrap(),
+            vec![1, 2, 3]
+        );
+        assert_eq!(file_system.size(Path::new("img/cover.png")).unwrap(), 3);
+
+        let error = file_system.read(Path::new("missing.png")).unwrap_err();
+        assert_eq!(error.kind(), io::ErrorKind::NotFound);
+    }
+
+    #[test]
+    fn test_std_file_system() {
+        let temp_dir = tempfile::tempdir().expect("Error creating tempdir");
+        let path = temp_dir.path().join("font.otf");
+        fs::write(&path, b"font").expect("Error writing mock font");
+
+        assert_eq!(StdFileSystem.read(&path).unwrap(), b"font");
+        assert_eq!(StdFileSystem.size(&path).unwrap(), 4);
+    }
+}
@@ -0,0 +1,287 @@
+use std::path::Path;
+
+use crate::{
+    epub::{Epub, EpubBuilder, IdGenerator, Identifier, Resource},
+    output::file_content::FileContent,
+};
+
+/// The number of leading bytes of a font obfuscated with the Adobe algorithm.
+const OBFUSCATED_LENGTH: usize = 1024;
+
+/// The **license of a font** resource, registered with [`EpubBuilder::font_license`].
+///
+/// Fonts whose license disallows embedding the raw font file (common in commercial licenses) require
+/// font obfuscation ([`EpubBuilder::obfuscate_fonts`]): creating the file fails otherwise.
+/// The license text, if provided, is written into the book next to the font.
+///
+/// ```rust
+/// use std::path::Path;
+/// use liber::epub::{EpubBuilder, FontLicense, MetadataBuilder, Resource};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+///     .add_resource(Resource::Font(Path::new("Serif.otf")))
+///     .font_license(
+///         Path::new("Serif.otf"),
+///         FontLicense::new("Foundry Desktop License")
+///             .raw_embedding(false)
+///             .license_file("Serif-LICENSE.txt", "Licensed for embedding in obfuscated form only."),
+///     )
+///     .obfuscate_fonts(true);
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct FontLicense {
+    /// The name of the license (e.g., `SIL Open Font License 1.1`).
+    name: String,
+    /// Whether the license allows embedding the raw (unobfuscated) font file.
+    raw_embedding: bool,
+    /// Optional `(filename, text)` of the license file written into the book.
+    file: Option<(String, String)>,
+}
+
+impl FontLicense {
+    /// Creates a license with the given **name**, allowing raw embedding.
+    pub fn new<S: Into<String>>(name: S) -> Self {
+        Self {
+            name: name.into(),
+            raw_embedding: true,
+            file: None,
+        }
+    }
+
+    /// Sets whether the license allows **embedding the raw font file**. Defaults to `true`.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn raw_embedding(mut self, raw_embedding: bool) -> Self {
+        self.raw_embedding = raw_embedding;
+        self
+    }
+
+    /// Sets the **license file** (e.g., `OFL.txt`) written into the book and listed in the manifest.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn license_file<F: Into<String>, T: Into<String>>(mut self, filename: F, text: T) -> Self {
+        self.file = Some((filename.into(), text.into()));
+        self
+    }
+
+    /// Generates the XML `<item>` tag of the license file, if any, used in the manifest section.
+    pub(crate) fn as_manifest_xml(&self, ids: &dyn IdGenerator) -> Option<String> {
+        self.file.as_ref().map(|(filename, _)| {
+            format!(
+                r#"<item id="{}" href="{filename}" media-type="text/plain"/>"#,
+                ids.item_id(filename)
+            )
+        })
+    }
+}
+
+impl<'a> Epub<'a> {
+    /// Checks that every font whose license disallows raw embedding is obfuscated.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] naming the first font found.
+    pub(crate) fn check_font_licenses(&self) -> crate::Result {
+        if self.obfuscate_fonts {
+            return Ok(());
+        }
+
+        match self
+            .font_licenses
+            .iter()
+            .flatten()
+            .find(|(_, license)| !license.raw_embedding)
+        {
+            Some((font, license)) => Err(crate::Error::Validation(format!(
+                "font '{}' is licensed under '{}', which disallows raw embedding; enable font obfuscation",
+                font.display(),
+                license.name
+            ))),
+            None => Ok(()),
+        }
+    }
+
+    /// Gets the key obfuscating the fonts, if enabled.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the identifier of the book is not a valid UUID.
+    pub(crate) fn font_obfuscation_key(&self) -> crate::Result<Option<[u8; 16]>> {
+        if !self.obfuscate_fonts {
+            return Ok(None);
+        }
+
+        let error = || {
+            crate::Error::Validation(format!(
+                "font obfuscation requires a UUID identifier, not '{}'",
+                self.metadata.identifier.value()
+            ))
+        };
+        let Identifier::UUID(ref uuid) = self.metadata.identifier else {
+            return Err(error());
+        };
+
+        let digits = uuid.replace('-', "");
+        if digits.len() != 32 {
+            return Err(error());
+        }
+        let mut key = [0; 16];
+        for (index, byte) in key.iter_mut().enumerate() {
+            *byte =
+                u8::from_str_radix(&digits[index * 2..index * 2 + 2], 16).map_err(|_| error())?;
+        }
+        Ok(Some(key))
+    }
+
+    /// Gets the license files of the fonts, as written into the archive.
+    pub(crate) fn font_license_files(&self) -> Vec<FileContent<String, String>> {
+        self.font_licenses
+            .iter()
+            .flatten()
+            .filter_map(|(_, license)| license.file.as_ref())
+            .map(|(filename, text)| FileContent::new(format!("OEBPS/{filename}"), text.clone()))
+            .collect()
+    }
+}
+
+/// **Obfuscates** the bytes of a font resource with the Adobe algorithm (supported by EPUB 2 reading systems):
+/// the first 1024 bytes are XORed with the key, derived from the UUID identifier of the book.
+///
+/// Returns `true` if the resource is a font, so it must be listed in `META-INF/encryption.xml`.
+pub(crate) fn obfuscate_font(resource: &Resource<'_>, bytes: &mut [u8], key: &[u8; 16]) -> bool {
+    if !matches!(resource, Resource::Font(_)) {
+        return false;
+    }
+
+    for (index, byte) in bytes.iter_mut().take(OBFUSCATED_LENGTH).enumerate() {
+        *byte ^= key[index % key.len()];
+    }
+    true
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Registers the **license of a font** resource (see [`FontLicense`]), added with [`Resource::Font`].
+    ///
+    /// Creating the file fails if the license disallows raw embedding and font obfuscation is not enabled.
+    pub fn font_license(mut self, font: &'a Path, license: FontLicense) -> Self {
+        let font_license = (font, license);
+        if let Some(ref mut font_licenses) = self.0.font_licenses {
+            font_licenses.push(font_license);
+        } else {
+            self.0.font_licenses = Some(vec![font_license]);
+        }
+        self
+    }
+
+    /// **Obfuscates the fonts** with the Adobe algorithm, as required by many commercial font licenses,
+    /// declaring them in `META-INF/encryption.xml`.
+    ///
+    /// The key is derived from the identifier of the book, which must be a UUID (the default).
+    pub fn obfuscate_fonts(mut self, obfuscate_fonts: bool) -> Self {
+        self.0.obfuscate_fonts = obfuscate_fonts;
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{MemoryFileSystem, MetadataBuilder};
+
+    #[test]
+    fn test_font_license_check() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_resource(Resource::Font(Path::new("Serif.otf")))
+            .font_license(
+                Path::new("Serif.otf"),
+                FontLicense::new("Desktop License").raw_embedding(false),
+            );
+
+        assert_eq!(
+            builder.0.clone().prepare().unwrap_err().to_string(),
+            "Validation error: font 'Serif.otf' is licensed under 'Desktop License', which disallows raw embedding; enable font obfuscation"
+        );
+        assert!(builder.obfuscate_fonts(true).0.prepare().is_ok());
+    }
+
+    #[test]
+    fn test_font_obfuscation_key() {
+        let builder = EpubBuilder::new(
+            MetadataBuilder::title("Title")
+                .identifier(Identifier::UUID(
+                    "01234567-89ab-cdef-0123-456789abcdef".to_string(),
+                ))
+                .build(),
+        );
+        assert_eq!(builder.0.font_obfuscation_key().unwrap(), None);
+
+        let key = builder
+            .obfuscate_fonts(true)
+            .0
+            .font_obfuscation_key()
+            .unwrap();
+        assert_eq!(
+            key,
+            Some([
+                0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab,
+                0xcd, 0xef
+            ])
+        );
+
+        let builder = EpubBuilder::new(
+            MetadataBuilder::title("Title")
+                .identifier(Identifier::ISBN("9780000000000".to_string()))
+                .build(),
+        )
+        .obfuscate_fonts(true);
+        assert!(builder.0.font_obfuscation_key().is_err());
+    }
+
+    #[test]
+    fn test_obfuscate_font() {
+        let key = [0xff; 16];
+        let mut bytes = vec![0u8; 1030];
+        assert!(obfuscate_font(
+            &Resource::Font(Path::new("a.otf")),
+            &mut bytes,
+            &key
+        ));
+        assert!(bytes[..1024].iter().all(|&byte| byte == 0xff));
+        assert!(bytes[1024..].iter().all(|&byte| byte == 0));
+
+        assert!(!obfuscate_font(
+            &Resource::Audio(Path::new("a.mp3")),
+            &mut bytes,
+            &key
+        ));
+    }
+
+    #[test]
+    fn test_font_license_files() {
+        let mut output = Vec::new();
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .file_system(MemoryFileSystem::new().file("Serif.otf", vec![0; 2048]))
+            .add_resource(Resource::Font(Path::new("Serif.otf")))
+            .font_license(
+                Path::new("Serif.otf"),
+                FontLicense::new("SIL Open Font License 1.1").license_file("OFL.txt", "Copyright"),
+            )
+            .obfuscate_fonts(true)
+            .create(&mut output)
+            .unwrap();
+
+        let mut archive = zip::ZipArchive::new(std::io::Cursor::new(output)).unwrap();
+        assert!(archive.by_name("OEBPS/OFL.txt").is_ok());
+
+        let mut encryption = String::new();
+        std::io::Read::read_to_string(
+            &mut archive.by_name("META-INF/encryption.xml").unwrap(),
+            &mut encryption,
+        )
+        .unwrap();
+        assert!(encryption.contains(r#"<enc:CipherReference URI="OEBPS/Serif.otf"/>"#));
+
+        let mut opf = String::new();
+        std::io::Read::read_to_string(&mut archive.by_name("OEBPS/content.opf").unwrap(), &mut opf)
+            .unwrap();
+        assert!(opf.contains(r#"href="OFL.txt" media-type="text/plain""#));
+    }
+}
@@ -0,0 +1,205 @@
+use quick_xml::escape::escape;
+
+use crate::epub::{ContentBuilder, ReferenceType};
+
+/// The start of a variable in the body of a fragment, followed by its name.
+const VARIABLE_START: &str = "{{";
+/// The end of a variable in the body of a fragment, following its name.
+const VARIABLE_END: &str = "}}";
+
+/// A registry of **shared fragments**: boilerplate sections (e.g., about the author, also by, a newsletter
+/// plug) defined once and included in many books.
+///
+/// The bodies may contain `{{name}}` variables, replaced (XML-escaped) by the values given when a fragment
+/// is included, or else by the default values of the registry.
+///
+/// ```rust
+/// use liber::epub::{EpubBuilder, Fragments, MetadataBuilder, ReferenceType};
+///
+/// let fragments = Fragments::new()
+///     .define(
+///         "newsletter",
+///         ReferenceType::Text("Newsletter".to_string()),
+///         "<body><p>Get news about {{title}} at {{url}}</p></body>",
+///     )
+///     .variable("url", "https://example.com/news");
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("The Sea").build()).add_content(
+///     fragments
+///         .content("newsletter", &[("title", "The Sea")])
+///         .unwrap()
+///         .build(),
+/// );
+///
+/// assert_eq!(
+///     fragments.render("newsletter", &[("title", "Dogs & Cats")]).unwrap(),
+///     "<body><p>Get news about Dogs &amp; Cats at https://example.com/news</p></body>"
+/// );
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct Fragments {
+    /// The `(name, reference type, body)` of every fragment.
+    fragments: Vec<(String, ReferenceType, String)>,
+    /// The `(name, value)` of the default variables.
+    variables: Vec<(String, String)>,
+}
+
+impl Fragments {
+    /// Creates an empty registry.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Defines a fragment: its name, the reference type (and title) of the content and its XHTML body.
+    /// Defining a name again replaces the fragment.
+    pub fn define<N: Into<String>, B: Into<String>>(
+        mut self,
+        name: N,
+        reference_type: ReferenceType,
+        body: B,
+    ) -> Self {
+        let name = name.into();
+        self.fragments.retain(|(defined, _, _)| *defined != name);
+        self.fragments.push((name, reference_type, body.into()));
+        self
+    }
+
+    /// Sets the default value of a variable, used when a fragment is included without a value for it.
+    pub fn variable<N: Into<String>, V: Into<String>>(mut self, name: N, value: V) -> Self {
+        let name = name.into();
+        self.variables.retain(|(defined, _)| *defined != name);
+        self.variables.push((name, value.into()));
+        self
+    }
+
+    /// Renders the body of a fragment, replacing its variables with the given `(name, value)` pairs
+    /// or the default values.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the fragment is not defined or a variable has no value.
+    pub fn render(&self, name: &str, variables: &[(&str, &str)]) -> crate::Result<String> {
+        let (_, _, body) = self.fragment(name)?;
+
+        let mut result = String::with_capacity(body.len());
+        let mut rest = body.as_str();
+        while let Some(start) = rest.find(VARIABLE_START) {
+            let Some(end) = rest[start..].find(VARIABLE_END) else {
+                break;
+            };
+            let variable = rest[start + VARIABLE_START.len()..start + end].trim();
+            let value = variables
+                .iter()
+                .find(|(defined, _)| *defined == variable)
+                .map(|(_, value)| *value)
+                .or_else(|| {
+                    self.variables
+                        .iter()
+                        .find(|(defined, _)| defined == variable)
+                        .map(|(_, value)| value.as_str())
+                })
+                .ok_or_else(|| {
+                    crate::Error::Validation(format!(
+                        "the fragment '{name}' has no value for the variable '{variable}'"
+                    ))
+                })?;
+
+            result.push_str(&rest[..start]);
+            result.push_str(&escape(value));
+            rest = &rest[start + end + VARIABLE_END.len()..];
+        }
+        result.push_str(rest);
+
+        Ok(result)
+    }
+
+    /// Builds the content of a fragment, with its variables replaced (see [`Fragments::render`]).
+    /// The returned builder can be configured further like any other content.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the fragment is not defined or a variable has no value.
+    pub fn content(
+        &self,
+        name: &str,
+        variables: &[(&str, &str)],
+    ) -> crate::Result<ContentBuilder<'static>> {
+        let (_, reference_type, _) = self.fragment(name)?;
+        Ok(ContentBuilder::new_owned(
+            self.render(name, variables)?,
+            reference_type.clone(),
+        ))
+    }
+
+    /// Gets a fragment by name.
+    fn fragment(&self, name: &str) -> crate::Result<&(String, ReferenceType, String)> {
+        self.fragments
+            .iter()
+            .find(|(defined, _, _)| defined == name)
+            .ok_or_else(|| crate::Error::Validation(format!("unknown fragment '{name}'")))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{EpubBuilder, MetadataBuilder};
+
+    fn fragments() -> Fragments {
+        Fragments::new()
+            .define(
+                "about",
+                ReferenceType::Text("About the Author".to_string()),
+                "<body><p>{{author}} lives in {{ city }}.</p></body>",
+            )
+            .variable("author", "Jane Doe")
+            .variable("city", "Lisbon")
+    }
+
+    #[test]
+    fn test_render() {
+        let fragments = fragments();
+        assert_eq!(
+            fragments.render("about", &[]).unwrap(),
+            "<body><p>Jane Doe lives in Lisbon.</p></body>"
+        );
+        assert_eq!(
+            fragments
+                .render("about", &[("city", "Rock & Roll Town")])
+                .unwrap(),
+            "<body><p>Jane Doe lives in Rock &amp; Roll Town.</p></body>"
+        );
+
+        let fragments = fragments.define(
+            "about",
+            ReferenceType::Text("About".to_string()),
+            "<body><p>{{bio}}</p></body>",
+        );
+        assert!(
+            matches!(fragments.render("about", &[]), Err(crate::Error::Validation(message)) if message.contains("'bio'"))
+        );
+        assert!(fragments.render("also-by", &[]).is_err());
+    }
+
+    #[test]
+    fn test_content() {
+        let fragments = fragments();
+        let body = b"<body><p>Chapter</p></body>";
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                crate::epub::ContentBuilder::new(body, ReferenceType::Text("One".to_string()))
+                    .build(),
+            )
+            .add_content(
+                fragments
+                    .content("about", &[("author", "John Roe")])
+                    .unwrap()
+                    .build(),
+            );
+
+        let contents = epub.0.contents.as_ref().unwrap();
+        assert_eq!(contents[1].title(), "About the Author");
+        let files = contents[1].file_content(&mut 1, "").unwrap();
+        assert!(files[0].bytes.contains("<p>John Roe lives in Lisbon.</p>"));
+    }
+}
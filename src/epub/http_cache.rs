@@ -0,0 +1,188 @@
+use std::{
+    fs, io,
+    path::{Path, PathBuf},
+};
+
+use crate::output::file_content;
+
+/// The response of an HTTP `GET` request performed by an [`HttpClient`].
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum HttpResponse {
+    /// The resource was (re)downloaded, carrying its body and optional `ETag` header.
+    Ok { body: Vec<u8>, etag: Option<String> },
+    /// The server answered `304 Not Modified`, so the cached body is still valid.
+    NotModified,
+}
+
+/// The transport used by [`HttpBodyCache`] to fetch bodies over HTTP, e.g., backed by `ureq` or `reqwest`.
+pub trait HttpClient {
+    /// Performs a `GET` request to `url`.
+    ///
+    /// When `etag` is `Some`, it must be sent as the `If-None-Match` header and a
+    /// `304 Not Modified` answer must be returned as [`HttpResponse::NotModified`].
+    fn get(&self, url: &str, etag: Option<&str>) -> io::Result<HttpResponse>;
+}
+
+/// Fetches content bodies (e.g., chapter HTML) over HTTP, keeping a local cache on disk.
+///
+/// Every cached body is stored along with its `ETag`, which is used to revalidate it on
+/// later fetches, so repeated builds of web-sourced books don't re-download unchanged content.
+#[derive(Debug, Clone)]
+pub struct HttpBodyCache<C> {
+    /// The HTTP client used to perform the requests.
+    client: C,
+    /// The directory where bodies and their `ETag` are cached.
+    cache_dir: PathBuf,
+}
+
+impl<C: HttpClient> HttpBodyCache<C> {
+    /// Creates a new cache storing the fetched bodies in `cache_dir`.
+    pub fn new<P: Into<PathBuf>>(client: C, cache_dir: P) -> Self {
+        Self {
+            client,
+            cache_dir: cache_dir.into(),
+        }
+    }
+
+    /// Fetches the body at `url`, revalidating the cached copy with its `ETag` if there is one.
+    ///
+    /// The returned bytes can be used as the body of a [`crate::epub::ContentBuilder`].
+    ///
+    /// # Errors
+    /// Returns an error if the request fails or the cache cannot be read or written.
+    pub fn fetch(&self, url: &str) -> crate::Result<Vec<u8>> {
+        let (body_path, etag_path) = self.cache_paths(url);
+
+        let etag = if body_path.is_file() {
+            read_optional(&etag_path)?
+        } else {
+            None
+        };
+
+        match self.client.get(url, etag.as_deref())? {
+            HttpResponse::NotModified if etag.is_some() => Ok(fs::read(&body_path)?),
+            HttpResponse::NotModified => Err(crate::Error::Io(io::Error::new(
+                io::ErrorKind::InvalidData,
+                format!("'{url}' answered 'Not Modified' without a cached body"),
+            ))),
+            HttpResponse::Ok { body, etag } => {
+                fs::create_dir_all(&self.cache_dir)?;
+                // The old ETag goes first, so a failed write never pairs it with a new body
+                // (without an ETag, the next fetch downloads the body again)
+                remove_optional(&etag_path)?;
+                fs::write(&body_path, &body)?;
+                if let Some(etag) = etag {
+                    fs::write(&etag_path, etag)?;
+                }
+                Ok(body)
+            }
+        }
+    }
+
+    /// Gets the paths of the cached body and `ETag` of `url`, named after the SHA-256 digest of the URL.
+    fn cache_paths(&self, url: &str) -> (PathBuf, PathBuf) {
+        let name = file_content::sha256_hex(url.as_bytes());
+        (
+            self.cache_dir.join(format!("{name}.body")),
+            self.cache_dir.join(format!("{name}.etag")),
+        )
+    }
+}
+
+/// Reads a text file, returning `None` if it does not exist.
+fn read_optional(path: &Path) -> io::Result<Option<String>> {
+    match fs::read_to_string(path) {
+        Ok(text) => Ok(Some(text)),
+        Err(e) if e.kind() == io::ErrorKind::NotFound => Ok(None),
+        Err(e) => Err(e),
+    }
+}
+
+/// Removes a file, doing nothing if it does not exist.
+fn remove_optional(path: &Path) -> io::Result<()> {
+    match fs::remove_file(path) {
+        Err(e) if e.kind() != io::ErrorKind::NotFound => Err(e),
+        _ => Ok(()),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::sync::Mutex;
+
+    use tempfile::tempdir;
+
+    use super::*;
+
+    /// A fake server returning a fixed body and `ETag`, recording the `ETag` of every request.
+    #[derive(Debug, Default)]
+    struct FakeClient {
+        etag: Option<String>,
+        requests: Mutex<Vec<Option<String>>>,
+    }
+
+    impl HttpClient for FakeClient {
+        fn get(&self, _url: &str, etag: Option<&str>) -> io::Result<HttpResponse> {
+            self.requests.lock().unwrap().push(etag.map(str::to_string));
+
+            if etag.is_some() && etag == self.etag.as_deref() {
+                Ok(HttpResponse::NotModified)
+            } else {
+                Ok(HttpResponse::Ok {
+                    body: b"<body><h1>Chapter 1</h1></body>".to_vec(),
+                    etag: self.etag.clone(),
+                })
+            }
+        }
+    }
+
+    #[test]
+    fn test_http_body_cache_revalidates_with_etag() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        let client = FakeClient {
+            etag: Some(r#""v1""#.to_string()),
+            ..Default::default()
+        };
+        let cache = HttpBodyCache::new(client, temp_dir.path());
+
+        let first = cache.fetch("https://example.com/ch1.html").unwrap();
+        let second = cache.fetch("https://example.com/ch1.html").unwrap();
+
+        assert_eq!(first, second);
+        assert_eq!(
+            *cache.client.requests.lock().unwrap(),
+            vec![None, Some(r#""v1""#.to_string())]
+        );
+    }
+
+    #[test]
+    fn test_http_body_cache_without_etag() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        let cache = HttpBodyCache::new(FakeClient::default(), temp_dir.path());
+
+        cache.fetch("https://example.com/ch1.html").unwrap();
+        cache.fetch("https://example.com/ch1.html").unwrap();
+
+        assert_eq!(*cache.client.requests.lock().unwrap(), vec![None, None]);
+    }
+
+    #[test]
+    fn test_http_body_cache_failed_write_drops_etag() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        let client = FakeClient {
+            etag: Some(r#""v1""#.to_string()),
+            ..Default::default()
+        };
+        let cache = HttpBodyCache::new(client, temp_dir.path());
+        let url = "https://example.com/ch1.html";
+        cache.fetch(url).unwrap();
+
+        // A directory in place of the body makes the next write fail
+        let (body_path, etag_path) = cache.cache_paths(url);
+        fs::remove_file(&body_path).unwrap();
+        fs::create_dir(&body_path).unwrap();
+
+        assert!(cache.fetch(url).is_err());
+        assert!(!etag_path.exists());
+    }
+}
@@ -0,0 +1,249 @@
+use std::{fmt::Debug, sync::Arc};
+
+use crate::{
+    epub::{Epub, EpubBuilder, slugify},
+    output::file_content,
+};
+
+/// Abstraction over the generation of the **ids** of the book: the ids of the manifest items (referenced
+/// from the spine and the package metadata) and the anchor ids of the content references without an explicit id
+/// (see [`crate::epub::ContentReference::id`]).
+///
+/// Implement this trait (or use [`PrefixedIds`], [`HashedIds`] or [`SlugIds`]) when the book is merged with externally
+/// produced files whose ids must not collide. Use [`EpubBuilder::id_generator`] to set it.
+pub trait IdGenerator: Debug + Send + Sync {
+    /// Gets the id of the manifest item whose default id is `id`: the filename of the item
+    /// (e.g., `c01.xhtml` or `cover.jpg`), or `ncx` and `page-template` for those files.
+    ///
+    /// Returns `id` by default.
+    fn item_id(&self, id: &str) -> String {
+        id.to_string()
+    }
+
+    /// Gets the anchor id of the `number`-th content reference (counted depth-first from 1) of the content
+    /// file `filename`, titled `title`.
+    ///
+    /// Returns `idNN` (e.g., `id01`) by default.
+    fn anchor_id(&self, filename: &str, title: &str, number: usize) -> String {
+        let _ = (filename, title);
+        format!("id{number:02}")
+    }
+}
+
+/// The default [`IdGenerator`]: manifest items are identified by their filename, and anchors are `idNN`.
+#[derive(Debug, Default, Clone, Copy)]
+pub struct DefaultIds;
+
+impl IdGenerator for DefaultIds {}
+
+/// An [`IdGenerator`] prepending a **prefix** to the default ids (e.g., `vol1-c01.xhtml` and `vol1-id01`).
+///
+/// ```rust
+/// use liber::epub::{IdGenerator, PrefixedIds};
+///
+/// let ids = PrefixedIds::new("vol1-");
+/// assert_eq!(ids.item_id("c01.xhtml"), "vol1-c01.xhtml");
+/// assert_eq!(ids.anchor_id("c01.xhtml", "The Sea", 2), "vol1-id02");
+/// ```
+#[derive(Debug, Clone)]
+pub struct PrefixedIds(String);
+
+impl PrefixedIds {
+    /// Creates a generator prepending the given **prefix**.
+    pub fn new<S: Into<String>>(prefix: S) -> Self {
+        Self(prefix.into())
+    }
+}
+
+impl IdGenerator for PrefixedIds {
+    fn item_id(&self, id: &str) -> String {
+        format!("{}{id}", self.0)
+    }
+
+    fn anchor_id(&self, filename: &str, title: &str, number: usize) -> String {
+        format!(
+            "{}{}",
+            self.0,
+            DefaultIds.anchor_id(filename, title, number)
+        )
+    }
+}
+
+/// An [`IdGenerator`] of **stable hashes**: every id is `h` followed by the first 12 hexadecimal digits of
+/// the SHA-256 digest of what it identifies (the default id of a manifest item, or the filename, title and
+/// number of an anchor), so ids do not depend on the order in which the book is built.
+#[derive(Debug, Default, Clone, Copy)]
+pub struct HashedIds;
+
+impl HashedIds {
+    /// Gets the hashed id of `source`.
+    fn hash(source: &str) -> String {
+        format!("h{}", &file_content::sha256_hex(source.as_bytes())[..12])
+    }
+}
+
+impl IdGenerator for HashedIds {
+    fn item_id(&self, id: &str) -> String {
+        Self::hash(id)
+    }
+
+    fn anchor_id(&self, filename: &str, title: &str, number: usize) -> String {
+        Self::hash(&format!("{filename}#{title}#{number}"))
+    }
+}
+
+/// An [`IdGenerator`] of **title slugs**: anchors are the slug of the title of their content reference
+/// (see [`crate::epub::slugify`], e.g., `the-beginning`), so deep links remain stable when sections are
+/// reordered. Manifest items keep their default ids.
+///
+/// Repeated titles within a content file get a numeric suffix (e.g., `notes-2`), slugs starting with
+/// a digit are prefixed with `id-`, and titles without letters or digits fall back to `idNN`.
+///
+/// ```rust
+/// use liber::epub::{IdGenerator, SlugIds};
+///
+/// assert_eq!(SlugIds.anchor_id("c01.xhtml", "The Beginning", 1), "the-beginning");
+/// assert_eq!(SlugIds.anchor_id("c01.xhtml", "1984", 2), "id-1984");
+/// ```
+#[derive(Debug, Default, Clone, Copy)]
+pub struct SlugIds;
+
+impl IdGenerator for SlugIds {
+    fn anchor_id(&self, filename: &str, title: &str, number: usize) -> String {
+        let slug = slugify(title);
+        if slug.is_empty() {
+            DefaultIds.anchor_id(filename, title, number)
+        } else if slug.starts_with(|c: char| c.is_ascii_digit()) {
+            format!("id-{slug}")
+        } else {
+            slug
+        }
+    }
+}
+
+impl Epub<'_> {
+    /// Gets the id generator, defaulting to [`DefaultIds`].
+    pub fn ids(&self) -> &dyn IdGenerator {
+        self.id_generator.as_deref().unwrap_or(&DefaultIds)
+    }
+
+    /// Sets the anchor id of every content reference without an explicit id, if an id generator is set,
+    /// deduplicating the ids within every content file.
+    pub(crate) fn assign_anchor_ids(&mut self) {
+        let Some(ids) = self.id_generator.clone() else {
+            return;
+        };
+
+        let mut number = 0;
+        for content in self.contents.iter_mut().flatten() {
+            content.assign_anchor_ids(&mut number, ids.as_ref());
+        }
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Sets the **id generator** (see [`IdGenerator`]) of the manifest items and the content reference anchors.
+    ///
+    /// ```rust
+    /// use liber::epub::{EpubBuilder, MetadataBuilder, PrefixedIds};
+    ///
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+    ///     .id_generator(PrefixedIds::new("vol1-"));
+    /// ```
+    pub fn id_generator<G: IdGenerator + 'static>(mut self, id_generator: G) -> Self {
+        self.0.id_generator = Some(Arc::new(id_generator));
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, ContentReference, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_default_ids() {
+        assert_eq!(DefaultIds.item_id("c01.xhtml"), "c01.xhtml");
+        assert_eq!(DefaultIds.anchor_id("c01.xhtml", "One", 3), "id03");
+    }
+
+    #[test]
+    fn test_hashed_ids() {
+        let id = HashedIds.item_id("c01.xhtml");
+        assert_eq!(id.len(), 13);
+        assert!(id.starts_with('h'));
+        assert_eq!(id, HashedIds.item_id("c01.xhtml"));
+        assert_ne!(id, HashedIds.item_id("c02.xhtml"));
+        assert_ne!(
+            HashedIds.anchor_id("c01.xhtml", "One", 1),
+            HashedIds.anchor_id("c01.xhtml", "One", 2)
+        );
+    }
+
+    #[test]
+    fn test_slug_ids() {
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><h2 id="notes">Notes</h2><h2 id="notes-2">Notes</h2><h2 id="notes-3">Notes</h2><h2 id="id04">?</h2></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .add_content_reference(ContentReference::new("Notes"))
+                .add_content_reference(ContentReference::new("Notes").id("notes-2"))
+                .add_content_reference(ContentReference::new("Notes"))
+                .add_content_reference(ContentReference::new("?"))
+                .build(),
+            )
+            .id_generator(SlugIds);
+        builder.0.prepare().unwrap();
+        builder.0.check().unwrap();
+
+        let content_references = builder.0.contents.as_ref().unwrap()[0]
+            .content_references
+            .as_ref()
+            .unwrap();
+        let anchors = content_references
+            .iter()
+            .enumerate()
+            .map(|(index, content_reference)| content_reference.anchor_id(index + 1).into_owned())
+            .collect::<Vec<_>>();
+        assert_eq!(anchors, ["notes", "notes-2", "notes-3", "id04"]);
+    }
+
+    #[test]
+    fn test_id_generator_on_create() {
+        let mut output = Vec::new();
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .stylesheet(b"p {}")
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><h1>One</h1><h2 id="vol1-id01">Section</h2><h2 id="named">Named</h2></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .add_content_reference(ContentReference::new("Section"))
+                .add_content_reference(ContentReference::new("Named").id("named"))
+                .build(),
+            )
+            .id_generator(PrefixedIds::new("vol1-"))
+            .collect_errors(true)
+            .create(&mut output)
+            .unwrap();
+
+        let mut archive = zip::ZipArchive::new(std::io::Cursor::new(output)).unwrap();
+        let read = |archive: &mut zip::ZipArchive<_>, name| {
+            let mut file = String::new();
+            std::io::Read::read_to_string(&mut archive.by_name(name).unwrap(), &mut file).unwrap();
+            file
+        };
+
+        let opf = read(&mut archive, "OEBPS/content.opf");
+        assert!(opf.contains(r#"<item id="vol1-ncx" href="toc.ncx""#));
+        assert!(opf.contains(r#"<spine toc="vol1-ncx">"#));
+        assert!(opf.contains(r#"<item id="vol1-style.css" href="style.css""#));
+        assert!(opf.contains(r#"<itemref idref="vol1-c01.xhtml"/>"#));
+
+        let ncx = read(&mut archive, "OEBPS/toc.ncx");
+        assert!(ncx.contains(r#"<content src="c01.xhtml#vol1-id01"/>"#));
+        assert!(ncx.contains(r#"<content src="c01.xhtml#named"/>"#));
+    }
+}
@@ -0,0 +1,290 @@
+use quick_xml::escape::escape;
+
+use crate::{
+    epub::{Content, ContentBuilder, ReferenceType},
+    output::xml,
+};
+
+/// The filename of the generated page of extended image descriptions.
+pub const DESCRIPTIONS_FILENAME: &str = "descriptions.xhtml";
+
+/// The **description of an image**: its alternative text and an optional long description,
+/// registered once (see [`crate::epub::EpubBuilder::describe_image`]) and applied to every `<img>` of the image.
+///
+/// ```rust
+/// use liber::epub::{EpubBuilder, ImageDescription, MetadataBuilder};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+///     .describe_image(
+///         "chart.png",
+///         ImageDescription::new("Sales by year").long_description("Sales grew from 10 units in 2020 to 40 in 2023."),
+///     )
+///     .describe_image(
+///         "flow.png",
+///         ImageDescription::new("Order flow").extended_description(
+///             "<ol><li>The customer places the order.</li><li>The warehouse ships it.</li></ol>",
+///         ),
+///     )
+///     .require_alt_text(true);
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ImageDescription {
+    /// The alternative text of the image.
+    alt: String,
+    /// Optional long description, for images whose content doesn't fit in the alternative text (e.g., charts).
+    long_description: Option<String>,
+    /// Optional extended description (XHTML markup), for complex images (e.g., diagrams or data tables).
+    extended_description: Option<String>,
+}
+
+impl ImageDescription {
+    /// Creates a description with the given **alternative text**.
+    pub fn new<S: Into<String>>(alt: S) -> Self {
+        Self {
+            alt: alt.into(),
+            long_description: None,
+            extended_description: None,
+        }
+    }
+
+    /// Sets a **long description**, added right after the image and referenced with `aria-describedby`.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn long_description<S: Into<String>>(mut self, long_description: S) -> Self {
+        self.long_description = Some(long_description.into());
+        self
+    }
+
+    /// Sets an **extended description** (XHTML markup, e.g., a list or a data table) for complex images.
+    ///
+    /// Every extended description becomes a section of a generated non-linear page ([`DESCRIPTIONS_FILENAME`]),
+    /// linking back to the image. The image is followed by a link to its section, referenced from the image
+    /// with `aria-details`.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn extended_description<S: Into<String>>(mut self, extended_description: S) -> Self {
+        self.extended_description = Some(extended_description.into());
+        self
+    }
+}
+
+/// Applies the registered image descriptions to the bodies, collecting the extended descriptions.
+#[derive(Debug, Default)]
+pub(crate) struct ImageDescriber {
+    /// The number of long descriptions inserted.
+    long_descriptions: usize,
+    /// The sections of the extended descriptions found, in reading order.
+    sections: Vec<String>,
+}
+
+impl ImageDescriber {
+    /// Applies the registered descriptions to the `<img>` elements of a body (of the content `filename`) whose `src`
+    /// has the same filename: the alternative text is set (replacing an empty or missing one), the long description,
+    /// if any, is inserted after the image and referenced with `aria-describedby`, and the extended description,
+    /// if any, is linked after the image and referenced with `aria-details`.
+    ///
+    /// Returns `None` if no image was described.
+    pub(crate) fn describe(
+        &mut self,
+        filename: &str,
+        body: &str,
+        descriptions: &[(String, ImageDescription)],
+    ) -> Option<String> {
+        let mut result = String::with_capacity(body.len());
+        let mut changed = false;
+        let mut rest = body;
+
+        while let Some(start) = find_img(rest) {
+            let tag_end = start + rest[start..].find('>')? + 1;
+            let original = &rest[start..tag_end];
+            result.push_str(&rest[..start]);
+            rest = &rest[tag_end..];
+
+            let Some(description) = xml::attribute(original, "src").and_then(|src| {
+                descriptions
+                    .iter()
+                    .find(|(name, _)| image_filename(name) == image_filename(src))
+                    .map(|(_, description)| description)
+            }) else {
+                result.push_str(original);
+                continue;
+            };
+
+            let alt = format!(r#" alt="{}""#, escape(&description.alt));
+            let mut tag = match xml::attribute(original, "alt") {
+                Some("") => original.replacen(r#" alt="""#, &alt, 1),
+                Some(_) => original.to_string(),
+                None => format!("<img{alt}{}", &original["<img".len()..]),
+            };
+            let mut following = String::new();
+
+            if let Some(ref long_description) = description.long_description
+                && xml::attribute(&tag, "aria-describedby").is_none()
+            {
+                self.long_descriptions += 1;
+                let id = format!("image-description-{}", self.long_descriptions);
+                tag = format!(r#"<img aria-describedby="{id}"{}"#, &tag["<img".len()..]);
+                following.push_str(&format!(
+                    r#"<span class="image-description" id="{id}">{}</span>"#,
+                    escape(long_description)
+                ));
+            }
+
+            if let Some(ref extended_description) = description.extended_description
+                && xml::attribute(&tag, "aria-details").is_none()
+            {
+                let number = self.sections.len() + 1;
+                let link_id = format!("image-details-link-{number}");
+                let section_id = format!("image-details-{number}");
+                tag = format!(r#"<img aria-details="{link_id}"{}"#, &tag["<img".len()..]);
+                following.push_str(&format!(
+                    r#"<a class="image-details-link" id="{link_id}" href="{DESCRIPTIONS_FILENAME}#{section_id}">Description of the image</a>"#
+                ));
+                self.sections.push(format!(
+                    r#"<div class="image-details" id="{section_id}"><h2>{}</h2>{extended_description}<p><a href="{filename}#{link_id}">Back</a></p></div>"#,
+                    escape(&description.alt)
+                ));
+            }
+
+            changed |= tag != original || !following.is_empty();
+            result.push_str(&tag);
+            result.push_str(&following);
+        }
+        result.push_str(rest);
+
+        changed.then_some(result)
+    }
+
+    /// Builds the non-linear page of the extended descriptions with the given title.
+    ///
+    /// Returns `None` if no extended description was found.
+    pub(crate) fn content<'a>(&self, title: &str) -> Option<Content<'a>> {
+        if self.sections.is_empty() {
+            return None;
+        }
+
+        let body = format!(
+            "<body><h1>{}</h1>{}</body>",
+            escape(title),
+            self.sections.concat()
+        );
+        Some(
+            ContentBuilder::new_owned(body, ReferenceType::Text(title.to_string()))
+                .filename(DESCRIPTIONS_FILENAME)
+                .linear(false)
+                .build(),
+        )
+    }
+}
+
+/// Finds the `src` of the first `<img>` of a body without an `alt` attribute.
+pub(crate) fn missing_alt_text(body: &str) -> Option<&str> {
+    let mut rest = body;
+    while let Some(start) = find_img(rest) {
+        let tag_end = start + rest[start..].find('>')?;
+        let tag = &rest[start..tag_end];
+        if xml::attribute(tag, "alt").is_none() {
+            return Some(xml::attribute(tag, "src").unwrap_or_default());
+        }
+        rest = &rest[tag_end..];
+    }
+    None
+}
+
+/// Finds the position of the next `<img>` start tag.
+fn find_img(xhtml: &str) -> Option<usize> {
+    xhtml
+        .match_indices("<img")
+        .map(|(start, _)| start)
+        .find(|start| {
+            xhtml[start + 4..].starts_with(|c: char| c.is_whitespace() || c == '/' || c == '>')
+        })
+}
+
+/// Gets the filename of an image path or URL (its last segment).
+fn image_filename(path: &str) -> &str {
+    path.rsplit('/').next().unwrap_or(path)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_describe() {
+        let descriptions = [
+            (
+                "images/map.png".to_string(),
+                ImageDescription::new("Map & legend"),
+            ),
+            (
+                "chart.png".to_string(),
+                ImageDescription::new("Sales").long_description("Sales doubled."),
+            ),
+        ];
+        let mut describer = ImageDescriber::default();
+        let described = describer
+            .describe(
+                "c01.xhtml",
+                r#"<p><img src="map.png"/> <img src="chart.png" alt=""/> <img src="other.png"/></p>"#,
+                &descriptions,
+            )
+            .unwrap();
+        assert_eq!(
+            described,
+            r#"<p><img alt="Map &amp; legend" src="map.png"/> <img aria-describedby="image-description-1" src="chart.png" alt="Sales"/><span class="image-description" id="image-description-1">Sales doubled.</span> <img src="other.png"/></p>"#
+        );
+        assert!(
+            describer
+                .describe(
+                    "c01.xhtml",
+                    r#"<img src="map.png" alt="Kept"/>"#,
+                    &descriptions
+                )
+                .is_none()
+        );
+        assert!(describer.content("Descriptions").is_none());
+    }
+
+    #[test]
+    fn test_describe_extended() {
+        let descriptions = [(
+            "flow.png".to_string(),
+            ImageDescription::new("Flow").extended_description("<ol><li>Order</li></ol>"),
+        )];
+        let mut describer = ImageDescriber::default();
+        let described = describer
+            .describe(
+                "c02.xhtml",
+                r#"<p><img src="flow.png"/></p>"#,
+                &descriptions,
+            )
+            .unwrap();
+        assert_eq!(
+            described,
+            r#"<p><img aria-details="image-details-link-1" alt="Flow" src="flow.png"/><a class="image-details-link" id="image-details-link-1" href="descriptions.xhtml#image-details-1">Description of the image</a></p>"#
+        );
+
+        let files = describer
+            .content("Descriptions")
+            .unwrap()
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/descriptions.xhtml");
+        assert!(files[0].bytes.contains(r#"id="image-details-1""#));
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="c02.xhtml#image-details-link-1">Back</a>"#)
+        );
+    }
+
+    #[test]
+    fn test_missing_alt_text() {
+        assert_eq!(
+            missing_alt_text(r#"<img src="a.png" alt=""/><img src="b.png"/>"#),
+            Some("b.png")
+        );
+        assert_eq!(missing_alt_text(r#"<img src="a.png" alt="A"/>"#), None);
+    }
+}
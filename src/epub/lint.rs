@@ -0,0 +1,681 @@
+use std::fmt::Display;
+
+use crate::{
+    epub::{Epub, Profile, Resource, profile},
+    output::xml,
+};
+
+/// The **severity** of a lint finding, from the least to the most serious.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Severity {
+    /// A construct worth reviewing, with a minor impact.
+    Info,
+    /// A construct rendered poorly by some readers.
+    Warning,
+    /// A construct known to break some readers.
+    Error,
+}
+
+impl AsRef<str> for Severity {
+    fn as_ref(&self) -> &str {
+        match self {
+            Self::Info => "info",
+            Self::Warning => "warning",
+            Self::Error => "error",
+        }
+    }
+}
+
+/// A **rule** of the [`Linter`], which can be suppressed.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum LintRule {
+    /// `epub:switch` elements nested inside another `epub:switch`, which break ADE and Kobo.
+    NestedSwitch,
+    /// Viewport units (`vw`, `vh`, `vmin`, `vmax`) in the stylesheet or inline styles, unsupported by ADE.
+    ViewportUnits,
+    /// Images larger than the configured size, slow to render (or dropped) on e-ink readers.
+    OversizedImage,
+    /// Audio or video resources, which Kindle and ADE don't play.
+    UnsupportedMedia,
+    /// Embedded web content (iframes), only rendered by Apple Books and other scripted readers (e.g., Thorium).
+    EmbeddedContent,
+    /// Text and background colors whose contrast ratio is below the WCAG 2 minimum (4.5:1),
+    /// hard to read on every reader.
+    LowContrast,
+    /// EPUB 3 markup in the bodies, not valid in the XHTML 1.1 of EPUB 2: HTML5 elements (e.g., `<aside>` or
+    /// `<video>`) and the `epub:type` and `role` semantics written by the markup helpers.
+    Epub3Only,
+}
+
+impl LintRule {
+    /// Gets the default severity of the findings of this rule.
+    fn severity(self) -> Severity {
+        match self {
+            Self::NestedSwitch | Self::UnsupportedMedia => Severity::Error,
+            Self::ViewportUnits
+            | Self::OversizedImage
+            | Self::EmbeddedContent
+            | Self::LowContrast
+            | Self::Epub3Only => Severity::Warning,
+        }
+    }
+
+    /// Gets the readers affected by the constructs of this rule.
+    fn profiles(self) -> &'static [Profile] {
+        match self {
+            Self::NestedSwitch => &[Profile::Ade, Profile::Kobo],
+            Self::ViewportUnits => &[Profile::Ade],
+            Self::OversizedImage => &[Profile::Kindle, Profile::Kobo],
+            Self::UnsupportedMedia => &[Profile::Kindle, Profile::Ade],
+            Self::EmbeddedContent => &[Profile::Kindle, Profile::Kobo, Profile::Ade],
+            Self::LowContrast | Self::Epub3Only => &[
+                Profile::Kindle,
+                Profile::AppleBooks,
+                Profile::Kobo,
+                Profile::Ade,
+            ],
+        }
+    }
+}
+
+impl AsRef<str> for LintRule {
+    fn as_ref(&self) -> &str {
+        match self {
+            Self::NestedSwitch => "nested-switch",
+            Self::ViewportUnits => "viewport-units",
+            Self::OversizedImage => "oversized-image",
+            Self::UnsupportedMedia => "unsupported-media",
+            Self::EmbeddedContent => "embedded-content",
+            Self::LowContrast => "low-contrast",
+            Self::Epub3Only => "epub3-only",
+        }
+    }
+}
+
+/// A **finding** of the [`Linter`]: a construct known to break (or render poorly on) specific readers.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Lint {
+    /// The rule that reported the finding.
+    pub rule: LintRule,
+    /// The severity of the finding.
+    pub severity: Severity,
+    /// The file where the construct was found (e.g., `c01.xhtml`, `style.css`, `content.opf` or an image path).
+    pub location: String,
+    /// A description of the construct found.
+    pub message: String,
+    /// The readers affected by the construct.
+    pub profiles: &'static [Profile],
+}
+
+/// Displays the finding like `warning[viewport-units] style.css: ... (ADE)`.
+impl Display for Lint {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(
+            f,
+            "{}[{}] {}: {} ({})",
+            self.severity.as_ref(),
+            self.rule.as_ref(),
+            self.location,
+            self.message,
+            self.profiles
+                .iter()
+                .map(Profile::to_string)
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    }
+}
+
+/// A **lint pass** reporting constructs known to break specific readers, independent of the
+/// target [`Profile`] of the book (see [`crate::epub::EpubBuilder::lint`]).
+///
+/// Nothing is changed nor rejected: the findings are only reported, so they can be reviewed,
+/// filtered by severity or suppressed per rule.
+#[derive(Debug, Clone)]
+pub struct Linter {
+    /// The rules whose findings are not reported.
+    suppressed: Vec<LintRule>,
+    /// The minimum severity of the reported findings.
+    min_severity: Severity,
+    /// The size in bytes above which an image is reported as oversized.
+    max_image_size: u64,
+}
+
+impl Default for Linter {
+    fn default() -> Self {
+        Self {
+            suppressed: Vec::new(),
+            min_severity: Severity::Info,
+            max_image_size: 2 * 1024 * 1024,
+        }
+    }
+}
+
+impl Linter {
+    /// Creates a linter reporting every rule, with images over 2 MiB reported as oversized.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// **Suppresses** the findings of a rule.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn suppress(mut self, rule: LintRule) -> Self {
+        self.suppressed.push(rule);
+        self
+    }
+
+    /// Sets the **minimum severity** of the reported findings. Defaults to [`Severity::Info`].
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn min_severity(mut self, min_severity: Severity) -> Self {
+        self.min_severity = min_severity;
+        self
+    }
+
+    /// Sets the size in bytes above which an image is reported as **oversized**. Defaults to 2 MiB.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn max_image_size(mut self, max_image_size: u64) -> Self {
+        self.max_image_size = max_image_size;
+        self
+    }
+
+    /// Runs the lint pass over a book, returning the findings in the order they were found.
+    pub(crate) fn lint(&self, epub: &Epub<'_>) -> Vec<Lint> {
+        let mut lints = Vec::new();
+        let mut report = |rule: LintRule, location: String, message: String| {
+            if !self.suppressed.contains(&rule) && rule.severity() >= self.min_severity {
+                lints.push(Lint {
+                    rule,
+                    severity: rule.severity(),
+                    location,
+                    message,
+                    profiles: rule.profiles(),
+                });
+            }
+        };
+
+        let stylesheet = epub
+            .stylesheet
+            .and_then(|stylesheet| std::str::from_utf8(stylesheet).ok());
+        if let Some(unit) = stylesheet.and_then(profile::viewport_unit) {
+            report(
+                LintRule::ViewportUnits,
+                "style.css".to_string(),
+                format!("uses the viewport unit '{unit}'"),
+            );
+        }
+
+        let palette = Palette::new(stylesheet.unwrap_or_default());
+        for (selector, declarations) in css_rules(stylesheet.unwrap_or_default()) {
+            if let Some(message) = palette.low_contrast(declarations) {
+                report(
+                    LintRule::LowContrast,
+                    "style.css".to_string(),
+                    format!("'{selector}' {message}"),
+                );
+            }
+        }
+
+        let mut number = 0;
+        for content in epub.contents.iter().flatten() {
+            content.for_each_body(&mut number, &mut |filename, body| {
+                if has_nested_switch(body) {
+                    report(
+                        LintRule::NestedSwitch,
+                        filename.to_string(),
+                        "nests an epub:switch inside another".to_string(),
+                    );
+                }
+                if xml::has_element(body, "iframe") {
+                    report(
+                        LintRule::EmbeddedContent,
+                        filename.to_string(),
+                        "embeds web content in an iframe".to_string(),
+                    );
+                }
+                let markup = xml::epub3_markup(body);
+                if !markup.is_empty() {
+                    report(
+                        LintRule::Epub3Only,
+                        filename.to_string(),
+                        format!(
+                            "uses the EPUB 3 markup '{}', not valid XHTML 1.1",
+                            markup.join("', '")
+                        ),
+                    );
+                }
+                if let Some(unit) = profile::viewport_unit(body) {
+                    report(
+                        LintRule::ViewportUnits,
+                        filename.to_string(),
+                        format!("uses the viewport unit '{unit}' in an inline style"),
+                    );
+                }
+                for style in inline_styles(body) {
+                    if let Some(message) = palette.low_contrast(style) {
+                        report(
+                            LintRule::LowContrast,
+                            filename.to_string(),
+                            format!("an inline style {message}"),
+                        );
+                    }
+                }
+            });
+        }
+
+        let resources = epub
+            .cover_image
+            .iter()
+            .chain(epub.resources.iter().flatten())
+            .chain(
+                epub.fallbacks
+                    .iter()
+                    .flatten()
+                    .flat_map(|(image, fallback)| [image, fallback]),
+            );
+        for resource in resources {
+            match resource {
+                Resource::Image(..) => {
+                    if let Some(size) = resource
+                        .size_hint(epub.base_dir, epub.file_system())
+                        .filter(|&size| size > self.max_image_size)
+                    {
+                        report(
+                            LintRule::OversizedImage,
+                            resource.to_string(),
+                            format!("has {size} bytes (more than {} bytes)", self.max_image_size),
+                        );
+                    }
+                }
+                Resource::Audio(_) | Resource::Video(_) => report(
+                    LintRule::UnsupportedMedia,
+                    resource.to_string(),
+                    "is an audio or video resource".to_string(),
+                ),
+                Resource::Font(_) => {}
+            }
+        }
+
+        lints
+    }
+}
+
+/// Checks whether an XHTML string nests an `epub:switch` element inside another.
+fn has_nested_switch(xhtml: &str) -> bool {
+    let mut depth = 0usize;
+    let mut rest = xhtml;
+
+    while let Some(position) = rest.find("epub:switch") {
+        let closing = rest[..position].ends_with("</");
+        let opening = rest[..position].ends_with('<');
+        rest = &rest[position + "epub:switch".len()..];
+
+        if opening {
+            depth += 1;
+            if depth > 1 {
+                return true;
+            }
+        } else if closing {
+            depth = depth.saturating_sub(1);
+        }
+    }
+    false
+}
+
+/// The minimum contrast ratio between text and background colors, as required by WCAG 2 (level AA) for normal text.
+const MIN_CONTRAST_RATIO: f64 = 4.5;
+
+/// A color as declared (e.g., `#fff` or `white`) and its `rgb` channels.
+type Color<'a> = (&'a str, [u8; 3]);
+
+/// The text and background colors of the page, declared on the `body` (or `html`) rules of the stylesheet,
+/// falling back to black text on a white background.
+///
+/// Rules declaring only one of the colors are checked against the other one of the page.
+struct Palette<'a> {
+    /// The text color of the page.
+    color: Color<'a>,
+    /// The background color of the page.
+    background: Color<'a>,
+}
+
+impl<'a> Palette<'a> {
+    /// Gets the palette of the page from a stylesheet.
+    fn new(css: &'a str) -> Self {
+        let mut palette = Self {
+            color: ("black", [0, 0, 0]),
+            background: ("white", [255, 255, 255]),
+        };
+        for (selector, declarations) in css_rules(css) {
+            if selector
+                .split(',')
+                .any(|selector| matches!(selector.trim(), "body" | "html"))
+            {
+                let (color, background) = declared_colors(declarations);
+                palette.color = color.unwrap_or(palette.color);
+                palette.background = background.unwrap_or(palette.background);
+            }
+        }
+        palette
+    }
+
+    /// Checks the colors declared in a list of declarations (e.g., `color: #999`), describing the
+    /// failure if their contrast ratio is below [`MIN_CONTRAST_RATIO`].
+    ///
+    /// Returns `None` if no color is declared or the contrast is enough.
+    fn low_contrast(&self, declarations: &str) -> Option<String> {
+        let (color, background) = declared_colors(declarations);
+        if color.is_none() && background.is_none() {
+            return None;
+        }
+        let (color, background) = (
+            color.unwrap_or(self.color),
+            background.unwrap_or(self.background),
+        );
+
+        let ratio = contrast_ratio(color.1, background.1);
+        (ratio < MIN_CONTRAST_RATIO).then(|| {
+            format!(
+                "has a contrast ratio of {ratio:.2}:1 between '{}' and '{}' (less than {MIN_CONTRAST_RATIO}:1)",
+                color.0, background.0
+            )
+        })
+    }
+}
+
+/// Splits a stylesheet into its `(selector, declarations)` rules, skipping comments.
+///
+/// The rules nested in at-rules (e.g., `@media`) are included.
+fn css_rules(css: &str) -> Vec<(&str, &str)> {
+    let mut rules = Vec::new();
+    let mut rest = css;
+
+    while let Some(end) = rest.find('}') {
+        let block = &rest[..end];
+        rest = &rest[end + 1..];
+
+        let Some(start) = block.rfind('{') else {
+            continue;
+        };
+        let selector = block[..start]
+            .rsplit(['{', '}', ';'])
+            .next()
+            .unwrap_or_default();
+        let selector = selector
+            .rsplit_once("*/")
+            .map_or(selector, |(_, selector)| selector)
+            .trim();
+        if !selector.is_empty() && !selector.starts_with('@') {
+            rules.push((selector, &block[start + 1..]));
+        }
+    }
+    rules
+}
+
+/// Finds the `style` attributes of an XHTML string.
+fn inline_styles(xhtml: &str) -> impl Iterator<Item = &str> {
+    xhtml
+        .match_indices(r#" style=""#)
+        .filter_map(|(position, pattern)| {
+            let start = position + pattern.len();
+            Some(&xhtml[start..start + xhtml[start..].find('"')?])
+        })
+}
+
+/// Gets the text and background colors declared in a list of declarations, as their `(value, rgb)`.
+fn declared_colors(declarations: &str) -> (Option<Color<'_>>, Option<Color<'_>>) {
+    let mut color = None;
+    let mut background = None;
+
+    for declaration in declarations.split(';') {
+        let Some((name, value)) = declaration.split_once(':') else {
+            continue;
+        };
+        let value = value.trim().trim_end_matches("!important").trim();
+        match name.trim() {
+            "color" => color = parse_color(value).map(|rgb| (value, rgb)),
+            "background-color" => background = parse_color(value).map(|rgb| (value, rgb)),
+            "background" => {
+                background = value
+                    .split_whitespace()
+                    .find_map(|token| parse_color(token).map(|rgb| (token, rgb)));
+            }
+            _ => {}
+        }
+    }
+    (color, background)
+}
+
+/// Parses an opaque CSS color: a hex color (`#rgb` or `#rrggbb`), an `rgb()` color or a basic named color.
+///
+/// Returns `None` for other values (e.g., `inherit`, `transparent` or colors with an alpha channel).
+fn parse_color(value: &str) -> Option<[u8; 3]> {
+    let value = value.to_ascii_lowercase();
+
+    if let Some(hex) = value.strip_prefix('#') {
+        let channel = |digits: &str| u8::from_str_radix(digits, 16).ok();
+        return match hex.len() {
+            3 => {
+                let mut rgb = [0; 3];
+                for (index, digit) in hex.char_indices() {
+                    rgb[index] = channel(&digit.to_string().repeat(2))?;
+                }
+                Some(rgb)
+            }
+            6 => Some([
+                channel(&hex[0..2])?,
+                channel(&hex[2..4])?,
+                channel(&hex[4..6])?,
+            ]),
+            _ => None,
+        };
+    }
+
+    if let Some(arguments) = value
+        .strip_prefix("rgb(")
+        .and_then(|value| value.strip_suffix(')'))
+    {
+        let channels = arguments
+            .split([',', ' '])
+            .filter(|channel| !channel.is_empty())
+            .map(|channel| match channel.strip_suffix('%') {
+                Some(percentage) => percentage
+                    .parse::<f64>()
+                    .ok()
+                    .map(|percentage| (percentage.clamp(0.0, 100.0) * 2.55).round() as u8),
+                None => channel.parse::<u8>().ok(),
+            })
+            .collect::<Option<Vec<_>>>()?;
+        return <[u8; 3]>::try_from(channels).ok();
+    }
+
+    let rgb = match value.as_str() {
+        "black" => [0, 0, 0],
+        "silver" => [192, 192, 192],
+        "gray" | "grey" => [128, 128, 128],
+        "white" => [255, 255, 255],
+        "maroon" => [128, 0, 0],
+        "red" => [255, 0, 0],
+        "purple" => [128, 0, 128],
+        "fuchsia" => [255, 0, 255],
+        "green" => [0, 128, 0],
+        "lime" => [0, 255, 0],
+        "olive" => [128, 128, 0],
+        "yellow" => [255, 255, 0],
+        "navy" => [0, 0, 128],
+        "blue" => [0, 0, 255],
+        "teal" => [0, 128, 128],
+        "aqua" => [0, 255, 255],
+        "orange" => [255, 165, 0],
+        _ => return None,
+    };
+    Some(rgb)
+}
+
+/// Computes the WCAG 2 contrast ratio (from 1 to 21) between two colors.
+fn contrast_ratio(first: [u8; 3], second: [u8; 3]) -> f64 {
+    let luminance = |rgb: [u8; 3]| {
+        let [r, g, b] = rgb.map(|channel| {
+            let channel = f64::from(channel) / 255.0;
+            if channel <= 0.039_28 {
+                channel / 12.92
+            } else {
+                ((channel + 0.055) / 1.055).powf(2.4)
+            }
+        });
+        0.2126 * r + 0.7152 * g + 0.0722 * b
+    };
+
+    let (first, second) = (luminance(first), luminance(second));
+    (first.max(second) + 0.05) / (first.min(second) + 0.05)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::epub::{
+        ContentBuilder, EpubBuilder, ImageType, MemoryFileSystem, MetadataBuilder, ReferenceType,
+    };
+    use crate::markup::{Figure, Footnotes};
+
+    #[test]
+    fn test_has_nested_switch() {
+        assert!(!has_nested_switch(
+            "<epub:switch><epub:case/></epub:switch><epub:switch></epub:switch>"
+        ));
+        assert!(has_nested_switch(
+            "<epub:switch><epub:case><epub:switch></epub:switch></epub:case></epub:switch>"
+        ));
+    }
+
+    #[test]
+    fn test_contrast_ratio() {
+        assert!((contrast_ratio([0, 0, 0], [255, 255, 255]) - 21.0).abs() < 0.01);
+        assert!((contrast_ratio([119, 119, 119], [255, 255, 255]) - 4.48).abs() < 0.01);
+        assert_eq!(parse_color("#FA0"), Some([255, 170, 0]));
+        assert_eq!(parse_color("rgb(10, 20, 100%)"), Some([10, 20, 255]));
+        assert_eq!(parse_color("grey"), Some([128, 128, 128]));
+        assert_eq!(parse_color("rgba(0, 0, 0, 0.5)"), None);
+        assert_eq!(parse_color("inherit"), None);
+    }
+
+    #[test]
+    fn test_linter_low_contrast() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .stylesheet(
+                b"/* theme */ body { background-color: #222; color: #eee; }
+                p.note { color: #444 !important; }
+                @media print { a { color: #fff; } }
+                h1 { color: yellow; background: #fff url(bg.png); }",
+            )
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><p style="color: #333">Dark</p><p style="color: white">Light</p></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            );
+
+        let lints = builder.lint(&Linter::new());
+        assert_eq!(
+            lints
+                .iter()
+                .map(|lint| (lint.location.as_str(), lint.message.as_str()))
+                .collect::<Vec<_>>(),
+            vec![
+                (
+                    "style.css",
+                    "'p.note' has a contrast ratio of 1.63:1 between '#444' and '#222' (less than 4.5:1)"
+                ),
+                (
+                    "style.css",
+                    "'h1' has a contrast ratio of 1.07:1 between 'yellow' and '#fff' (less than 4.5:1)"
+                ),
+                (
+                    "c01.xhtml",
+                    "an inline style has a contrast ratio of 1.26:1 between '#333' and '#222' (less than 4.5:1)"
+                ),
+            ]
+        );
+        assert_eq!(lints[0].severity, Severity::Warning);
+    }
+
+    #[test]
+    fn test_linter() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .file_system(
+                MemoryFileSystem::new()
+                    .file("big.jpg", vec![0; 2048])
+                    .file("small.jpg", vec![0; 16]),
+            )
+            .stylesheet(b"img { height: 90vh; }")
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><epub:switch><epub:switch/></epub:switch><iframe src="w.xhtml"/></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .build(),
+            )
+            .add_resource(Resource::Image(Path::new("big.jpg"), ImageType::Jpg))
+            .add_resource(Resource::Image(Path::new("small.jpg"), ImageType::Jpg))
+            .add_resource(Resource::Audio(Path::new("track.mp3")));
+
+        let lints = builder.lint(&Linter::new().max_image_size(1024));
+        assert_eq!(
+            lints.iter().map(|lint| lint.rule).collect::<Vec<_>>(),
+            vec![
+                LintRule::ViewportUnits,
+                LintRule::NestedSwitch,
+                LintRule::EmbeddedContent,
+                LintRule::Epub3Only,
+                LintRule::OversizedImage,
+                LintRule::UnsupportedMedia
+            ]
+        );
+        assert_eq!(
+            lints[0].to_string(),
+            "warning[viewport-units] style.css: uses the viewport unit 'vh' (ADE)"
+        );
+        assert_eq!(lints[1].location, "c01.xhtml");
+
+        let lints = builder.lint(
+            &Linter::new()
+                .suppress(LintRule::NestedSwitch)
+                .min_severity(Severity::Error),
+        );
+        assert_eq!(
+            lints.iter().map(|lint| lint.rule).collect::<Vec<_>>(),
+            vec![LintRule::UnsupportedMedia]
+        );
+    }
+
+    #[test]
+    fn test_linter_epub3_only() {
+        let mut footnotes = Footnotes::new("c2");
+        let body = format!(
+            "<body><p>Text{}</p>{}</body>",
+            footnotes.note_ref("Note."),
+            footnotes.build()
+        );
+        let figure = Figure::new("a.png", "A").to_string();
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+            ContentBuilder::new(figure.as_bytes(), ReferenceType::Text("One".to_string()))
+                .add_child(
+                    ContentBuilder::new(body.as_bytes(), ReferenceType::Text("Two".to_string()))
+                        .build(),
+                )
+                .build(),
+        );
+
+        let lints = builder.lint(&Linter::new());
+        assert_eq!(lints.len(), 1);
+        assert_eq!(
+            lints[0].to_string(),
+            "warning[epub3-only] c02.xhtml: uses the EPUB 3 markup '<aside>', 'epub:type', 'role', not valid XHTML 1.1 (Kindle, Apple Books, Kobo, ADE)"
+        );
+    }
+}
@@ -1,6 +1,7 @@
 use std::fmt::Display;
 
-use chrono::{DateTime, Utc};
+use chrono::{DateTime, NaiveDate, Utc};
+use quick_xml::escape::escape;
 use uuid::Uuid;
 
 /// Core structure holding all necessary descriptive information about a resource (e.g., a book).
@@ -20,12 +21,39 @@ pub struct Metadata {
     pub contributor: Option<String>,
     /// The entity responsible for making the resource available.
     pub publisher: Option<String>,
+    /// The imprint (brand) the resource is published under, which can differ from the corporate publisher.
+    pub imprint: Option<String>,
     /// The date of the resource's publication or creation. Defaults to the current UTC time when created via `new()`.
     pub date: Option<DateTime<Utc>>,
+    /// The precision of the `date` (year, month or day). Defaults to the day.
+    pub date_precision: DatePrecision,
     /// Keywords or phrases describing the content of the resource.
     pub subject: Option<String>,
+    /// Optional subjects classified by an authority (e.g., BISAC or Thema codes).
+    pub subjects: Option<Vec<Subject>>,
+    /// Optional free-text keywords, kept apart from the classified subjects.
+    pub keywords: Option<Vec<String>>,
     /// A short summary or description of the resource's content.
     pub description: Option<String>,
+    /// The rights statement (e.g., a copyright or license notice).
+    pub rights: Option<String>,
+    /// Optional Creative Commons license, declared as a `<link rel="cc:license">` and, if no rights statement
+    /// is set, as the rights statement.
+    pub license: Option<CreativeCommons>,
+    /// The edition statement (e.g., `"2nd edition, revised"`).
+    pub edition: Option<String>,
+    /// An internal revision or build number, distinguishing updated files from the original release.
+    pub revision: Option<String>,
+    /// Optional ISBN of the print edition the book is derived from, declared as its `dc:source`.
+    pub print_isbn: Option<String>,
+    /// Optional number of pages of the print edition.
+    pub print_pages: Option<u32>,
+    /// Optional periodical data (issue, volume and frequency) of a magazine or journal.
+    pub periodical: Option<Periodical>,
+    /// Whether the `generator` meta, naming this crate and its version, is stamped. Defaults to `true`.
+    pub generator: bool,
+    /// Optional custom `(name, content)` metas, such as the flags required by vendor platforms.
+    pub metas: Option<Vec<(String, String)>>,
 }
 
 impl Metadata {
@@ -40,12 +68,129 @@ impl Metadata {
             creator: None,
             contributor: None,
             publisher: None,
+            imprint: None,
             date: Some(Utc::now()),
+            date_precision: DatePrecision::default(),
             subject: None,
+            subjects: None,
+            keywords: None,
             description: None,
+            rights: None,
+            license: None,
+            edition: None,
+            revision: None,
+            print_isbn: None,
+            print_pages: None,
+            periodical: None,
+            generator: true,
+            metas: None,
         }
     }
 
+    /// Checks that the mandatory fields are set and that no optional field is blank.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] describing the first invalid field.
+    pub(crate) fn validate(&self) -> crate::Result {
+        if self.title.trim().is_empty() {
+            return Err(crate::Error::Validation(
+                "metadata title must not be empty".to_string(),
+            ));
+        }
+
+        if self.identifier.value().trim().is_empty() {
+            return Err(crate::Error::Validation(format!(
+                "metadata identifier ({}) must not be empty",
+                self.identifier
+            )));
+        }
+
+        let optionals = [
+            ("creator", &self.creator),
+            ("contributor", &self.contributor),
+            ("publisher", &self.publisher),
+            ("imprint", &self.imprint),
+            ("subject", &self.subject),
+            ("description", &self.description),
+            ("rights", &self.rights),
+            ("edition", &self.edition),
+            ("revision", &self.revision),
+            ("print ISBN", &self.print_isbn),
+        ];
+
+        for (name, value) in optionals {
+            if value.as_ref().is_some_and(|value| value.trim().is_empty()) {
+                return Err(crate::Error::Validation(format!(
+                    "metadata {name} is set but empty"
+                )));
+            }
+        }
+
+        if let Some(ref print_isbn) = self.print_isbn {
+            let isbn_digits = |isbn: &str| {
+                isbn.chars()
+                    .filter(|c| !matches!(c, '-' | ' '))
+                    .collect::<String>()
+            };
+            let digits = isbn_digits(print_isbn);
+            let valid = match digits.len() {
+                10 => {
+                    digits[..9].chars().all(|c| c.is_ascii_digit())
+                        && digits.ends_with(|c: char| c.is_ascii_digit() || c == 'X')
+                }
+                13 => digits.chars().all(|c| c.is_ascii_digit()),
+                _ => false,
+            };
+            if !valid {
+                return Err(crate::Error::Validation(format!(
+                    "metadata print ISBN '{print_isbn}' is not an ISBN-10 or ISBN-13"
+                )));
+            }
+            if matches!(self.identifier, Identifier::ISBN(ref isbn) if isbn_digits(isbn) == digits)
+            {
+                return Err(crate::Error::Validation(
+                    "metadata print ISBN must differ from the ISBN of the EPUB".to_string(),
+                ));
+            }
+        }
+
+        if let Some((name, _)) = self
+            .metas
+            .iter()
+            .flatten()
+            .find(|(name, _)| !is_xml_name(name))
+        {
+            return Err(crate::Error::Validation(format!(
+                "metadata custom meta name '{name}' is not a valid XML name"
+            )));
+        }
+
+        if self
+            .keywords
+            .iter()
+            .flatten()
+            .any(|keyword| keyword.trim().is_empty() || keyword.contains(','))
+        {
+            return Err(crate::Error::Validation(
+                "metadata keywords must not be empty nor contain commas".to_string(),
+            ));
+        }
+
+        if let Some(subject) = self
+            .subjects
+            .iter()
+            .flatten()
+            .find(|subject| subject.code.trim().is_empty())
+        {
+            return Err(crate::Error::Validation(format!(
+                "metadata {} subject has an empty code",
+                subject.authority.as_ref()
+            )));
+        }
+
+        Ok(())
+    }
+
     /// Generates the XML representation for the **title** element.
     pub(crate) fn title_as_metadata_xml(&self) -> String {
         format!("<dc:title>{}</dc:title>", self.title)
@@ -81,13 +226,23 @@ impl Metadata {
         ))
     }
 
+    /// Generates the XML `<meta>` tag with the **imprint**, since EPUB 2 has no Dublin Core element for it.
+    ///
+    /// Returns `None` if the imprint is not set.
+    pub(crate) fn imprint_as_metadata_xml(&self) -> Option<String> {
+        Some(format!(
+            r#"<meta name="imprint" content="{}"/>"#,
+            escape(self.imprint.as_ref()?)
+        ))
+    }
+
     /// Generates the XML representation for the **date** element, formatted as YYYY-MM-DD.
     ///
     /// Returns `None` if the date is not set.
     pub(crate) fn date_as_metadata_xml(&self) -> Option<String> {
         Some(format!(
             r#"<dc:date opf:event="publication">{}</dc:date>"#,
-            self.date?.format("%Y-%m-%d")
+            self.date?.format(self.date_precision.format())
         ))
     }
 
@@ -101,6 +256,24 @@ impl Metadata {
         ))
     }
 
+    /// Generates the XML `<meta>` tag with the free-text keywords, separated by commas.
+    ///
+    /// Returns `None` if there are no keywords.
+    pub(crate) fn keywords_as_metadata_xml(&self) -> Option<String> {
+        Some(format!(
+            r#"<meta name="keywords" content="{}"/>"#,
+            escape(self.keywords.as_ref()?.join(", "))
+        ))
+    }
+
+    /// Generates the XML `<dc:subject>` tags of the classified subjects, each followed by its authority and code.
+    ///
+    /// Returns `None` if there are no classified subjects.
+    pub(crate) fn subjects_as_metadata_xml(&self) -> Option<String> {
+        let subjects = self.subjects.as_ref()?;
+        Some(subjects.iter().map(Subject::as_metadata_xml).collect())
+    }
+
     /// Generates the XML representation for the **description** element.
     ///
     /// Returns `None` if the description is not set.
@@ -110,12 +283,113 @@ impl Metadata {
             self.description.as_ref()?
         ))
     }
+
+    /// Generates the XML representation for the **rights** element: the rights statement or, if not set,
+    /// the notice of the Creative Commons license.
+    ///
+    /// Returns `None` if neither is set.
+    pub(crate) fn rights_as_metadata_xml(&self) -> Option<String> {
+        let rights = self
+            .rights
+            .clone()
+            .or_else(|| self.license.map(CreativeCommons::notice))?;
+        Some(format!("<dc:rights>{}</dc:rights>", escape(&rights)))
+    }
+
+    /// Generates the XML `<link>` tag with the canonical URL of the **Creative Commons license**.
+    ///
+    /// Returns `None` if no license is set.
+    pub(crate) fn license_as_metadata_xml(&self) -> Option<String> {
+        Some(format!(
+            r#"<link rel="cc:license" href="{}"/>"#,
+            self.license?.url()
+        ))
+    }
+
+    /// Generates the XML `<meta>` tags with the **edition** statement and the **revision** number.
+    ///
+    /// Returns `None` if neither is set.
+    pub(crate) fn edition_as_metadata_xml(&self) -> Option<String> {
+        let xml = [("edition", &self.edition), ("revision", &self.revision)]
+            .into_iter()
+            .filter_map(|(name, value)| {
+                Some(format!(
+                    r#"<meta name="{name}" content="{}"/>"#,
+                    escape(value.as_ref()?)
+                ))
+            })
+            .collect::<String>();
+
+        (!xml.is_empty()).then_some(xml)
+    }
+
+    /// Generates the XML `<dc:source>` tag with the URN of the **print edition** ISBN and the `<meta>` tag
+    /// with its number of pages.
+    ///
+    /// Returns `None` if neither is set.
+    pub(crate) fn print_edition_as_metadata_xml(&self) -> Option<String> {
+        let mut xml = String::new();
+        if let Some(ref print_isbn) = self.print_isbn {
+            xml.push_str(&format!(
+                "<dc:source>urn:isbn:{}</dc:source>",
+                escape(print_isbn)
+            ));
+        }
+        if let Some(print_pages) = self.print_pages {
+            xml.push_str(&format!(
+                r#"<meta name="schema:numberOfPages" content="{print_pages}"/>"#
+            ));
+        }
+
+        (!xml.is_empty()).then_some(xml)
+    }
+
+    /// Generates the XML `<meta>` tags of the **periodical** data.
+    ///
+    /// Returns `None` if the periodical data is not set or empty.
+    pub(crate) fn periodical_as_metadata_xml(&self) -> Option<String> {
+        let xml = self.periodical.as_ref()?.as_metadata_xml();
+        (!xml.is_empty()).then_some(xml)
+    }
+
+    /// Generates the XML `<meta>` tags of the **custom metas**.
+    ///
+    /// Returns `None` if there are no custom metas.
+    pub(crate) fn metas_as_metadata_xml(&self) -> Option<String> {
+        Some(
+            self.metas
+                .as_ref()?
+                .iter()
+                .map(|(name, content)| {
+                    format!(
+                        r#"<meta name="{}" content="{}"/>"#,
+                        escape(name),
+                        escape(content)
+                    )
+                })
+                .collect(),
+        )
+    }
+
+    /// Generates the XML representation for the **generator** meta (e.g., `liber 0.1.1`),
+    /// which identifies the tool and version that produced the file.
+    ///
+    /// Returns `None` if the generator meta is suppressed.
+    pub(crate) fn generator_as_metadata_xml(&self) -> Option<String> {
+        self.generator.then(|| {
+            format!(
+                r#"<meta name="generator" content="liber {}"/>"#,
+                env!("CARGO_PKG_VERSION")
+            )
+        })
+    }
 }
 
 /// A builder for easily constructing [`Metadata`] structs.
 ///
 /// This uses a **fluent interface** to set optional fields before finalizing the structure with `build()`.
-#[derive(Debug)]
+/// The builder can be cloned to reuse common metadata (e.g., publisher and language) across books.
+#[derive(Debug, Clone)]
 pub struct MetadataBuilder(Metadata);
 
 impl MetadataBuilder {
@@ -129,6 +403,12 @@ impl MetadataBuilder {
         ))
     }
 
+    /// Replaces the **title** (e.g., when patching a copy of existing metadata).
+    pub fn retitle<S: Into<String>>(mut self, title: S) -> Self {
+        self.0.title = title.into();
+        self
+    }
+
     /// Sets the primary **language** of the resource.
     pub fn language(mut self, language: Language) -> Self {
         self.0.language = language;
@@ -159,32 +439,534 @@ impl MetadataBuilder {
         self
     }
 
+    /// Sets the **imprint** of the resource (e.g., `"Vintage"` for the publisher `"Penguin Random House"`).
+    pub fn imprint<S: Into<String>>(mut self, imprint: S) -> Self {
+        self.0.imprint = Some(imprint.into());
+        self
+    }
+
     /// Sets the publication **date** using a specific `DateTime<Utc>`.
     pub fn date(mut self, date: DateTime<Utc>) -> Self {
         self.0.date = Some(date);
         self
     }
 
+    /// Sets the **precision** of the publication date, so it is emitted as `2006`, `2006-01` or `2006-01-02`.
+    pub fn date_precision(mut self, date_precision: DatePrecision) -> Self {
+        self.0.date_precision = date_precision;
+        self
+    }
+
+    /// Sets the publication date from a **partial date** (`2006`, `2006-01` or `2006-01-02`),
+    /// taking the precision from its format. Many backlist titles only have a year.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the value is not a valid date with any of those precisions.
+    pub fn partial_date(mut self, date: &str) -> crate::Result<Self> {
+        let (date, date_precision) = DatePrecision::parse(date)?;
+        self.0.date = Some(date);
+        self.0.date_precision = date_precision;
+        Ok(self)
+    }
+
+    /// Sets the **edition** statement (e.g., `"2nd edition, revised"`).
+    pub fn edition<S: Into<String>>(mut self, edition: S) -> Self {
+        self.0.edition = Some(edition.into());
+        self
+    }
+
+    /// Sets the internal **revision** (or build) number, so updated files pushed to retailers
+    /// can be told apart from the original release.
+    pub fn revision<S: Into<String>>(mut self, revision: S) -> Self {
+        self.0.revision = Some(revision.into());
+        self
+    }
+
+    /// Sets the **ISBN of the print edition** the book is derived from (e.g., for print-on-demand companions),
+    /// declared as its `dc:source` so aggregators can connect the EPUB to its paper edition.
+    ///
+    /// The print page numbers can be marked in the contents with [`crate::markup::PageMarker`], listed in
+    /// the page list of the `toc.ncx`.
+    pub fn print_isbn<S: Into<String>>(mut self, print_isbn: S) -> Self {
+        self.0.print_isbn = Some(print_isbn.into());
+        self
+    }
+
+    /// Sets the **number of pages of the print edition**, declared as a `schema:numberOfPages` meta.
+    pub fn print_pages(mut self, print_pages: u32) -> Self {
+        self.0.print_pages = Some(print_pages);
+        self
+    }
+
+    /// Sets the **periodical** data (issue, volume and frequency) of a magazine or journal,
+    /// usually along with an [`Identifier::ISSN`].
+    pub fn periodical(mut self, periodical: Periodical) -> Self {
+        self.0.periodical = Some(periodical);
+        self
+    }
+
+    /// Adds a **custom meta** to the package metadata, as an extension point for the flags required
+    /// by vendor platforms (e.g., Duokan or Vivlio) that this crate has no explicit knowledge of.
+    ///
+    /// The `name` must be a valid XML name, checked by [`MetadataBuilder::build_validated`].
+    pub fn add_meta<N: Into<String>, C: Into<String>>(mut self, name: N, content: C) -> Self {
+        let meta = (name.into(), content.into());
+        if let Some(ref mut metas) = self.0.metas {
+            metas.push(meta);
+        } else {
+            self.0.metas = Some(vec![meta]);
+        }
+        self
+    }
+
     /// Sets the **subject** (keywords/tags) for the resource.
     pub fn subject<S: Into<String>>(mut self, subject: S) -> Self {
         self.0.subject = Some(subject.into());
         self
     }
 
+    /// Adds a free-text **keyword**, emitted in a dedicated `keywords` meta instead of a `<dc:subject>`
+    /// (which is meant for classification subjects).
+    pub fn add_keyword<S: Into<String>>(mut self, keyword: S) -> Self {
+        if let Some(ref mut keywords) = self.0.keywords {
+            keywords.push(keyword.into());
+        } else {
+            self.0.keywords = Some(vec![keyword.into()]);
+        }
+        self
+    }
+
+    /// Adds several free-text **keywords** (see [`MetadataBuilder::add_keyword`]).
+    pub fn add_keywords<I, S>(self, keywords: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        keywords.into_iter().fold(self, Self::add_keyword)
+    }
+
+    /// Adds a **subject classified by an authority** (e.g., a BISAC or Thema code), so retailers can
+    /// map the categories automatically.
+    pub fn add_subject(mut self, subject: Subject) -> Self {
+        if let Some(ref mut subjects) = self.0.subjects {
+            subjects.push(subject);
+        } else {
+            self.0.subjects = Some(vec![subject]);
+        }
+        self
+    }
+
     /// Sets the **description** (summary) for the resource.
     pub fn description<S: Into<String>>(mut self, description: S) -> Self {
         self.0.description = Some(description.into());
         self
     }
 
+    /// Sets the **rights** statement (e.g., `"© 2024 Jane Doe. All rights reserved."`).
+    pub fn rights<S: Into<String>>(mut self, rights: S) -> Self {
+        self.0.rights = Some(rights.into());
+        self
+    }
+
+    /// Sets a **Creative Commons license** (see [`CreativeCommons`]): its canonical URL is declared with
+    /// a `<link rel="cc:license">` and, unless a rights statement is set, its notice becomes the rights statement.
+    pub fn creative_commons(mut self, license: CreativeCommons) -> Self {
+        self.0.license = Some(license);
+        self
+    }
+
+    /// Sets whether the **generator** meta is stamped. Pass `false` to suppress it.
+    pub fn generator(mut self, generator: bool) -> Self {
+        self.0.generator = generator;
+        self
+    }
+
     /// Consumes the builder and returns the final [`Metadata`] instance.
     pub fn build(self) -> Metadata {
         self.0
     }
+
+    /// Consumes the builder and returns the final [`Metadata`] instance, checking the mandatory fields first.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the title or the identifier is empty,
+    /// or if an optional field is set to a blank value.
+    pub fn build_validated(self) -> crate::Result<Metadata> {
+        self.0.validate()?;
+        Ok(self.0)
+    }
+}
+
+/// Starts the builder from existing metadata, e.g., to patch a copy of it.
+impl From<Metadata> for MetadataBuilder {
+    fn from(metadata: Metadata) -> Self {
+        Self(metadata)
+    }
+}
+
+/// A **Creative Commons license** (version 4.0, or CC0 1.0), set with [`MetadataBuilder::creative_commons`].
+///
+/// ```rust
+/// use liber::epub::{CreativeCommons, MetadataBuilder};
+///
+/// let metadata = MetadataBuilder::title("Title")
+///     .creative_commons(CreativeCommons::BySa4)
+///     .build();
+/// assert_eq!(CreativeCommons::BySa4.to_string(), "CC BY-SA 4.0");
+/// assert_eq!(CreativeCommons::BySa4.url(), "https://creativecommons.org/licenses/by-sa/4.0/");
+/// ```
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum CreativeCommons {
+    /// Attribution 4.0 International.
+    By4,
+    /// Attribution-ShareAlike 4.0 International.
+    BySa4,
+    /// Attribution-NoDerivatives 4.0 International.
+    ByNd4,
+    /// Attribution-NonCommercial 4.0 International.
+    ByNc4,
+    /// Attribution-NonCommercial-ShareAlike 4.0 International.
+    ByNcSa4,
+    /// Attribution-NonCommercial-NoDerivatives 4.0 International.
+    ByNcNd4,
+    /// CC0 1.0 Universal public domain dedication.
+    Zero1,
+}
+
+impl CreativeCommons {
+    /// Gets the **code** of the license in its canonical URL (e.g., `by-sa`).
+    fn code(self) -> &'static str {
+        match self {
+            Self::By4 => "by",
+            Self::BySa4 => "by-sa",
+            Self::ByNd4 => "by-nd",
+            Self::ByNc4 => "by-nc",
+            Self::ByNcSa4 => "by-nc-sa",
+            Self::ByNcNd4 => "by-nc-nd",
+            Self::Zero1 => "zero",
+        }
+    }
+
+    /// Gets the full **title** of the license (e.g., `Creative Commons Attribution-ShareAlike 4.0 International`).
+    pub fn title(self) -> &'static str {
+        match self {
+            Self::By4 => "Creative Commons Attribution 4.0 International",
+            Self::BySa4 => "Creative Commons Attribution-ShareAlike 4.0 International",
+            Self::ByNd4 => "Creative Commons Attribution-NoDerivatives 4.0 International",
+            Self::ByNc4 => "Creative Commons Attribution-NonCommercial 4.0 International",
+            Self::ByNcSa4 => {
+                "Creative Commons Attribution-NonCommercial-ShareAlike 4.0 International"
+            }
+            Self::ByNcNd4 => {
+                "Creative Commons Attribution-NonCommercial-NoDerivatives 4.0 International"
+            }
+            Self::Zero1 => "CC0 1.0 Universal",
+        }
+    }
+
+    /// Gets the canonical **URL** of the license deed.
+    pub fn url(self) -> String {
+        match self {
+            Self::Zero1 => "https://creativecommons.org/publicdomain/zero/1.0/".to_string(),
+            _ => format!("https://creativecommons.org/licenses/{}/4.0/", self.code()),
+        }
+    }
+
+    /// Gets the standard **notice** of the license, as recommended by Creative Commons.
+    pub fn notice(self) -> String {
+        match self {
+            Self::Zero1 => format!(
+                "To the extent possible under law, the author has waived all copyright and related or neighboring rights to this work under {}. To view a copy of this dedication, visit {}",
+                self.title(),
+                self.url()
+            ),
+            _ => format!(
+                "This work is licensed under {}. To view a copy of this license, visit {}",
+                self.title(),
+                self.url()
+            ),
+        }
+    }
+
+    /// Gets the `(name, description)` of the **freedoms** granted by the license, as worded in its deed.
+    pub fn freedoms(self) -> Vec<(&'static str, &'static str)> {
+        let mut freedoms = vec![(
+            "Share",
+            "copy and redistribute the material in any medium or format.",
+        )];
+        if !matches!(self, Self::ByNd4 | Self::ByNcNd4) {
+            freedoms.push(("Adapt", "remix, transform, and build upon the material."));
+        }
+        freedoms
+    }
+
+    /// Gets the `(name, description)` of the **terms** of the license, as worded in its deed.
+    ///
+    /// CC0 has no terms.
+    pub fn terms(self) -> Vec<(&'static str, &'static str)> {
+        if self == Self::Zero1 {
+            return Vec::new();
+        }
+
+        let mut terms = vec![(
+            "Attribution",
+            "You must give appropriate credit, provide a link to the license, and indicate if changes were made.",
+        )];
+        if matches!(self, Self::ByNc4 | Self::ByNcSa4 | Self::ByNcNd4) {
+            terms.push((
+                "NonCommercial",
+                "You may not use the material for commercial purposes.",
+            ));
+        }
+        if matches!(self, Self::BySa4 | Self::ByNcSa4) {
+            terms.push((
+                "ShareAlike",
+                "If you remix, transform, or build upon the material, you must distribute your contributions under the same license as the original.",
+            ));
+        }
+        if matches!(self, Self::ByNd4 | Self::ByNcNd4) {
+            terms.push((
+                "NoDerivatives",
+                "If you remix, transform, or build upon the material, you may not distribute the modified material.",
+            ));
+        }
+        terms
+    }
+}
+
+/// Displays the short name of the license (e.g., `CC BY-SA 4.0` or `CC0 1.0`).
+impl Display for CreativeCommons {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Self::Zero1 => write!(f, "CC0 1.0"),
+            _ => write!(f, "CC {} 4.0", self.code().to_uppercase()),
+        }
+    }
+}
+
+/// The **authority** (classification scheme) of a [`Subject`] code.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SubjectAuthority {
+    /// BISAC subject headings (e.g., `FIC009020`), used mainly in North America.
+    Bisac,
+    /// Thema subject categories (e.g., `FBA`), the international standard.
+    Thema,
+    /// BIC subject categories (e.g., `FA`), used mainly in the UK.
+    Bic,
+    /// Any other authority, by name.
+    Other(String),
+}
+
+impl AsRef<str> for SubjectAuthority {
+    fn as_ref(&self) -> &str {
+        match self {
+            Self::Bisac => "BISAC",
+            Self::Thema => "THEMA",
+            Self::Bic => "BIC",
+            Self::Other(authority) => authority,
+        }
+    }
+}
+
+/// A **subject classified by an authority**, carrying its code and an optional human-readable label.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Subject {
+    /// The classification scheme of the code.
+    pub authority: SubjectAuthority,
+    /// The code of the subject (e.g., `FIC009020`).
+    pub code: String,
+    /// Optional label of the subject (e.g., `Fiction / Fantasy / Epic`). Defaults to the code.
+    pub label: Option<String>,
+}
+
+impl Subject {
+    /// Creates a subject with the given authority and code.
+    pub fn new<S: Into<String>>(authority: SubjectAuthority, code: S) -> Self {
+        Self {
+            authority,
+            code: code.into(),
+            label: None,
+        }
+    }
+
+    /// Creates a **BISAC** subject.
+    pub fn bisac<S: Into<String>>(code: S) -> Self {
+        Self::new(SubjectAuthority::Bisac, code)
+    }
+
+    /// Creates a **Thema** subject.
+    pub fn thema<S: Into<String>>(code: S) -> Self {
+        Self::new(SubjectAuthority::Thema, code)
+    }
+
+    /// Sets the human-readable **label** of the subject.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn label<S: Into<String>>(mut self, label: S) -> Self {
+        self.label = Some(label.into());
+        self
+    }
+
+    /// Generates the XML `<dc:subject>` tag with the label, followed by a `<meta>` tag naming the authority
+    /// with the code, since the `dc:subject` of EPUB 2 has no attributes for them.
+    fn as_metadata_xml(&self) -> String {
+        format!(
+            r#"<dc:subject>{}</dc:subject><meta name="{}" content="{}"/>"#,
+            escape(self.label.as_deref().unwrap_or(&self.code)),
+            escape(self.authority.as_ref()),
+            escape(&self.code)
+        )
+    }
+}
+
+/// The **publication frequency** of a periodical.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum PublicationFrequency {
+    Daily,
+    Weekly,
+    Biweekly,
+    Monthly,
+    Bimonthly,
+    Quarterly,
+    Yearly,
+    /// Any other frequency, by name.
+    Other(String),
+}
+
+impl AsRef<str> for PublicationFrequency {
+    fn as_ref(&self) -> &str {
+        match self {
+            Self::Daily => "daily",
+            Self::Weekly => "weekly",
+            Self::Biweekly => "biweekly",
+            Self::Monthly => "monthly",
+            Self::Bimonthly => "bimonthly",
+            Self::Quarterly => "quarterly",
+            Self::Yearly => "yearly",
+            Self::Other(frequency) => frequency,
+        }
+    }
+}
+
+/// The **periodical** data of a magazine or journal issue.
+///
+/// Every field is optional and emitted as a `<meta>` tag (`issue`, `volume` and `frequency`).
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct Periodical {
+    /// The issue number (e.g., `"42"` or `"Spring 2024"`).
+    pub issue: Option<String>,
+    /// The volume number.
+    pub volume: Option<String>,
+    /// How often the periodical is published.
+    pub frequency: Option<PublicationFrequency>,
+}
+
+impl Periodical {
+    /// Creates empty periodical data.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Sets the **issue** number.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn issue<S: Into<String>>(mut self, issue: S) -> Self {
+        self.issue = Some(issue.into());
+        self
+    }
+
+    /// Sets the **volume** number.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn volume<S: Into<String>>(mut self, volume: S) -> Self {
+        self.volume = Some(volume.into());
+        self
+    }
+
+    /// Sets the publication **frequency**.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn frequency(mut self, frequency: PublicationFrequency) -> Self {
+        self.frequency = Some(frequency);
+        self
+    }
+
+    /// Generates the XML `<meta>` tags of the set fields.
+    fn as_metadata_xml(&self) -> String {
+        [
+            ("issue", self.issue.as_deref()),
+            ("volume", self.volume.as_deref()),
+            ("frequency", self.frequency.as_ref().map(AsRef::as_ref)),
+        ]
+        .into_iter()
+        .filter_map(|(name, value)| {
+            Some(format!(
+                r#"<meta name="{name}" content="{}"/>"#,
+                escape(value?)
+            ))
+        })
+        .collect()
+    }
+}
+
+/// The **precision** of the publication date.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum DatePrecision {
+    /// Only the year, like `2006`.
+    Year,
+    /// The year and month, like `2006-01`.
+    Month,
+    /// The full date, like `2006-01-02`.
+    #[default]
+    Day,
+}
+
+impl DatePrecision {
+    /// Gets the `chrono` format of a date with this precision.
+    fn format(self) -> &'static str {
+        match self {
+            Self::Year => "%Y",
+            Self::Month => "%Y-%m",
+            Self::Day => "%Y-%m-%d",
+        }
+    }
+
+    /// Parses a partial date (`2006`, `2006-01` or `2006-01-02`), validating it against the precision of its format.
+    fn parse(date: &str) -> crate::Result<(DateTime<Utc>, DatePrecision)> {
+        let invalid = || {
+            crate::Error::Validation(format!(
+                "'{date}' is not a valid date (expected YYYY, YYYY-MM or YYYY-MM-DD)"
+            ))
+        };
+
+        let parts = date.trim().split('-').collect::<Vec<_>>();
+        let precision = match parts.as_slice() {
+            [year] if year.len() == 4 => Self::Year,
+            [year, month] if year.len() == 4 && month.len() == 2 => Self::Month,
+            [year, month, day] if year.len() == 4 && month.len() == 2 && day.len() == 2 => {
+                Self::Day
+            }
+            _ => return Err(invalid()),
+        };
+
+        let numbers = parts
+            .iter()
+            .map(|part| part.parse::<u32>().map_err(|_| invalid()))
+            .collect::<crate::Result<Vec<_>>>()?;
+
+        let year = i32::try_from(numbers[0]).map_err(|_| invalid())?;
+        let month = numbers.get(1).copied().unwrap_or(1);
+        let day = numbers.get(2).copied().unwrap_or(1);
+
+        NaiveDate::from_ymd_opt(year, month, day)
+            .and_then(|date| date.and_hms_opt(0, 0, 0))
+            .map(|date| (date.and_utc(), precision))
+            .ok_or_else(invalid)
+    }
 }
 
 /// Represents the primary language of the resource content, using its corresponding **ISO 639-1** code.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
 pub enum Language {
     Arabic,
     Bulgarian,
@@ -242,6 +1024,122 @@ impl Language {
     pub fn as_metadata_xml(&self) -> String {
         format!("<dc:language>{}</dc:language>", self.as_ref())
     }
+
+    /// **Detects** the language of a plain text, using a simple heuristic: the writing system
+    /// for non-Latin scripts and the most frequent function words for the main Latin-script languages.
+    ///
+    /// Returns `None` if the language cannot be told with reasonable confidence.
+    pub fn detect(text: &str) -> Option<Language> {
+        let mut scripts = [0usize; 9];
+        for c in text.chars() {
+            let script = match c {
+                '\u{3040}'..='\u{30FF}' => 0,
+                '\u{AC00}'..='\u{D7AF}' | '\u{1100}'..='\u{11FF}' => 1,
+                '\u{4E00}'..='\u{9FFF}' => 2,
+                '\u{0600}'..='\u{06FF}' => 3,
+                '\u{0590}'..='\u{05FF}' => 4,
+                '\u{0370}'..='\u{03FF}' => 5,
+                '\u{0E00}'..='\u{0E7F}' => 6,
+                '\u{0400}'..='\u{04FF}' => 7,
+                c if c.is_alphabetic() => 8,
+                _ => continue,
+            };
+            scripts[script] += 1;
+        }
+
+        let total: usize = scripts.iter().sum();
+        if total == 0 {
+            return None;
+        }
+
+        // Any kana means Japanese, even if most of the text is written with Han characters
+        if scripts[0] > 0 && scripts[0] + scripts[2] > total / 2 {
+            return Some(Language::Japanese);
+        }
+
+        let (script, count) = scripts
+            .iter()
+            .enumerate()
+            .max_by_key(|(_, count)| **count)
+            .map(|(script, count)| (script, *count))?;
+        if count * 2 < total {
+            return None;
+        }
+
+        match script {
+            1 => Some(Language::Korean),
+            2 => Some(Language::Chinese),
+            3 => Some(Language::Arabic),
+            4 => Some(Language::Hebrew),
+            5 => Some(Language::Greek),
+            6 => Some(Language::Thai),
+            7 if text.chars().any(|c| matches!(c, 'є' | 'і' | 'ї' | 'ґ')) => {
+                Some(Language::Ukrainian)
+            }
+            7 => Some(Language::Russian),
+            _ => Self::detect_latin(text),
+        }
+    }
+
+    /// Detects a Latin-script language by counting its most frequent function words.
+    ///
+    /// Returns `None` unless a language has at least two hits and more than any other (e.g., on a tie
+    /// between Spanish and Portuguese, which share `que`).
+    fn detect_latin(text: &str) -> Option<Language> {
+        const FUNCTION_WORDS: [(Language, &[&str]); 7] = [
+            (
+                Language::English,
+                &["the", "and", "of", "to", "is", "that", "with"],
+            ),
+            (
+                Language::Spanish,
+                &["el", "los", "las", "del", "que", "y", "por"],
+            ),
+            (
+                Language::French,
+                &["le", "les", "des", "et", "est", "une", "du"],
+            ),
+            (
+                Language::German,
+                &["der", "die", "und", "das", "ist", "nicht", "ein"],
+            ),
+            (
+                Language::Italian,
+                &["il", "che", "di", "gli", "della", "non", "per"],
+            ),
+            (
+                Language::Portuguese,
+                &["os", "do", "da", "não", "uma", "em", "que"],
+            ),
+            (
+                Language::Dutch,
+                &["het", "een", "van", "en", "niet", "dat", "ik"],
+            ),
+        ];
+
+        let words = text
+            .split(|c: char| !c.is_alphabetic())
+            .filter(|word| !word.is_empty())
+            .map(str::to_lowercase)
+            .collect::<Vec<_>>();
+
+        let mut hits = FUNCTION_WORDS
+            .into_iter()
+            .map(|(language, function_words)| {
+                let hits = words
+                    .iter()
+                    .filter(|word| function_words.contains(&word.as_str()))
+                    .count();
+                (language, hits)
+            })
+            .collect::<Vec<_>>();
+        hits.sort_by_key(|(_, hits)| std::cmp::Reverse(*hits));
+
+        let mut hits = hits.into_iter();
+        let (language, first) = hits.next()?;
+        let runner_up = hits.next().map_or(0, |(_, hits)| hits);
+        (first >= 2 && first > runner_up).then_some(language)
+    }
 }
 
 /// Helper implementation to get the two-letter ISO 639-1 code for the language.
@@ -305,12 +1203,14 @@ pub enum Identifier {
     UUID(String),
     /// An **ISBN** (International Standard Book Number).
     ISBN(String),
+    /// An **ISSN** (International Standard Serial Number), for magazines and journals.
+    ISSN(String),
 }
 
 impl Identifier {
     /// Generates the XML representation for the **identifier** element.
     ///
-    /// The scheme (`UUID` or `ISBN`) and the URN value are included.
+    /// The scheme (`UUID`, `ISBN` or `ISSN`) and the URN value are included.
     pub(crate) fn as_metadata_xml(&self) -> String {
         format!(
             r#"<dc:identifier id="BookId" opf:scheme="{}">{}</dc:identifier>"#,
@@ -319,6 +1219,13 @@ impl Identifier {
         )
     }
 
+    /// Gets the raw value of the identifier (e.g., the UUID or the ISBN itself).
+    pub fn value(&self) -> &str {
+        match self {
+            Self::UUID(value) | Self::ISBN(value) | Self::ISSN(value) => value,
+        }
+    }
+
     /// Generates the XML representation for the **TOC (Table of Contents)** metadata, typically used for DTB UID.
     pub(crate) fn as_toc_xml(&self) -> String {
         format!(
@@ -328,12 +1235,13 @@ impl Identifier {
     }
 }
 
-/// Converts the identifier into its URN (Uniform Resource Name) format, e.g., `urn:uuid:...` or `urn:isbn:...`.
+/// Converts the identifier into its URN (Uniform Resource Name) format, e.g., `urn:uuid:...`, `urn:isbn:...` or `urn:issn:...`.
 impl From<&Identifier> for String {
     fn from(value: &Identifier) -> Self {
         match value {
             Identifier::UUID(value) => format!("urn:uuid:{}", value),
             Identifier::ISBN(value) => format!("urn:isbn:{}", value),
+            Identifier::ISSN(value) => format!("urn:issn:{}", value),
         }
     }
 }
@@ -345,16 +1253,27 @@ impl Default for Identifier {
     }
 }
 
-/// Displays the identifier scheme (`UUID` or `ISBN`).
+/// Displays the identifier scheme (`UUID`, `ISBN` or `ISSN`).
 impl Display for Identifier {
     fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
         match self {
             Self::UUID(_) => write!(f, "UUID"),
             Self::ISBN(_) => write!(f, "ISBN"),
+            Self::ISSN(_) => write!(f, "ISSN"),
         }
     }
 }
 
+/// Checks whether `name` is a valid **XML name** (e.g., `duokan-book-id` or `vivlio:layout`): a letter,
+/// `_` or `:` followed by letters, digits, `-`, `.`, `_` or `:`.
+fn is_xml_name(name: &str) -> bool {
+    let mut chars = name.chars();
+    chars
+        .next()
+        .is_some_and(|first| first.is_alphabetic() || matches!(first, '_' | ':'))
+        && chars.all(|c| c.is_alphanumeric() || matches!(c, '-' | '.' | '_' | ':'))
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -414,6 +1333,361 @@ mod tests {
         assert_eq!(metadata.description, Some(description.to_string()));
     }
 
+    #[test]
+    fn test_metadata_generator() {
+        let metadata = MetadataBuilder::title("Title").build();
+        assert_eq!(
+            metadata.generator_as_metadata_xml().unwrap(),
+            format!(
+                r#"<meta name="generator" content="liber {}"/>"#,
+                env!("CARGO_PKG_VERSION")
+            )
+        );
+
+        let metadata = MetadataBuilder::title("Title").generator(false).build();
+        assert!(metadata.generator_as_metadata_xml().is_none());
+    }
+
+    #[test]
+    fn test_metadata_build_validated() {
+        assert!(MetadataBuilder::title("Title").build_validated().is_ok());
+
+        let result = MetadataBuilder::title("  ").build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = MetadataBuilder::title("Title")
+            .identifier(Identifier::ISBN("".to_string()))
+            .build_validated();
+        assert!(matches!(result, Err(crate::Error::Validation(_))));
+
+        let result = MetadataBuilder::title("Title")
+            .creator("")
+            .build_validated();
+        match result {
+            Err(crate::Error::Validation(message)) => assert!(message.contains("creator")),
+            _ => panic!("Expected a validation error for the empty creator"),
+        }
+    }
+
+    #[test]
+    fn test_metadata_edition() {
+        assert_eq!(
+            MetadataBuilder::title("Title")
+                .build()
+                .edition_as_metadata_xml(),
+            None
+        );
+
+        let metadata = MetadataBuilder::title("Title")
+            .edition("2nd edition, revised")
+            .revision("14")
+            .build();
+
+        assert_eq!(
+            metadata.edition_as_metadata_xml().unwrap(),
+            r#"<meta name="edition" content="2nd edition, revised"/><meta name="revision" content="14"/>"#
+        );
+    }
+
+    #[test]
+    fn test_metadata_print_edition() {
+        let metadata = MetadataBuilder::title("Title").build();
+        assert_eq!(metadata.print_edition_as_metadata_xml(), None);
+
+        let metadata = MetadataBuilder::title("Title")
+            .identifier(Identifier::ISBN("9780000000002".to_string()))
+            .print_isbn("978-0-00-000000-1")
+            .print_pages(320)
+            .build();
+        assert!(metadata.validate().is_ok());
+        assert_eq!(
+            metadata.print_edition_as_metadata_xml().unwrap(),
+            r#"<dc:source>urn:isbn:978-0-00-000000-1</dc:source><meta name="schema:numberOfPages" content="320"/>"#
+        );
+
+        for print_isbn in ["978000", "97800000000AB", "978-0-00-000000-2"] {
+            let metadata = MetadataBuilder::from(metadata.clone())
+                .print_isbn(print_isbn)
+                .build();
+            assert!(metadata.validate().is_err());
+        }
+        let metadata = MetadataBuilder::from(metadata)
+            .print_isbn("0-306-40615-X")
+            .build();
+        assert!(metadata.validate().is_ok());
+    }
+
+    #[test]
+    fn test_metadata_imprint() {
+        let metadata = MetadataBuilder::title("Title")
+            .publisher("Penguin Random House")
+            .imprint("Vintage")
+            .build();
+
+        assert_eq!(
+            metadata.publisher_as_metadata_xml().unwrap(),
+            "<dc:publisher>Penguin Random House</dc:publisher>"
+        );
+        assert_eq!(
+            metadata.imprint_as_metadata_xml().unwrap(),
+            r#"<meta name="imprint" content="Vintage"/>"#
+        );
+        assert!(
+            MetadataBuilder::title("Title")
+                .imprint(" ")
+                .build_validated()
+                .is_err()
+        );
+    }
+
+    #[test]
+    fn test_metadata_custom_metas() {
+        let metadata = MetadataBuilder::title("Title")
+            .add_meta("duokan-book-id", "123")
+            .add_meta("vivlio:layout", "reflowable")
+            .build();
+
+        assert_eq!(
+            metadata.metas_as_metadata_xml().unwrap(),
+            r#"<meta name="duokan-book-id" content="123"/><meta name="vivlio:layout" content="reflowable"/>"#
+        );
+        assert!(
+            MetadataBuilder::title("Title")
+                .add_meta("", "value")
+                .build_validated()
+                .is_err()
+        );
+
+        let metadata = MetadataBuilder::title("Title")
+            .add_meta("vendor", r#"a & "b" <c>"#)
+            .build();
+        assert_eq!(
+            metadata.metas_as_metadata_xml().unwrap(),
+            r#"<meta name="vendor" content="a &amp; &quot;b&quot; &lt;c&gt;"/>"#
+        );
+        assert!(metadata.validate().is_ok());
+
+        for name in ["1st", "a b", "a\"", "-flag", "a&b"] {
+            assert!(
+                MetadataBuilder::title("Title")
+                    .add_meta(name, "value")
+                    .build_validated()
+                    .is_err(),
+                "{name}"
+            );
+        }
+        assert!(is_xml_name("_vendor.flag-2"));
+    }
+
+    #[test]
+    fn test_metadata_rights() {
+        let metadata = MetadataBuilder::title("Title")
+            .creative_commons(CreativeCommons::Zero1)
+            .build();
+        assert_eq!(
+            metadata.rights_as_metadata_xml().unwrap(),
+            "<dc:rights>To the extent possible under law, the author has waived all copyright and related or neighboring rights to this work under CC0 1.0 Universal. To view a copy of this dedication, visit https://creativecommons.org/publicdomain/zero/1.0/</dc:rights>"
+        );
+        assert_eq!(
+            metadata.license_as_metadata_xml().unwrap(),
+            r#"<link rel="cc:license" href="https://creativecommons.org/publicdomain/zero/1.0/"/>"#
+        );
+
+        let metadata = MetadataBuilder::title("Title")
+            .rights("© 2024 Jane & John")
+            .build();
+        assert_eq!(
+            metadata.rights_as_metadata_xml().unwrap(),
+            "<dc:rights>© 2024 Jane &amp; John</dc:rights>"
+        );
+        assert!(metadata.license_as_metadata_xml().is_none());
+        assert_eq!(CreativeCommons::ByNcSa4.to_string(), "CC BY-NC-SA 4.0");
+    }
+
+    #[test]
+    fn test_metadata_keywords() {
+        let metadata = MetadataBuilder::title("Title")
+            .subject("Fiction")
+            .add_keyword("dragons")
+            .add_keywords(["quests", "magic"])
+            .build();
+
+        assert_eq!(
+            metadata.keywords_as_metadata_xml().unwrap(),
+            r#"<meta name="keywords" content="dragons, quests, magic"/>"#
+        );
+        assert_eq!(
+            metadata.subject_as_metadata_xml().unwrap(),
+            "<dc:subject>Fiction</dc:subject>"
+        );
+        assert!(
+            MetadataBuilder::title("Title")
+                .add_keyword("a, b")
+                .build_validated()
+                .is_err()
+        );
+    }
+
+    #[test]
+    fn test_metadata_subjects() {
+        let metadata = MetadataBuilder::title("Title")
+            .add_subject(Subject::bisac("FIC002000").label("Fiction / Action & Adventure"))
+            .add_subject(Subject::thema("FBA"))
+            .build();
+
+        assert_eq!(
+            metadata.subjects_as_metadata_xml().unwrap(),
+            r#"<dc:subject>Fiction / Action &amp; Adventure</dc:subject><meta name="BISAC" content="FIC002000"/><dc:subject>FBA</dc:subject><meta name="THEMA" content="FBA"/>"#
+        );
+        assert!(
+            MetadataBuilder::title("Title")
+                .build()
+                .subjects_as_metadata_xml()
+                .is_none()
+        );
+        assert!(
+            MetadataBuilder::title("Title")
+                .add_subject(Subject::bisac(" "))
+                .build_validated()
+                .is_err()
+        );
+    }
+
+    #[test]
+    fn test_metadata_date_precision() {
+        let metadata = MetadataBuilder::title("Title")
+            .partial_date("1999")
+            .unwrap()
+            .build();
+        assert_eq!(metadata.date_precision, DatePrecision::Year);
+        assert_eq!(
+            metadata.date_as_metadata_xml().unwrap(),
+            r#"<dc:date opf:event="publication">1999</dc:date>"#
+        );
+
+        let metadata = MetadataBuilder::title("Title")
+            .partial_date("2006-02")
+            .unwrap()
+            .build();
+        assert_eq!(
+            metadata.date_as_metadata_xml().unwrap(),
+            r#"<dc:date opf:event="publication">2006-02</dc:date>"#
+        );
+
+        let metadata = MetadataBuilder::title("Title")
+            .partial_date("2006-01-02")
+            .unwrap()
+            .date_precision(DatePrecision::Month)
+            .build();
+        assert_eq!(
+            metadata.date_as_metadata_xml().unwrap(),
+            r#"<dc:date opf:event="publication">2006-01</dc:date>"#
+        );
+
+        for invalid in ["99", "2006-13", "2006-02-30", "2006-1-02", "year"] {
+            assert!(
+                MetadataBuilder::title("Title")
+                    .partial_date(invalid)
+                    .is_err(),
+                "{invalid}"
+            );
+        }
+    }
+
+    #[test]
+    fn test_language_detect() {
+        assert_eq!(
+            Language::detect("The cat and the dog went to the park."),
+            Some(Language::English)
+        );
+        assert_eq!(
+            Language::detect("El gato y el perro van al parque de los niños."),
+            Some(Language::Spanish)
+        );
+        assert_eq!(
+            Language::detect("Der Hund und die Katze sind nicht da."),
+            Some(Language::German)
+        );
+        assert_eq!(
+            Language::detect("猫はかわいいです。"),
+            Some(Language::Japanese)
+        );
+        assert_eq!(Language::detect("我爱读书。"), Some(Language::Chinese));
+        assert_eq!(Language::detect("Кошка спит."), Some(Language::Russian));
+        assert_eq!(Language::detect("Καλημέρα κόσμε"), Some(Language::Greek));
+        assert_eq!(Language::detect("1234 !!"), None);
+        assert_eq!(Language::detect("Xyzzy"), None);
+        assert_eq!(Language::detect("que que"), None);
+        assert_eq!(Language::detect("que que los"), Some(Language::Spanish));
+    }
+
+    #[test]
+    fn test_metadata_periodical() {
+        let metadata = MetadataBuilder::title("Monthly Review")
+            .identifier(Identifier::ISSN("0027-0520".to_string()))
+            .periodical(
+                Periodical::new()
+                    .volume("75")
+                    .issue("3")
+                    .frequency(PublicationFrequency::Monthly),
+            )
+            .build();
+
+        assert_eq!(
+            metadata.identifier.as_metadata_xml(),
+            r#"<dc:identifier id="BookId" opf:scheme="ISSN">urn:issn:0027-0520</dc:identifier>"#
+        );
+        assert_eq!(
+            metadata.periodical_as_metadata_xml().unwrap(),
+            r#"<meta name="issue" content="3"/><meta name="volume" content="75"/><meta name="frequency" content="monthly"/>"#
+        );
+        assert_eq!(
+            MetadataBuilder::title("Title")
+                .periodical(Periodical::new())
+                .build()
+                .periodical_as_metadata_xml(),
+            None
+        );
+    }
+
+    #[test]
+    fn test_metadata_markup_characters() {
+        let metadata = MetadataBuilder::title("Title")
+            .imprint("Smith & Sons")
+            .add_keyword(r#"<b>"bold"</b>"#)
+            .add_subject(Subject::bisac("FIC002000").label("Action & Adventure"))
+            .edition(r#"2nd "revised""#)
+            .revision("<14>")
+            .periodical(
+                Periodical::new()
+                    .issue("Fall & Winter")
+                    .volume(r#"7 "B""#)
+                    .frequency(PublicationFrequency::Other("<irregular>".to_string())),
+            )
+            .build();
+
+        assert_eq!(
+            metadata.imprint_as_metadata_xml().unwrap(),
+            r#"<meta name="imprint" content="Smith &amp; Sons"/>"#
+        );
+        assert_eq!(
+            metadata.keywords_as_metadata_xml().unwrap(),
+            r#"<meta name="keywords" content="&lt;b&gt;&quot;bold&quot;&lt;/b&gt;"/>"#
+        );
+        assert_eq!(
+            metadata.subjects_as_metadata_xml().unwrap(),
+            r#"<dc:subject>Action &amp; Adventure</dc:subject><meta name="BISAC" content="FIC002000"/>"#
+        );
+        assert_eq!(
+            metadata.edition_as_metadata_xml().unwrap(),
+            r#"<meta name="edition" content="2nd &quot;revised&quot;"/><meta name="revision" content="&lt;14&gt;"/>"#
+        );
+        assert_eq!(
+            metadata.periodical_as_metadata_xml().unwrap(),
+            r#"<meta name="issue" content="Fall &amp; Winter"/><meta name="volume" content="7 &quot;B&quot;"/><meta name="frequency" content="&lt;irregular&gt;"/>"#
+        );
+    }
+
     #[test]
     fn test_identifier_default_uuid() {
         let default_identifier = Identifier::default();
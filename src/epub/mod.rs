@@ -1,11 +1,70 @@
+mod also_by;
+mod audiobook;
+mod call_to_action;
 mod content;
 mod content_reference;
+mod credit;
+mod device_class;
+mod digest;
 mod epub_builder;
+mod file_hook;
+mod file_system;
+mod font_license;
+mod fragment;
+mod http_cache;
+mod id_generator;
+mod image_description;
+mod lint;
 mod metadata;
+mod outline;
+mod part;
+mod periodical;
+mod play_order;
+mod profile;
+mod raw_file;
 mod resource;
+mod rootfile;
+mod script;
+mod serve;
+mod size_budget;
+mod slug;
+mod transcript;
+mod transformer;
+mod variant;
+mod video;
+mod watermark;
 
+pub use also_by::*;
+pub use audiobook::*;
+pub use call_to_action::*;
 pub use content::*;
 pub use content_reference::*;
+pub use credit::*;
+pub use device_class::*;
+pub use digest::*;
 pub use epub_builder::*;
+pub use file_system::*;
+pub use font_license::*;
+pub use fragment::*;
+pub use http_cache::*;
+pub use id_generator::*;
+pub use image_description::*;
+pub use lint::*;
 pub use metadata::*;
+pub use outline::*;
+pub use part::*;
+pub use periodical::*;
+pub use profile::*;
+pub use raw_file::*;
 pub use resource::*;
+pub use rootfile::*;
+pub use script::*;
+pub use serve::*;
+pub use size_budget::*;
+pub use slug::*;
+pub use transcript::*;
+pub use variant::*;
+pub use video::*;
+pub use watermark::*;
+
+pub(crate) use play_order::PlayOrders;
@@ -0,0 +1,250 @@
+use std::fmt::Display;
+
+use crate::{
+    epub::{Content, ContentReference, EpubBuilder, epub_builder::Epub},
+    output::file_content,
+};
+
+/// The headings found in the body of a content file.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OutlineFile {
+    /// The filename of the content (e.g., `c01.xhtml`).
+    pub filename: String,
+    /// The `(level, text)` of every heading, in document order.
+    pub headings: Vec<(u8, String)>,
+}
+
+/// An entry of the navigation tree (the TOC), with its nested entries.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OutlineEntry {
+    /// The title shown in the TOC.
+    pub title: String,
+    /// The target of the entry (e.g., `c01.xhtml` or `c01.xhtml#id01`).
+    pub src: String,
+    /// The nested entries.
+    pub children: Vec<OutlineEntry>,
+}
+
+/// The **effective outline** of a book, as generated: the headings of every file, the navigation tree and
+/// the spine order, so authors can review its structure without opening the file in a reader.
+///
+/// It is printable (through `Display`) as an indented report.
+///
+/// ```rust
+/// use liber::epub::{ContentBuilder, EpubBuilder, MetadataBuilder, ReferenceType};
+///
+/// let outline = EpubBuilder::new(MetadataBuilder::title("Title").build())
+///     .add_content(
+///         ContentBuilder::new(b"<body><h1>One</h1><h2>Scene</h2></body>", ReferenceType::Text("One".to_string())).build(),
+///     )
+///     .outline()
+///     .unwrap();
+/// assert_eq!(outline.spine, vec!["c01.xhtml"]);
+/// assert!(outline.to_string().contains("h2 Scene"));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Outline {
+    /// The headings of every content file, in the order of the tree.
+    pub files: Vec<OutlineFile>,
+    /// The navigation tree (the TOC).
+    pub navigation: Vec<OutlineEntry>,
+    /// The filenames of the spine, in reading order.
+    pub spine: Vec<String>,
+}
+
+impl Outline {
+    /// Builds the outline of a prepared book.
+    pub(crate) fn new(epub: &Epub<'_>) -> crate::Result<Self> {
+        let contents = epub.contents.as_deref().unwrap_or_default();
+
+        let mut files = Vec::new();
+        collect_files(&mut 0, contents, &mut files);
+
+        let navigation = navigation(&mut 0, contents, epub.flat_toc);
+
+        let mut itemrefs = Vec::new();
+        file_content::collect_spine_items(
+            &mut 0,
+            0,
+            Some(contents),
+            &mut itemrefs,
+            |filename, _| filename,
+        )?;
+        itemrefs.sort_by_key(|(spine_order, _)| *spine_order);
+        let spine = itemrefs.into_iter().map(|(_, filename)| filename).collect();
+
+        Ok(Self {
+            files,
+            navigation,
+            spine,
+        })
+    }
+}
+
+impl Display for Outline {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        writeln!(f, "Files:")?;
+        for file in &self.files {
+            writeln!(f, "  {}", file.filename)?;
+            for (level, text) in &file.headings {
+                writeln!(f, "  {}h{level} {text}", "  ".repeat(usize::from(*level)))?;
+            }
+        }
+
+        writeln!(f, "Navigation:")?;
+        write_entries(f, &self.navigation, 1)?;
+
+        writeln!(f, "Spine:")?;
+        for (index, filename) in self.spine.iter().enumerate() {
+            writeln!(f, "  {}. {filename}", index + 1)?;
+        }
+        Ok(())
+    }
+}
+
+/// Writes the navigation entries, indented by their `depth`.
+fn write_entries(
+    f: &mut std::fmt::Formatter<'_>,
+    entries: &[OutlineEntry],
+    depth: usize,
+) -> std::fmt::Result {
+    for entry in entries {
+        writeln!(f, "{}{} ({})", "  ".repeat(depth), entry.title, entry.src)?;
+        write_entries(f, &entry.children, depth + 1)?;
+    }
+    Ok(())
+}
+
+/// Recursively collects the headings of every written content file.
+fn collect_files(file_number: &mut usize, contents: &[Content<'_>], files: &mut Vec<OutlineFile>) {
+    for content in contents {
+        *file_number += 1;
+        if !content.link {
+            files.push(OutlineFile {
+                filename: content.filename(*file_number).into_owned(),
+                headings: content.headings(),
+            });
+        }
+        collect_files(
+            file_number,
+            content.subcontents.as_deref().unwrap_or_default(),
+            files,
+        );
+    }
+}
+
+/// Recursively builds the navigation entries of the contents, as the `navMap` of the `toc.ncx` does.
+///
+/// A `flat` navigation lists the subcontents right after their parent instead of as its children.
+fn navigation(file_number: &mut usize, contents: &[Content<'_>], flat: bool) -> Vec<OutlineEntry> {
+    let mut entries = Vec::new();
+    for content in contents {
+        *file_number += 1;
+        let filename = content.filename(*file_number).into_owned();
+        let mut children = reference_entries(
+            &filename,
+            content.content_references.as_deref().unwrap_or_default(),
+            &mut 0,
+        );
+        let subs = navigation(
+            file_number,
+            content.subcontents.as_deref().unwrap_or_default(),
+            flat,
+        );
+        let siblings = if flat {
+            subs
+        } else {
+            children.extend(subs);
+            Vec::new()
+        };
+
+        entries.push(OutlineEntry {
+            title: content.title().into_owned(),
+            src: filename,
+            children,
+        });
+        entries.extend(siblings);
+    }
+    entries
+}
+
+/// Recursively builds the navigation entries of the content references of a file.
+fn reference_entries(
+    filename: &str,
+    content_references: &[ContentReference],
+    link_number: &mut usize,
+) -> Vec<OutlineEntry> {
+    content_references
+        .iter()
+        .map(|content_reference| {
+            *link_number += 1;
+            OutlineEntry {
+                title: content_reference.title.clone(),
+                src: content_reference.reference_name(filename, *link_number),
+                children: reference_entries(
+                    filename,
+                    content_reference
+                        .subcontent_references
+                        .as_deref()
+                        .unwrap_or_default(),
+                    link_number,
+                ),
+            }
+        })
+        .collect()
+}
+
+impl EpubBuilder<'_> {
+    /// Gets the **effective outline** of the book (see [`Outline`]), including the generated pages,
+    /// without creating the file.
+    ///
+    /// # Errors
+    /// Returns the errors of preparing the contents, as when the file is created, or a
+    /// [`crate::Error::ContentFilename`] if a content filename does not end with `.xhtml`.
+    pub fn outline(&self) -> crate::Result<Outline> {
+        let mut epub = self.0.clone();
+        epub.prepare()?;
+        Outline::new(&epub)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_outline() {
+        let outline = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><h1>One</h1><h2 id="id01">Scene</h2></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .add_content_reference(ContentReference::new("Scene"))
+                .spine_order(1)
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><h1>Two</h1></body>",
+                    ReferenceType::Text("Two".to_string()),
+                )
+                .build(),
+            )
+            .outline()
+            .unwrap();
+
+        assert_eq!(
+            outline.files[0].headings,
+            vec![(1, "One".to_string()), (2, "Scene".to_string())]
+        );
+        assert_eq!(outline.navigation[0].children[0].src, "c01.xhtml#id01");
+        assert_eq!(outline.spine, vec!["c02.xhtml", "c01.xhtml"]);
+
+        let report = outline.to_string();
+        assert!(report.contains("\n  One (c01.xhtml)\n"));
+        assert!(report.contains("    Scene (c01.xhtml#id01)\n"));
+        assert!(report.ends_with("Spine:\n  1. c02.xhtml\n  2. c01.xhtml\n"));
+    }
+}
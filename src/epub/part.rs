@@ -0,0 +1,201 @@
+use quick_xml::escape::escape;
+
+use crate::epub::{Content, ContentBuilder, EpubBuilder, Matter, ReferenceType};
+
+/// The CSS rules used by the part title pages generated by [`PartBuilder`].
+pub const PART_CSS: &str = "body.part { text-align: center; }
+p.part-number { margin-top: 30%; font-variant: small-caps; letter-spacing: 0.2em; }
+h1.part-title { margin-top: 0.5em; }";
+
+/// A builder grouping **chapters under a part**, the standard structure of trade non-fiction.
+///
+/// The part becomes a level of the TOC holding its chapters. By default it gets a generated part title page
+/// (styled with [`PART_CSS`]); without it, the part is a heading-only entry pointing to its first chapter
+/// (see [`ContentBuilder::heading`]). Parts added with [`EpubBuilder::add_parts`] can be numbered
+/// consistently in the navigation: "Part 1: ..." for the parts and "1. ...", "2. ..." for the chapters,
+/// continuing across parts.
+///
+/// ```rust
+/// use liber::epub::{ContentBuilder, EpubBuilder, MetadataBuilder, PartBuilder, ReferenceType};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("The Essay").build()).add_parts(
+///     vec![
+///         PartBuilder::new("Beginnings").add_chapter(
+///             ContentBuilder::new(b"<body><h1>Origins</h1></body>", ReferenceType::Text("Origins".to_string())).build(),
+///         ),
+///         PartBuilder::new("Endings").title_page(false).add_chapter(
+///             ContentBuilder::new(b"<body><h1>Legacy</h1></body>", ReferenceType::Text("Legacy".to_string())).build(),
+///         ),
+///     ],
+///     true,
+/// );
+/// ```
+#[derive(Debug, Clone)]
+pub struct PartBuilder<'a> {
+    /// The title of the part.
+    title: String,
+    /// Whether a part title page is generated.
+    title_page: bool,
+    /// The chapters of the part, in reading order.
+    chapters: Vec<Content<'a>>,
+}
+
+impl<'a> PartBuilder<'a> {
+    /// Creates a part with the given title, without chapters and with a generated title page.
+    pub fn new<S: Into<String>>(title: S) -> Self {
+        Self {
+            title: title.into(),
+            title_page: true,
+            chapters: Vec::new(),
+        }
+    }
+
+    /// Sets whether a **part title page** is generated. Defaults to `true`.
+    ///
+    /// Without it, the part only appears in the TOC, pointing to its first chapter.
+    pub fn title_page(mut self, title_page: bool) -> Self {
+        self.title_page = title_page;
+        self
+    }
+
+    /// Adds a **chapter** to the part.
+    pub fn add_chapter(mut self, chapter: Content<'a>) -> Self {
+        self.chapters.push(chapter);
+        self
+    }
+
+    /// Adds several **chapters** to the part.
+    pub fn add_chapters(mut self, chapters: Vec<Content<'a>>) -> Self {
+        self.chapters.extend(chapters);
+        self
+    }
+
+    /// Consumes the builder and returns the part as a [`Content`] holding its chapters, without numbering.
+    pub fn build(self) -> Content<'a> {
+        self.into_content(None, &mut 0)
+    }
+
+    /// Builds the part, numbering it with `number` (if any) and its chapters from `chapter_number`.
+    fn into_content(self, number: Option<usize>, chapter_number: &mut usize) -> Content<'a> {
+        let mut chapters = self.chapters;
+        if number.is_some() {
+            for chapter in chapters
+                .iter_mut()
+                .filter(|chapter| chapter.matter != Some(Matter::Front))
+            {
+                *chapter_number += 1;
+                chapter.prefix_title(&format!("{chapter_number}. "));
+            }
+        }
+
+        let label = number.map(|number| format!("Part {number}"));
+        let title = label.as_ref().map_or_else(
+            || self.title.clone(),
+            |label| format!("{label}: {}", self.title),
+        );
+
+        let builder = if self.title_page {
+            ContentBuilder::new_owned(
+                format!(
+                    r#"<body>{}<h1 class="part-title">{}</h1></body>"#,
+                    label
+                        .map(|label| format!(r#"<p class="part-number">{label}</p>"#))
+                        .unwrap_or_default(),
+                    escape(&self.title)
+                ),
+                ReferenceType::Text(title),
+            )
+            .body_attribute("class", "part")
+            .body_attribute("epub:type", "part")
+            .body_attribute("role", "doc-part")
+        } else {
+            ContentBuilder::heading(ReferenceType::Text(title))
+        };
+
+        builder.add_children(chapters).build()
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds **parts** (see [`PartBuilder`]) to the main book flow, in order.
+    ///
+    /// If `numbered` is `true`, the parts are numbered ("Part 1: ...") and their chapters too ("1. ..."),
+    /// continuing across parts. Chapters tagged as front matter (see [`ContentBuilder::matter`]) are not numbered.
+    pub fn add_parts(self, parts: Vec<PartBuilder<'a>>, numbered: bool) -> Self {
+        let mut chapter_number = 0;
+        let contents = parts
+            .into_iter()
+            .enumerate()
+            .map(|(index, part)| {
+                part.into_content(numbered.then_some(index + 1), &mut chapter_number)
+            })
+            .collect();
+        self.add_contents(contents)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::MetadataBuilder;
+
+    fn chapter(title: &str) -> Content<'static> {
+        ContentBuilder::new(
+            b"<body><p>Text</p></body>",
+            ReferenceType::Text(title.to_string()),
+        )
+        .build()
+    }
+
+    #[test]
+    fn test_part_builder() {
+        let part = PartBuilder::new("Beginnings")
+            .add_chapter(chapter("Origins"))
+            .build();
+        assert_eq!(part.title(), "Beginnings");
+        assert!(!part.link);
+        let files = part.file_content(&mut 0, "").unwrap();
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"class="part-title">Beginnings</h1>"#)
+        );
+
+        let part = PartBuilder::new("Endings")
+            .title_page(false)
+            .add_chapter(chapter("Legacy"))
+            .build();
+        assert!(part.link);
+        assert_eq!(part.filename(1), "c02.xhtml");
+    }
+
+    #[test]
+    fn test_add_parts_numbered() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_parts(
+            vec![
+                PartBuilder::new("One").add_chapters(vec![chapter("A"), chapter("B")]),
+                PartBuilder::new("Two")
+                    .title_page(false)
+                    .add_chapter(chapter("C")),
+            ],
+            true,
+        );
+        assert!(builder.validate().is_ok());
+
+        let contents = builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents[0].title(), "Part 1: One");
+        let files = contents[0].file_content(&mut 0, "").unwrap();
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<p class="part-number">Part 1</p>"#)
+        );
+        assert_eq!(contents[1].title(), "Part 2: Two");
+        assert_eq!(contents[1].subcontents.as_ref().unwrap()[0].title(), "3. C");
+
+        let toc_ncx = crate::output::file_content::toc_ncx(&builder.0)
+            .unwrap()
+            .bytes;
+        assert!(toc_ncx.contains("<text>2. B</text>"));
+    }
+}
@@ -0,0 +1,378 @@
+use std::{borrow::Cow, path::Path};
+
+use crate::{
+    epub::{Content, ContentBuilder, EpubBuilder, ImageType, Metadata, ReferenceType},
+    output::xml,
+};
+
+/// The CSS rules used by the pages generated by [`MagazineBuilder`].
+pub const PERIODICAL_CSS: &str = "p.issue { font-style: italic; }
+ol.issue-contents, ol.section-contents { list-style: none; padding: 0; }
+ol.issue-contents > li { margin-top: 1em; font-weight: bold; }
+ol.section-contents li { margin: 0.5em 0; font-weight: normal; }
+span.article-authors { display: block; font-size: 0.9em; font-style: italic; }
+p.byline { font-style: italic; margin: 0 0 1em; }";
+
+/// The filename of the generated issue contents page.
+pub const ISSUE_CONTENTS_FILENAME: &str = "contents.xhtml";
+
+/// A single **article** of a periodical issue, with its own authors.
+#[derive(Debug, Clone)]
+pub struct Article<'a> {
+    /// The title of the article, used in the navigation and the contents pages.
+    title: String,
+    /// The authors of the article, shown in its byline.
+    authors: Vec<String>,
+    /// The raw XHTML body of the article.
+    body: Cow<'a, [u8]>,
+}
+
+impl<'a> Article<'a> {
+    /// Creates an article with the given title and XHTML body.
+    pub fn new<S: Into<String>>(title: S, body: &'a [u8]) -> Self {
+        Self {
+            title: title.into(),
+            authors: Vec::new(),
+            body: Cow::Borrowed(body),
+        }
+    }
+
+    /// Creates an article from an **owned** body, such as a downloaded page.
+    pub fn new_owned<S: Into<String>, B: Into<Vec<u8>>>(title: S, body: B) -> Self {
+        Self {
+            title: title.into(),
+            authors: Vec::new(),
+            body: Cow::Owned(body.into()),
+        }
+    }
+
+    /// Adds an **author** to the article.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn author<S: Into<String>>(mut self, author: S) -> Self {
+        self.authors.push(author.into());
+        self
+    }
+
+    /// Joins the authors as `A`, `A and B` or `A, B and C`.
+    fn authors(&self) -> Option<String> {
+        match self.authors.as_slice() {
+            [] => None,
+            [author] => Some(author.clone()),
+            [authors @ .., last] => Some(format!("{} and {last}", authors.join(", "))),
+        }
+    }
+
+    /// Builds the article content, adding a byline with its authors after the first heading.
+    fn content(self, filename: String) -> Content<'a> {
+        let body = match (self.authors(), std::str::from_utf8(&self.body)) {
+            (Some(authors), Ok(body)) => {
+                xml::insert_after_heading(body, &format!(r#"<p class="byline">By {authors}</p>"#))
+                    .map_or(self.body, |body| Cow::Owned(body.into_bytes()))
+            }
+            _ => self.body,
+        };
+
+        let reference_type = ReferenceType::Text(self.title);
+        match body {
+            Cow::Borrowed(body) => ContentBuilder::new(body, reference_type),
+            Cow::Owned(body) => ContentBuilder::new_owned(body, reference_type),
+        }
+        .filename(filename)
+        .build()
+    }
+}
+
+/// A **section** of a periodical issue (e.g., "Features" or "Reviews"), grouping articles.
+#[derive(Debug, Clone)]
+pub struct Section<'a> {
+    /// The title of the section.
+    title: String,
+    /// The articles of the section, in reading order.
+    articles: Vec<Article<'a>>,
+}
+
+impl<'a> Section<'a> {
+    /// Creates an empty section with the given title.
+    pub fn new<S: Into<String>>(title: S) -> Self {
+        Self {
+            title: title.into(),
+            articles: Vec::new(),
+        }
+    }
+
+    /// Adds an **article** to the section.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_article(mut self, article: Article<'a>) -> Self {
+        self.articles.push(article);
+        self
+    }
+
+    /// Adds several **articles** to the section.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_articles(mut self, articles: Vec<Article<'a>>) -> Self {
+        self.articles.extend(articles);
+        self
+    }
+
+    /// Builds the list items linking to the articles of this section, with their authors.
+    fn article_list(&self, number: usize) -> String {
+        self.articles
+            .iter()
+            .enumerate()
+            .map(|(index, article)| {
+                let authors = article
+                    .authors()
+                    .map(|authors| format!(r#"<span class="article-authors">{authors}</span>"#))
+                    .unwrap_or_default();
+                format!(
+                    r#"<li><a href="{}">{}</a>{authors}</li>"#,
+                    article_filename(number, index + 1),
+                    article.title
+                )
+            })
+            .collect()
+    }
+}
+
+/// A higher-level builder for **periodicals** (magazines and journals), mapping an issue onto the content tree.
+///
+/// Every [`Section`] becomes a generated section page listing its articles, with the articles as its children,
+/// so the navigation is organized by section. An issue contents page, listing every section and article with
+/// their authors, is generated first. Style the generated pages with [`PERIODICAL_CSS`].
+///
+/// ```rust
+/// use liber::epub::{Article, Identifier, MagazineBuilder, MetadataBuilder, Periodical, Section};
+///
+/// let metadata = MetadataBuilder::title("Monthly Review")
+///     .identifier(Identifier::ISSN("0027-0520".to_string()))
+///     .periodical(Periodical::new().volume("75").issue("3"))
+///     .build();
+///
+/// let epub_builder = MagazineBuilder::new(metadata)
+///     .add_section(
+///         Section::new("Features")
+///             .add_article(Article::new("The Lead", b"<body><h1>The Lead</h1></body>").author("Jane Doe")),
+///     )
+///     .build();
+///
+/// assert!(epub_builder.create(&mut Vec::new()).is_ok());
+/// ```
+#[derive(Debug, Clone)]
+pub struct MagazineBuilder<'a> {
+    /// The metadata of the issue, usually with an ISSN and periodical data.
+    metadata: Metadata,
+    /// Optional cover image of the issue.
+    cover_image: Option<(&'a Path, ImageType)>,
+    /// The title of the generated issue contents page.
+    contents_title: String,
+    /// The sections of the issue, in reading order.
+    sections: Vec<Section<'a>>,
+}
+
+impl<'a> MagazineBuilder<'a> {
+    /// Creates a builder for an issue with the given metadata.
+    pub fn new(metadata: Metadata) -> Self {
+        Self {
+            metadata,
+            cover_image: None,
+            contents_title: "Contents".to_string(),
+            sections: Vec::new(),
+        }
+    }
+
+    /// Sets the **cover image** of the issue.
+    pub fn cover_image(mut self, path: &'a Path, image_type: ImageType) -> Self {
+        self.cover_image = Some((path, image_type));
+        self
+    }
+
+    /// Sets the title of the generated **issue contents** page. Defaults to `"Contents"`.
+    pub fn contents_title<S: Into<String>>(mut self, title: S) -> Self {
+        self.contents_title = title.into();
+        self
+    }
+
+    /// Adds a **section** to the issue.
+    pub fn add_section(mut self, section: Section<'a>) -> Self {
+        self.sections.push(section);
+        self
+    }
+
+    /// Consumes the builder and returns an [`EpubBuilder`] with the generated pages and articles,
+    /// which can be further configured (e.g., with a stylesheet) before creating the file.
+    pub fn build(self) -> EpubBuilder<'a> {
+        let contents = ContentBuilder::new_owned(
+            self.contents_body(),
+            ReferenceType::Toc(self.contents_title),
+        )
+        .filename(ISSUE_CONTENTS_FILENAME)
+        .build();
+
+        let sections = self
+            .sections
+            .into_iter()
+            .enumerate()
+            .map(|(index, section)| {
+                let number = index + 1;
+                let body = format!(
+                    r#"<body><h1>{}</h1><ol class="section-contents">{}</ol></body>"#,
+                    section.title,
+                    section.article_list(number)
+                );
+                let articles = section
+                    .articles
+                    .into_iter()
+                    .enumerate()
+                    .map(|(index, article)| article.content(article_filename(number, index + 1)))
+                    .collect::<Vec<_>>();
+
+                let builder = ContentBuilder::new_owned(body, ReferenceType::Text(section.title))
+                    .filename(section_filename(number));
+                if articles.is_empty() {
+                    builder.build()
+                } else {
+                    builder.add_children(articles).build()
+                }
+            })
+            .collect();
+
+        let mut epub_builder = EpubBuilder::new(self.metadata)
+            .add_content(contents)
+            .add_contents(sections);
+
+        if let Some((path, image_type)) = self.cover_image {
+            epub_builder = epub_builder.cover_image(path, image_type);
+        }
+
+        epub_builder
+    }
+
+    /// Builds the body of the issue contents page, with the volume and issue numbers if they are set.
+    fn contents_body(&self) -> String {
+        let issue = self
+            .metadata
+            .periodical
+            .as_ref()
+            .map(|periodical| {
+                [
+                    periodical
+                        .volume
+                        .as_ref()
+                        .map(|volume| format!("Vol. {volume}")),
+                    periodical
+                        .issue
+                        .as_ref()
+                        .map(|issue| format!("No. {issue}")),
+                ]
+                .into_iter()
+                .flatten()
+                .collect::<Vec<_>>()
+                .join(", ")
+            })
+            .filter(|issue| !issue.is_empty())
+            .map(|issue| format!(r#"<p class="issue">{issue}</p>"#))
+            .unwrap_or_default();
+
+        let sections = self
+            .sections
+            .iter()
+            .enumerate()
+            .map(|(index, section)| {
+                format!(
+                    r#"<li><a href="{}">{}</a><ol class="section-contents">{}</ol></li>"#,
+                    section_filename(index + 1),
+                    section.title,
+                    section.article_list(index + 1)
+                )
+            })
+            .collect::<String>();
+
+        format!(
+            r#"<body><h1>{}</h1>{issue}<ol class="issue-contents">{sections}</ol></body>"#,
+            self.contents_title
+        )
+    }
+}
+
+/// Gets the filename of the section numbered `number`.
+fn section_filename(number: usize) -> String {
+    format!("section{number:02}.xhtml")
+}
+
+/// Gets the filename of the article numbered `article` in the section numbered `section`.
+fn article_filename(section: usize, article: usize) -> String {
+    format!("section{section:02}-{article:02}.xhtml")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{MetadataBuilder, Periodical};
+
+    #[test]
+    fn test_article_authors() {
+        let article = Article::new("Title", b"<body/>");
+        assert_eq!(article.authors(), None);
+        assert_eq!(article.clone().author("A").authors(), Some("A".to_string()));
+        assert_eq!(
+            article.author("A").author("B").author("C").authors(),
+            Some("A, B and C".to_string())
+        );
+    }
+
+    #[test]
+    fn test_magazine_builder() {
+        let metadata = MetadataBuilder::title("Monthly")
+            .periodical(Periodical::new().volume("75").issue("3"))
+            .build();
+
+        let mut epub_builder = MagazineBuilder::new(metadata)
+            .add_section(
+                Section::new("Features")
+                    .add_article(
+                        Article::new("Lead", b"<body><h1>Lead</h1><p>Text</p></body>")
+                            .author("Jane Doe")
+                            .author("John Roe"),
+                    )
+                    .add_article(Article::new("Second", b"<body><p>Text</p></body>")),
+            )
+            .add_section(Section::new("Reviews"))
+            .build();
+
+        let contents = epub_builder.0.contents.as_ref().unwrap();
+        assert_eq!(contents.len(), 3);
+
+        let files = contents[0].file_content(&mut 0, "").unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/contents.xhtml");
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<p class="issue">Vol. 75, No. 3</p>"#)
+        );
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="section01.xhtml">Features</a>"#)
+        );
+
+        let files = contents[1].file_content(&mut 1, "").unwrap();
+        assert_eq!(files.len(), 3);
+        assert_eq!(files[1].filepath, "OEBPS/section01-01.xhtml");
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<a href="section01-02.xhtml">Second</a>"#)
+        );
+        assert!(
+            files[1]
+                .bytes
+                .contains(r#"<p class="byline">By Jane Doe and John Roe</p>"#)
+        );
+
+        epub_builder.0.prepare().unwrap();
+        assert!(epub_builder.create(&mut Vec::new()).is_ok());
+    }
+}
@@ -0,0 +1,182 @@
+use std::collections::HashMap;
+
+use crate::epub::{Content, Epub, Outline, OutlineEntry};
+
+/// The `playOrder` of every target of the `toc.ncx` (the entries of the `navMap` and the `pageTarget`s of
+/// the `pageList`), by `src`, following the reading order: the spine order of the files and the position of
+/// the anchors in their bodies. Entries with the same target share their `playOrder`.
+///
+/// The `navMap` may list its entries out of reading order (e.g., an appendix nested under a part but read
+/// at the end of the book with [`crate::epub::ContentBuilder::spine_order`]): they keep the `playOrder` of
+/// their target, so the values of the `navMap` are not always increasing.
+#[derive(Debug, Default)]
+pub(crate) struct PlayOrders(HashMap<String, usize>);
+
+impl PlayOrders {
+    /// Numbers the targets of the navigation of a prepared book and its page targets (as `(src, label)` pairs),
+    /// sorting the page targets in reading order (the order of the `pageList`), as the contents moved with
+    /// [`crate::epub::ContentBuilder::spine_order`] are collected in tree order.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if the navigation of the book cannot be built.
+    pub(crate) fn new(
+        epub: &Epub<'_>,
+        page_targets: &mut [(String, String)],
+    ) -> crate::Result<Self> {
+        let outline = Outline::new(epub)?;
+
+        let mut bodies = HashMap::new();
+        collect_bodies(
+            &mut 0,
+            epub.contents.as_deref().unwrap_or_default(),
+            &mut bodies,
+        );
+
+        let mut entries = Vec::new();
+        flatten(&outline.navigation, &mut entries);
+
+        let position = |src: &str| {
+            let (filename, id) = src.split_once('#').unwrap_or((src, ""));
+            let file = outline
+                .spine
+                .iter()
+                .position(|spine_filename| spine_filename == filename)
+                .unwrap_or(usize::MAX);
+            let anchor = if id.is_empty() {
+                0
+            } else {
+                bodies
+                    .get(filename)
+                    .and_then(|content: &&Content<'_>| content.anchor_position(id))
+                    .map_or(usize::MAX, |position| position + 1)
+            };
+            (file, anchor)
+        };
+
+        page_targets.sort_by_cached_key(|(src, _)| position(src));
+
+        let mut targets = entries
+            .iter()
+            .map(|(src, _)| src.as_str())
+            .chain(page_targets.iter().map(|(src, _)| src.as_str()))
+            .map(|src| (position(src), src))
+            .collect::<Vec<_>>();
+        targets.sort_by_key(|(position, _)| *position);
+
+        let mut play_orders = HashMap::new();
+        for (_, src) in targets {
+            let next = play_orders.len() + 1;
+            play_orders.entry(src.to_string()).or_insert(next);
+        }
+        Ok(Self(play_orders))
+    }
+
+    /// Gets the `playOrder` of the target `src`.
+    pub(crate) fn get(&self, src: &str) -> usize {
+        self.0.get(src).copied().unwrap_or_default()
+    }
+}
+
+#[cfg(test)]
+impl FromIterator<(String, usize)> for PlayOrders {
+    fn from_iter<I: IntoIterator<Item = (String, usize)>>(iter: I) -> Self {
+        Self(iter.into_iter().collect())
+    }
+}
+
+/// Recursively collects the `(src, title)` of the navigation entries, depth-first as the `navMap` lists them.
+fn flatten(entries: &[OutlineEntry], flattened: &mut Vec<(String, String)>) {
+    for entry in entries {
+        flattened.push((entry.src.clone(), entry.title.clone()));
+        flatten(&entry.children, flattened);
+    }
+}
+
+/// Recursively collects the written contents (not the links) by filename.
+fn collect_bodies<'c, 'a>(
+    file_number: &mut usize,
+    contents: &'c [Content<'a>],
+    bodies: &mut HashMap<String, &'c Content<'a>>,
+) {
+    for content in contents {
+        *file_number += 1;
+        if !content.link {
+            bodies.insert(content.filename(*file_number).into_owned(), content);
+        }
+        collect_bodies(
+            file_number,
+            content.subcontents.as_deref().unwrap_or_default(),
+            bodies,
+        );
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{
+        ContentBuilder, ContentReference, EpubBuilder, MetadataBuilder, ReferenceType,
+    };
+
+    #[test]
+    fn test_play_orders_reading_order() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><span id="page-1"/><h2 id="id01">Scene</h2><span id="page-2"/></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .add_content_reference(ContentReference::new("Scene"))
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::link(ReferenceType::Text("Again".to_string()), "c01.xhtml#id01")
+                    .build(),
+            );
+
+        let mut page_targets = [
+            ("c01.xhtml#page-2".to_string(), "2".to_string()),
+            ("c01.xhtml#page-1".to_string(), "1".to_string()),
+        ];
+        let play_orders = PlayOrders::new(&epub.0, &mut page_targets).unwrap();
+        assert_eq!(page_targets[0].1, "1");
+
+        assert_eq!(play_orders.get("c01.xhtml"), 1);
+        assert_eq!(play_orders.get("c01.xhtml#page-1"), 2);
+        assert_eq!(play_orders.get("c01.xhtml#id01"), 3);
+        assert_eq!(play_orders.get("c01.xhtml#page-2"), 4);
+        assert_eq!(play_orders.get("unknown.xhtml"), 0);
+    }
+
+    #[test]
+    fn test_play_orders_spine_order() {
+        let appendix = ContentBuilder::new(b"<body/>", ReferenceType::Text("Appendix".to_string()))
+            .spine_order(1)
+            .add_child(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Tables".to_string())).build(),
+            )
+            .build();
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part I".to_string()))
+                    .add_child(
+                        ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string()))
+                            .build(),
+                    )
+                    .add_child(appendix)
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part II".to_string())).build(),
+            );
+
+        let play_orders = PlayOrders::new(&epub.0, &mut []).unwrap();
+        assert_eq!(play_orders.get("c01.xhtml"), 1);
+        assert_eq!(play_orders.get("c02.xhtml"), 2);
+        assert_eq!(play_orders.get("c05.xhtml"), 3);
+        assert_eq!(play_orders.get("c03.xhtml"), 4);
+        assert_eq!(play_orders.get("c04.xhtml"), 5);
+
+        assert!(epub.create(&mut Vec::new()).is_ok());
+    }
+}
@@ -0,0 +1,212 @@
+use std::fmt::Display;
+
+use crate::epub::{Content, Epub, ImageType, ReferenceType, Resource};
+
+/// A **target reading system** (or store), adjusting the generation to its requirements
+/// and running its specific validations when the file is created.
+///
+/// * **Cover handling**: Kindle wants the cover page out of the linear reading order, so the
+///   contents with [`ReferenceType::Cover`] are marked `linear="no"`.
+/// * **Formats allowed**: Kindle and ADE don't play audio or video, and SVG covers are only accepted by ADE.
+/// * **Navigation**: Kindle requires an HTML table of contents ([`ReferenceType::Toc`]) besides the NCX
+///   (always generated, as EPUB 2 requires it for every profile).
+/// * **CSS restrictions**: ADE (RMSDK) doesn't support viewport units (`vw`, `vh`, `vmin`, `vmax`).
+/// * **Embedded web content**: only Apple Books renders iframes (interactive widgets).
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Profile {
+    /// Amazon Kindle (KDP).
+    Kindle,
+    /// Apple Books.
+    AppleBooks,
+    /// Kobo (Kobo Writing Life).
+    Kobo,
+    /// Adobe Digital Editions and other RMSDK-based readers.
+    Ade,
+}
+
+impl Profile {
+    /// Adjusts the generation of the book to this profile.
+    pub(crate) fn apply(self, epub: &mut Epub<'_>) {
+        if self == Self::Kindle {
+            for content in epub.contents.iter_mut().flatten() {
+                exclude_covers(content);
+            }
+        }
+    }
+
+    /// Runs the validations specific to this profile.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] describing the first requirement of the profile not met.
+    pub(crate) fn validate(self, epub: &Epub<'_>) -> crate::Result {
+        match epub.cover_image {
+            None if self != Self::Ade => return Err(self.error("a cover image is required")),
+            Some(Resource::Image(_, ImageType::Svg)) if self != Self::Ade => {
+                return Err(self.error("the cover image must not be an SVG image"));
+            }
+            _ => {}
+        }
+
+        if matches!(self, Self::Kindle | Self::Ade)
+            && let Some(resource) = epub
+                .resources
+                .iter()
+                .flatten()
+                .find(|resource| matches!(resource, Resource::Audio(_) | Resource::Video(_)))
+        {
+            return Err(self.error(&format!("audio and video are not supported ('{resource}')")));
+        }
+
+        if self != Self::AppleBooks
+            && epub
+                .contents
+                .iter()
+                .flatten()
+                .any(|content| content.any(&|content| content.has_element("iframe")))
+        {
+            return Err(self.error("embedded web content (iframes) is not supported"));
+        }
+
+        if self == Self::Kindle
+            && !epub.contents.iter().flatten().any(|content| {
+                content.any(&|content| matches!(content.reference_type, ReferenceType::Toc(_)))
+            })
+        {
+            return Err(self.error("an HTML table of contents is required"));
+        }
+
+        if self == Self::Ade
+            && let Some(unit) = epub
+                .stylesheet
+                .and_then(|stylesheet| std::str::from_utf8(stylesheet).ok())
+                .and_then(viewport_unit)
+        {
+            return Err(self.error(&format!(
+                "the stylesheet uses the unsupported viewport unit '{unit}'"
+            )));
+        }
+
+        Ok(())
+    }
+
+    /// Creates a validation error naming this profile.
+    fn error(self, message: &str) -> crate::Error {
+        crate::Error::Validation(format!("{self} profile: {message}"))
+    }
+}
+
+impl Display for Profile {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Self::Kindle => write!(f, "Kindle"),
+            Self::AppleBooks => write!(f, "Apple Books"),
+            Self::Kobo => write!(f, "Kobo"),
+            Self::Ade => write!(f, "ADE"),
+        }
+    }
+}
+
+/// Recursively marks the cover pages as non-linear.
+fn exclude_covers(content: &mut Content<'_>) {
+    if matches!(content.reference_type, ReferenceType::Cover(_)) {
+        content.linear = false;
+    }
+    for content in content.subcontents.iter_mut().flatten() {
+        exclude_covers(content);
+    }
+}
+
+/// Finds the first viewport unit (a number followed by `vw`, `vh`, `vmin` or `vmax`) used in a stylesheet or an XHTML string.
+pub(crate) fn viewport_unit(css: &str) -> Option<&'static str> {
+    ["vmin", "vmax", "vw", "vh"].into_iter().find(|unit| {
+        css.match_indices(unit).any(|(position, _)| {
+            let before = css[..position].chars().next_back();
+            let after = css[position + unit.len()..].chars().next();
+            before.is_some_and(|c| c.is_ascii_digit())
+                && !after.is_some_and(|c| c.is_ascii_alphanumeric())
+        })
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::epub::{ContentBuilder, EpubBuilder, MetadataBuilder};
+
+    fn epub_builder<'a>() -> EpubBuilder<'a> {
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .cover_image(Path::new("cover.jpg"), ImageType::Jpg)
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Cover("Cover".to_string())).build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+    }
+
+    #[test]
+    fn test_profile_kindle() {
+        let mut epub = epub_builder().0;
+        Profile::Kindle.apply(&mut epub);
+        assert!(!epub.contents.as_ref().unwrap()[0].linear);
+        assert!(epub.contents.as_ref().unwrap()[1].linear);
+
+        assert_eq!(
+            Profile::Kindle.validate(&epub).unwrap_err().to_string(),
+            "Validation error: Kindle profile: an HTML table of contents is required"
+        );
+
+        let epub = epub_builder()
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Toc("Contents".to_string())).build(),
+            )
+            .add_resource(Resource::Audio(Path::new("track.mp3")))
+            .0;
+        assert!(Profile::Kindle.validate(&epub).is_err());
+        assert!(Profile::Kobo.validate(&epub).is_ok());
+    }
+
+    #[test]
+    fn test_profile_cover() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build()).0;
+        assert!(Profile::AppleBooks.validate(&epub).is_err());
+        assert!(Profile::Ade.validate(&epub).is_ok());
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .cover_image(Path::new("cover.svg"), ImageType::Svg)
+            .0;
+        assert!(Profile::Kobo.validate(&epub).is_err());
+    }
+
+    #[test]
+    fn test_profile_iframes() {
+        let epub = epub_builder()
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><iframe src="widget.xhtml"/></body>"#,
+                    ReferenceType::Text("Widget".to_string()),
+                )
+                .build(),
+            )
+            .0;
+        assert!(Profile::AppleBooks.validate(&epub).is_ok());
+        assert_eq!(
+            Profile::Kobo.validate(&epub).unwrap_err().to_string(),
+            "Validation error: Kobo profile: embedded web content (iframes) is not supported"
+        );
+    }
+
+    #[test]
+    fn test_profile_ade_viewport_units() {
+        assert_eq!(viewport_unit("img { max-height: 95vh; }"), Some("vh"));
+        assert_eq!(viewport_unit("p { width: 10vmin }"), Some("vmin"));
+        assert_eq!(viewport_unit(".vh { width: 50%; }"), None);
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .stylesheet(b"img { max-width: 100vw; }")
+            .0;
+        assert!(Profile::Ade.validate(&epub).is_err());
+    }
+}
@@ -0,0 +1,212 @@
+use std::borrow::Cow;
+
+use quick_xml::escape::escape;
+
+use crate::epub::{Epub, EpubBuilder, IdGenerator};
+
+/// The paths written by the library, never overwritten by a raw file.
+const RESERVED_PATHS: [&str; 2] = ["mimetype", "META-INF/container.xml"];
+
+/// A **raw file**, written as is into the archive (see [`EpubBuilder::add_raw_file`]): vendor sidecars,
+/// or files reproduced from an existing EPUB during round-trip edits.
+///
+/// ```rust
+/// use liber::epub::{EpubBuilder, MetadataBuilder, RawFile};
+///
+/// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+///     .add_raw_file(RawFile::new("META-INF/vendor.xml", b"<vendor/>"))
+///     .add_raw_file(RawFile::new("OEBPS/data/terms.json", b"{}").manifest("application/json"));
+/// ```
+#[derive(Debug, Clone)]
+pub struct RawFile<'a> {
+    /// The path of the file from the root of the archive (e.g., `META-INF/vendor.xml`).
+    path: String,
+    /// The bytes of the file.
+    bytes: Cow<'a, [u8]>,
+    /// Optional media type, registering the file in the manifest.
+    media_type: Option<String>,
+}
+
+impl<'a> RawFile<'a> {
+    /// Creates a raw file with its **path** from the root of the archive (e.g., `META-INF/vendor.xml`)
+    /// and its borrowed **bytes**.
+    pub fn new<S: Into<String>>(path: S, bytes: &'a [u8]) -> Self {
+        Self {
+            path: path.into(),
+            bytes: Cow::Borrowed(bytes),
+            media_type: None,
+        }
+    }
+
+    /// Creates a raw file with its **path** from the root of the archive and its owned **bytes**.
+    pub fn new_owned<S: Into<String>>(path: S, bytes: Vec<u8>) -> Self {
+        Self {
+            path: path.into(),
+            bytes: Cow::Owned(bytes),
+            media_type: None,
+        }
+    }
+
+    /// **Registers the file in the manifest** with the given media type (e.g., `application/json`).
+    ///
+    /// The file must be inside the content directory (`OEBPS` by default, see [`EpubBuilder::rootfile`]).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn manifest<S: Into<String>>(mut self, media_type: S) -> Self {
+        self.media_type = Some(media_type.into());
+        self
+    }
+
+    /// Gets the path of the file from the root of the archive.
+    pub(crate) fn path(&self) -> &str {
+        &self.path
+    }
+
+    /// Gets the bytes of the file.
+    pub(crate) fn bytes(&self) -> &[u8] {
+        &self.bytes
+    }
+
+    /// Generates the XML `<item>` tag of the file, if registered in the manifest, used in the manifest section.
+    pub(crate) fn as_manifest_xml(
+        &self,
+        content_dir: &str,
+        ids: &dyn IdGenerator,
+    ) -> Option<String> {
+        let media_type = self.media_type.as_ref()?;
+        let href = self
+            .path
+            .strip_prefix(content_dir)
+            .and_then(|path| path.strip_prefix('/'))?;
+        Some(format!(
+            r#"<item id="{}" href="{}" media-type="{}"/>"#,
+            escape(ids.item_id(href)),
+            escape(href),
+            escape(media_type)
+        ))
+    }
+}
+
+impl Epub<'_> {
+    /// Checks that the raw files have valid paths, outside the files written by the library, and that those
+    /// registered in the manifest are inside the content directory.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] naming the first invalid raw file.
+    pub(crate) fn check_raw_files(&self) -> crate::Result {
+        let content_dir = format!("{}/", self.content_dir());
+        for raw_file in self.raw_files.iter().flatten() {
+            let path = raw_file.path();
+            if path.is_empty()
+                || path.starts_with('/')
+                || path.ends_with('/')
+                || path.split('/').any(|part| matches!(part, "" | "." | ".."))
+                || RESERVED_PATHS.contains(&path)
+            {
+                return Err(crate::Error::Validation(format!(
+                    "invalid raw file path '{path}'"
+                )));
+            }
+
+            if raw_file.media_type.is_some() && !path.starts_with(&content_dir) {
+                return Err(crate::Error::Validation(format!(
+                    "raw file '{path}' is registered in the manifest but is not inside '{content_dir}'"
+                )));
+            }
+        }
+        Ok(())
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds a **raw file** (see [`RawFile`]), written as is into the archive at its path, optionally registered
+    /// in the manifest.
+    ///
+    /// Raw files are not relocated with the content directory nor transformed (except by the file hooks, see
+    /// [`EpubBuilder::add_file_hook`]). Creating the file fails if the path is not a relative path inside the
+    /// archive, is `mimetype` or `META-INF/container.xml`, or is the path of another file.
+    pub fn add_raw_file(mut self, raw_file: RawFile<'a>) -> Self {
+        if let Some(ref mut raw_files) = self.0.raw_files {
+            raw_files.push(raw_file);
+        } else {
+            self.0.raw_files = Some(vec![raw_file]);
+        }
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{DefaultIds, MetadataBuilder};
+
+    #[test]
+    fn test_raw_file_as_manifest_xml() {
+        let raw_file = RawFile::new("OEBPS/data/terms.json", b"{}");
+        assert!(raw_file.as_manifest_xml("OEBPS", &DefaultIds).is_none());
+
+        let raw_file = raw_file.manifest("application/json");
+        assert_eq!(
+            raw_file.as_manifest_xml("OEBPS", &DefaultIds).unwrap(),
+            r#"<item id="data/terms.json" href="data/terms.json" media-type="application/json"/>"#
+        );
+    }
+
+    #[test]
+    fn test_check_raw_files() {
+        let builder = |raw_file| {
+            EpubBuilder::new(MetadataBuilder::title("Title").build()).add_raw_file(raw_file)
+        };
+        assert!(
+            builder(RawFile::new("META-INF/vendor.xml", b""))
+                .0
+                .check_raw_files()
+                .is_ok()
+        );
+        assert!(
+            builder(RawFile::new("mimetype", b""))
+                .0
+                .check_raw_files()
+                .is_err()
+        );
+        assert!(
+            builder(RawFile::new("../escape.txt", b""))
+                .0
+                .check_raw_files()
+                .is_err()
+        );
+        assert!(
+            builder(RawFile::new("META-INF/vendor.xml", b"").manifest("application/xml"))
+                .0
+                .check_raw_files()
+                .is_err()
+        );
+    }
+
+    #[test]
+    fn test_raw_files_on_create() {
+        let mut output = Vec::new();
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_raw_file(RawFile::new("META-INF/vendor.xml", b"<vendor/>"))
+            .add_raw_file(
+                RawFile::new_owned("EPUB/data/terms.json", b"{}".to_vec())
+                    .manifest("application/json"),
+            )
+            .rootfile("EPUB", "package.opf")
+            .create(&mut output)
+            .unwrap();
+
+        let mut archive = zip::ZipArchive::new(std::io::Cursor::new(output)).unwrap();
+        let read = |archive: &mut zip::ZipArchive<_>, name| {
+            let mut file = String::new();
+            std::io::Read::read_to_string(&mut archive.by_name(name).unwrap(), &mut file).unwrap();
+            file
+        };
+        assert_eq!(read(&mut archive, "META-INF/vendor.xml"), "<vendor/>");
+        assert_eq!(read(&mut archive, "EPUB/data/terms.json"), "{}");
+        assert!(
+            read(&mut archive, "EPUB/package.opf")
+                .contains(r#"href="data/terms.json" media-type="application/json""#)
+        );
+    }
+}
@@ -1,6 +1,9 @@
-use std::{ffi::OsStr, fmt::Display, fs, path::Path};
+use std::{borrow::Cow, ffi::OsStr, fmt::Display, path::Path};
 
-use crate::output::file_content::FileContent;
+use crate::{
+    epub::{FileSystem, IdGenerator},
+    output::file_content::FileContent,
+};
 
 /// Represents the common image file types supported for inclusion as resources.
 ///
@@ -64,36 +67,94 @@ impl<'a> Resource<'a> {
         }
     }
 
+    /// Gets the path used to read the resource: relative paths are resolved against `base_dir` when it is set.
+    pub(crate) fn resolve_path(&self, base_dir: Option<&Path>) -> Cow<'a, Path> {
+        match *self {
+            Self::Image(path, _) | Self::Font(path) | Self::Audio(path) | Self::Video(path) => {
+                match base_dir {
+                    Some(base_dir) if path.is_relative() => Cow::Owned(base_dir.join(path)),
+                    _ => Cow::Borrowed(path),
+                }
+            }
+        }
+    }
+
+    /// Gets a **size hint** of the resource in bytes, taken from the file metadata without reading the file.
+    ///
+    /// Returns `None` if the metadata cannot be read (e.g., the file does not exist).
+    pub(crate) fn size_hint(
+        &self,
+        base_dir: Option<&Path>,
+        file_system: &dyn FileSystem,
+    ) -> Option<u64> {
+        file_system.size(&self.resolve_path(base_dir)).ok()
+    }
+
+    /// Checks that the resource has a filename and points to an existing file, without reading it.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Resource`] naming the path if the file does not exist.
+    pub(crate) fn check(
+        &self,
+        base_dir: Option<&Path>,
+        file_system: &dyn FileSystem,
+    ) -> crate::Result {
+        self.filename()?;
+        file_system
+            .size(&self.resolve_path(base_dir))
+            .map_err(|e| self.context_error(e.into()))?;
+        Ok(())
+    }
+
     /// Reads the file content synchronously and wraps it in a [`FileContent`] structure.
     ///
     /// The output path is prefixed with `OEBPS/` and the filename.
+    /// Relative paths are resolved against `base_dir` when it is set.
     ///
     /// # Errors
-    /// Returns an error if the file cannot be read or if the filename cannot be extracted.
-    pub(crate) fn file_content(&self) -> crate::Result<FileContent<String, Vec<u8>>> {
-        match self {
-            Self::Image(path, _) | Self::Font(path) | Self::Audio(path) | Self::Video(path) => Ok(
-                FileContent::new(format!("OEBPS/{}", self.filename()?), fs::read(path)?),
-            ),
-        }
+    /// Returns a [`crate::Error::Resource`] naming the path if the file cannot be read,
+    /// or an error if the filename cannot be extracted.
+    pub(crate) fn file_content(
+        &self,
+        base_dir: Option<&Path>,
+        file_system: &dyn FileSystem,
+    ) -> crate::Result<FileContent<String, Vec<u8>>> {
+        Ok(FileContent::new(
+            format!("OEBPS/{}", self.filename()?),
+            file_system
+                .read(&self.resolve_path(base_dir))
+                .map_err(|e| self.context_error(e.into()))?,
+        ))
     }
 
     /// Reads the file content asynchronously (using `tokio::fs`) and wraps it in a [`FileContent`] structure.
     ///
+    /// If a custom `file_system` is set, the file is read through it instead.
     /// This method is only compiled when the **`async` feature** is enabled.
     ///
     /// # Errors
     /// Returns an error if the file cannot be read asynchronously or if the filename cannot be extracted.
     #[cfg(feature = "async")]
-    pub(crate) async fn async_file_content(&self) -> crate::Result<FileContent<String, Vec<u8>>> {
-        match self {
-            Self::Image(path, _) | Self::Font(path) | Self::Audio(path) | Self::Video(path) => {
-                Ok(FileContent::new(
-                    format!("OEBPS/{}", self.filename()?),
-                    tokio::fs::read(path).await?,
-                ))
-            }
-        }
+    pub(crate) async fn async_file_content(
+        &self,
+        base_dir: Option<&Path>,
+        file_system: Option<&dyn FileSystem>,
+    ) -> crate::Result<FileContent<String, Vec<u8>>> {
+        let path = self.resolve_path(base_dir);
+        let bytes = match file_system {
+            Some(file_system) => file_system.read(&path),
+            None => tokio::fs::read(&path).await,
+        };
+
+        Ok(FileContent::new(
+            format!("OEBPS/{}", self.filename()?),
+            bytes.map_err(|e| self.context_error(e.into()))?,
+        ))
+    }
+
+    /// Wraps an error with the path of this resource, so the failing file can be identified.
+    fn context_error(&self, error: crate::Error) -> crate::Error {
+        crate::Error::Resource(self.to_string(), Box::new(error))
     }
 
     /// Extracts the final filename (e.g., `image.png`) from the full path reference.
@@ -116,13 +177,31 @@ impl<'a> Resource<'a> {
     /// Generates the **XML `<item>` tag** used in the package manifest (e.g., EPUB's `content.opf`).
     ///
     /// Returns `None` if the filename cannot be extracted.
-    pub(crate) fn as_manifest_xml(&self) -> Option<String> {
+    pub(crate) fn as_manifest_xml(&self, ids: &dyn IdGenerator) -> Option<String> {
+        let filename = self.filename().ok()?;
         Some(format!(
-            r#"<item id="{filename}" href="{filename}" media-type="{media_type}"/>"#,
-            filename = self.filename().ok()?,
+            r#"<item id="{id}" href="{filename}" media-type="{media_type}"/>"#,
+            id = ids.item_id(&filename),
             media_type = self.media_type()
         ))
     }
+
+    /// Generates the manifest **XML `<item>` tag** declaring another resource as its `fallback`.
+    ///
+    /// Returns `None` if the filename of either resource cannot be extracted.
+    pub(crate) fn as_manifest_xml_with_fallback(
+        &self,
+        fallback: &Resource<'_>,
+        ids: &dyn IdGenerator,
+    ) -> Option<String> {
+        let filename = self.filename().ok()?;
+        Some(format!(
+            r#"<item id="{id}" href="{filename}" media-type="{media_type}" fallback="{fallback}"/>"#,
+            id = ids.item_id(&filename),
+            media_type = self.media_type(),
+            fallback = ids.item_id(&fallback.filename().ok()?)
+        ))
+    }
 }
 
 /// Implements display for [`Resource`], outputting the file's full path string.
@@ -141,6 +220,8 @@ mod tests {
     use tempfile::tempdir;
 
     use super::*;
+    use crate::epub::DefaultIds;
+    use crate::epub::{MemoryFileSystem, StdFileSystem};
     use std::fs;
     use std::io::Write;
     use std::path::PathBuf;
@@ -194,6 +275,18 @@ mod tests {
         assert_eq!(resource.filename().unwrap(), "font.ttf");
     }
 
+    #[test]
+    fn test_resource_as_manifest_xml_with_fallback() {
+        let svg = Resource::Image(Path::new("/images/map.svg"), ImageType::Svg);
+        let png = Resource::Image(Path::new("/images/map.png"), ImageType::Png);
+
+        assert_eq!(
+            svg.as_manifest_xml_with_fallback(&png, &DefaultIds)
+                .unwrap(),
+            r#"<item id="map.svg" href="map.svg" media-type="image/svg+xml" fallback="map.png"/>"#
+        );
+    }
+
     #[test]
     fn test_resource_file_content_success() {
         let temp_dir = tempfile::tempdir().unwrap();
@@ -203,7 +296,7 @@ mod tests {
 
         let resource = Resource::Image(&file_path, ImageType::Jpg);
 
-        let file_content = resource.file_content().unwrap();
+        let file_content = resource.file_content(None, &StdFileSystem).unwrap();
 
         let expected_filepath = format!("OEBPS/{}", filename);
         let expected_content = FileContent::new(expected_filepath, content);
@@ -216,12 +309,80 @@ mod tests {
         let non_existent_path = Path::new("non_existent_file_for_test.mp4");
         let resource = Resource::Video(non_existent_path);
 
-        match resource.file_content() {
-            Err(e) => assert!(matches!(e, crate::Error::Io(_))),
-            _ => panic!("Expected Io error when reading non-existent file"),
+        match resource.file_content(None, &StdFileSystem) {
+            Err(crate::Error::Resource(path, e)) => {
+                assert_eq!(path, "non_existent_file_for_test.mp4");
+                assert!(matches!(*e, crate::Error::Io(_)));
+            }
+            _ => panic!("Expected Resource error when reading non-existent file"),
         }
     }
 
+    #[test]
+    fn test_resource_size_hint() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let file_path = create_temp_file(temp_dir.path(), "font.otf", &[0; 16]);
+
+        assert_eq!(
+            Resource::Font(&file_path).size_hint(None, &StdFileSystem),
+            Some(16)
+        );
+        assert_eq!(
+            Resource::Font(Path::new("non_existent.otf")).size_hint(None, &StdFileSystem),
+            None
+        );
+    }
+
+    #[test]
+    fn test_resource_resolve_path() {
+        let base_dir = Path::new("/project");
+
+        let resource = Resource::Image(Path::new("img/fig1.png"), ImageType::Png);
+        assert_eq!(resource.resolve_path(None), Path::new("img/fig1.png"));
+        assert_eq!(
+            resource.resolve_path(Some(base_dir)),
+            Path::new("/project/img/fig1.png")
+        );
+
+        let resource = Resource::Font(Path::new("/fonts/font.otf"));
+        assert_eq!(
+            resource.resolve_path(Some(base_dir)),
+            Path::new("/fonts/font.otf")
+        );
+    }
+
+    #[test]
+    fn test_resource_file_content_base_dir() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir(temp_dir.path().join("img")).unwrap();
+        std::fs::write(temp_dir.path().join("img/fig1.png"), [1, 2, 3]).unwrap();
+
+        let resource = Resource::Image(Path::new("img/fig1.png"), ImageType::Png);
+
+        let file_content = resource
+            .file_content(Some(temp_dir.path()), &StdFileSystem)
+            .unwrap();
+        assert_eq!(file_content.filepath, "OEBPS/fig1.png");
+        assert_eq!(file_content.bytes, vec![1, 2, 3]);
+        assert!(resource.file_content(None, &StdFileSystem).is_err());
+    }
+
+    #[test]
+    fn test_resource_file_content_memory_file_system() {
+        let file_system = MemoryFileSystem::new().file("/book/img/fig1.png", vec![7, 8, 9]);
+        let resource = Resource::Image(Path::new("img/fig1.png"), ImageType::Png);
+
+        let file_content = resource
+            .file_content(Some(Path::new("/book")), &file_system)
+            .unwrap();
+        assert_eq!(file_content.bytes, vec![7, 8, 9]);
+        assert_eq!(
+            resource.size_hint(Some(Path::new("/book")), &file_system),
+            Some(3)
+        );
+        assert!(resource.check(None, &file_system).is_err());
+    }
+
     #[test]
     fn test_resource_display_trait() {
         let path = Path::new("/some/long/path/file.svg");
@@ -0,0 +1,187 @@
+use std::borrow::Cow;
+
+use crate::epub::{Epub, EpubBuilder};
+
+/// The default directory of the package files (contents, resources, OPF and NCX) in the archive.
+pub const DEFAULT_CONTENT_DIR: &str = "OEBPS";
+
+/// The default filename of the OPF package document.
+pub const DEFAULT_PACKAGE_FILENAME: &str = "content.opf";
+
+impl Epub<'_> {
+    /// Gets the directory of the package files in the archive, defaulting to [`DEFAULT_CONTENT_DIR`].
+    pub fn content_dir(&self) -> &str {
+        self.rootfile
+            .as_ref()
+            .map_or(DEFAULT_CONTENT_DIR, |(content_dir, _)| content_dir)
+    }
+
+    /// Gets the filename of the OPF package document, defaulting to [`DEFAULT_PACKAGE_FILENAME`].
+    pub fn package_filename(&self) -> &str {
+        self.rootfile
+            .as_ref()
+            .map_or(DEFAULT_PACKAGE_FILENAME, |(_, package_filename)| {
+                package_filename
+            })
+    }
+
+    /// Gets the path of a generated file in the archive: the files are generated in [`DEFAULT_CONTENT_DIR`]
+    /// and relocated here to the configured content directory. Any other path is kept.
+    pub(crate) fn archive_path<'p>(&self, filepath: &'p str) -> Cow<'p, str> {
+        let content_dir = self.content_dir();
+        match filepath
+            .strip_prefix(DEFAULT_CONTENT_DIR)
+            .and_then(|path| path.strip_prefix('/'))
+        {
+            Some(path) if content_dir != DEFAULT_CONTENT_DIR => {
+                Cow::Owned(format!("{content_dir}/{path}"))
+            }
+            _ => Cow::Borrowed(filepath),
+        }
+    }
+
+    /// Checks that the content directory and the package filename are valid.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] describing the invalid part of the rootfile.
+    pub(crate) fn check_rootfile(&self) -> crate::Result {
+        let content_dir = self.content_dir();
+        if content_dir.is_empty()
+            || content_dir.starts_with('/')
+            || content_dir.ends_with('/')
+            || content_dir
+                .split('/')
+                .any(|part| matches!(part, "" | "." | ".."))
+            || content_dir.eq_ignore_ascii_case("META-INF")
+        {
+            return Err(crate::Error::Validation(format!(
+                "invalid content directory '{content_dir}'"
+            )));
+        }
+
+        let package_filename = self.package_filename();
+        if !package_filename.ends_with(".opf") || package_filename.contains('/') {
+            return Err(crate::Error::Validation(format!(
+                "invalid package filename '{package_filename}'"
+            )));
+        }
+        Ok(())
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Sets the **rootfile** of the book: the directory of the package files in the archive (defaults to
+    /// `OEBPS`) and the filename of the OPF package document (defaults to `content.opf`), as declared in
+    /// `META-INF/container.xml`.
+    ///
+    /// ```rust
+    /// use liber::epub::{EpubBuilder, MetadataBuilder};
+    ///
+    /// // The layout of many EPUB 3 samples: `EPUB/package.opf`
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+    ///     .rootfile("EPUB", "package.opf");
+    /// ```
+    ///
+    /// Creating the file fails if the directory is not a relative path inside the archive
+    /// or the filename does not end with `.opf`.
+    pub fn rootfile<D: Into<String>, F: Into<String>>(
+        mut self,
+        content_dir: D,
+        package_filename: F,
+    ) -> Self {
+        self.0.rootfile = Some((content_dir.into(), package_filename.into()));
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_archive_path() {
+        let builder = EpubBuilder::new(MetadataBuilder::title("Title").build());
+        assert_eq!(builder.0.archive_path("OEBPS/c01.xhtml"), "OEBPS/c01.xhtml");
+
+        let builder = builder.rootfile("EPUB", "package.opf");
+        assert_eq!(builder.0.archive_path("OEBPS/c01.xhtml"), "EPUB/c01.xhtml");
+        assert_eq!(
+            builder.0.archive_path("OEBPSX/c01.xhtml"),
+            "OEBPSX/c01.xhtml"
+        );
+        assert_eq!(builder.0.archive_path("mimetype"), "mimetype");
+    }
+
+    #[test]
+    fn test_check_rootfile() {
+        let builder = |content_dir: &str, package_filename: &str| {
+            EpubBuilder::new(MetadataBuilder::title("Title").build())
+                .rootfile(content_dir, package_filename)
+        };
+        assert!(builder("EPUB", "package.opf").0.check_rootfile().is_ok());
+        assert!(
+            builder("book/EPUB", "package.opf")
+                .0
+                .check_rootfile()
+                .is_ok()
+        );
+        assert!(builder("", "package.opf").0.check_rootfile().is_err());
+        assert!(builder("/EPUB", "package.opf").0.check_rootfile().is_err());
+        assert!(
+            builder("../EPUB", "package.opf")
+                .0
+                .check_rootfile()
+                .is_err()
+        );
+        assert!(
+            builder("META-INF", "package.opf")
+                .0
+                .check_rootfile()
+                .is_err()
+        );
+        assert!(builder("EPUB", "package.xml").0.check_rootfile().is_err());
+    }
+
+    #[test]
+    fn test_rootfile_on_create() {
+        let mut output = Vec::new();
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .stylesheet(b"p {}")
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .rootfile("EPUB", "package.opf")
+            .integrity_manifest(true)
+            .create(&mut output)
+            .unwrap();
+
+        let mut archive = zip::ZipArchive::new(std::io::Cursor::new(output)).unwrap();
+        let names = archive.file_names().map(String::from).collect::<Vec<_>>();
+        for name in [
+            "EPUB/package.opf",
+            "EPUB/toc.ncx",
+            "EPUB/style.css",
+            "EPUB/c01.xhtml",
+        ] {
+            assert!(names.iter().any(|n| n == name), "missing {name}");
+        }
+        assert!(!names.iter().any(|name| name.starts_with("OEBPS/")));
+
+        let mut container = String::new();
+        std::io::Read::read_to_string(
+            &mut archive.by_name("META-INF/container.xml").unwrap(),
+            &mut container,
+        )
+        .unwrap();
+        assert!(container.contains(r#"<rootfile full-path="EPUB/package.opf""#));
+
+        let mut checksums = String::new();
+        std::io::Read::read_to_string(
+            &mut archive.by_name("META-INF/checksums.sha256").unwrap(),
+            &mut checksums,
+        )
+        .unwrap();
+        assert!(checksums.contains("  EPUB/c01.xhtml\n"));
+    }
+}
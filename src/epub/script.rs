@@ -0,0 +1,210 @@
+use std::borrow::Cow;
+
+use crate::epub::IdGenerator;
+
+/// The filename of the bundle joining every script, when bundling is enabled.
+pub const SCRIPT_BUNDLE_FILENAME: &str = "scripts.js";
+
+/// A **script resource** (JavaScript), referenced from the content bodies by its filename.
+#[derive(Debug, Clone)]
+pub(crate) struct Script<'a> {
+    /// The filename of the script inside the book (e.g., `quiz.js`).
+    pub filename: String,
+    /// The source of the script.
+    pub source: Cow<'a, [u8]>,
+}
+
+impl Script<'_> {
+    /// Generates the XML `<item>` tag of the script, used in the manifest section.
+    pub(crate) fn as_manifest_xml(&self, ids: &dyn IdGenerator) -> String {
+        format!(
+            r#"<item id="{id}" href="{filename}" media-type="application/javascript"/>"#,
+            id = ids.item_id(&self.filename),
+            filename = self.filename
+        )
+    }
+}
+
+/// Joins the scripts (in order) into a single bundle, separating them so each one ends its last statement.
+pub(crate) fn bundle<'a>(scripts: &[Script<'_>]) -> Script<'a> {
+    let mut source = Vec::new();
+    for script in scripts {
+        source.extend_from_slice(format!("/* {} */\n", script.filename).as_bytes());
+        source.extend_from_slice(script.source.trim_ascii_end());
+        source.extend_from_slice(b"\n;\n");
+    }
+
+    Script {
+        filename: SCRIPT_BUNDLE_FILENAME.to_string(),
+        source: Cow::Owned(source),
+    }
+}
+
+/// **Minifies** a script conservatively: comments, indentation, trailing whitespace and empty lines are removed,
+/// while line breaks are kept (so automatic semicolon insertion is not affected) and strings are left untouched.
+pub(crate) fn minify(js: &str) -> String {
+    let chars = js.chars().collect::<Vec<_>>();
+    let mut minified = String::with_capacity(js.len());
+    let mut quote: Option<char> = None;
+    let mut escaped = false;
+    let mut line_start = true;
+    let mut i = 0;
+
+    while i < chars.len() {
+        let c = chars[i];
+        let next = chars.get(i + 1).copied();
+
+        if let Some(q) = quote {
+            minified.push(c);
+            if escaped {
+                escaped = false;
+            } else if c == '\\' {
+                escaped = true;
+            } else if c == q {
+                quote = None;
+            }
+            i += 1;
+            continue;
+        }
+
+        match c {
+            '/' if next == Some('/') && !minified.ends_with('\\') => {
+                while i < chars.len() && chars[i] != '\n' {
+                    i += 1;
+                }
+                continue;
+            }
+            '/' if next == Some('*') => {
+                i += 2;
+                while i < chars.len() && !(chars[i] == '*' && chars.get(i + 1) == Some(&'/')) {
+                    i += 1;
+                }
+                i += 2;
+                continue;
+            }
+            '\n' => {
+                let trimmed = minified.trim_end_matches([' ', '\t', '\r']).len();
+                minified.truncate(trimmed);
+                if !line_start {
+                    minified.push('\n');
+                }
+                line_start = true;
+            }
+            ' ' | '\t' | '\r' if line_start => {}
+            _ => {
+                if matches!(c, '"' | '\'' | '`') {
+                    quote = Some(c);
+                }
+                line_start = false;
+                minified.push(c);
+            }
+        }
+        i += 1;
+    }
+
+    let trimmed = minified.trim_end().len();
+    minified.truncate(trimmed);
+    minified
+}
+
+/// Replaces the `<script>` elements of a body referencing any of `filenames` by a single reference to the bundle,
+/// placed where the first one was.
+///
+/// Returns `None` if the body references none of the scripts.
+pub(crate) fn reference_bundle(body: &str, filenames: &[String]) -> Option<String> {
+    let mut rewritten = String::with_capacity(body.len());
+    let mut rest = body;
+    let mut replaced = false;
+
+    while let Some(start) = rest.find("<script") {
+        let tag_end = start + rest[start..].find('>')? + 1;
+        let tag = &rest[start..tag_end];
+        let src = tag
+            .find(r#"src=""#)
+            .map(|position| &tag[position + 5..])
+            .and_then(|src| src.find('"').map(|end| &src[..end]));
+
+        let end = if tag.ends_with("/>") {
+            tag_end
+        } else {
+            tag_end + rest[tag_end..].find("</script>")? + "</script>".len()
+        };
+
+        rewritten.push_str(&rest[..start]);
+        if src.is_some_and(|src| filenames.iter().any(|filename| filename == src)) {
+            if !replaced {
+                rewritten.push_str(&format!(
+                    r#"<script type="text/javascript" src="{SCRIPT_BUNDLE_FILENAME}"></script>"#
+                ));
+                replaced = true;
+            }
+        } else {
+            rewritten.push_str(&rest[start..end]);
+        }
+        rest = &rest[end..];
+    }
+
+    rewritten.push_str(rest);
+    replaced.then_some(rewritten)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_minify() {
+        let js = r#"// Quiz
+function check(answer) {
+    /* compare
+       the answer */
+    var url = "http://example.com"; // trailing
+    return answer === 'a // b';
+}
+"#;
+        assert_eq!(
+            minify(js),
+            r#"function check(answer) {
+var url = "http://example.com";
+return answer === 'a // b';
+}"#
+        );
+    }
+
+    #[test]
+    fn test_bundle() {
+        let scripts = [
+            Script {
+                filename: "a.js".to_string(),
+                source: Cow::Borrowed(b"var a = 1\n"),
+            },
+            Script {
+                filename: "b.js".to_string(),
+                source: Cow::Borrowed(b"var b = 2"),
+            },
+        ];
+        let bundle = bundle(&scripts);
+        assert_eq!(bundle.filename, SCRIPT_BUNDLE_FILENAME);
+        assert_eq!(
+            bundle.source.as_ref(),
+            b"/* a.js */\nvar a = 1\n;\n/* b.js */\nvar b = 2\n;\n"
+        );
+    }
+
+    #[test]
+    fn test_reference_bundle() {
+        let filenames = ["a.js".to_string(), "b.js".to_string()];
+        assert_eq!(
+            reference_bundle(
+                r#"<body><script src="a.js"></script><p>Text</p><script src="b.js"/><script>run()</script></body>"#,
+                &filenames
+            )
+            .unwrap(),
+            r#"<body><script type="text/javascript" src="scripts.js"></script><p>Text</p><script>run()</script></body>"#
+        );
+        assert_eq!(
+            reference_bundle(r#"<body><script src="c.js"/></body>"#, &filenames),
+            None
+        );
+    }
+}
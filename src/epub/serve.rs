@@ -0,0 +1,260 @@
+use std::io::Write;
+
+use sha2::{Digest, Sha256};
+
+use crate::epub::{EpubBuilder, slugify};
+
+/// The media type of an EPUB file, sent as its `Content-Type`.
+pub const EPUB_MEDIA_TYPE: &str = "application/epub+zip";
+
+/// A book ready to be **served over HTTP** (see [`EpubBuilder::serve`]): the headers of the response, known
+/// before its body is generated, and the builder streaming the body.
+///
+/// Any HTTP server (e.g., `axum`, `actix-web` or `hyper`) sends it by copying the [`EpubResponse::headers`]
+/// and streaming the body with [`EpubResponse::write_body`] (e.g., with chunked transfer encoding, as its
+/// length is unknown in advance).
+///
+/// ```rust
+/// use liber::epub::{ContentBuilder, EpubBuilder, MetadataBuilder, ReferenceType};
+///
+/// let response = EpubBuilder::new(MetadataBuilder::title("The Sea").build())
+///     .add_content(ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build())
+///     .serve()
+///     .unwrap();
+///
+/// let headers = response.headers();
+/// assert_eq!(headers[0], ("Content-Type", "application/epub+zip".to_string()));
+/// assert_eq!(headers[1], ("Content-Disposition", r#"attachment; filename="the-sea.epub""#.to_string()));
+///
+/// if !response.is_not_modified(Some(r#""stale""#)) {
+///     let mut body = Vec::new();
+///     response.write_body(&mut body).unwrap();
+/// }
+/// ```
+#[derive(Debug, Clone)]
+pub struct EpubResponse<'a> {
+    /// The builder of the book, generating the body.
+    builder: EpubBuilder<'a>,
+    /// The filename offered to the client (the slug of the title, e.g., `the-sea.epub`).
+    pub filename: String,
+    /// The quoted `ETag` of the file: the SHA-256 digest of the generated archive, so builds from the same
+    /// inputs (e.g., with a fixed identifier and date) share it.
+    pub etag: String,
+}
+
+impl<'a> EpubResponse<'a> {
+    /// Gets the `(name, value)` headers of the response: `Content-Type`, `Content-Disposition` and `ETag`.
+    pub fn headers(&self) -> Vec<(&'static str, String)> {
+        vec![
+            ("Content-Type", EPUB_MEDIA_TYPE.to_string()),
+            (
+                "Content-Disposition",
+                format!(r#"attachment; filename="{}""#, self.filename),
+            ),
+            ("ETag", self.etag.clone()),
+        ]
+    }
+
+    /// Checks the `If-None-Match` header of a request against the `ETag`: when `true`, the client copy is
+    /// up to date and `304 Not Modified` should be answered without generating the body.
+    pub fn is_not_modified(&self, if_none_match: Option<&str>) -> bool {
+        if_none_match.is_some_and(|if_none_match| {
+            if_none_match.split(',').any(|etag| {
+                let etag = etag.trim();
+                etag == "*" || etag.trim_start_matches("W/") == self.etag
+            })
+        })
+    }
+
+    /// Generates the book, **streaming** it to `writer` as the body of the response
+    /// (see [`EpubBuilder::create`]).
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    pub fn write_body<W>(self, writer: &mut W) -> crate::Result
+    where
+        W: Write + Send,
+    {
+        self.builder.create(writer)
+    }
+
+    /// **Asynchronously** generates the book, streaming it to `writer` as the body of the response
+    /// (see [`EpubBuilder::async_create`]).
+    ///
+    /// This method is only available when the **`async` feature** is enabled.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if there are any I/O issues or errors during XML generation.
+    #[cfg(feature = "async")]
+    pub async fn async_write_body<W>(self, writer: &mut W) -> crate::Result
+    where
+        W: tokio::io::AsyncWrite + Unpin + Send,
+    {
+        self.builder.async_create(writer).await
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Prepares the book to be **served over HTTP** as an [`EpubResponse`], with the `application/epub+zip`
+    /// content type, a download filename and an `ETag`: a `304 Not Modified` can be answered before the
+    /// body is generated.
+    ///
+    /// The `ETag` is the digest of the archive itself, so it reflects every setting (including the file
+    /// hooks, transformers and image processing, which cannot be compared otherwise). To compute it, the book
+    /// is generated **twice**: once streamed into the digest (holding one file at a time, as
+    /// [`EpubBuilder::create`] does) and once more by [`EpubResponse::write_body`], trading CPU for an exact
+    /// `ETag` without buffering the archive. As the archive entries have fixed timestamps, the same inputs
+    /// generate the same bytes.
+    ///
+    /// [`crate::epub::MetadataBuilder`] defaults the identifier to a random UUID and the date to the current
+    /// time, so the `ETag` only matches across requests when both are set (e.g., from the stored book).
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if the book cannot be generated (e.g., a file cannot be read).
+    pub fn serve(self) -> crate::Result<EpubResponse<'a>> {
+        let slug = slugify(&self.0.metadata.title);
+        let filename = if slug.is_empty() {
+            "book.epub".to_string()
+        } else {
+            format!("{slug}.epub")
+        };
+        let etag = format!(r#""{}""#, self.fingerprint()?);
+
+        Ok(EpubResponse {
+            builder: self,
+            filename,
+            etag,
+        })
+    }
+}
+
+/// A writer feeding a SHA-256 digest, so the archive is hashed without being held in memory.
+struct DigestWriter(Sha256);
+
+impl Write for DigestWriter {
+    fn write(&mut self, buf: &[u8]) -> std::io::Result<usize> {
+        self.0.update(buf);
+        Ok(buf.len())
+    }
+
+    fn flush(&mut self) -> std::io::Result<()> {
+        Ok(())
+    }
+}
+
+impl EpubBuilder<'_> {
+    /// Gets the hex SHA-256 digest of the archive, generating a copy of the book into the digest.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Result`] if the book cannot be generated.
+    fn fingerprint(&self) -> crate::Result<String> {
+        let mut digest = DigestWriter(Sha256::new());
+        self.clone().create(&mut digest)?;
+
+        Ok(digest
+            .0
+            .finalize()
+            .iter()
+            .map(|byte| format!("{byte:02x}"))
+            .collect())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::epub::{
+        ContentBuilder, Identifier, ImageType, MemoryFileSystem, MetadataBuilder, ReferenceType,
+        Resource,
+    };
+
+    fn builder<'a>(title: &str) -> EpubBuilder<'a> {
+        EpubBuilder::new(
+            MetadataBuilder::title(title)
+                .identifier(Identifier::UUID("1".to_string()))
+                .date(chrono::DateTime::UNIX_EPOCH)
+                .build(),
+        )
+        .add_content(
+            ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+        )
+    }
+
+    #[test]
+    fn test_serve() {
+        let response = builder("¿Qué?").serve().unwrap();
+        assert_eq!(response.filename, "que.epub");
+        assert_eq!(response.headers()[2], ("ETag", response.etag.clone()));
+
+        let mut body = Vec::new();
+        response.write_body(&mut body).unwrap();
+        assert!(!body.is_empty());
+
+        assert_eq!(builder("!").serve().unwrap().filename, "book.epub");
+    }
+
+    #[test]
+    fn test_serve_etag() {
+        let first = builder("Title").serve().unwrap();
+        let second = builder("Title").serve().unwrap();
+        assert_eq!(first.etag, second.etag);
+        assert_ne!(first.etag, builder("Other").serve().unwrap().etag);
+
+        let mut first_body = Vec::new();
+        first.write_body(&mut first_body).unwrap();
+        let mut second_body = Vec::new();
+        second.write_body(&mut second_body).unwrap();
+        assert_eq!(first_body, second_body);
+
+        let with_image = |bytes: &[u8]| {
+            builder("Title")
+                .add_resource(Resource::Image(Path::new("a.png"), ImageType::Png))
+                .file_system(MemoryFileSystem::new().file("a.png", bytes))
+                .serve()
+                .unwrap()
+                .etag
+        };
+        assert_eq!(with_image(b"one"), with_image(b"one"));
+        assert_ne!(with_image(b"one"), with_image(b"two"));
+
+        let missing = builder("Title")
+            .add_resource(Resource::Image(Path::new("missing.png"), ImageType::Png));
+        assert!(missing.serve().is_err());
+    }
+
+    #[test]
+    fn test_serve_etag_transformer() {
+        let plain = builder("Title").serve().unwrap();
+        let transformed = builder("Title")
+            .add_transformer(|_, body| Ok(body.replace("<body/>", "<body><p>Two</p></body>")))
+            .serve()
+            .unwrap();
+        assert_ne!(plain.etag, transformed.etag);
+        assert!(!transformed.is_not_modified(Some(&plain.etag)));
+
+        let hooked = builder("Title")
+            .add_file_hook(|_, bytes| Ok(bytes.to_ascii_uppercase()))
+            .serve()
+            .unwrap();
+        assert_ne!(plain.etag, hooked.etag);
+
+        let etag = transformed.etag.clone();
+        let mut body = Vec::new();
+        transformed.write_body(&mut body).unwrap();
+        assert_eq!(etag, format!(r#""{:x}""#, Sha256::digest(&body)));
+    }
+
+    #[test]
+    fn test_is_not_modified() {
+        let response = builder("Title").serve().unwrap();
+        let etag = response.etag.clone();
+
+        assert!(!response.is_not_modified(None));
+        assert!(!response.is_not_modified(Some(r#""other""#)));
+        assert!(response.is_not_modified(Some(&etag)));
+        assert!(response.is_not_modified(Some(&format!(r#""other", W/{etag}"#))));
+        assert!(response.is_not_modified(Some("*")));
+    }
+}
@@ -0,0 +1,286 @@
+use std::fmt::Display;
+
+use crate::epub::{Epub, Resource};
+
+/// A file (or the whole book) exceeding a [`SizeBudget`].
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SizeViolation {
+    /// The path of the offending file inside the archive, or `None` for the whole book.
+    pub filepath: Option<String>,
+    /// The size in bytes of the file or book.
+    pub size: u64,
+    /// The limit in bytes exceeded.
+    pub limit: u64,
+    /// A suggestion on how to fix the offending resource (e.g., splitting long audio).
+    pub hint: Option<&'static str>,
+}
+
+/// Displays the violation like `'OEBPS/c01.xhtml' has 400000 bytes (limit 300000)`,
+/// followed by the hint if any.
+impl Display for SizeViolation {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self.filepath {
+            Some(ref filepath) => write!(f, "'{filepath}'"),
+            None => write!(f, "the book"),
+        }?;
+        write!(f, " has {} bytes (limit {})", self.size, self.limit)?;
+        if let Some(hint) = self.hint {
+            write!(f, "; {hint}")?;
+        }
+        Ok(())
+    }
+}
+
+/// Configurable **size budgets** (e.g., retailer limits), checked while the file is created.
+///
+/// The limits apply to the whole archive (as written, so after compression), to every spine item
+/// (the content XHTML files, uncompressed) and to every resource (measured through its file metadata,
+/// before anything is written). Oversized resources are not split nor downscaled: the violation names
+/// the asset and suggests how to fix it (e.g., splitting long audio into tracks). By default an exceeded budget fails the creation with a
+/// [`crate::Error::Validation`] listing the offending files, unless a warning handler is set.
+///
+/// The archive is not buffered to be checked: its bytes are counted as they are streamed, so an exceeded
+/// total or spine item budget fails the creation once the archive has been written, and the output must
+/// be discarded (as [`crate::epub::EpubBuilder::create_file`] does). Resources are checked before
+/// anything is written.
+///
+/// ```rust
+/// use liber::epub::SizeBudget;
+///
+/// let budget = SizeBudget::new()
+///     .total(650 * 1024 * 1024)
+///     .per_item(300 * 1024)
+///     .per_resource(50 * 1024 * 1024)
+///     .warn(|violations| violations.iter().for_each(|violation| eprintln!("{violation}")));
+/// ```
+#[derive(Debug, Clone, Copy, Default)]
+pub struct SizeBudget {
+    /// The maximum size in bytes of the whole archive.
+    total: Option<u64>,
+    /// The maximum size in bytes of every spine item.
+    per_item: Option<u64>,
+    /// The maximum size in bytes of every resource (cover, images, fonts, audio, video).
+    per_resource: Option<u64>,
+    /// The handler receiving the violations as warnings, instead of failing the creation.
+    warning: Option<fn(&[SizeViolation])>,
+}
+
+impl SizeBudget {
+    /// Creates a budget without limits.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Creates the budget of **Amazon KDP**, which rejects files over 650 MB.
+    pub fn kindle() -> Self {
+        Self::new().total(650 * 1000 * 1000)
+    }
+
+    /// Sets the maximum size in bytes of the **whole archive**.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn total(mut self, total: u64) -> Self {
+        self.total = Some(total);
+        self
+    }
+
+    /// Sets the maximum size in bytes of **every spine item** (e.g., the 300 KB limit of older ADE readers).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn per_item(mut self, per_item: u64) -> Self {
+        self.per_item = Some(per_item);
+        self
+    }
+
+    /// Sets the maximum size in bytes of **every resource** (e.g., long audio or high resolution images).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn per_resource(mut self, per_resource: u64) -> Self {
+        self.per_resource = Some(per_resource);
+        self
+    }
+
+    /// Reports the violations as **warnings** to `handler`, instead of failing the creation.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn warn(mut self, handler: fn(&[SizeViolation])) -> Self {
+        self.warning = Some(handler);
+        self
+    }
+
+    /// Checks the `(path, size)` of the spine items and the total size of the archive against the budget.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] listing the violations, unless a warning handler is set.
+    pub(crate) fn check(&self, items: &[(String, u64)], total: u64) -> crate::Result {
+        let mut violations = self
+            .per_item
+            .map(|limit| {
+                items
+                    .iter()
+                    .filter(|(_, size)| *size > limit)
+                    .map(|(filepath, size)| SizeViolation {
+                        filepath: Some(filepath.clone()),
+                        size: *size,
+                        limit,
+                        hint: None,
+                    })
+                    .collect::<Vec<_>>()
+            })
+            .unwrap_or_default();
+
+        if let Some(limit) = self.total.filter(|&limit| total > limit) {
+            violations.push(SizeViolation {
+                filepath: None,
+                size: total,
+                limit,
+                hint: None,
+            });
+        }
+
+        self.report(&violations)
+    }
+
+    /// Checks the size of every resource of a book against the budget, **without reading them**.
+    ///
+    /// Resources whose metadata cannot be read are skipped (they are reported when written).
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] listing the oversized resources with a suggestion
+    /// on how to fix each one, unless a warning handler is set.
+    pub(crate) fn check_resources(&self, epub: &Epub<'_>) -> crate::Result {
+        let Some(limit) = self.per_resource else {
+            return Ok(());
+        };
+
+        let violations = epub
+            .cover_image
+            .iter()
+            .chain(epub.cover_thumbnail.iter())
+            .chain(epub.resources.iter().flatten())
+            .chain(
+                epub.fallbacks
+                    .iter()
+                    .flatten()
+                    .flat_map(|(image, fallback)| [image, fallback]),
+            )
+            .filter_map(|resource| {
+                let size = resource
+                    .size_hint(epub.base_dir, epub.file_system())
+                    .filter(|&size| size > limit)?;
+                Some(SizeViolation {
+                    filepath: Some(resource.to_string()),
+                    size,
+                    limit,
+                    hint: Some(hint(resource)),
+                })
+            })
+            .collect::<Vec<_>>();
+
+        self.report(&violations)
+    }
+
+    /// Passes the violations to the warning handler, or fails listing them if there is none.
+    fn report(&self, violations: &[SizeViolation]) -> crate::Result {
+        match self.warning {
+            _ if violations.is_empty() => Ok(()),
+            Some(handler) => {
+                handler(violations);
+                Ok(())
+            }
+            None => Err(crate::Error::Validation(format!(
+                "size budget exceeded: {}",
+                violations
+                    .iter()
+                    .map(SizeViolation::to_string)
+                    .collect::<Vec<_>>()
+                    .join(", ")
+            ))),
+        }
+    }
+}
+
+/// Suggests how to bring an oversized resource under the budget, depending on its type.
+fn hint(resource: &Resource<'_>) -> &'static str {
+    match resource {
+        Resource::Audio(_) => {
+            "split it into shorter tracks (e.g., one per chapter) and add each one"
+        }
+        Resource::Video(_) => "re-encode it at a lower bitrate or split it into shorter clips",
+        Resource::Image(..) => "downscale or recompress it (e.g., a lower JPEG quality)",
+        Resource::Font(_) => "subset it to the glyphs used in the book",
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::epub::{EpubBuilder, ImageType, MemoryFileSystem, MetadataBuilder};
+
+    #[test]
+    fn test_size_budget_check() {
+        let items = vec![
+            ("OEBPS/c01.xhtml".to_string(), 100),
+            ("OEBPS/c02.xhtml".to_string(), 500),
+        ];
+
+        assert!(SizeBudget::new().check(&items, 10_000).is_ok());
+        assert!(
+            SizeBudget::new()
+                .per_item(500)
+                .total(600)
+                .check(&items, 600)
+                .is_ok()
+        );
+
+        assert_eq!(
+            SizeBudget::new()
+                .per_item(200)
+                .total(550)
+                .check(&items, 600)
+                .unwrap_err()
+                .to_string(),
+            "Validation error: size budget exceeded: 'OEBPS/c02.xhtml' has 500 bytes (limit 200), the book has 600 bytes (limit 550)"
+        );
+
+        assert!(
+            SizeBudget::new()
+                .total(1)
+                .warn(|violations| assert_eq!(violations.len(), 1))
+                .check(&items, 600)
+                .is_ok()
+        );
+    }
+
+    #[test]
+    fn test_size_budget_check_resources() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .file_system(
+                MemoryFileSystem::new()
+                    .file("cover.jpg", vec![0; 16])
+                    .file("book.mp3", vec![0; 4096]),
+            )
+            .cover_image(Path::new("cover.jpg"), ImageType::Jpg)
+            .add_resource(Resource::Audio(Path::new("book.mp3")))
+            .add_resource(Resource::Audio(Path::new("missing.mp3")))
+            .0;
+
+        assert!(SizeBudget::new().check_resources(&epub).is_ok());
+        assert!(
+            SizeBudget::new()
+                .per_resource(4096)
+                .check_resources(&epub)
+                .is_ok()
+        );
+        assert_eq!(
+            SizeBudget::new()
+                .per_resource(1024)
+                .check_resources(&epub)
+                .unwrap_err()
+                .to_string(),
+            "Validation error: size budget exceeded: 'book.mp3' has 4096 bytes (limit 1024); split it into shorter tracks (e.g., one per chapter) and add each one"
+        );
+    }
+}
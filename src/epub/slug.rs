@@ -0,0 +1,206 @@
+use std::collections::HashSet;
+
+use crate::{
+    epub::{
+        ALSO_BY_FILENAME, CALL_TO_ACTION_FILENAME, CREDITS_FILENAME, DESCRIPTIONS_FILENAME, Epub,
+        EpubBuilder, ISSUE_CONTENTS_FILENAME, LICENSE_FILENAME, MASTHEAD_FILENAME,
+    },
+    markup::{
+        ABBREVIATIONS_FILENAME, BIBLIOGRAPHY_FILENAME, CHARACTERS_FILENAME, ENDNOTES_FILENAME,
+        INDEX_FILENAME, LOI_FILENAME,
+    },
+};
+
+/// The filenames of the generated pages, never used for slugified filenames.
+const GENERATED_FILENAMES: [&str; 13] = [
+    ABBREVIATIONS_FILENAME,
+    ALSO_BY_FILENAME,
+    CALL_TO_ACTION_FILENAME,
+    BIBLIOGRAPHY_FILENAME,
+    CHARACTERS_FILENAME,
+    CREDITS_FILENAME,
+    DESCRIPTIONS_FILENAME,
+    ENDNOTES_FILENAME,
+    INDEX_FILENAME,
+    ISSUE_CONTENTS_FILENAME,
+    LICENSE_FILENAME,
+    LOI_FILENAME,
+    MASTHEAD_FILENAME,
+];
+
+/// Gets the **slug** of a text: lowercase ASCII letters and digits, with words joined by hyphens
+/// (e.g., `"the-sea"` for `"The Sea!"`).
+///
+/// Latin letters with diacritics and ligatures are transliterated (e.g., `"cafe-a-la-creme"` for
+/// `"Café à la crème"`); any other character is a word separator.
+///
+/// ```rust
+/// use liber::epub::slugify;
+///
+/// assert_eq!(slugify("Chapter 1: The Sea"), "chapter-1-the-sea");
+/// assert_eq!(slugify("Straße über Œuvres"), "strasse-uber-oeuvres");
+/// ```
+#[must_use]
+pub fn slugify(text: &str) -> String {
+    let mut slug = String::with_capacity(text.len());
+    for c in text.chars().flat_map(char::to_lowercase) {
+        match transliterate(c) {
+            Some(ascii) => slug.push_str(ascii),
+            None if c.is_ascii_alphanumeric() => slug.push(c),
+            None => {
+                if !slug.is_empty() && !slug.ends_with('-') {
+                    slug.push('-');
+                }
+            }
+        }
+    }
+
+    slug.truncate(slug.trim_end_matches('-').len());
+    slug
+}
+
+/// Gets the ASCII transliteration of a lowercase Latin letter with diacritics or ligature.
+fn transliterate(c: char) -> Option<&'static str> {
+    Some(match c {
+        'à' | 'á' | 'â' | 'ã' | 'ä' | 'å' | 'ā' | 'ă' | 'ą' => "a",
+        'æ' => "ae",
+        'ç' | 'ć' | 'č' => "c",
+        'ď' | 'đ' | 'ð' => "d",
+        'è' | 'é' | 'ê' | 'ë' | 'ē' | 'ė' | 'ę' | 'ě' => "e",
+        'ğ' => "g",
+        'ì' | 'í' | 'î' | 'ï' | 'ī' | 'į' | 'ı' => "i",
+        'ł' | 'ľ' => "l",
+        'ñ' | 'ń' | 'ň' => "n",
+        'ò' | 'ó' | 'ô' | 'õ' | 'ö' | 'ø' | 'ō' | 'ő' => "o",
+        'œ' => "oe",
+        'ř' => "r",
+        'ś' | 'š' | 'ş' => "s",
+        'ß' => "ss",
+        'ť' | 'ţ' => "t",
+        'þ' => "th",
+        'ù' | 'ú' | 'û' | 'ü' | 'ū' | 'ů' | 'ű' | 'ų' => "u",
+        'ý' | 'ÿ' => "y",
+        'ź' | 'ż' | 'ž' => "z",
+        _ => return None,
+    })
+}
+
+/// A registry of the slugs in use, deduplicating new ones with numeric suffixes (e.g., `the-sea-2`).
+#[derive(Debug, Default)]
+pub(crate) struct Slugs(HashSet<String>);
+
+impl Slugs {
+    /// Registers a slug already in use (e.g., an explicit filename).
+    pub(crate) fn reserve<S: Into<String>>(&mut self, slug: S) {
+        self.0.insert(slug.into());
+    }
+
+    /// Gets a slug not in use, made from `slug` followed by `suffix` (e.g., `.xhtml`): `slug` itself or,
+    /// if taken, `slug-2`, `slug-3` and so on. The slug returned is registered.
+    pub(crate) fn unique(&mut self, slug: &str, suffix: &str) -> String {
+        let mut candidate = format!("{slug}{suffix}");
+        let mut number = 1;
+        while self.0.contains(&candidate) {
+            number += 1;
+            candidate = format!("{slug}-{number}{suffix}");
+        }
+        self.0.insert(candidate.clone());
+        candidate
+    }
+}
+
+impl Epub<'_> {
+    /// Sets the filename of every content without an explicit filename to the slug of its title,
+    /// if enabled (see [`EpubBuilder::slug_filenames`]).
+    pub(crate) fn assign_slug_filenames(&mut self) {
+        if !self.slug_filenames {
+            return;
+        }
+
+        let mut slugs = Slugs::default();
+        for filename in GENERATED_FILENAMES {
+            slugs.reserve(filename);
+        }
+        for content in self.contents.iter().flatten() {
+            content.reserve_filenames(&mut slugs);
+        }
+
+        let mut number = 0;
+        for content in self.contents.iter_mut().flatten() {
+            content.assign_slug_filenames(&mut number, &mut slugs);
+        }
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Derives the **filenames of the contents from their titles** (e.g., `the-sea.xhtml` for "The Sea")
+    /// instead of `cNN.xhtml`, so exploded books are easier to read and debug.
+    ///
+    /// Titles are slugified (see [`slugify`]) and repeated ones get a numeric suffix (e.g., `the-sea-2.xhtml`).
+    /// Contents with an explicit filename (see [`crate::epub::ContentBuilder::filename`]) keep it, and contents
+    /// whose title has no letter or digit keep `cNN.xhtml`.
+    pub fn slug_filenames(mut self, slug_filenames: bool) -> Self {
+        self.0.slug_filenames = slug_filenames;
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_slugify() {
+        assert_eq!(slugify("The Sea"), "the-sea");
+        assert_eq!(slugify("  --Hello,  World!-- "), "hello-world");
+        assert_eq!(slugify("Café à la crème"), "cafe-a-la-creme");
+        assert_eq!(slugify("Ærøskøbing"), "aeroskobing");
+        assert_eq!(slugify("第一章"), "");
+    }
+
+    #[test]
+    fn test_slugs_unique() {
+        let mut slugs = Slugs::default();
+        slugs.reserve("index.xhtml");
+        assert_eq!(slugs.unique("the-sea", ".xhtml"), "the-sea.xhtml");
+        assert_eq!(slugs.unique("the-sea", ".xhtml"), "the-sea-2.xhtml");
+        assert_eq!(slugs.unique("index", ".xhtml"), "index-2.xhtml");
+    }
+
+    #[test]
+    fn test_slug_filenames() {
+        let chapter =
+            |title: &str| ContentBuilder::new(b"<body/>", ReferenceType::Text(title.to_string()));
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("The Sea").build())
+            .add_content(chapter("The Sea").build())
+            .add_content(chapter("Index").build())
+            .add_content(chapter("Named").filename("the-sea-3.xhtml").build())
+            .add_content(chapter("The Sea").build())
+            .add_content(chapter("?").build())
+            .slug_filenames(true);
+        builder.0.prepare().unwrap();
+
+        let filenames = builder
+            .0
+            .contents
+            .as_ref()
+            .unwrap()
+            .iter()
+            .enumerate()
+            .map(|(index, content)| content.filename(index + 1).into_owned())
+            .collect::<Vec<_>>();
+        assert_eq!(
+            filenames,
+            [
+                "the-sea.xhtml",
+                "the-sea-2.xhtml",
+                "index-2.xhtml",
+                "the-sea-3.xhtml",
+                "the-sea-4.xhtml",
+                "c06.xhtml"
+            ]
+        );
+    }
+}
@@ -0,0 +1,182 @@
+use std::borrow::Cow;
+
+use quick_xml::escape::escape;
+
+use crate::epub::{Content, ContentBuilder, ReferenceType};
+
+/// A **transcript** of an audio or video resource embedded in a content, as required by accessibility guidelines.
+///
+/// The transcript becomes a non-linear page following its content. The media element (`<audio>` or `<video>`)
+/// whose `src` (or the `src` of one of its `<source>` children) is the media filename gets an
+/// `aria-describedby` pointing to a link to the transcript, inserted right after the element, and the book
+/// declares the `transcript` accessibility feature.
+///
+/// ```rust
+/// use liber::epub::{ContentBuilder, ReferenceType, Transcript};
+///
+/// let content = ContentBuilder::new(
+///     br#"<body><h1>Interview</h1><audio controls="controls" src="interview.mp3"/></body>"#,
+///     ReferenceType::Text("Interview".to_string()),
+/// )
+/// .add_transcript(Transcript::new("interview.mp3", b"<body><p>Q: Welcome.</p></body>"))
+/// .build();
+/// ```
+#[derive(Debug, Clone)]
+pub struct Transcript<'a> {
+    /// The filename of the transcribed media, as referenced by the `src` of the media element.
+    media: String,
+    /// The title of the transcript page, also used as the text of the link.
+    title: String,
+    /// The raw XHTML body of the transcript.
+    body: Cow<'a, [u8]>,
+}
+
+impl<'a> Transcript<'a> {
+    /// Creates a transcript of the given media filename, with its XHTML body.
+    pub fn new<S: Into<String>>(media: S, body: &'a [u8]) -> Self {
+        Self {
+            media: media.into(),
+            title: "Transcript".to_string(),
+            body: Cow::Borrowed(body),
+        }
+    }
+
+    /// Creates a transcript from an **owned** body, such as the output of a speech-to-text tool.
+    pub fn new_owned<S: Into<String>, B: Into<Vec<u8>>>(media: S, body: B) -> Self {
+        Self {
+            media: media.into(),
+            title: "Transcript".to_string(),
+            body: Cow::Owned(body.into()),
+        }
+    }
+
+    /// Sets the **title** of the transcript page and link. Defaults to `"Transcript"`.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn title<S: Into<String>>(mut self, title: S) -> Self {
+        self.title = title.into();
+        self
+    }
+
+    /// Links the media element of a body to the transcript page `filename`.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the body has no media element with the media filename.
+    pub(crate) fn link(&self, body: &str, number: usize, filename: &str) -> crate::Result<String> {
+        let id = format!("transcript-link-{number}");
+        link_media(
+            body,
+            &self.media,
+            &id,
+            &format!(
+                r#"<p class="transcript-link" id="{id}"><a href="{filename}">{}</a></p>"#,
+                escape(&self.title)
+            ),
+        )
+        .ok_or_else(|| {
+            crate::Error::Validation(format!(
+                "no audio or video element with src '{}' to link the transcript to",
+                self.media
+            ))
+        })
+    }
+
+    /// Builds the non-linear transcript page, linking back to the content `back` of the media.
+    pub(crate) fn content(&self, filename: String, back: &str) -> Content<'a> {
+        let back = format!(r#"<p class="transcript-back"><a href="{back}">Back</a></p>"#);
+        let body = match std::str::from_utf8(&self.body) {
+            Ok(body) => match body.rfind("</body>") {
+                Some(position) => Cow::Owned(
+                    format!("{}{back}{}", &body[..position], &body[position..]).into_bytes(),
+                ),
+                None => self.body.clone(),
+            },
+            Err(_) => self.body.clone(),
+        };
+
+        let reference_type = ReferenceType::Text(self.title.clone());
+        match body {
+            Cow::Borrowed(body) => ContentBuilder::new(body, reference_type),
+            Cow::Owned(body) => ContentBuilder::new_owned(body, reference_type),
+        }
+        .filename(filename)
+        .linear(false)
+        .build()
+    }
+}
+
+/// Gets the filename of the generated transcript page number `number`.
+pub(crate) fn transcript_filename(number: usize) -> String {
+    format!("transcript{number:02}.xhtml")
+}
+
+/// Sets `aria-describedby="{id}"` on the media element referencing `media` and inserts `link` right after it.
+///
+/// Returns `None` if there is no `<audio>` or `<video>` element referencing `media`.
+fn link_media(body: &str, media: &str, id: &str, link: &str) -> Option<String> {
+    let src = body.find(&format!(r#"src="{media}""#))?;
+    let (start, name) = ["audio", "video"]
+        .into_iter()
+        .filter_map(|name| Some((body[..src].rfind(&format!("<{name}"))?, name)))
+        .max_by_key(|(start, _)| *start)?;
+
+    let start_tag_end = start + body[start..].find('>')? + 1;
+    let end = if body[..start_tag_end].ends_with("/>") {
+        start_tag_end
+    } else {
+        let close = format!("</{name}>");
+        start_tag_end + body[start_tag_end..].find(&close)? + close.len()
+    };
+
+    let attribute_position = start + name.len() + 1;
+    Some(format!(
+        r#"{} aria-describedby="{id}"{}{link}{}"#,
+        &body[..attribute_position],
+        &body[attribute_position..end],
+        &body[end..]
+    ))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_link_media() {
+        assert_eq!(
+            link_media(
+                r#"<body><audio src="a.mp3"/><p>Text</p></body>"#,
+                "a.mp3",
+                "t1",
+                "<p>link</p>"
+            )
+            .unwrap(),
+            r#"<body><audio aria-describedby="t1" src="a.mp3"/><p>link</p><p>Text</p></body>"#
+        );
+        assert_eq!(
+            link_media(
+                r#"<body><video controls="controls"><source src="v.mp4"/></video></body>"#,
+                "v.mp4",
+                "t1",
+                "<p>link</p>"
+            )
+            .unwrap(),
+            r#"<body><video aria-describedby="t1" controls="controls"><source src="v.mp4"/></video><p>link</p></body>"#
+        );
+        assert_eq!(
+            link_media(r#"<body><img src="a.mp3"/></body>"#, "a.mp3", "t1", ""),
+            None
+        );
+    }
+
+    #[test]
+    fn test_transcript_content() {
+        let transcript = Transcript::new("a.mp3", b"<body><p>Hello</p></body>").title("Words");
+        let files = transcript
+            .content(transcript_filename(1), "c01.xhtml")
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert_eq!(files[0].filepath, "OEBPS/transcript01.xhtml");
+        assert!(files[0].bytes.contains(r#"<a href="c01.xhtml">Back</a>"#));
+    }
+}
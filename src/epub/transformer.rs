@@ -0,0 +1,123 @@
+use std::{
+    fmt::{Debug, Formatter},
+    sync::Arc,
+};
+
+use crate::epub::{Epub, EpubBuilder};
+
+/// The signature of a content transformer: receives the filename of a content and its body,
+/// returning the transformed body.
+type TransformFn = dyn Fn(&str, &str) -> crate::Result<String> + Send + Sync;
+
+/// A **content transformer**, rewriting every content body before the book is generated
+/// (see [`EpubBuilder::add_transformer`]).
+#[derive(Clone)]
+pub(crate) struct Transformer(Arc<TransformFn>);
+
+impl Debug for Transformer {
+    fn fmt(&self, f: &mut Formatter<'_>) -> std::fmt::Result {
+        f.write_str("Transformer")
+    }
+}
+
+impl Epub<'_> {
+    /// Runs the content transformers, in order, on every content body.
+    ///
+    /// # Errors
+    /// Returns the first error returned by a transformer, with the content it failed on.
+    pub(crate) fn transform_bodies(&mut self) -> crate::Result {
+        let Some(transformers) = self.transformers.clone() else {
+            return Ok(());
+        };
+
+        self.rewrite_bodies(|filename, _, body| {
+            transformers
+                .iter()
+                .try_fold(body.to_string(), |body, Transformer(transform)| {
+                    transform(filename, &body)
+                })
+                .map(Some)
+        })
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Adds a **content transformer** (middleware), invoked with the filename (e.g., `c01.xhtml`) and the body
+    /// of every content, returning the body to use instead.
+    ///
+    /// Transformers enable body-level changes (profanity filtering, link rewriting, analytics tag stripping)
+    /// and run in the order they are added, on the bodies as given, before any generated markup
+    /// (e.g., endnotes or index anchors) is added. Generated pages are not transformed.
+    ///
+    /// ```rust
+    /// use liber::epub::{EpubBuilder, MetadataBuilder};
+    ///
+    /// let epub_builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+    ///     .add_transformer(|_, body| Ok(body.replace("http://", "https://")));
+    /// ```
+    ///
+    /// Returning an error from the transformer aborts the creation of the file with that error.
+    pub fn add_transformer<F>(mut self, transformer: F) -> Self
+    where
+        F: Fn(&str, &str) -> crate::Result<String> + Send + Sync + 'static,
+    {
+        let transformer = Transformer(Arc::new(transformer));
+        if let Some(ref mut transformers) = self.0.transformers {
+            transformers.push(transformer);
+        } else {
+            self.0.transformers = Some(vec![transformer]);
+        }
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_transform_bodies() {
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><p>Darn it.</p><script src="https://tracker.example.com/a.js"></script></body>"#,
+                    ReferenceType::Text("One".to_string()),
+                )
+                .filename("one.xhtml")
+                .build(),
+            )
+            .add_transformer(|_, body| Ok(body.replace("Darn", "****")))
+            .add_transformer(|filename, body| {
+                assert_eq!(filename, "one.xhtml");
+                Ok(body.replace(
+                    r#"<script src="https://tracker.example.com/a.js"></script>"#,
+                    "",
+                ))
+            });
+        builder.0.prepare().unwrap();
+
+        let files = builder.0.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(files[0].bytes.contains("<p>**** it.</p>"));
+        assert!(!files[0].bytes.contains("tracker"));
+    }
+
+    #[test]
+    fn test_transformer_error() {
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+            .add_transformer(|_, _| Err(crate::Error::Validation("rejected".to_string())));
+        assert!(
+            builder
+                .0
+                .prepare()
+                .unwrap_err()
+                .to_string()
+                .contains("rejected")
+        );
+    }
+}
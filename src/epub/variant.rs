@@ -0,0 +1,378 @@
+use std::{io::Write, path::Path};
+
+use crate::epub::{
+    CallToAction, Content, DeviceClass, EpubBuilder, GrayscaleConverter, Identifier, ImageResizer,
+    ImageType, LinkTracking, Metadata, MetadataBuilder, Resource, Watermark,
+    device_class::ImageProcessing,
+};
+
+/// A patch of the metadata of a variant, applied to a builder initialized with the metadata of the template.
+type MetadataPatch<'a> = Box<dyn FnOnce(MetadataBuilder) -> MetadataBuilder + Send + 'a>;
+
+/// The **overrides** of a variant of a template book (see [`EpubBuilder::variant`]): a metadata patch or
+/// identifier, extra contents (e.g., a personalized chapter), store-specific assets, a watermark, a
+/// call-to-action page, link tracking parameters and the processing of the images (a target device class,
+/// grayscale conversion), applied on a copy of the template.
+///
+/// ```rust
+/// use liber::epub::{ContentBuilder, Overrides, ReferenceType};
+///
+/// let overrides = Overrides::new()
+///     .metadata(|metadata| metadata.retitle("Title (Special edition)").edition("Special edition"))
+///     .add_content(
+///         ContentBuilder::new(b"<body><h1>Bonus</h1></body>", ReferenceType::Text("Bonus".to_string())).build(),
+///     );
+/// ```
+#[derive(Default)]
+pub struct Overrides<'a> {
+    /// Optional patch of the metadata.
+    metadata: Option<MetadataPatch<'a>>,
+    /// The contents appended to the main book flow.
+    contents: Vec<Content<'a>>,
+    /// Optional buyer watermark.
+    watermark: Option<Watermark>,
+    /// Optional tracking parameters of the links of the generated pages.
+    link_tracking: Option<LinkTracking>,
+    /// Optional identifier, replacing the one of the template.
+    identifier: Option<Identifier>,
+    /// The resources added to the package.
+    resources: Vec<Resource<'a>>,
+    /// Optional cover image, replacing the one of the template.
+    cover_image: Option<Resource<'a>>,
+    /// Optional call-to-action page, replacing the one of the template.
+    call_to_action: Option<CallToAction>,
+    /// Optional processing of the images, replacing the settings of the template it sets.
+    image_processing: Option<ImageProcessing>,
+}
+
+impl<'a> Overrides<'a> {
+    /// Creates empty overrides, producing an identical copy of the template.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Sets the **metadata patch**: a function receiving a builder initialized with the metadata of the
+    /// template (e.g., to change the title or the identifier).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn metadata<F>(mut self, patch: F) -> Self
+    where
+        F: FnOnce(MetadataBuilder) -> MetadataBuilder + Send + 'a,
+    {
+        self.metadata = Some(Box::new(patch));
+        self
+    }
+
+    /// Adds an **extra content** (e.g., a chapter), appended to the main book flow of the variant.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_content(mut self, content: Content<'a>) -> Self {
+        self.contents.push(content);
+        self
+    }
+
+    /// Sets a **buyer watermark** (see [`Watermark`]) on the variant.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn watermark(mut self, watermark: Watermark) -> Self {
+        self.watermark = Some(watermark);
+        self
+    }
+
+    /// Sets the **tracking parameters** of the links of the generated pages (see [`EpubBuilder::link_tracking`]),
+    /// e.g., the UTM parameters of the store the variant is built for.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn link_tracking(mut self, link_tracking: LinkTracking) -> Self {
+        self.link_tracking = Some(link_tracking);
+        self
+    }
+
+    /// Sets the **identifier** of the variant (e.g., the ISBN of a store edition), applied after the metadata patch.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn identifier(mut self, identifier: Identifier) -> Self {
+        self.identifier = Some(identifier);
+        self
+    }
+
+    /// Adds a **store-specific asset** (e.g., a badge image or a font) to the package of the variant.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_resource(mut self, resource: Resource<'a>) -> Self {
+        self.resources.push(resource);
+        self
+    }
+
+    /// Sets the **cover image** of the variant, replacing the one of the template.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn cover_image(mut self, path: &'a Path, image_type: ImageType) -> Self {
+        self.cover_image = Some(Resource::Image(path, image_type));
+        self
+    }
+
+    /// Sets the **call-to-action page** (see [`CallToAction`]) of the variant, replacing the one of the template
+    /// (e.g., with the links to the store).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn call_to_action(mut self, call_to_action: CallToAction) -> Self {
+        self.call_to_action = Some(call_to_action);
+        self
+    }
+
+    /// Sets the **target device class** (see [`EpubBuilder::device_class`]) of the variant, replacing the one
+    /// of the template (e.g., shrinking the images of an e-ink distribution).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn device_class<R>(mut self, device_class: DeviceClass, resizer: R) -> Self
+    where
+        R: ImageResizer + Send + Sync + 'static,
+    {
+        self.image_processing
+            .get_or_insert_with(ImageProcessing::default)
+            .resize(device_class, resizer);
+        self
+    }
+
+    /// **Converts the images to grayscale** (see [`EpubBuilder::grayscale_images`]) in the variant
+    /// (e.g., for an e-ink distribution).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn grayscale_images<C>(mut self, converter: C) -> Self
+    where
+        C: GrayscaleConverter + Send + Sync + 'static,
+    {
+        self.image_processing
+            .get_or_insert_with(ImageProcessing::default)
+            .grayscale(converter);
+        self
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// **Patches the metadata** with a function receiving a builder initialized with the current metadata.
+    pub fn patch_metadata<F>(mut self, patch: F) -> Self
+    where
+        F: FnOnce(MetadataBuilder) -> MetadataBuilder,
+    {
+        self.0.metadata = patch(MetadataBuilder::from(self.0.metadata.clone())).build();
+        self
+    }
+
+    /// Creates a **variant** of this book (used as a template): a deep copy with the given [`Overrides`] applied.
+    ///
+    /// The content tree is not rebuilt: the copy shares the borrowed bodies of the template, so producing
+    /// thousands of variants of a base book (e.g., on a server) only copies the owned data.
+    pub fn variant(&self, overrides: Overrides<'a>) -> Self {
+        let mut variant = self.clone();
+        if let Some(patch) = overrides.metadata {
+            variant = variant.patch_metadata(patch);
+        }
+        if let Some(identifier) = overrides.identifier {
+            variant = variant.patch_metadata(|metadata| metadata.identifier(identifier));
+        }
+        if !overrides.resources.is_empty() {
+            variant = variant.add_resources(overrides.resources);
+        }
+        if let Some(cover_image) = overrides.cover_image {
+            variant.0.cover_image = Some(cover_image);
+        }
+        if let Some(call_to_action) = overrides.call_to_action {
+            variant = variant.call_to_action(call_to_action);
+        }
+        if !overrides.contents.is_empty() {
+            variant = variant.add_contents(overrides.contents);
+        }
+        if let Some(watermark) = overrides.watermark {
+            variant = variant.watermark(watermark);
+        }
+        if let Some(link_tracking) = overrides.link_tracking {
+            variant = variant.link_tracking(link_tracking);
+        }
+        if let Some(image_processing) = overrides.image_processing {
+            variant
+                .0
+                .image_processing
+                .get_or_insert_with(ImageProcessing::default)
+                .merge(image_processing);
+        }
+        variant
+    }
+
+    /// Generates a **variant** (see [`EpubBuilder::variant`]) of this book for every overrides, one at a time,
+    /// writing each one to the writer returned by `writer` for its metadata (e.g., a file named after its identifier).
+    ///
+    /// # Errors
+    /// Returns the first error creating a variant (see [`EpubBuilder::create`]) or returned by `writer`.
+    pub fn create_variants<I, F, W>(&self, variants: I, mut writer: F) -> crate::Result
+    where
+        I: IntoIterator<Item = Overrides<'a>>,
+        F: FnMut(&Metadata) -> crate::Result<W>,
+        W: Write + Send,
+    {
+        for overrides in variants {
+            let variant = self.variant(overrides);
+            let mut output = writer(&variant.0.metadata)?;
+            variant.create(&mut output)?;
+        }
+        Ok(())
+    }
+
+    /// Generates the **named variants** of this book (e.g., one per retailer) in one run, one at a time, writing
+    /// each one to the writer returned by `writer` for its name and metadata (e.g., `the-sea-kobo.epub`).
+    ///
+    /// ```rust
+    /// use liber::epub::{CallToAction, EpubBuilder, Identifier, LinkTracking, MetadataBuilder, Overrides};
+    ///
+    /// let template = EpubBuilder::new(MetadataBuilder::title("The Sea").build())
+    ///     .call_to_action(CallToAction::new("Newsletter").link("Sign up", "https://example.com/news"));
+    ///
+    /// let stores = [("kobo", "9780000000001"), ("apple", "9780000000002")].map(|(store, isbn)| {
+    ///     let overrides = Overrides::new()
+    ///         .identifier(Identifier::ISBN(isbn.to_string()))
+    ///         .link_tracking(LinkTracking::utm(store, "ebook", "the-sea"));
+    ///     (store, overrides)
+    /// });
+    ///
+    /// let mut written = Vec::new();
+    /// template
+    ///     .create_named_variants(stores, |name, _| {
+    ///         written.push(name.to_string());
+    ///         Ok(Vec::new())
+    ///     })
+    ///     .unwrap();
+    /// assert_eq!(written, ["kobo", "apple"]);
+    /// ```
+    ///
+    /// # Errors
+    /// Returns the first error creating a variant (see [`EpubBuilder::create`]) or returned by `writer`.
+    pub fn create_named_variants<I, N, F, W>(&self, variants: I, mut writer: F) -> crate::Result
+    where
+        I: IntoIterator<Item = (N, Overrides<'a>)>,
+        N: AsRef<str>,
+        F: FnMut(&str, &Metadata) -> crate::Result<W>,
+        W: Write + Send,
+    {
+        for (name, overrides) in variants {
+            let variant = self.variant(overrides);
+            let mut output = writer(name.as_ref(), &variant.0.metadata)?;
+            variant.create(&mut output)?;
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, ReferenceType};
+
+    fn template<'a>() -> EpubBuilder<'a> {
+        EpubBuilder::new(MetadataBuilder::title("Title").creator("Jane Doe").build()).add_content(
+            ContentBuilder::new(
+                b"<body><h1>One</h1></body>",
+                ReferenceType::Text("One".to_string()),
+            )
+            .build(),
+        )
+    }
+
+    #[test]
+    fn test_variant() {
+        let template = template();
+        let variant = template.variant(
+            Overrides::new()
+                .metadata(|metadata| metadata.retitle("Title for Ann"))
+                .add_content(
+                    ContentBuilder::new(b"<body/>", ReferenceType::Text("Bonus".to_string()))
+                        .build(),
+                ),
+        );
+
+        assert_eq!(variant.0.metadata.title, "Title for Ann");
+        assert_eq!(variant.0.metadata.creator.as_deref(), Some("Jane Doe"));
+        assert_eq!(variant.0.contents.as_ref().unwrap().len(), 2);
+        assert_eq!(template.0.metadata.title, "Title");
+        assert_eq!(template.0.contents.as_ref().unwrap().len(), 1);
+
+        let variant = template
+            .variant(Overrides::new().link_tracking(LinkTracking::utm("kobo", "ebook", "title")));
+        assert_eq!(
+            variant.0.tracked_link("https://example.com"),
+            "https://example.com?utm_source=kobo&utm_medium=ebook&utm_campaign=title"
+        );
+        assert_eq!(
+            template.0.tracked_link("https://example.com"),
+            "https://example.com"
+        );
+
+        let variant = template.variant(
+            Overrides::new()
+                .device_class(
+                    DeviceClass::EInk,
+                    |_: &[u8], _: &ImageType, _: u32, _: u32| Ok(b"GIF89a\x01\0\x01\0".to_vec()),
+                )
+                .grayscale_images(|_: &[u8], _: &ImageType| Ok(b"GIF89a\x01\0\x01\0".to_vec())),
+        );
+        let gif = b"GIF89a\x00\x01\x00\x01";
+        assert!(variant.0.process_image(gif).unwrap().is_some());
+        assert!(template.0.process_image(gif).unwrap().is_none());
+    }
+
+    #[test]
+    fn test_variant_store_assets() {
+        let template = template().call_to_action(CallToAction::new("Newsletter"));
+        let variant = template.variant(
+            Overrides::new()
+                .identifier(Identifier::ISBN("9780000000001".to_string()))
+                .add_resource(Resource::Image(Path::new("badge.png"), ImageType::Png))
+                .cover_image(Path::new("cover-kobo.jpg"), ImageType::Jpg)
+                .call_to_action(CallToAction::new("Kobo newsletter")),
+        );
+
+        assert_eq!(variant.0.metadata.identifier.value(), "9780000000001");
+        assert_eq!(variant.0.metadata.title, "Title");
+        assert_eq!(variant.0.resources.as_ref().unwrap().len(), 1);
+        assert_eq!(
+            variant.0.cover_image.as_ref().unwrap().filename().unwrap(),
+            "cover-kobo.jpg"
+        );
+        assert_eq!(
+            variant.0.call_to_action,
+            Some(CallToAction::new("Kobo newsletter"))
+        );
+        assert!(template.0.resources.is_none());
+        assert!(template.0.cover_image.is_none());
+    }
+
+    #[test]
+    fn test_create_variants() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        template()
+            .create_variants(
+                (1..=3).map(|number| {
+                    Overrides::new().metadata(move |metadata| {
+                        metadata.identifier(Identifier::ISBN(format!("978000000000{number}")))
+                    })
+                }),
+                |metadata| {
+                    Ok(std::fs::File::create(
+                        temp_dir
+                            .path()
+                            .join(format!("{}.epub", metadata.identifier.value())),
+                    )?)
+                },
+            )
+            .unwrap();
+
+        for number in 1..=3 {
+            assert!(
+                temp_dir
+                    .path()
+                    .join(format!("978000000000{number}.epub"))
+                    .exists()
+            );
+        }
+    }
+}
@@ -0,0 +1,105 @@
+use std::{fmt::Display, path::Path};
+
+use quick_xml::escape::escape;
+
+use crate::epub::{ImageType, Resource};
+
+/// An embedded **video**, with an optional poster image shown before it plays.
+///
+/// It is rendered (through `Display`) as the `<video>` markup to embed in a content body, while
+/// [`crate::epub::EpubBuilder::add_video`] adds the video and its poster to the manifest. `<video>` is an
+/// HTML5 element, reported by the [`crate::epub::Linter`] (see [`crate::epub::LintRule::Epub3Only`]).
+///
+/// ```rust
+/// use std::path::Path;
+/// use liber::epub::{ImageType, Video};
+///
+/// let video = Video::new(Path::new("media/intro.mp4")).poster(Path::new("media/intro.jpg"), ImageType::Jpg);
+/// assert_eq!(
+///     video.to_string(),
+///     r#"<video src="intro.mp4" controls="controls" poster="intro.jpg">Your reading system does not support video.</video>"#
+/// );
+/// ```
+#[derive(Debug, Clone)]
+pub struct Video<'a> {
+    /// The path of the video file.
+    path: &'a Path,
+    /// Optional poster image, shown until the video plays.
+    poster: Option<(&'a Path, ImageType)>,
+    /// The text shown by reading systems that don't play video.
+    fallback: String,
+}
+
+impl<'a> Video<'a> {
+    /// Creates an embedded video from the given file.
+    pub fn new(path: &'a Path) -> Self {
+        Self {
+            path,
+            poster: None,
+            fallback: "Your reading system does not support video.".to_string(),
+        }
+    }
+
+    /// Sets the **poster image**, shown until the video plays.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn poster(mut self, path: &'a Path, image_type: ImageType) -> Self {
+        self.poster = Some((path, image_type));
+        self
+    }
+
+    /// Sets the **fallback text** shown by reading systems that don't play video.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn fallback<S: Into<String>>(mut self, fallback: S) -> Self {
+        self.fallback = fallback.into();
+        self
+    }
+
+    /// Gets the resources of the video: the video itself and its poster image, if any.
+    pub(crate) fn resources(&self) -> Vec<Resource<'a>> {
+        let mut resources = vec![Resource::Video(self.path)];
+        resources.extend(
+            self.poster
+                .clone()
+                .map(|(path, image_type)| Resource::Image(path, image_type)),
+        );
+        resources
+    }
+}
+
+impl Display for Video<'_> {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        let src = Resource::Video(self.path).filename().unwrap_or_default();
+        write!(f, r#"<video src="{}" controls="controls""#, escape(&src))?;
+        if let Some((path, ref image_type)) = self.poster {
+            let poster = Resource::Image(path, image_type.clone())
+                .filename()
+                .unwrap_or_default();
+            write!(f, r#" poster="{}""#, escape(&poster))?;
+        }
+        write!(f, ">{}</video>", escape(&self.fallback))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_video() {
+        let video = Video::new(Path::new("clip.mp4")).fallback("No video");
+        assert_eq!(
+            video.to_string(),
+            r#"<video src="clip.mp4" controls="controls">No video</video>"#
+        );
+        assert_eq!(video.resources().len(), 1);
+
+        let video = video.poster(Path::new("images/clip.png"), ImageType::Png);
+        assert!(video.to_string().contains(r#"poster="clip.png""#));
+        assert!(matches!(
+            video.resources()[1],
+            Resource::Image(_, ImageType::Png)
+        ));
+    }
+}
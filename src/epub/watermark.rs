@@ -0,0 +1,241 @@
+use std::io::Write;
+
+use quick_xml::escape::escape;
+
+use crate::{
+    epub::{Epub, EpubBuilder},
+    output::file_content,
+};
+
+/// The name of the hidden `<meta>` carrying the buyer identifier of a watermarked copy.
+pub const WATERMARK_META_NAME: &str = "liber:watermark";
+
+/// A **buyer watermark** (social DRM), stamping a personalized copy of a book with buyer-specific data:
+///
+/// - the buyer **identifier** (e.g., an order or transaction id), always stamped in a hidden `<meta>` of the package;
+/// - an optional visible **footer** (e.g., `Licensed to Jane Doe`), appended to the copyright pages
+///   (contents of type [`crate::epub::ReferenceType::Copyright`]);
+/// - a **salt** derived from the identifier, to name the file of the copy (see [`Watermark::salted_filename`]).
+///
+/// ```rust
+/// use liber::epub::Watermark;
+///
+/// let watermark = Watermark::new("order-1042").footer("Licensed to Jane Doe <jane@example.com>");
+/// assert!(watermark.salted_filename("moby-dick").starts_with("moby-dick-"));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Watermark {
+    /// The buyer identifier, stamped in the hidden metadata.
+    identifier: String,
+    /// Optional visible text appended to the copyright pages.
+    footer: Option<String>,
+}
+
+impl Watermark {
+    /// Creates a watermark with the buyer **identifier** (e.g., an order or transaction id).
+    pub fn new<S: Into<String>>(identifier: S) -> Self {
+        Self {
+            identifier: identifier.into(),
+            footer: None,
+        }
+    }
+
+    /// Sets the visible **footer** appended to the copyright pages (e.g., `Licensed to Jane Doe`).
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn footer<S: Into<String>>(mut self, footer: S) -> Self {
+        self.footer = Some(footer.into());
+        self
+    }
+
+    /// Gets the buyer **identifier**.
+    pub fn identifier(&self) -> &str {
+        &self.identifier
+    }
+
+    /// Gets the **salt** of the copy: the first 8 hexadecimal digits of the SHA-256 digest of the identifier.
+    pub fn salt(&self) -> String {
+        file_content::sha256_hex(self.identifier.as_bytes())[..8].to_string()
+    }
+
+    /// Gets the **filename of the copy**: the `stem` followed by the salt (e.g., `moby-dick-3fa2c1d0.epub`),
+    /// so leaked files can be traced without revealing the identifier.
+    pub fn salted_filename(&self, stem: &str) -> String {
+        format!("{stem}-{}.epub", self.salt())
+    }
+
+    /// Generates the markup of the visible footer, if set.
+    pub(crate) fn footer_markup(&self) -> Option<String> {
+        Some(format!(
+            r#"<p class="watermark">{}</p>"#,
+            escape(self.footer.as_ref()?)
+        ))
+    }
+}
+
+impl Epub<'_> {
+    /// Stamps the visible footer of the watermark, if any, on the copyright pages.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] if the watermark has a footer but there is no copyright page.
+    pub(crate) fn stamp_watermark(&mut self) -> crate::Result {
+        let Some(footer) = self.watermark.as_ref().and_then(Watermark::footer_markup) else {
+            return Ok(());
+        };
+
+        let mut stamped = false;
+        for content in self.contents.iter_mut().flatten() {
+            stamped |= content.stamp_copyright_footer(&footer);
+        }
+        if stamped {
+            Ok(())
+        } else {
+            Err(crate::Error::Validation(
+                "the watermark footer requires a copyright page".to_string(),
+            ))
+        }
+    }
+
+    /// Generates the hidden XML `<meta>` tag with the buyer identifier of the watermark, used in the
+    /// content package metadata.
+    ///
+    /// Returns `None` if no watermark is set.
+    pub fn watermark_as_metadata_xml(&self) -> Option<String> {
+        Some(format!(
+            r#"<meta name="{WATERMARK_META_NAME}" content="{}"/>"#,
+            escape(self.watermark.as_ref()?.identifier())
+        ))
+    }
+}
+
+impl<'a> EpubBuilder<'a> {
+    /// Stamps a **buyer watermark** (see [`Watermark`]) on the book.
+    pub fn watermark(mut self, watermark: Watermark) -> Self {
+        self.0.watermark = Some(watermark);
+        self
+    }
+
+    /// Generates a **personalized copy** of this book (used as a template) for every watermark, one at a time,
+    /// writing each copy to the writer returned by `writer` for its watermark (e.g., a file named with
+    /// [`Watermark::salted_filename`], or a network stream).
+    ///
+    /// The watermarks are consumed lazily, so any number of copies can be streamed from one template.
+    ///
+    /// ```rust
+    /// use liber::epub::{ContentBuilder, EpubBuilder, MetadataBuilder, ReferenceType, Watermark};
+    ///
+    /// let template = EpubBuilder::new(MetadataBuilder::title("Title").build()).add_content(
+    ///     ContentBuilder::new(b"<body><p>All rights reserved.</p></body>", ReferenceType::Copyright("Copyright".to_string()))
+    ///         .build(),
+    /// );
+    ///
+    /// template
+    ///     .create_watermarked(
+    ///         ["order-1", "order-2"].map(|order| Watermark::new(order).footer(format!("Licensed to {order}"))),
+    ///         // e.g., `std::fs::File::create(watermark.salted_filename("title"))`
+    ///         |_watermark| Ok(std::io::sink()),
+    ///     )
+    ///     .unwrap();
+    /// ```
+    ///
+    /// # Errors
+    /// Returns the first error creating a copy (see [`EpubBuilder::create`]) or returned by `writer`.
+    pub fn create_watermarked<I, F, W>(&self, watermarks: I, mut writer: F) -> crate::Result
+    where
+        I: IntoIterator<Item = Watermark>,
+        F: FnMut(&Watermark) -> crate::Result<W>,
+        W: Write + Send,
+    {
+        for watermark in watermarks {
+            let mut output = writer(&watermark)?;
+            self.clone().watermark(watermark).create(&mut output)?;
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    fn template<'a>() -> EpubBuilder<'a> {
+        EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(
+                    b"<body><p>All rights reserved.</p></body>",
+                    ReferenceType::Copyright("Copyright".to_string()),
+                )
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+            )
+    }
+
+    #[test]
+    fn test_watermark_salt() {
+        let watermark = Watermark::new("order-1");
+        assert_eq!(watermark.salt().len(), 8);
+        assert_ne!(watermark.salt(), Watermark::new("order-2").salt());
+        assert_eq!(
+            watermark.salted_filename("book"),
+            format!("book-{}.epub", watermark.salt())
+        );
+    }
+
+    #[test]
+    fn test_stamp_watermark() {
+        let mut builder =
+            template().watermark(Watermark::new("order-1").footer("Licensed to J & J"));
+        builder.0.prepare().unwrap();
+
+        let files = builder.0.contents.as_ref().unwrap()[0]
+            .file_content(&mut 0, "")
+            .unwrap();
+        assert!(
+            files[0]
+                .bytes
+                .contains(r#"<p class="watermark">Licensed to J &amp; J</p>"#)
+        );
+        assert_eq!(
+            builder.0.watermark_as_metadata_xml().unwrap(),
+            r#"<meta name="liber:watermark" content="order-1"/>"#
+        );
+
+        let mut builder = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .watermark(Watermark::new("order-1").footer("Licensed"));
+        assert!(builder.0.prepare().is_err());
+    }
+
+    #[test]
+    fn test_create_watermarked() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        template()
+            .create_watermarked(
+                (1..=3).map(|number| Watermark::new(format!("order-{number}"))),
+                |watermark| {
+                    Ok(std::fs::File::create(
+                        temp_dir.path().join(watermark.salted_filename("book")),
+                    )?)
+                },
+            )
+            .unwrap();
+
+        for number in 1..=3 {
+            let watermark = Watermark::new(format!("order-{number}"));
+            let file = std::fs::File::open(temp_dir.path().join(watermark.salted_filename("book")))
+                .unwrap();
+            let mut archive = zip::ZipArchive::new(file).unwrap();
+            let mut opf = String::new();
+            std::io::Read::read_to_string(
+                &mut archive.by_name("OEBPS/content.opf").unwrap(),
+                &mut opf,
+            )
+            .unwrap();
+            assert!(opf.contains(&format!(
+                r#"<meta name="liber:watermark" content="order-{number}"/>"#
+            )));
+        }
+    }
+}
@@ -27,7 +27,6 @@
 //! }
 //!
 //! fn create() -> Result<(), Box<dyn std::error::Error>> {
-//!     let mut file = std::fs::File::create("book.epub")?;
 //!     let title = "My Book";
 //!
 //!     let contents = vec![
@@ -64,7 +63,7 @@
 //!             .build(),
 //!         );
 //!
-//!     epub_builder.create(&mut file)?;
+//!     epub_builder.create_file("book.epub")?;
 //!
 //!     Ok(())
 //! }
@@ -76,6 +75,28 @@
 //! - [`epub`] — Core types to model the epub.
 //! - [`epub::Content`], [`epub::ContentReference`], [`epub::Resource`], [`epub::Language`], [`epub::Identifier`], [`epub::Metadata`] — Main data structures.
 //! - [`epub::EpubBuilder`], [`epub::ContentBuilder`], [`epub::MetadataBuilder`] — Builders.
+//! - [`markup`] — Helpers emitting XHTML markup and CSS for common book typography.
+//!
+//! ## Memory
+//!
+//! The archive is **streamed** to the writer as every file is compressed, so it is never held in memory
+//! and a slow writer (e.g., a client download) blocks or suspends the generation instead of piling up
+//! bytes. The memory of every concurrent build is bounded by:
+//!
+//! - the builder itself (borrowed contents are not copied);
+//! - the file being written: a generated XHTML file (a few times the size of its source while it is
+//!   formatted) or a resource (read whole);
+//! - the compressor state and the small per-file records of the archive (its central directory).
+//!
+//! A [`epub::SizeBudget`] counts the bytes as they are streamed instead of buffering the archive. The
+//! `generation` benchmark (`cargo bench`) reports the peak memory of single and concurrent builds.
+//!
+//! ## Integrations
+//!
+//! This crate does not ship an image codec, an HTTP client or an HTTP server, so any of them can be
+//! plugged in: the codecs implement [`epub::ImageResizer`] and [`epub::GrayscaleConverter`] (or are
+//! closures of the same signature), the clients implement [`epub::HttpClient`], and the servers send
+//! the headers and stream the body of an [`epub::EpubResponse`].
 //!
 //! ## Error Handling
 //!
@@ -85,14 +106,19 @@
 //! ## Feature Flags
 //!
 //! - `async` — Enables the asynchronous API (`search`).
+//! - `testing` — Enables the `testing` module with golden-file test helpers.
 //!
 //! ## License
 //!
 //! This is free software, published under the [MIT License](https://mit-license.org/).
 
 pub mod epub;
+pub mod markup;
 mod output;
 
+#[cfg(feature = "testing")]
+pub mod testing;
+
 pub use output::creator::ZipCompression;
 
 /// Error type for all fallible operations in this crate.
@@ -129,6 +155,41 @@ pub enum Error {
 
     #[error("Error at position {0}: {1:?}")]
     XmlParser(u64, quick_xml::Error),
+
+    #[error("Validation error: {0}")]
+    Validation(String),
+
+    #[error("Error in resource '{0}': {1}")]
+    Resource(String, #[source] Box<Error>),
+
+    #[error("Error in content '{0}' ({1}): {2}")]
+    Content(String, String, #[source] Box<Error>),
+
+    #[error("{} errors found:\n{}", .0.len(), join_errors(.0))]
+    Multiple(Vec<Error>),
+}
+
+impl Error {
+    /// Aggregates the collected errors into a single one.
+    ///
+    /// Returns `Ok(())` if there are no errors, the error itself if there is only one,
+    /// or an [`Error::Multiple`] otherwise.
+    pub(crate) fn aggregate(mut errors: Vec<Error>) -> Result {
+        match errors.len() {
+            0 => Ok(()),
+            1 => Err(errors.remove(0)),
+            _ => Err(Error::Multiple(errors)),
+        }
+    }
+}
+
+/// Joins the messages of the errors, one per line.
+fn join_errors(errors: &[Error]) -> String {
+    errors
+        .iter()
+        .map(|error| format!("- {error}"))
+        .collect::<Vec<_>>()
+        .join("\n")
 }
 
 /// A convenient alias for `Result` with the crate's [`Error`] type.
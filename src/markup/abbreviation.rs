@@ -0,0 +1,149 @@
+use quick_xml::escape::escape;
+
+use crate::output::xml;
+
+/// The filename of the generated list of abbreviations.
+pub const ABBREVIATIONS_FILENAME: &str = "abbreviations.xhtml";
+
+/// The elements whose text is never marked up with `<abbr>`.
+const SKIPPED_ELEMENTS: [&str; 4] = ["abbr", "script", "style", "title"];
+
+/// A registry of **abbreviations and acronyms** (see [`crate::epub::EpubBuilder::abbreviations`]).
+///
+/// When the book is generated, every whole-word occurrence in the content bodies is marked up as
+/// `<abbr title="...">`, and a list of abbreviations is generated as front matter.
+///
+/// ```rust
+/// use liber::markup::Abbreviations;
+///
+/// let abbreviations = Abbreviations::new()
+///     .define("RAM", "Random Access Memory")
+///     .define("CPU", "Central Processing Unit");
+/// assert_eq!(
+///     abbreviations.mark_up("<p>RAM and CPU</p>").unwrap(),
+///     r#"<p><abbr title="Random Access Memory">RAM</abbr> and <abbr title="Central Processing Unit">CPU</abbr></p>"#
+/// );
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct Abbreviations(Vec<(String, String)>);
+
+impl Abbreviations {
+    /// Creates an empty registry.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Defines an abbreviation (made of letters and digits, like `RAM`) and its expansion.
+    pub fn define<A: Into<String>, E: Into<String>>(
+        mut self,
+        abbreviation: A,
+        expansion: E,
+    ) -> Self {
+        self.0.push((abbreviation.into(), expansion.into()));
+        self
+    }
+
+    /// Gets the expansion of an abbreviation.
+    #[must_use]
+    pub fn get(&self, abbreviation: &str) -> Option<&str> {
+        self.0
+            .iter()
+            .find(|(a, _)| a == abbreviation)
+            .map(|(_, expansion)| expansion.as_str())
+    }
+
+    /// Marks up every whole-word occurrence of the abbreviations in the text of an XHTML fragment,
+    /// skipping tags and the text of `<abbr>`, `<script>`, `<style>` and `<title>` elements.
+    ///
+    /// Returns `None` if there is no occurrence.
+    #[must_use]
+    pub fn mark_up(&self, xhtml: &str) -> Option<String> {
+        xml::rewrite_text(xhtml, &SKIPPED_ELEMENTS, |text, result| {
+            self.mark_up_text(text, result)
+        })
+    }
+
+    /// Builds the markup of the list of abbreviations, sorted alphabetically.
+    #[must_use]
+    pub fn build(&self) -> String {
+        let mut abbreviations = self.0.iter().collect::<Vec<_>>();
+        abbreviations.sort_by_key(|(abbreviation, _)| abbreviation.to_lowercase());
+
+        let entries = abbreviations
+            .into_iter()
+            .map(|(abbreviation, expansion)| {
+                format!(
+                    "<dt><abbr>{}</abbr></dt><dd>{}</dd>",
+                    escape(abbreviation),
+                    escape(expansion)
+                )
+            })
+            .collect::<String>();
+
+        format!(r#"<dl class="abbreviations">{entries}</dl>"#)
+    }
+
+    /// Marks up the whole-word occurrences in a text node, returning `true` if any was found.
+    fn mark_up_text(&self, text: &str, result: &mut String) -> bool {
+        let mut found = false;
+        let mut word_start = None;
+
+        for (position, c) in text.char_indices().chain([(text.len(), ' ')]) {
+            match (c.is_alphanumeric(), word_start) {
+                (true, None) => word_start = Some(position),
+                (false, Some(start)) => {
+                    let word = &text[start..position];
+                    match self.get(word) {
+                        Some(expansion) => {
+                            found = true;
+                            result.push_str(&format!(
+                                r#"<abbr title="{}">{word}</abbr>"#,
+                                escape(expansion)
+                            ));
+                        }
+                        None => result.push_str(word),
+                    }
+                    word_start = None;
+                    if position < text.len() {
+                        result.push(c);
+                    }
+                }
+                (false, None) if position < text.len() => result.push(c),
+                _ => {}
+            }
+        }
+
+        found
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_abbreviations_mark_up() {
+        let abbreviations = Abbreviations::new().define("RAM", "Random \"Access\" Memory");
+
+        assert_eq!(
+            abbreviations
+                .mark_up(r#"<p class="RAM">RAM, RAMP and <abbr>RAM</abbr> (RAM)</p><br/>RAM"#)
+                .unwrap(),
+            r#"<p class="RAM"><abbr title="Random &quot;Access&quot; Memory">RAM</abbr>, RAMP and <abbr>RAM</abbr> (<abbr title="Random &quot;Access&quot; Memory">RAM</abbr>)</p><br/><abbr title="Random &quot;Access&quot; Memory">RAM</abbr>"#
+        );
+        assert!(abbreviations.mark_up("<p>No abbreviations</p>").is_none());
+    }
+
+    #[test]
+    fn test_abbreviations_build() {
+        let abbreviations = Abbreviations::new()
+            .define("RAM", "Random Access Memory")
+            .define("CPU", "Central Processing Unit");
+
+        assert_eq!(
+            abbreviations.build(),
+            r#"<dl class="abbreviations"><dt><abbr>CPU</abbr></dt><dd>Central Processing Unit</dd><dt><abbr>RAM</abbr></dt><dd>Random Access Memory</dd></dl>"#
+        );
+    }
+}
@@ -0,0 +1,182 @@
+use quick_xml::escape::escape;
+
+use crate::output::xml;
+
+/// The filename of the generated list of characters.
+pub const CHARACTERS_FILENAME: &str = "characters.xhtml";
+
+/// The CSS rules used by the generated list of characters.
+pub const CHARACTERS_CSS: &str = ".characters dt { margin-top: 0.5em; font-variant: small-caps; }
+.characters dd { margin: 0 0 0 2em; font-style: italic; }";
+
+/// The elements whose text is never linked to the list of characters.
+const SKIPPED_ELEMENTS: [&str; 6] = ["a", "abbr", "h1", "script", "style", "title"];
+
+/// A registry of the **characters** of a work of fiction or drama (the *dramatis personae*),
+/// rendered into a generated list of characters (see [`crate::epub::EpubBuilder::characters`]).
+///
+/// ```rust
+/// use liber::markup::Characters;
+///
+/// let characters = Characters::new()
+///     .add_character("Hamlet", "Prince of Denmark")
+///     .add_character("Ophelia", "daughter of Polonius")
+///     .link_first_occurrences(true);
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct Characters {
+    /// The `(name, description)` of every character, in the order they are listed.
+    characters: Vec<(String, String)>,
+    /// Whether the first occurrence of every name in the text links to the list.
+    link_first_occurrences: bool,
+}
+
+impl Characters {
+    /// Creates an empty registry.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a character with a short description (an XHTML fragment, which may be empty).
+    pub fn add_character<N: Into<String>, D: Into<String>>(
+        mut self,
+        name: N,
+        description: D,
+    ) -> Self {
+        self.characters.push((name.into(), description.into()));
+        self
+    }
+
+    /// Links the **first occurrence** of every name in the text to its entry in the list of characters.
+    pub fn link_first_occurrences(mut self, link_first_occurrences: bool) -> Self {
+        self.link_first_occurrences = link_first_occurrences;
+        self
+    }
+
+    /// Builds the markup of the list of characters, in the order they were added.
+    #[must_use]
+    pub fn build(&self) -> String {
+        let entries = self
+            .characters
+            .iter()
+            .map(|(name, description)| {
+                let description = if description.is_empty() {
+                    String::new()
+                } else {
+                    format!("<dd>{description}</dd>")
+                };
+                format!(
+                    r#"<dt id="{}">{}</dt>{description}"#,
+                    character_id(name),
+                    escape(name)
+                )
+            })
+            .collect::<String>();
+
+        format!(r#"<dl class="characters">{entries}</dl>"#)
+    }
+
+    /// Creates the state used to link the first occurrences across all the contents, in reading order.
+    ///
+    /// Returns `None` if linking is not enabled.
+    pub(crate) fn linker(&self) -> Option<CharacterLinker<'_>> {
+        self.link_first_occurrences.then(|| CharacterLinker {
+            pending: self
+                .characters
+                .iter()
+                .map(|(name, _)| name.as_str())
+                .collect(),
+        })
+    }
+}
+
+/// Links the first occurrence of every character name, remembering the names already linked.
+#[derive(Debug)]
+pub(crate) struct CharacterLinker<'c> {
+    /// The names not found in the text yet.
+    pending: Vec<&'c str>,
+}
+
+impl CharacterLinker<'_> {
+    /// Links the first occurrence of the pending names in a body.
+    ///
+    /// Returns `None` if no name was found.
+    pub(crate) fn link(&mut self, body: &str) -> Option<String> {
+        if self.pending.is_empty() {
+            return None;
+        }
+
+        xml::rewrite_text(body, &SKIPPED_ELEMENTS, |text, result| {
+            let mut changed = false;
+            let mut rest = text;
+
+            // Link the earliest pending name of the text until there is none left
+            while let Some((position, index)) = self
+                .pending
+                .iter()
+                .enumerate()
+                .filter_map(|(index, name)| xml::find_word(rest, name).map(|p| (p, index)))
+                .min()
+            {
+                let name = self.pending.remove(index);
+                result.push_str(&rest[..position]);
+                result.push_str(&format!(
+                    r##"<a class="character" href="{CHARACTERS_FILENAME}#{}">{name}</a>"##,
+                    character_id(name)
+                ));
+                rest = &rest[position + name.len()..];
+                changed = true;
+            }
+
+            result.push_str(rest);
+            changed
+        })
+    }
+}
+
+/// Gets the id of the entry of a character, made of the lowercase alphanumeric characters of the name.
+fn character_id(name: &str) -> String {
+    let slug = name
+        .split(|c: char| !c.is_alphanumeric())
+        .filter(|word| !word.is_empty())
+        .map(str::to_lowercase)
+        .collect::<Vec<_>>()
+        .join("-");
+    format!("character-{slug}")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_characters_build() {
+        let characters = Characters::new()
+            .add_character("Lady Macbeth", "wife of <i>Macbeth</i>")
+            .add_character("Porter", "");
+
+        assert_eq!(
+            characters.build(),
+            r#"<dl class="characters"><dt id="character-lady-macbeth">Lady Macbeth</dt><dd>wife of <i>Macbeth</i></dd><dt id="character-porter">Porter</dt></dl>"#
+        );
+        assert!(characters.linker().is_none());
+    }
+
+    #[test]
+    fn test_characters_link_first_occurrences() {
+        let characters = Characters::new()
+            .add_character("Hamlet", "")
+            .add_character("Horatio", "")
+            .link_first_occurrences(true);
+        let mut linker = characters.linker().unwrap();
+
+        assert_eq!(
+            linker
+                .link("<h1>Hamlet</h1><p>Horatio and Hamlet. Hamlet again.</p>")
+                .unwrap(),
+            r##"<h1>Hamlet</h1><p><a class="character" href="characters.xhtml#character-horatio">Horatio</a> and <a class="character" href="characters.xhtml#character-hamlet">Hamlet</a>. Hamlet again.</p>"##
+        );
+        assert!(linker.link("<p>Hamlet and Horatio</p>").is_none());
+    }
+}
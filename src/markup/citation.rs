@@ -0,0 +1,490 @@
+use std::fmt::Display;
+
+use quick_xml::escape::escape;
+
+/// The CSS rules used by [`Bibliography`].
+pub const BIBLIOGRAPHY_CSS: &str =
+    ".bibliography p { margin: 0 0 0.5em 0; padding-left: 2em; text-indent: -2em; }
+a.citation { text-decoration: none; }";
+
+/// The filename of the generated bibliography page.
+pub const BIBLIOGRAPHY_FILENAME: &str = "bibliography.xhtml";
+
+/// The style used to format the bibliography entries and the inline citations.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum CitationStyle {
+    /// APA style: `Smith, J. (2020). Title. Publisher.`, cited as `(Smith, 2020)`.
+    #[default]
+    Apa,
+    /// Chicago author-date style: `Smith, John. 2020. Title. Publisher.`, cited as `(Smith 2020)`.
+    Chicago,
+}
+
+/// A **reference** to a cited work, registered in a [`Bibliography`] under a unique key.
+#[derive(Debug, Clone)]
+pub struct Citation {
+    /// The unique key of the reference (e.g., `"smith2020"`).
+    pub(crate) key: String,
+    /// The title of the work.
+    title: String,
+    /// The authors, as `"Last, First"` or `"First Last"`.
+    authors: Vec<String>,
+    /// Optional year of publication.
+    year: Option<u16>,
+    /// Optional publisher (books).
+    publisher: Option<String>,
+    /// Optional journal or container title (articles).
+    journal: Option<String>,
+    /// Optional DOI, like `10.1000/182`.
+    doi: Option<String>,
+    /// Optional URL, used if there is no DOI.
+    url: Option<String>,
+}
+
+impl Citation {
+    /// Gets the surname of every author.
+    fn surnames(&self) -> Vec<&str> {
+        self.authors
+            .iter()
+            .map(|author| split_name(author).0)
+            .collect()
+    }
+
+    /// Gets the DOI (as a URL) or the URL of the work.
+    fn link(&self) -> Option<String> {
+        self.doi
+            .as_ref()
+            .map(|doi| format!("https://doi.org/{doi}"))
+            .or_else(|| self.url.clone())
+    }
+}
+
+/// A builder for a [`Citation`].
+#[derive(Debug, Clone)]
+pub struct CitationBuilder(Citation);
+
+impl CitationBuilder {
+    /// Creates a reference with its unique key and the title of the work.
+    pub fn new<K: Into<String>, T: Into<String>>(key: K, title: T) -> Self {
+        Self(Citation {
+            key: key.into(),
+            title: title.into(),
+            authors: Vec::new(),
+            year: None,
+            publisher: None,
+            journal: None,
+            doi: None,
+            url: None,
+        })
+    }
+
+    /// Adds an **author**, as `"Last, First"` or `"First Last"`.
+    pub fn author<S: Into<String>>(mut self, author: S) -> Self {
+        self.0.authors.push(author.into());
+        self
+    }
+
+    /// Sets the **year** of publication.
+    pub fn year(mut self, year: u16) -> Self {
+        self.0.year = Some(year);
+        self
+    }
+
+    /// Sets the **publisher** of a book.
+    pub fn publisher<S: Into<String>>(mut self, publisher: S) -> Self {
+        self.0.publisher = Some(publisher.into());
+        self
+    }
+
+    /// Sets the **journal** of an article.
+    pub fn journal<S: Into<String>>(mut self, journal: S) -> Self {
+        self.0.journal = Some(journal.into());
+        self
+    }
+
+    /// Sets the **DOI** of the work, like `10.1000/182`.
+    pub fn doi<S: Into<String>>(mut self, doi: S) -> Self {
+        self.0.doi = Some(doi.into());
+        self
+    }
+
+    /// Sets the **URL** of the work, shown if there is no DOI.
+    pub fn url<S: Into<String>>(mut self, url: S) -> Self {
+        self.0.url = Some(url.into());
+        self
+    }
+
+    /// Consumes the builder and returns the final [`Citation`].
+    #[must_use]
+    pub fn build(self) -> Citation {
+        self.0
+    }
+}
+
+/// A registry of [`Citation`]s rendered into a generated **bibliography page**
+/// (see [`crate::epub::EpubBuilder::bibliography`]), sorted by author and year.
+///
+/// ```rust
+/// use liber::markup::{Bibliography, CitationBuilder, CitationStyle};
+///
+/// let bibliography = Bibliography::new(CitationStyle::Apa).add_citation(
+///     CitationBuilder::new("smith2020", "Rust in Depth")
+///         .author("Smith, John")
+///         .year(2020)
+///         .publisher("Acme")
+///         .build(),
+/// );
+/// assert_eq!(
+///     bibliography.link("smith2020").unwrap(),
+///     r##"<a class="citation" href="bibliography.xhtml#ref-smith2020">(Smith, 2020)</a>"##
+/// );
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct Bibliography {
+    /// The style of the entries and inline citations.
+    style: CitationStyle,
+    /// The registered references.
+    citations: Vec<Citation>,
+}
+
+impl Bibliography {
+    /// Creates an empty bibliography with the given style.
+    #[must_use]
+    pub fn new(style: CitationStyle) -> Self {
+        Self {
+            style,
+            citations: Vec::new(),
+        }
+    }
+
+    /// Registers a reference.
+    pub fn add_citation(mut self, citation: Citation) -> Self {
+        self.citations.push(citation);
+        self
+    }
+
+    /// Registers several references.
+    pub fn add_citations(mut self, citations: Vec<Citation>) -> Self {
+        self.citations.extend(citations);
+        self
+    }
+
+    /// Gets the registered reference with the given key.
+    #[must_use]
+    pub fn get(&self, key: &str) -> Option<&Citation> {
+        self.citations.iter().find(|citation| citation.key == key)
+    }
+
+    /// Gets an **inline citation** (e.g., `(Smith, 2020)`) of the reference with the given key,
+    /// linked to its entry in the bibliography page.
+    ///
+    /// Returns `None` if there is no reference with that key.
+    #[must_use]
+    pub fn link(&self, key: &str) -> Option<String> {
+        self.get(key).map(|citation| {
+            format!(
+                r##"<a class="citation" href="{BIBLIOGRAPHY_FILENAME}#{}">{}</a>"##,
+                entry_id(key),
+                escape(&self.inline(citation))
+            )
+        })
+    }
+
+    /// Builds the markup of the entries, sorted by the surname of the first author and the year.
+    #[must_use]
+    pub fn build(&self) -> String {
+        let mut citations = self.citations.iter().collect::<Vec<_>>();
+        citations.sort_by_key(|citation| {
+            (
+                citation
+                    .surnames()
+                    .first()
+                    .map(|surname| surname.to_lowercase()),
+                citation.year,
+            )
+        });
+
+        let entries = citations
+            .into_iter()
+            .map(|citation| {
+                format!(
+                    r#"<p id="{}">{}</p>"#,
+                    entry_id(&citation.key),
+                    self.entry(citation)
+                )
+            })
+            .collect::<String>();
+
+        format!(r#"<div class="bibliography">{entries}</div>"#)
+    }
+
+    /// Gets the inline citation text of a reference.
+    fn inline(&self, citation: &Citation) -> String {
+        let surnames = citation.surnames();
+        let (and, separator) = match self.style {
+            CitationStyle::Apa => ("&", ", "),
+            CitationStyle::Chicago => ("and", " "),
+        };
+
+        let authors = match surnames.as_slice() {
+            [] => citation.title.clone(),
+            [author] => author.to_string(),
+            [first, second] => format!("{first} {and} {second}"),
+            [first, ..] => format!("{first} et al."),
+        };
+        let year = citation
+            .year
+            .map_or_else(|| "n.d.".to_string(), |year| year.to_string());
+
+        format!("({authors}{separator}{year})")
+    }
+
+    /// Gets the formatted (and escaped) bibliography entry of a reference.
+    fn entry(&self, citation: &Citation) -> String {
+        let year = citation
+            .year
+            .map_or_else(|| "n.d.".to_string(), |year| year.to_string());
+        let title = escape(&citation.title);
+
+        let mut entry = match self.style {
+            CitationStyle::Apa => {
+                let authors = citation
+                    .authors
+                    .iter()
+                    .map(|author| {
+                        let (last, first) = split_name(author);
+                        let initials = first
+                            .split_whitespace()
+                            .filter_map(|name| name.chars().next())
+                            .map(|initial| format!("{initial}."))
+                            .collect::<Vec<_>>()
+                            .join(" ");
+                        if initials.is_empty() {
+                            last.to_string()
+                        } else {
+                            format!("{last}, {initials}")
+                        }
+                    })
+                    .collect::<Vec<_>>();
+
+                let title = match citation.journal {
+                    Some(ref journal) => format!("{title}. <i>{}</i>", escape(journal)),
+                    None => format!("<i>{title}</i>"),
+                };
+                format!("{} ({year}). {title}.", escape(&join_names(&authors, "&")))
+            }
+            CitationStyle::Chicago => {
+                let authors = citation
+                    .authors
+                    .iter()
+                    .enumerate()
+                    .map(|(index, author)| {
+                        let (last, first) = split_name(author);
+                        match (index, first.is_empty()) {
+                            (_, true) => last.to_string(),
+                            (0, false) => format!("{last}, {first}"),
+                            _ => format!("{first} {last}"),
+                        }
+                    })
+                    .collect::<Vec<_>>();
+
+                let title = match citation.journal {
+                    Some(ref journal) => format!("“{title}.” <i>{}</i>", escape(journal)),
+                    None => format!("<i>{title}</i>"),
+                };
+                let year = if year.ends_with('.') {
+                    year
+                } else {
+                    format!("{year}.")
+                };
+                format!("{}. {year} {title}.", escape(&join_names(&authors, "and")))
+            }
+        };
+
+        if let Some(ref publisher) = citation.publisher {
+            entry.push_str(&format!(" {}.", escape(publisher)));
+        }
+        if let Some(link) = citation.link() {
+            entry.push_str(&format!(" {}", escape(&link)));
+        }
+        entry
+    }
+}
+
+/// An **inline citation marker** of the reference with the given key, rendered (through `Display`)
+/// as a placeholder that is resolved at build time into the linked citation, like `(Smith, 2020)`.
+///
+/// Generating the book fails if the key is not registered in the [`Bibliography`] set with
+/// [`crate::epub::EpubBuilder::bibliography`].
+///
+/// ```rust
+/// use liber::markup::CitationRef;
+///
+/// let body = format!("<body><p>As shown before {}.</p></body>", CitationRef::new("smith2020"));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CitationRef(String);
+
+impl CitationRef {
+    /// The start of the placeholder, followed by the key.
+    pub(crate) const PLACEHOLDER_START: &str = r#"<a class="citation" data-cite=""#;
+    /// The end of the placeholder, following the key.
+    pub(crate) const PLACEHOLDER_END: &str = r#""></a>"#;
+
+    /// Creates a marker citing the reference with the given key.
+    pub fn new<S: Into<String>>(key: S) -> Self {
+        Self(key.into())
+    }
+}
+
+impl Display for CitationRef {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(
+            f,
+            "{}{}{}",
+            Self::PLACEHOLDER_START,
+            escape(&self.0),
+            Self::PLACEHOLDER_END
+        )
+    }
+}
+
+impl Bibliography {
+    /// Replaces every [`CitationRef`] placeholder of a body with its linked inline citation.
+    ///
+    /// Returns `None` if there is no placeholder.
+    ///
+    /// # Errors
+    /// Returns a [`crate::Error::Validation`] naming the first key that is not registered.
+    pub(crate) fn resolve(&self, body: &str) -> crate::Result<Option<String>> {
+        if !body.contains(CitationRef::PLACEHOLDER_START) {
+            return Ok(None);
+        }
+
+        let mut result = String::with_capacity(body.len());
+        let mut rest = body;
+        while let Some(start) = rest.find(CitationRef::PLACEHOLDER_START) {
+            let key_start = start + CitationRef::PLACEHOLDER_START.len();
+            let Some(key_len) = rest[key_start..].find(CitationRef::PLACEHOLDER_END) else {
+                break;
+            };
+            let key = &rest[key_start..key_start + key_len];
+
+            let link = self
+                .link(key)
+                .ok_or_else(|| crate::Error::Validation(format!("unknown citation key '{key}'")))?;
+
+            result.push_str(&rest[..start]);
+            result.push_str(&link);
+            rest = &rest[key_start + key_len + CitationRef::PLACEHOLDER_END.len()..];
+        }
+        result.push_str(rest);
+
+        Ok(Some(result))
+    }
+}
+
+/// Gets the id of the bibliography entry of the reference with the given key.
+fn entry_id(key: &str) -> String {
+    format!("ref-{key}")
+}
+
+/// Splits a name, given as `"Last, First"` or `"First Last"`, into its surname and first names.
+fn split_name(name: &str) -> (&str, &str) {
+    match name.split_once(',') {
+        Some((last, first)) => (last.trim(), first.trim()),
+        None => match name.trim().rsplit_once(' ') {
+            Some((first, last)) => (last, first.trim()),
+            None => (name.trim(), ""),
+        },
+    }
+}
+
+/// Joins names as `A`, `A, and B` or `A, B, and C` (names are usually inverted, like `Smith, J.`).
+fn join_names(names: &[String], and: &str) -> String {
+    match names {
+        [] => String::new(),
+        [name] => name.clone(),
+        [init @ .., last] => format!("{}, {and} {last}", init.join(", ")),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn citations() -> Vec<Citation> {
+        vec![
+            CitationBuilder::new("smith2020", "Rust in Depth")
+                .author("John Smith")
+                .author("Doe, Anne Marie")
+                .year(2020)
+                .publisher("Acme & Sons")
+                .build(),
+            CitationBuilder::new("adams1999", "On Ownership")
+                .author("Adams, Ada")
+                .journal("Journal of Systems")
+                .doi("10.1000/182")
+                .build(),
+        ]
+    }
+
+    #[test]
+    fn test_bibliography_apa() {
+        let bibliography = Bibliography::new(CitationStyle::Apa).add_citations(citations());
+
+        assert_eq!(
+            bibliography.build(),
+            r#"<div class="bibliography"><p id="ref-adams1999">Adams, A. (n.d.). On Ownership. <i>Journal of Systems</i>. https://doi.org/10.1000/182</p><p id="ref-smith2020">Smith, J., &amp; Doe, A. M. (2020). <i>Rust in Depth</i>. Acme &amp; Sons.</p></div>"#
+        );
+        assert_eq!(
+            bibliography.link("smith2020").unwrap(),
+            r##"<a class="citation" href="bibliography.xhtml#ref-smith2020">(Smith &amp; Doe, 2020)</a>"##
+        );
+        assert!(bibliography.link("unknown").is_none());
+    }
+
+    #[test]
+    fn test_bibliography_resolve() {
+        let bibliography = Bibliography::new(CitationStyle::Apa).add_citations(citations());
+        let body = format!(
+            "<p>See {} and {}.</p>",
+            CitationRef::new("adams1999"),
+            CitationRef::new("smith2020")
+        );
+
+        assert_eq!(
+            bibliography.resolve(&body).unwrap().unwrap(),
+            r##"<p>See <a class="citation" href="bibliography.xhtml#ref-adams1999">(Adams, n.d.)</a> and <a class="citation" href="bibliography.xhtml#ref-smith2020">(Smith &amp; Doe, 2020)</a>.</p>"##
+        );
+        assert!(bibliography.resolve("<p/>").unwrap().is_none());
+
+        let error = bibliography
+            .resolve(&CitationRef::new("missing").to_string())
+            .unwrap_err();
+        assert_eq!(
+            error.to_string(),
+            "Validation error: unknown citation key 'missing'"
+        );
+    }
+
+    #[test]
+    fn test_bibliography_chicago() {
+        let bibliography = Bibliography::new(CitationStyle::Chicago).add_citations(citations());
+
+        assert!(bibliography.build().contains(
+            r#"<p id="ref-smith2020">Smith, John, and Anne Marie Doe. 2020. <i>Rust in Depth</i>. Acme &amp; Sons.</p>"#
+        ));
+        assert!(
+            bibliography
+                .build()
+                .contains("Adams, Ada. n.d. “On Ownership.” <i>Journal of Systems</i>.")
+        );
+        assert!(
+            bibliography
+                .link("adams1999")
+                .unwrap()
+                .ends_with(">(Adams n.d.)</a>")
+        );
+    }
+}
@@ -0,0 +1,105 @@
+/// The CSS rules used by [`drop_cap`].
+///
+/// The drop cap floats instead of relying on `::first-letter`, which is ignored or misplaced by
+/// several reading systems, and its line height is fixed so it doesn't push the following lines.
+pub const DROP_CAP_CSS: &str = ".chapter-opening { text-indent: 0; margin-top: 0; }
+.chapter-opening::first-line { font-variant: small-caps; }
+.chapter-opening .drop-cap { float: left; font-size: 3.2em; line-height: 0.85em; height: 0.85em; margin: 0.05em 0.08em 0 0; padding: 0; font-weight: normal; }";
+
+/// Gets the **opening paragraph of a chapter** with a drop cap: its first letter (along with any
+/// opening punctuation, like `“`) is wrapped in a `drop-cap` span and its first line is set in small caps.
+///
+/// `paragraph` is the XHTML content of the paragraph, so leading inline tags are kept outside the
+/// drop cap. Use [`DROP_CAP_CSS`] to style it.
+///
+/// ```rust
+/// use liber::markup::drop_cap;
+///
+/// assert_eq!(
+///     drop_cap("It was a dark night."),
+///     r#"<p class="chapter-opening"><span class="drop-cap">I</span>t was a dark night.</p>"#
+/// );
+/// ```
+#[must_use]
+pub fn drop_cap(paragraph: &str) -> String {
+    let text_start = leading_tags_len(paragraph);
+    let (tags, text) = paragraph.split_at(text_start);
+
+    let mut letter_end = 0;
+    let mut rest = text;
+    while let Some(len) = first_char_len(rest) {
+        let is_letter = rest[..len]
+            .chars()
+            .next()
+            .is_some_and(char::is_alphanumeric);
+        letter_end += len;
+        rest = &rest[len..];
+        if is_letter {
+            break;
+        }
+    }
+
+    if letter_end == 0 {
+        return format!(r#"<p class="chapter-opening">{paragraph}</p>"#);
+    }
+
+    format!(
+        r#"<p class="chapter-opening">{tags}<span class="drop-cap">{}</span>{rest}</p>"#,
+        &text[..letter_end]
+    )
+}
+
+/// Gets the length of the tags (and whitespace) at the start of an XHTML fragment.
+fn leading_tags_len(fragment: &str) -> usize {
+    let mut position = 0;
+    loop {
+        let rest = &fragment[position..];
+        let trimmed = rest.trim_start();
+        position += rest.len() - trimmed.len();
+
+        match trimmed.strip_prefix('<').and_then(|tag| tag.find('>')) {
+            Some(end) => position += end + 2,
+            None => return position,
+        }
+    }
+}
+
+/// Gets the length of the first character of a text, counting an entity (like `&#8220;`) as one character.
+fn first_char_len(text: &str) -> Option<usize> {
+    if text.starts_with('&') {
+        if let Some(end) = text.find(';') {
+            return Some(end + 1);
+        }
+    }
+    text.chars().next().map(char::len_utf8)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_drop_cap_with_punctuation() {
+        assert_eq!(
+            drop_cap("“Émile,” she said."),
+            r#"<p class="chapter-opening"><span class="drop-cap">“É</span>mile,” she said.</p>"#
+        );
+        assert_eq!(
+            drop_cap("&#8220;Yes.&#8221;"),
+            r#"<p class="chapter-opening"><span class="drop-cap">&#8220;Y</span>es.&#8221;</p>"#
+        );
+    }
+
+    #[test]
+    fn test_drop_cap_with_leading_tags() {
+        assert_eq!(
+            drop_cap(r#" <a id="start"></a><em>Once</em> upon"#),
+            r#"<p class="chapter-opening"> <a id="start"></a><em><span class="drop-cap">O</span>nce</em> upon</p>"#
+        );
+    }
+
+    #[test]
+    fn test_drop_cap_without_text() {
+        assert_eq!(drop_cap("<br/>"), r#"<p class="chapter-opening"><br/></p>"#);
+    }
+}
@@ -0,0 +1,250 @@
+use std::fmt::Display;
+
+use quick_xml::escape::escape;
+
+use crate::output::xml;
+
+/// The CSS rules used by [`Figure`] and the generated list of illustrations.
+pub const FIGURE_CSS: &str = "div.figure { margin: 1em 0; text-align: center; }
+div.figure img { max-width: 100%; }
+p.caption { font-size: 0.9em; font-style: italic; }
+ol.loi { list-style-type: none; padding-left: 0; }";
+
+/// The filename of the generated list of illustrations.
+pub const LOI_FILENAME: &str = "loi.xhtml";
+
+/// A **figure**: an image with an optional caption, rendered (through `Display`) as markup to embed in a content body.
+///
+/// Figures (and the `<figure>` elements of imported HTML) are listed in the generated list of illustrations
+/// (see [`crate::epub::EpubBuilder::list_of_illustrations`]), labeled with their caption.
+///
+/// ```rust
+/// use liber::markup::Figure;
+///
+/// let figure = Figure::new("map.png", "Map of the island").caption("The island in 1850");
+/// assert_eq!(
+///     figure.to_string(),
+///     r#"<div class="figure"><img src="map.png" alt="Map of the island"/><p class="caption">The island in 1850</p></div>"#
+/// );
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Figure {
+    /// The `src` of the image (e.g., its filename).
+    src: String,
+    /// The alternative text of the image.
+    alt: String,
+    /// Optional caption shown under the image.
+    caption: Option<String>,
+    /// Optional id of the figure element, generated if not set.
+    id: Option<String>,
+}
+
+impl Figure {
+    /// Creates a figure of the image `src` with its alternative text.
+    pub fn new<S: Into<String>, A: Into<String>>(src: S, alt: A) -> Self {
+        Self {
+            src: src.into(),
+            alt: alt.into(),
+            caption: None,
+            id: None,
+        }
+    }
+
+    /// Sets the **caption** shown under the image, also used in the list of illustrations.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn caption<S: Into<String>>(mut self, caption: S) -> Self {
+        self.caption = Some(caption.into());
+        self
+    }
+
+    /// Sets the **id** of the figure element. Defaults to a generated `figure-N` id.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn id<S: Into<String>>(mut self, id: S) -> Self {
+        self.id = Some(id.into());
+        self
+    }
+}
+
+impl Display for Figure {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str(r#"<div class="figure""#)?;
+        if let Some(ref id) = self.id {
+            write!(f, r#" id="{}""#, escape(id))?;
+        }
+        write!(
+            f,
+            r#"><img src="{}" alt="{}"/>"#,
+            escape(&self.src),
+            escape(&self.alt)
+        )?;
+        if let Some(ref caption) = self.caption {
+            write!(f, r#"<p class="caption">{}</p>"#, escape(caption))?;
+        }
+        f.write_str("</div>")
+    }
+}
+
+/// The figures found in the content bodies, in reading order.
+#[derive(Debug, Default)]
+pub(crate) struct FigureList {
+    /// The `(href, caption)` of every figure.
+    figures: Vec<(String, String)>,
+}
+
+impl FigureList {
+    /// Records every figure of a body (a [`Figure`] or a `<figure>` element), adding a generated
+    /// `figure-N` id to the figures without one.
+    ///
+    /// Returns `None` if no id was added.
+    pub(crate) fn resolve(&mut self, filename: &str, body: &str) -> Option<String> {
+        let mut result = String::with_capacity(body.len());
+        let mut changed = false;
+        let mut rest = body;
+
+        while let Some((start, name)) = find_figure(rest) {
+            let tag_end = start + rest[start..].find('>')?;
+            let close = format!("</{name}>");
+            let end = rest[tag_end..]
+                .find(&close)
+                .map_or(rest.len(), |position| tag_end + position);
+            let number = self.figures.len() + 1;
+
+            let tag = &rest[start..tag_end];
+            let id = match xml::attribute(tag, "id") {
+                Some(id) => id.to_string(),
+                None => {
+                    let id = format!("figure-{number}");
+                    let insert = start + name.len() + 1;
+                    result.push_str(&rest[..insert]);
+                    result.push_str(&format!(r#" id="{id}""#));
+                    result.push_str(&rest[insert..tag_end]);
+                    rest = &rest[tag_end..];
+                    changed = true;
+                    self.figures.push((
+                        format!("{filename}#{id}"),
+                        caption(&rest[..end - tag_end], number),
+                    ));
+                    continue;
+                }
+            };
+
+            self.figures.push((
+                format!("{filename}#{id}"),
+                caption(&rest[tag_end..end], number),
+            ));
+            result.push_str(&rest[..tag_end]);
+            rest = &rest[tag_end..];
+        }
+        result.push_str(rest);
+
+        changed.then_some(result)
+    }
+
+    /// Returns `true` if no figure was found.
+    pub(crate) fn is_empty(&self) -> bool {
+        self.figures.is_empty()
+    }
+
+    /// Builds the markup of the list of illustrations: a link to every figure, labeled with its caption.
+    pub(crate) fn build(&self) -> String {
+        let items = self
+            .figures
+            .iter()
+            .map(|(href, caption)| format!(r#"<li><a href="{href}">{caption}</a></li>"#))
+            .collect::<String>();
+        format!(r#"<ol class="loi">{items}</ol>"#)
+    }
+}
+
+/// Finds the next figure start tag: a `<figure>` element or a `<div>` of class `figure`.
+///
+/// Returns its position and element name.
+fn find_figure(xhtml: &str) -> Option<(usize, &'static str)> {
+    xhtml.match_indices('<').find_map(|(start, _)| {
+        let rest = &xhtml[start + 1..];
+        let tag = &rest[..rest.find('>')?];
+        let is_named = |name: &str| {
+            tag.starts_with(name)
+                && tag[name.len()..].starts_with(|c: char| c.is_whitespace() || c == '/')
+                || tag == name
+        };
+
+        if is_named("figure") {
+            Some((start, "figure"))
+        } else if is_named("div")
+            && xml::attribute(tag, "class")
+                .is_some_and(|class| class.split_whitespace().any(|class| class == "figure"))
+        {
+            Some((start, "div"))
+        } else {
+            None
+        }
+    })
+}
+
+/// Gets the caption of a figure from its inner markup: the text of its `<figcaption>` (or
+/// `class="caption"` element), falling back to the `alt` of its image and then to `Figure N`.
+fn caption(inner: &str, number: usize) -> String {
+    let text = |start: usize| {
+        let name_end = inner[start + 1..].find(|c: char| c.is_whitespace() || c == '>')?;
+        let close = format!("</{}>", &inner[start + 1..start + 1 + name_end]);
+        let content_start = start + inner[start..].find('>')? + 1;
+        let content_end = content_start + inner[content_start..].find(&close)?;
+        let text = xml::strip_tags(&inner[content_start..content_end])
+            .split_whitespace()
+            .collect::<Vec<_>>()
+            .join(" ");
+        (!text.is_empty()).then_some(text)
+    };
+
+    inner
+        .find("<figcaption")
+        .or_else(|| {
+            inner
+                .find(r#"class="caption""#)
+                .and_then(|position| inner[..position].rfind('<'))
+        })
+        .and_then(text)
+        .or_else(|| {
+            let img = inner.find("<img")?;
+            let tag = &inner[img..img + inner[img..].find('>')?];
+            xml::attribute(tag, "alt")
+                .filter(|alt| !alt.is_empty())
+                .map(str::to_string)
+        })
+        .unwrap_or_else(|| format!("Figure {number}"))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_figure() {
+        let figure = Figure::new("a.png", "A & B").id("f1");
+        assert_eq!(
+            figure.to_string(),
+            r#"<div class="figure" id="f1"><img src="a.png" alt="A &amp; B"/></div>"#
+        );
+    }
+
+    #[test]
+    fn test_figure_list() {
+        let mut figures = FigureList::default();
+        let body = format!(
+            r#"<body>{}<figure id="chart"><img src="c.png" alt=""/><figcaption>Sales <em>2020</em> report</figcaption></figure><figure><img src="d.png" alt="Diagram"/></figure><div class="note">Text</div></body>"#,
+            Figure::new("m.png", "Map").caption("The island")
+        );
+        let resolved = figures.resolve("c01.xhtml", &body).unwrap();
+        assert!(resolved.contains(r#"<div id="figure-1" class="figure">"#));
+        assert!(resolved.contains(r#"<figure id="figure-3">"#));
+
+        assert_eq!(
+            figures.build(),
+            r#"<ol class="loi"><li><a href="c01.xhtml#figure-1">The island</a></li><li><a href="c01.xhtml#chart">Sales 2020 report</a></li><li><a href="c01.xhtml#figure-3">Diagram</a></li></ol>"#
+        );
+        assert!(figures.resolve("c02.xhtml", "<body/>").is_none());
+    }
+}
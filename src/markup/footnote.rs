@@ -0,0 +1,171 @@
+/// The CSS rules used by [`Footnotes`] and the generated endnotes page.
+pub const FOOTNOTE_CSS: &str =
+    "a.noteref { text-decoration: none; vertical-align: super; font-size: 0.75em; line-height: 0; }
+aside.footnote, .endnote { margin: 0.5em 0; font-size: 0.9em; }
+aside.footnote p, .endnote p { margin: 0; text-indent: 0; }";
+
+/// The filename of the generated endnotes page.
+pub const ENDNOTES_FILENAME: &str = "notes.xhtml";
+
+/// Collects the **footnotes** of a content, using the EPUB3 semantics that make reading systems
+/// (e.g., Apple Books or Kobo) show them as pop-ups instead of jumping to the end of the chapter.
+///
+/// Each note reference is an `epub:type="noteref"` link (with `role="doc-noteref"`) and each note an
+/// `<aside epub:type="footnote">` (with `role="doc-footnote"`), linking back to its reference. This markup
+/// is EPUB 3, reported by the [`crate::epub::Linter`] under [`crate::epub::LintRule::Epub3Only`].
+///
+/// ```rust
+/// use liber::markup::Footnotes;
+///
+/// let mut footnotes = Footnotes::new("ch1");
+/// let body = format!(
+///     "<body><p>A claim.{}</p>{}</body>",
+///     footnotes.note_ref("The source."),
+///     footnotes.build()
+/// );
+/// assert!(body.contains(r##"href="#ch1-fn1""##));
+/// ```
+#[derive(Debug, Default, Clone)]
+pub struct Footnotes {
+    /// The prefix of the ids, which must be unique across the book.
+    prefix: String,
+    /// The file the note references link to, empty if the notes are in the same content.
+    target: String,
+    /// The text of every note, in order.
+    notes: Vec<String>,
+}
+
+impl Footnotes {
+    /// Creates an empty collection whose ids start with `prefix` (e.g., `"ch1"` gives `ch1-fn1`).
+    pub fn new<S: Into<String>>(prefix: S) -> Self {
+        Self {
+            prefix: prefix.into(),
+            target: String::new(),
+            notes: Vec::new(),
+        }
+    }
+
+    /// Creates an empty collection of **endnotes**, whose references link to the generated endnotes page.
+    ///
+    /// Register it with [`crate::epub::ContentBuilder::notes`] instead of building it into the body,
+    /// and enable the page with [`crate::epub::EpubBuilder::endnotes`].
+    pub fn endnotes<S: Into<String>>(prefix: S) -> Self {
+        Self {
+            target: ENDNOTES_FILENAME.to_string(),
+            ..Self::new(prefix)
+        }
+    }
+
+    /// Adds a note with the given text (an XHTML fragment), returning the markup of its reference.
+    pub fn note_ref<S: Into<String>>(&mut self, text: S) -> String {
+        self.notes.push(text.into());
+        let number = self.notes.len();
+
+        format!(
+            r##"<a xmlns:epub="http://www.idpf.org/2007/ops" class="noteref" epub:type="noteref" role="doc-noteref" id="{ref_id}" href="{target}#{id}">{number}</a>"##,
+            ref_id = self.ref_id(number),
+            target = self.target,
+            id = self.id(number),
+        )
+    }
+
+    /// Returns `true` if no note has been added.
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.notes.is_empty()
+    }
+
+    /// Gets the `(id, text)` of every note, in order.
+    pub fn notes(&self) -> impl Iterator<Item = (String, &str)> {
+        self.notes
+            .iter()
+            .enumerate()
+            .map(|(index, text)| (self.id(index + 1), text.as_str()))
+    }
+
+    /// Builds the markup of the notes, usually placed at the end of the content body.
+    #[must_use]
+    pub fn build(&self) -> String {
+        self.notes
+            .iter()
+            .enumerate()
+            .map(|(index, text)| {
+                let number = index + 1;
+                format!(
+                    r##"<aside xmlns:epub="http://www.idpf.org/2007/ops" class="footnote" epub:type="footnote" role="doc-footnote" id="{id}"><p><a href="#{ref_id}">{number}.</a> {text}</p></aside>"##,
+                    id = self.id(number),
+                    ref_id = self.ref_id(number),
+                )
+            })
+            .collect()
+    }
+
+    /// Builds the markup of the notes for the endnotes page, linking back to their references in `filename`.
+    pub(crate) fn endnotes_xml(&self, filename: &str) -> String {
+        self.notes
+            .iter()
+            .enumerate()
+            .map(|(index, text)| {
+                let number = index + 1;
+                format!(
+                    r##"<div class="endnote" epub:type="endnote" role="doc-endnote" id="{id}"><p><a href="{filename}#{ref_id}">{number}.</a> {text}</p></div>"##,
+                    id = self.id(number),
+                    ref_id = self.ref_id(number),
+                )
+            })
+            .collect()
+    }
+
+    /// Gets the id of the note numbered `number`.
+    fn id(&self, number: usize) -> String {
+        format!("{}-fn{number}", self.prefix)
+    }
+
+    /// Gets the id of the reference to the note numbered `number`.
+    fn ref_id(&self, number: usize) -> String {
+        format!("{}-fnref{number}", self.prefix)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_endnotes() {
+        let mut endnotes = Footnotes::endnotes("c2");
+
+        assert!(
+            endnotes
+                .note_ref("Note.")
+                .ends_with(r##"id="c2-fnref1" href="notes.xhtml#c2-fn1">1</a>"##)
+        );
+        assert_eq!(
+            endnotes.endnotes_xml("c02.xhtml"),
+            r##"<div class="endnote" epub:type="endnote" role="doc-endnote" id="c2-fn1"><p><a href="c02.xhtml#c2-fnref1">1.</a> Note.</p></div>"##
+        );
+    }
+
+    #[test]
+    fn test_footnotes() {
+        let mut footnotes = Footnotes::new("c1");
+        assert!(footnotes.is_empty());
+
+        assert_eq!(
+            footnotes.note_ref("First."),
+            r##"<a xmlns:epub="http://www.idpf.org/2007/ops" class="noteref" epub:type="noteref" role="doc-noteref" id="c1-fnref1" href="#c1-fn1">1</a>"##
+        );
+        footnotes.note_ref("<i>Second</i>.");
+
+        assert_eq!(
+            footnotes.notes().collect::<Vec<_>>(),
+            vec![
+                ("c1-fn1".to_string(), "First."),
+                ("c1-fn2".to_string(), "<i>Second</i>.")
+            ]
+        );
+        assert!(footnotes.build().ends_with(
+            r##"role="doc-footnote" id="c1-fn2"><p><a href="#c1-fnref2">2.</a> <i>Second</i>.</p></aside>"##
+        ));
+    }
+}
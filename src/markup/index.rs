@@ -0,0 +1,157 @@
+use std::fmt::Display;
+
+use quick_xml::escape::escape;
+
+/// The CSS rules used by the generated index page.
+pub const INDEX_CSS: &str = ".index h2 { margin: 1em 0 0.5em 0; }
+.index p { margin: 0; padding-left: 2em; text-indent: -2em; }";
+
+/// The filename of the generated index page.
+pub const INDEX_FILENAME: &str = "index.xhtml";
+
+/// An **index term marker**, rendered (through `Display`) as a placeholder that is resolved at build
+/// time into an invisible anchor, listed under the term in the generated index page
+/// (see [`crate::epub::EpubBuilder::index`]).
+///
+/// The index is driven from the manuscript: every marker adds a location to its term.
+///
+/// ```rust
+/// use liber::markup::IndexTerm;
+///
+/// let body = format!("<body><p>{}Recursion is...</p></body>", IndexTerm::new("recursion"));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct IndexTerm(String);
+
+impl IndexTerm {
+    /// The start of the placeholder, followed by the term.
+    const PLACEHOLDER_START: &str = r#"<a class="index-term" data-term=""#;
+    /// The end of the placeholder, following the term.
+    const PLACEHOLDER_END: &str = r#""></a>"#;
+
+    /// Creates a marker of the given term.
+    pub fn new<S: Into<String>>(term: S) -> Self {
+        Self(term.into())
+    }
+}
+
+impl Display for IndexTerm {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(
+            f,
+            "{}{}{}",
+            Self::PLACEHOLDER_START,
+            escape(&self.0),
+            Self::PLACEHOLDER_END
+        )
+    }
+}
+
+/// The locations of the index terms found in the content bodies, in reading order.
+#[derive(Debug, Default)]
+pub(crate) struct TermIndex {
+    /// The `(term, href, content title)` of every location.
+    locations: Vec<(String, String, String)>,
+}
+
+impl TermIndex {
+    /// Replaces every [`IndexTerm`] placeholder of a body with an anchor, recording its location.
+    ///
+    /// Returns `None` if there is no placeholder.
+    pub(crate) fn resolve(&mut self, filename: &str, title: &str, body: &str) -> Option<String> {
+        if !body.contains(IndexTerm::PLACEHOLDER_START) {
+            return None;
+        }
+
+        let mut result = String::with_capacity(body.len());
+        let mut rest = body;
+        while let Some(start) = rest.find(IndexTerm::PLACEHOLDER_START) {
+            let term_start = start + IndexTerm::PLACEHOLDER_START.len();
+            let Some(term_len) = rest[term_start..].find(IndexTerm::PLACEHOLDER_END) else {
+                break;
+            };
+
+            let id = format!("index-term-{}", self.locations.len() + 1);
+            self.locations.push((
+                rest[term_start..term_start + term_len].to_string(),
+                format!("{filename}#{id}"),
+                title.to_string(),
+            ));
+
+            result.push_str(&rest[..start]);
+            result.push_str(&format!(r#"<a id="{id}"></a>"#));
+            rest = &rest[term_start + term_len + IndexTerm::PLACEHOLDER_END.len()..];
+        }
+        result.push_str(rest);
+
+        Some(result)
+    }
+
+    /// Returns `true` if no term was found.
+    pub(crate) fn is_empty(&self) -> bool {
+        self.locations.is_empty()
+    }
+
+    /// Builds the markup of the index: the terms sorted alphabetically, grouped by their initial,
+    /// each one followed by the links to its locations (labeled with the content title).
+    pub(crate) fn build(&self) -> String {
+        let mut terms: Vec<(&str, Vec<(&str, &str)>)> = Vec::new();
+        for (term, href, title) in &self.locations {
+            match terms.iter_mut().find(|(t, _)| t == term) {
+                Some((_, locations)) => locations.push((href, title)),
+                None => terms.push((term, vec![(href, title)])),
+            }
+        }
+        terms.sort_by_key(|(term, _)| term.to_lowercase());
+
+        let mut result = String::from(r#"<div class="index">"#);
+        let mut initial = None;
+        for (term, locations) in terms {
+            let term_initial = term.chars().next().map(|c| c.to_uppercase().to_string());
+            if term_initial != initial {
+                result.push_str(&format!(
+                    "<h2>{}</h2>",
+                    term_initial.as_deref().unwrap_or_default()
+                ));
+                initial = term_initial;
+            }
+
+            let links = locations
+                .into_iter()
+                .map(|(href, title)| format!(r#"<a href="{href}">{title}</a>"#))
+                .collect::<Vec<_>>()
+                .join(", ");
+            result.push_str(&format!("<p>{term}, {links}</p>"));
+        }
+        result.push_str("</div>");
+        result
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_term_index() {
+        let mut index = TermIndex::default();
+        assert!(index.is_empty());
+
+        let body = format!(
+            "<p>{}Loops and {}recursion.</p>",
+            IndexTerm::new("loop"),
+            IndexTerm::new("Recursion")
+        );
+        assert_eq!(
+            index.resolve("c01.xhtml", "One", &body).unwrap(),
+            r#"<p><a id="index-term-1"></a>Loops and <a id="index-term-2"></a>recursion.</p>"#
+        );
+        index.resolve("c02.xhtml", "Two", &IndexTerm::new("loop").to_string());
+        assert!(index.resolve("c03.xhtml", "Three", "<p/>").is_none());
+
+        assert_eq!(
+            index.build(),
+            r##"<div class="index"><h2>L</h2><p>loop, <a href="c01.xhtml#index-term-1">One</a>, <a href="c02.xhtml#index-term-3">Two</a></p><h2>R</h2><p>Recursion, <a href="c01.xhtml#index-term-2">One</a></p></div>"##
+        );
+    }
+}
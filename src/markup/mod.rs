@@ -0,0 +1,38 @@
+//! Helpers emitting XHTML markup (and its companion CSS) for common book typography.
+//!
+//! Every helper returns a markup string to be embedded in a content body. The related CSS
+//! constants are meant to be appended to the stylesheet set with
+//! [`crate::epub::EpubBuilder::stylesheet`].
+//!
+//! Some helpers (e.g., [`Footnotes`], [`page_break_marker`] or [`EpigraphBuilder`]) write EPUB 3 semantics
+//! (`epub:type`, `role` and HTML5 elements like `<aside>`) that are not valid XHTML 1.1: the
+//! [`crate::epub::Linter`] reports them (see [`crate::epub::LintRule::Epub3Only`]).
+mod abbreviation;
+mod character;
+mod citation;
+mod drop_cap;
+mod figure;
+mod footnote;
+mod index;
+mod page_break;
+mod quiz;
+mod quote;
+mod reading_time;
+mod ruby;
+mod screenplay;
+mod verse;
+
+pub use abbreviation::*;
+pub use character::*;
+pub use citation::*;
+pub use drop_cap::*;
+pub use figure::*;
+pub use footnote::*;
+pub use index::*;
+pub use page_break::*;
+pub use quiz::*;
+pub use quote::*;
+pub use reading_time::*;
+pub use ruby::*;
+pub use screenplay::*;
+pub use verse::*;
@@ -0,0 +1,221 @@
+use std::fmt::Display;
+
+use quick_xml::escape::escape;
+
+/// The class that makes an element (e.g., a `<body>`, using
+/// [`crate::epub::ContentBuilder::body_attribute`]) start on a new page.
+pub const PAGE_BREAK_BEFORE_CLASS: &str = "page-break-before";
+
+/// The CSS rules used by the page-break helpers.
+pub const PAGE_BREAK_CSS: &str =
+    ".page-break-before { page-break-before: always; break-before: page; }
+.page-break { page-break-after: always; break-after: page; height: 0; margin: 0; }";
+
+/// Gets a **forced page break**, rendering the following markup on a new page.
+///
+/// The break is set inline too, so it works even if [`PAGE_BREAK_CSS`] is not used.
+#[must_use]
+pub fn page_break() -> &'static str {
+    r#"<div class="page-break" style="page-break-after: always;"></div>"#
+}
+
+/// Gets an **invisible print page anchor** for the page labeled `label` (e.g., `"12"` or `"xiv"`).
+///
+/// The anchor is an empty EPUB3 `epub:type="pagebreak"` span with `role="doc-pagebreak"` (ignored by
+/// EPUB 2 reading systems), whose id is given by [`page_id`].
+#[must_use]
+pub fn page_break_marker(label: &str) -> String {
+    format!(
+        r#"<span xmlns:epub="http://www.idpf.org/2007/ops" epub:type="pagebreak" role="doc-pagebreak" id="{}" title="{}"></span>"#,
+        page_id(label),
+        escape(label)
+    )
+}
+
+/// The placeholder rendered by [`PageMarker::auto`], labeled when the book is generated.
+const AUTO_PAGE_MARKER: &str = r#"<span xmlns:epub="http://www.idpf.org/2007/ops" epub:type="pagebreak" role="doc-pagebreak"></span>"#;
+
+/// A **print page marker**, rendered (through `Display`) as the invisible anchor of [`page_break_marker`].
+///
+/// Every marker found in the content bodies is also listed in the `pageList` of the `toc.ncx`,
+/// so reading systems can map the print page numbers to the text.
+///
+/// Markers created with [`PageMarker::auto`] are numbered when the book is generated, following print
+/// conventions: lowercase roman numerals (i, ii, iii) in the front matter and arabic numbers (1, 2, 3)
+/// from the body matter on (see [`crate::epub::ContentBuilder::matter`]).
+///
+/// ```rust
+/// use liber::markup::PageMarker;
+///
+/// let body = format!("<body><p>End of page 11.</p>{}<p>Page 12.</p></body>", PageMarker::new("12"));
+/// assert!(body.contains(r#"id="page-12""#));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct PageMarker(String);
+
+impl PageMarker {
+    /// Creates a marker for the page labeled `label` (e.g., `"12"` or `"xiv"`).
+    pub fn new<S: Into<String>>(label: S) -> Self {
+        Self(label.into())
+    }
+
+    /// Creates a marker **numbered automatically** when the book is generated.
+    pub fn auto() -> Self {
+        Self(String::new())
+    }
+
+    /// Gets the label of the page (empty for automatically numbered markers).
+    #[must_use]
+    pub fn label(&self) -> &str {
+        &self.0
+    }
+}
+
+impl Display for PageMarker {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        if self.0.is_empty() {
+            f.write_str(AUTO_PAGE_MARKER)
+        } else {
+            f.write_str(&page_break_marker(&self.0))
+        }
+    }
+}
+
+/// Gets the **lowercase roman numeral** of a number (e.g., `"xiv"` for `14`), as used to number front matter pages.
+///
+/// Returns an empty string for `0`.
+#[must_use]
+pub fn roman_numeral(mut number: usize) -> String {
+    const NUMERALS: [(usize, &str); 13] = [
+        (1000, "m"),
+        (900, "cm"),
+        (500, "d"),
+        (400, "cd"),
+        (100, "c"),
+        (90, "xc"),
+        (50, "l"),
+        (40, "xl"),
+        (10, "x"),
+        (9, "ix"),
+        (5, "v"),
+        (4, "iv"),
+        (1, "i"),
+    ];
+
+    let mut numeral = String::new();
+    for (value, symbol) in NUMERALS {
+        while number >= value {
+            numeral.push_str(symbol);
+            number -= value;
+        }
+    }
+    numeral
+}
+
+/// Labels the automatically numbered markers (see [`PageMarker::auto`]) of a body, in document order,
+/// with the labels given by `next_label`.
+///
+/// Returns `None` if the body has no automatically numbered marker.
+pub(crate) fn number_pages<F>(body: &str, mut next_label: F) -> Option<String>
+where
+    F: FnMut() -> String,
+{
+    body.contains(AUTO_PAGE_MARKER).then(|| {
+        body.split(AUTO_PAGE_MARKER)
+            .enumerate()
+            .map(|(index, part)| {
+                if index == 0 {
+                    part.to_string()
+                } else {
+                    page_break_marker(&next_label()) + part
+                }
+            })
+            .collect()
+    })
+}
+
+/// Gets the **anchor id** of the page labeled `label`, keeping its ASCII alphanumeric characters and
+/// encoding the rest as their hex code point between underscores, so different labels never share an id
+/// (e.g., `"page-12"` for `"12"` and `"page-1_2d_2"` for `"1-2"`).
+#[must_use]
+pub fn page_id(label: &str) -> String {
+    label.chars().fold(String::from("page-"), |mut id, c| {
+        if c.is_ascii_alphanumeric() {
+            id.push(c);
+        } else {
+            id.push_str(&format!("_{:x}_", u32::from(c)));
+        }
+        id
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_page_break_marker() {
+        assert_eq!(
+            page_break_marker("xiv"),
+            r#"<span xmlns:epub="http://www.idpf.org/2007/ops" epub:type="pagebreak" role="doc-pagebreak" id="page-xiv" title="xiv"></span>"#
+        );
+        assert!(
+            page_break_marker("1 & 2").contains(r#"id="page-1_20__26__20_2" title="1 &amp; 2""#)
+        );
+    }
+
+    #[test]
+    fn test_page_id() {
+        assert_eq!(page_id("12"), "page-12");
+        assert_eq!(page_id("1-2"), "page-1_2d_2");
+        assert_eq!(page_id("ix."), "page-ix_2e_");
+        assert_eq!(page_id("ix"), "page-ix");
+        assert_ne!(page_id("-5"), page_id("\u{2d5}"));
+        assert_ne!(page_id("_"), page_id("_5f_"));
+    }
+
+    #[test]
+    fn test_page_marker_display() {
+        let marker = PageMarker::new("7");
+        assert_eq!(marker.label(), "7");
+        assert_eq!(marker.to_string(), page_break_marker("7"));
+    }
+
+    #[test]
+    fn test_roman_numeral() {
+        assert_eq!(roman_numeral(0), "");
+        assert_eq!(roman_numeral(4), "iv");
+        assert_eq!(roman_numeral(14), "xiv");
+        assert_eq!(roman_numeral(1994), "mcmxciv");
+    }
+
+    #[test]
+    fn test_number_pages() {
+        let body = format!(
+            "<p>A</p>{}<p>B</p>{}",
+            PageMarker::auto(),
+            PageMarker::auto()
+        );
+        let mut page = 0;
+        let numbered = number_pages(&body, || {
+            page += 1;
+            roman_numeral(page)
+        })
+        .unwrap();
+        assert_eq!(
+            numbered,
+            format!(
+                "<p>A</p>{}<p>B</p>{}",
+                page_break_marker("i"),
+                page_break_marker("ii")
+            )
+        );
+        assert!(number_pages("<p>A</p>", String::new).is_none());
+    }
+
+    #[test]
+    fn test_page_break() {
+        assert!(page_break().contains("page-break-after: always"));
+        assert!(PAGE_BREAK_CSS.contains(PAGE_BREAK_BEFORE_CLASS));
+    }
+}
@@ -0,0 +1,234 @@
+use std::fmt::Display;
+
+use quick_xml::escape::escape;
+
+/// The CSS rules used by [`Quiz`].
+pub const QUIZ_CSS: &str = "div.quiz { margin: 1em 0; }
+div.quiz-question { margin: 1em 0; }
+ol.quiz-choices { list-style-type: upper-alpha; }
+ol.quiz-choices li.quiz-correct { color: #1b5e20; font-weight: bold; }
+ol.quiz-choices li.quiz-incorrect { color: #b71c1c; text-decoration: line-through; }
+p.quiz-answer { font-style: italic; }";
+
+/// The script making a quiz interactive: it hides the answers, marks the chosen choices as correct
+/// or incorrect and adds a button revealing each answer. `QUIZ_ID` is replaced by the id of the quiz.
+const QUIZ_SCRIPT: &str = r#"(function () {
+  var quiz = document.getElementById("QUIZ_ID");
+  if (!quiz || !quiz.querySelectorAll) { return; }
+  var questions = quiz.querySelectorAll(".quiz-question");
+  for (var i = 0; i < questions.length; i++) {
+    (function (question) {
+      var answer = question.querySelector(".quiz-answer");
+      answer.style.display = "none";
+      var choices = question.querySelectorAll(".quiz-choices li");
+      for (var j = 0; j < choices.length; j++) {
+        choices[j].addEventListener("click", function () {
+          var correct = this.getAttribute("data-correct") === "true";
+          this.className = correct ? "quiz-correct" : "quiz-incorrect";
+          if (correct) { answer.style.display = ""; }
+        });
+      }
+      var button = document.createElement("button");
+      button.appendChild(document.createTextNode("Show answer"));
+      button.addEventListener("click", function () { answer.style.display = ""; });
+      question.appendChild(button);
+    })(questions[i]);
+  }
+})();"#;
+
+/// A **multiple choice question** of a [`Quiz`].
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Question {
+    /// The text of the question.
+    prompt: String,
+    /// The choices, in the displayed order.
+    choices: Vec<String>,
+    /// The index of the correct choice.
+    answer: Option<usize>,
+    /// An optional explanation shown along with the answer.
+    explanation: Option<String>,
+}
+
+impl Question {
+    /// Creates a question without choices.
+    pub fn new<S: Into<String>>(prompt: S) -> Self {
+        Self {
+            prompt: prompt.into(),
+            choices: Vec::new(),
+            answer: None,
+            explanation: None,
+        }
+    }
+
+    /// Adds an incorrect **choice**.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn choice<S: Into<String>>(mut self, choice: S) -> Self {
+        self.choices.push(choice.into());
+        self
+    }
+
+    /// Adds the **correct choice**. If several are added, the last one is the correct one.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn correct_choice<S: Into<String>>(mut self, choice: S) -> Self {
+        self.answer = Some(self.choices.len());
+        self.choices.push(choice.into());
+        self
+    }
+
+    /// Sets an **explanation** shown along with the answer.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn explanation<S: Into<String>>(mut self, explanation: S) -> Self {
+        self.explanation = Some(explanation.into());
+        self
+    }
+
+    /// Builds the markup of the question: the prompt, the choices and the answer, visible by default.
+    fn markup(&self) -> String {
+        let choices = self
+            .choices
+            .iter()
+            .enumerate()
+            .map(|(index, choice)| {
+                format!(
+                    r#"<li data-correct="{}">{}</li>"#,
+                    self.answer == Some(index),
+                    escape(choice)
+                )
+            })
+            .collect::<String>();
+
+        let answer = match self.answer {
+            Some(index) => format!(
+                "Answer: {}.{}",
+                choice_letter(index),
+                self.explanation
+                    .as_ref()
+                    .map(|explanation| format!(" {}", escape(explanation)))
+                    .unwrap_or_default()
+            ),
+            None => self
+                .explanation
+                .as_ref()
+                .map(|explanation| escape(explanation).into_owned())
+                .unwrap_or_default(),
+        };
+
+        format!(
+            r#"<div class="quiz-question"><p class="quiz-prompt">{}</p><ol class="quiz-choices">{choices}</ol><p class="quiz-answer">{answer}</p></div>"#,
+            escape(&self.prompt)
+        )
+    }
+}
+
+/// An **interactive quiz** of multiple choice questions, rendered (through `Display`) as markup to embed in a content body.
+///
+/// Scripted reading systems (e.g., Apple Books or Thorium) hide the answers, mark the chosen choices and
+/// add a button revealing each answer. Other readers ignore the script and show the questions with their
+/// answers as static text. Style it with [`QUIZ_CSS`].
+///
+/// ```rust
+/// use liber::markup::{Question, Quiz};
+///
+/// let quiz = Quiz::new("quiz-1").title("Check your understanding").add_question(
+///     Question::new("What is the capital of France?")
+///         .choice("Lyon")
+///         .correct_choice("Paris")
+///         .explanation("Paris has been the capital since 987."),
+/// );
+/// let body = format!("<body>{quiz}</body>");
+/// assert!(body.contains(r#"<p class="quiz-answer">Answer: B. Paris has been the capital since 987.</p>"#));
+/// ```
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Quiz {
+    /// The id of the quiz element, unique within the content.
+    id: String,
+    /// Optional heading of the quiz.
+    title: Option<String>,
+    /// The questions, in order.
+    questions: Vec<Question>,
+}
+
+impl Quiz {
+    /// Creates an empty quiz with the given element id, unique within the content.
+    pub fn new<S: Into<String>>(id: S) -> Self {
+        Self {
+            id: id.into(),
+            title: None,
+            questions: Vec::new(),
+        }
+    }
+
+    /// Sets the **title** of the quiz, rendered as a heading.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn title<S: Into<String>>(mut self, title: S) -> Self {
+        self.title = Some(title.into());
+        self
+    }
+
+    /// Adds a **question** to the quiz.
+    ///
+    /// This is a fluent method, returning `Self`.
+    pub fn add_question(mut self, question: Question) -> Self {
+        self.questions.push(question);
+        self
+    }
+}
+
+impl Display for Quiz {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        let id = escape(&self.id);
+        write!(f, r#"<div class="quiz" id="{id}">"#)?;
+        if let Some(ref title) = self.title {
+            write!(f, "<h2>{}</h2>", escape(title))?;
+        }
+        for question in &self.questions {
+            f.write_str(&question.markup())?;
+        }
+        write!(
+            f,
+            r#"</div><script type="text/javascript">//<![CDATA[
+{}
+//]]></script>"#,
+            QUIZ_SCRIPT.replace("QUIZ_ID", &id)
+        )
+    }
+}
+
+/// Gets the letter of a choice (`A`, `B`, ...), as numbered by the `upper-alpha` list style.
+fn choice_letter(index: usize) -> char {
+    (b'A' + (index % 26) as u8) as char
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_question_markup() {
+        let question = Question::new("2 + 2?")
+            .choice("3")
+            .choice("5")
+            .correct_choice("4");
+        assert_eq!(
+            question.markup(),
+            r#"<div class="quiz-question"><p class="quiz-prompt">2 + 2?</p><ol class="quiz-choices"><li data-correct="false">3</li><li data-correct="false">5</li><li data-correct="true">4</li></ol><p class="quiz-answer">Answer: C.</p></div>"#
+        );
+    }
+
+    #[test]
+    fn test_quiz() {
+        let quiz = Quiz::new("q1")
+            .title("Quiz")
+            .add_question(Question::new("A < B?").correct_choice("Yes"));
+        let markup = quiz.to_string();
+
+        assert!(markup.starts_with(r#"<div class="quiz" id="q1"><h2>Quiz</h2>"#));
+        assert!(markup.contains("A &lt; B?"));
+        assert!(markup.contains(r#"document.getElementById("q1")"#));
+        assert!(markup.ends_with("//]]></script>"));
+    }
+}
@@ -0,0 +1,187 @@
+use quick_xml::escape::escape;
+
+/// The CSS rules used by [`EpigraphBuilder`] and [`BlockquoteBuilder`].
+pub const QUOTE_CSS: &str = ".epigraph { margin: 2em 2em 2em 25%; font-style: italic; }
+.epigraph p, blockquote p { margin: 0; text-indent: 0; }
+.epigraph .attribution, blockquote .attribution { margin-top: 0.5em; text-align: right; font-style: normal; }
+blockquote { margin: 1em 2em; }";
+
+/// A builder for an **epigraph** (a quote and its attribution), marked up with `epub:type="epigraph"`
+/// and `role="doc-epigraph"`.
+///
+/// It pairs with a content of type [`crate::epub::ReferenceType::Epigraph`], or it can open a chapter.
+///
+/// ```rust
+/// use liber::markup::EpigraphBuilder;
+///
+/// let epigraph = EpigraphBuilder::new("All happy families are alike.")
+///     .attribution("Leo Tolstoy")
+///     .cite("Anna Karenina")
+///     .build();
+/// assert!(epigraph.contains(r#"<p class="attribution">— Leo Tolstoy, <cite>Anna Karenina</cite></p>"#));
+/// ```
+#[derive(Debug, Clone)]
+pub struct EpigraphBuilder(Quote);
+
+impl EpigraphBuilder {
+    /// Creates an epigraph with the given quote, an XHTML fragment (paragraphs are wrapped in `<p>`
+    /// unless the quote already starts with a tag).
+    pub fn new<S: Into<String>>(quote: S) -> Self {
+        Self(Quote::new(quote.into()))
+    }
+
+    /// Sets the **attribution** (e.g., the author) of the quote.
+    pub fn attribution<S: Into<String>>(mut self, attribution: S) -> Self {
+        self.0.attribution = Some(attribution.into());
+        self
+    }
+
+    /// Sets the **title of the cited work**, rendered in `<cite>`.
+    pub fn cite<S: Into<String>>(mut self, cite: S) -> Self {
+        self.0.cite = Some(cite.into());
+        self
+    }
+
+    /// Builds the epigraph markup, to be styled with [`QUOTE_CSS`].
+    #[must_use]
+    pub fn build(self) -> String {
+        format!(
+            r#"<div xmlns:epub="http://www.idpf.org/2007/ops" class="epigraph" epub:type="epigraph" role="doc-epigraph">{}</div>"#,
+            self.0.inner()
+        )
+    }
+}
+
+/// A builder for a **block quote** with an optional attribution and source URL.
+///
+/// ```rust
+/// use liber::markup::BlockquoteBuilder;
+///
+/// let quote = BlockquoteBuilder::new("<p>To be, or not to be.</p>")
+///     .attribution("Hamlet")
+///     .build();
+/// assert!(quote.starts_with("<blockquote><p>To be, or not to be.</p>"));
+/// ```
+#[derive(Debug, Clone)]
+pub struct BlockquoteBuilder(Quote);
+
+impl BlockquoteBuilder {
+    /// Creates a block quote with the given quote, an XHTML fragment (paragraphs are wrapped in `<p>`
+    /// unless the quote already starts with a tag).
+    pub fn new<S: Into<String>>(quote: S) -> Self {
+        Self(Quote::new(quote.into()))
+    }
+
+    /// Sets the **attribution** (e.g., the author) of the quote.
+    pub fn attribution<S: Into<String>>(mut self, attribution: S) -> Self {
+        self.0.attribution = Some(attribution.into());
+        self
+    }
+
+    /// Sets the **title of the cited work**, rendered in `<cite>`.
+    pub fn cite<S: Into<String>>(mut self, cite: S) -> Self {
+        self.0.cite = Some(cite.into());
+        self
+    }
+
+    /// Sets the **URL of the source**, set as the `cite` attribute of the `<blockquote>`.
+    pub fn source_url<S: Into<String>>(mut self, url: S) -> Self {
+        self.0.source_url = Some(url.into());
+        self
+    }
+
+    /// Builds the block quote markup, to be styled with [`QUOTE_CSS`].
+    #[must_use]
+    pub fn build(self) -> String {
+        let cite = self
+            .0
+            .source_url
+            .as_ref()
+            .map(|url| format!(r#" cite="{}""#, escape(url)))
+            .unwrap_or_default();
+
+        format!("<blockquote{cite}>{}</blockquote>", self.0.inner())
+    }
+}
+
+/// The parts shared by epigraphs and block quotes.
+#[derive(Debug, Clone)]
+struct Quote {
+    /// The quote, as an XHTML fragment.
+    quote: String,
+    /// Optional attribution (e.g., the author).
+    attribution: Option<String>,
+    /// Optional title of the cited work.
+    cite: Option<String>,
+    /// Optional URL of the source (only used by block quotes).
+    source_url: Option<String>,
+}
+
+impl Quote {
+    fn new(quote: String) -> Self {
+        Self {
+            quote,
+            attribution: None,
+            cite: None,
+            source_url: None,
+        }
+    }
+
+    /// Gets the quote followed by its attribution line, if any.
+    fn inner(&self) -> String {
+        let quote = if self.quote.trim_start().starts_with('<') {
+            self.quote.clone()
+        } else {
+            format!("<p>{}</p>", self.quote)
+        };
+
+        let cite = self
+            .cite
+            .as_ref()
+            .map(|cite| format!("<cite>{cite}</cite>"));
+        let attribution = match (&self.attribution, cite) {
+            (Some(attribution), Some(cite)) => format!("{attribution}, {cite}"),
+            (Some(attribution), None) => attribution.clone(),
+            (None, Some(cite)) => cite,
+            (None, None) => return quote,
+        };
+
+        format!(r#"{quote}<p class="attribution">— {attribution}</p>"#)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_epigraph_builder() {
+        assert_eq!(
+            EpigraphBuilder::new("Call me Ishmael.").build(),
+            r#"<div xmlns:epub="http://www.idpf.org/2007/ops" class="epigraph" epub:type="epigraph" role="doc-epigraph"><p>Call me Ishmael.</p></div>"#
+        );
+        assert!(
+            EpigraphBuilder::new("Quote")
+                .cite("Work")
+                .build()
+                .contains(r#"<p class="attribution">— <cite>Work</cite></p>"#)
+        );
+    }
+
+    #[test]
+    fn test_blockquote_builder() {
+        assert_eq!(
+            BlockquoteBuilder::new("<p>One</p><p>Two</p>")
+                .attribution("Someone")
+                .source_url("https://example.com")
+                .build(),
+            r#"<blockquote cite="https://example.com"><p>One</p><p>Two</p><p class="attribution">— Someone</p></blockquote>"#
+        );
+        assert!(
+            BlockquoteBuilder::new("<p>One</p>")
+                .source_url(r#"https://example.com/?a=1&b="2""#)
+                .build()
+                .starts_with(r#"<blockquote cite="https://example.com/?a=1&amp;b=&quot;2&quot;">"#)
+        );
+    }
+}
@@ -0,0 +1,92 @@
+/// The CSS rules used by the reading time badges.
+pub const READING_TIME_CSS: &str = ".reading-time { margin: 0 0 1.5em 0; text-indent: 0; font-size: 0.8em; font-style: italic; opacity: 0.7; }";
+
+/// The default reading speed, in words per minute.
+pub const DEFAULT_WORDS_PER_MINUTE: u32 = 230;
+
+/// The settings of the **estimated reading time** (see [`crate::epub::EpubBuilder::reading_time`]),
+/// computed from the word count of every content.
+///
+/// ```rust
+/// use liber::markup::ReadingTime;
+///
+/// let reading_time = ReadingTime::new().words_per_minute(200).badges(true);
+/// assert_eq!(reading_time.minutes(1_000), 5);
+/// assert_eq!(reading_time.badge(5), r#"<p class="reading-time">5 min read</p>"#);
+/// ```
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct ReadingTime {
+    /// The reading speed, in words per minute.
+    words_per_minute: u32,
+    /// Whether the total reading time is emitted as a `<meta name="reading-time">` in the `content.opf`.
+    pub(crate) meta: bool,
+    /// Whether a "X min read" badge is injected after the heading of every content.
+    pub(crate) badges: bool,
+}
+
+impl Default for ReadingTime {
+    fn default() -> Self {
+        Self {
+            words_per_minute: DEFAULT_WORDS_PER_MINUTE,
+            meta: true,
+            badges: false,
+        }
+    }
+}
+
+impl ReadingTime {
+    /// Creates the default settings: 230 words per minute, with the metadata and without badges.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Sets the reading speed, in **words per minute**.
+    pub fn words_per_minute(mut self, words_per_minute: u32) -> Self {
+        self.words_per_minute = words_per_minute.max(1);
+        self
+    }
+
+    /// Sets whether the total reading time (in minutes) is emitted as a `<meta name="reading-time">`
+    /// in the `content.opf`.
+    pub fn meta(mut self, meta: bool) -> Self {
+        self.meta = meta;
+        self
+    }
+
+    /// Sets whether a **"X min read" badge** is injected right after the first `<h1>`/`<h2>` of every
+    /// content, as usual in web-style non-fiction books.
+    pub fn badges(mut self, badges: bool) -> Self {
+        self.badges = badges;
+        self
+    }
+
+    /// Gets the reading time of the given number of words, rounded up to whole minutes.
+    #[must_use]
+    pub fn minutes(&self, words: usize) -> u32 {
+        let words = u32::try_from(words).unwrap_or(u32::MAX);
+        words.div_ceil(self.words_per_minute)
+    }
+
+    /// Gets the markup of the badge showing the given reading time.
+    #[must_use]
+    pub fn badge(&self, minutes: u32) -> String {
+        format!(r#"<p class="reading-time">{minutes} min read</p>"#)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_reading_time_minutes() {
+        let reading_time = ReadingTime::new();
+
+        assert_eq!(reading_time.minutes(0), 0);
+        assert_eq!(reading_time.minutes(1), 1);
+        assert_eq!(reading_time.minutes(230), 1);
+        assert_eq!(reading_time.minutes(231), 2);
+        assert_eq!(ReadingTime::new().words_per_minute(0).minutes(3), 3);
+    }
+}
@@ -0,0 +1,199 @@
+/// The CSS rules for reliable **ruby** (furigana) rendering: the annotation is kept small and
+/// unselectable, and the fallback parentheses are hidden by reading systems supporting ruby.
+pub const RUBY_CSS: &str = "ruby { ruby-position: over; -epub-ruby-position: over; -webkit-ruby-position: before; }
+rt { font-size: 0.5em; line-height: 1; text-emphasis: none; -webkit-user-select: none; user-select: none; }
+rp { display: none; }";
+
+/// Gets a **ruby annotation** of `base` (e.g., a kanji) with its reading `annotation` (e.g., furigana),
+/// with `<rp>` parentheses as fallback for reading systems without ruby support.
+///
+/// ```rust
+/// use liber::markup::ruby;
+///
+/// assert_eq!(ruby("漢字", "かんじ"), "<ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>");
+/// ```
+#[must_use]
+pub fn ruby(base: &str, annotation: &str) -> String {
+    format!("<ruby>{}</ruby>", ruby_pair(base, annotation))
+}
+
+/// A builder for a **ruby annotation** made of several base/reading pairs (e.g., one per kanji),
+/// so every reading stays aligned with its base.
+///
+/// ```rust
+/// use liber::markup::RubyBuilder;
+///
+/// let ruby = RubyBuilder::new().add_pair("漢", "かん").add_pair("字", "じ").build();
+/// assert_eq!(
+///     ruby,
+///     "<ruby>漢<rp>(</rp><rt>かん</rt><rp>)</rp>字<rp>(</rp><rt>じ</rt><rp>)</rp></ruby>"
+/// );
+/// ```
+#[derive(Debug, Default, Clone)]
+pub struct RubyBuilder(Vec<(String, String)>);
+
+impl RubyBuilder {
+    /// Creates an empty ruby annotation.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a **base** text and its **reading**.
+    pub fn add_pair<B: Into<String>, A: Into<String>>(mut self, base: B, annotation: A) -> Self {
+        self.0.push((base.into(), annotation.into()));
+        self
+    }
+
+    /// Builds the ruby markup, to be styled with [`RUBY_CSS`].
+    #[must_use]
+    pub fn build(self) -> String {
+        let pairs = self
+            .0
+            .iter()
+            .map(|(base, annotation)| ruby_pair(base, annotation))
+            .collect::<String>();
+        format!("<ruby>{pairs}</ruby>")
+    }
+}
+
+/// Gets the markup of a base text and its reading.
+fn ruby_pair(base: &str, annotation: &str) -> String {
+    format!("{base}<rp>(</rp><rt>{annotation}</rt><rp>)</rp>")
+}
+
+/// **Validates** the ruby markup of an XHTML fragment: every `<ruby>` must be closed, must not be
+/// nested, must have a base text and at least one non-empty `<rt>`, and `<rt>`/`<rp>` can only
+/// appear inside a `<ruby>`.
+///
+/// # Errors
+/// Returns a [`crate::Error::Validation`] describing the first malformed ruby annotation.
+pub fn validate_ruby(xhtml: &str) -> crate::Result {
+    let error = |message: &str| Err(crate::Error::Validation(format!("ruby markup: {message}")));
+
+    let mut rest = xhtml;
+    while let Some(start) = rest.find('<') {
+        rest = &rest[start..];
+        let name = tag_name(rest);
+
+        match name {
+            "ruby" => {
+                let Some(end) = rest.find("</ruby>") else {
+                    return error("<ruby> is not closed");
+                };
+                let ruby = &rest["<ruby".len()..end];
+                let content = &ruby[ruby.find('>').map_or(0, |p| p + 1)..];
+
+                if content.contains("<ruby") {
+                    return error("<ruby> cannot be nested");
+                }
+                validate_ruby_content(content)?;
+                rest = &rest[end + "</ruby>".len()..];
+            }
+            "rt" | "rp" => return error(&format!("<{name}> outside of <ruby>")),
+            "/ruby" => return error("</ruby> without <ruby>"),
+            _ => rest = &rest[1..],
+        }
+    }
+    Ok(())
+}
+
+/// Validates the content of a `<ruby>` element.
+fn validate_ruby_content(content: &str) -> crate::Result {
+    let error = |message: &str| Err(crate::Error::Validation(format!("ruby markup: {message}")));
+
+    let mut annotations = 0;
+    let mut bases = 0;
+    let mut rest = content;
+    while !rest.is_empty() {
+        if rest.starts_with('<') {
+            let name = tag_name(rest);
+            if name == "rt" || name == "rp" {
+                let closing = format!("</{name}>");
+                let Some(end) = rest.find(&closing) else {
+                    return error(&format!("<{name}> is not closed"));
+                };
+                let text = &rest[rest.find('>').map_or(0, |p| p + 1)..end];
+                if name == "rt" {
+                    if text.trim().is_empty() {
+                        return error("empty <rt>");
+                    }
+                    annotations += 1;
+                }
+                rest = &rest[end + closing.len()..];
+            } else {
+                rest = &rest[rest.find('>').map_or(rest.len(), |p| p + 1)..];
+            }
+        } else {
+            let text_len = rest.find('<').unwrap_or(rest.len());
+            if !rest[..text_len].trim().is_empty() {
+                bases += 1;
+            }
+            rest = &rest[text_len..];
+        }
+    }
+
+    match (bases, annotations) {
+        (0, _) => error("<ruby> without base text"),
+        (_, 0) => error("<ruby> without <rt>"),
+        _ => Ok(()),
+    }
+}
+
+/// Gets the name of the tag at the start of a text (e.g., `rt` or `/ruby`).
+fn tag_name(tag: &str) -> &str {
+    let name = &tag[1..];
+    let closing = usize::from(name.starts_with('/'));
+    let end = name[closing..]
+        .find(|c: char| c.is_whitespace() || c == '>' || c == '/')
+        .map_or(name.len(), |position| position + closing);
+    &name[..end]
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_ruby_markup_is_valid() {
+        let ruby = RubyBuilder::new()
+            .add_pair("東", "とう")
+            .add_pair("京", "きょう")
+            .build();
+        let body = format!("<p>{ruby}に行く。{}</p>", super::ruby("日本", "にほん"));
+
+        assert!(validate_ruby(&body).is_ok());
+        assert!(validate_ruby("<p>No ruby</p>").is_ok());
+        assert!(validate_ruby("<ruby>漢<rt>かん</rt></ruby>").is_ok());
+    }
+
+    #[test]
+    fn test_validate_ruby_errors() {
+        let message = |xhtml| validate_ruby(xhtml).unwrap_err().to_string();
+
+        assert_eq!(
+            message("<ruby>漢<rt>かん</rt>"),
+            "Validation error: ruby markup: <ruby> is not closed"
+        );
+        assert_eq!(
+            message("<ruby>漢</ruby>"),
+            "Validation error: ruby markup: <ruby> without <rt>"
+        );
+        assert_eq!(
+            message("<ruby><rt>かん</rt></ruby>"),
+            "Validation error: ruby markup: <ruby> without base text"
+        );
+        assert_eq!(
+            message("<ruby>漢<rt> </rt></ruby>"),
+            "Validation error: ruby markup: empty <rt>"
+        );
+        assert_eq!(
+            message("<p>漢<rt>かん</rt></p>"),
+            "Validation error: ruby markup: <rt> outside of <ruby>"
+        );
+        assert_eq!(
+            message("<ruby>漢<ruby>字<rt>じ</rt></ruby><rt>かん</rt></ruby>"),
+            "Validation error: ruby markup: <ruby> cannot be nested"
+        );
+    }
+}
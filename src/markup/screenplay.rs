@@ -0,0 +1,109 @@
+/// The CSS rules used by [`ScreenplayBuilder`], following the usual screenplay layout.
+pub const SCREENPLAY_CSS: &str = ".screenplay { font-family: \"Courier New\", Courier, monospace; }
+.screenplay p { margin: 0; text-indent: 0; }
+.screenplay .scene-heading { margin-top: 1.5em; font-weight: bold; text-transform: uppercase; }
+.screenplay .action { margin-top: 1em; }
+.screenplay .character { margin: 1em 0 0 40%; text-transform: uppercase; }
+.screenplay .parenthetical { margin-left: 32%; margin-right: 25%; }
+.screenplay .dialogue { margin-left: 25%; margin-right: 20%; }
+.screenplay .transition { margin-top: 1em; text-align: right; text-transform: uppercase; }";
+
+/// A builder for **screenplay and dialogue** markup (scene headings, character cues,
+/// parentheticals, dialogue and transitions), in the order the elements are added.
+///
+/// Every text is an XHTML fragment. Use [`SCREENPLAY_CSS`] to style it.
+///
+/// ```rust
+/// use liber::markup::ScreenplayBuilder;
+///
+/// let scene = ScreenplayBuilder::new()
+///     .scene_heading("INT. KITCHEN - NIGHT")
+///     .character("ANNA")
+///     .parenthetical("whispering")
+///     .dialogue("Did you hear that?")
+///     .transition("CUT TO:")
+///     .build();
+/// assert!(scene.contains(r#"<p class="parenthetical">(whispering)</p>"#));
+/// ```
+#[derive(Debug, Default, Clone)]
+pub struct ScreenplayBuilder(Vec<(&'static str, String)>);
+
+impl ScreenplayBuilder {
+    /// Creates an empty screenplay.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a **scene heading** (slugline), like `INT. KITCHEN - NIGHT`.
+    pub fn scene_heading<S: Into<String>>(self, text: S) -> Self {
+        self.add("scene-heading", text.into())
+    }
+
+    /// Adds an **action** (scene description) paragraph.
+    pub fn action<S: Into<String>>(self, text: S) -> Self {
+        self.add("action", text.into())
+    }
+
+    /// Adds a **character cue**, the name of the character about to speak.
+    pub fn character<S: Into<String>>(self, name: S) -> Self {
+        self.add("character", name.into())
+    }
+
+    /// Adds a **parenthetical** direction. The parentheses are added if missing.
+    pub fn parenthetical<S: Into<String>>(self, text: S) -> Self {
+        let text = text.into();
+        if text.starts_with('(') {
+            self.add("parenthetical", text)
+        } else {
+            self.add("parenthetical", format!("({text})"))
+        }
+    }
+
+    /// Adds a **dialogue** line of the last character cue.
+    pub fn dialogue<S: Into<String>>(self, text: S) -> Self {
+        self.add("dialogue", text.into())
+    }
+
+    /// Adds a **transition**, like `CUT TO:`.
+    pub fn transition<S: Into<String>>(self, text: S) -> Self {
+        self.add("transition", text.into())
+    }
+
+    /// Builds the screenplay markup.
+    #[must_use]
+    pub fn build(self) -> String {
+        let mut result = String::from(r#"<div class="screenplay">"#);
+        for (class, text) in self.0 {
+            result.push_str(&format!(r#"<p class="{class}">{text}</p>"#));
+        }
+        result.push_str("</div>");
+        result
+    }
+
+    /// Adds an element with the given class.
+    fn add(mut self, class: &'static str, text: String) -> Self {
+        self.0.push((class, text));
+        self
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_screenplay_builder() {
+        assert_eq!(
+            ScreenplayBuilder::new()
+                .scene_heading("EXT. BEACH - DAY")
+                .action("Waves crash.")
+                .character("TOM")
+                .parenthetical("(beat)")
+                .dialogue("Hello.")
+                .transition("FADE OUT.")
+                .build(),
+            r#"<div class="screenplay"><p class="scene-heading">EXT. BEACH - DAY</p><p class="action">Waves crash.</p><p class="character">TOM</p><p class="parenthetical">(beat)</p><p class="dialogue">Hello.</p><p class="transition">FADE OUT.</p></div>"#
+        );
+    }
+}
@@ -0,0 +1,93 @@
+/// The CSS rules used by [`VerseBuilder`]: long lines wrap with a hanging indent and stanzas are spaced.
+pub const VERSE_CSS: &str = ".verse { margin: 1em 0 1em 2em; }
+.verse .stanza { margin: 0 0 1em 0; }
+.verse .line { margin: 0; padding-left: 2em; text-indent: -2em; text-align: left; }
+.verse .indent-1 { padding-left: 3em; }
+.verse .indent-2 { padding-left: 4em; }
+.verse .indent-3 { padding-left: 5em; }";
+
+/// The deepest indent level of a verse line.
+const MAX_INDENT: usize = 3;
+
+/// A builder for **poetry and verse** markup, preserving line breaks, hanging indents and stanza spacing.
+///
+/// Every line is an XHTML fragment (so it may contain inline markup like `<i>`). Leading spaces
+/// indent the line, one level every two spaces (up to three levels).
+///
+/// ```rust
+/// use liber::markup::VerseBuilder;
+///
+/// let verse = VerseBuilder::new()
+///     .add_stanza(["Tyger Tyger, burning bright,", "  In the forests of the night;"])
+///     .build();
+/// assert!(verse.contains(r#"<div class="line indent-1">In the forests of the night;</div>"#));
+/// ```
+#[derive(Debug, Default, Clone)]
+pub struct VerseBuilder(Vec<Vec<String>>);
+
+impl VerseBuilder {
+    /// Creates an empty verse.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a **stanza** made of the given lines.
+    pub fn add_stanza<I, S>(mut self, lines: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        self.0.push(lines.into_iter().map(Into::into).collect());
+        self
+    }
+
+    /// Builds the verse markup, to be styled with [`VERSE_CSS`].
+    #[must_use]
+    pub fn build(self) -> String {
+        let mut result = String::from(r#"<div class="verse">"#);
+
+        for stanza in self.0 {
+            result.push_str(r#"<div class="stanza">"#);
+            for line in stanza {
+                let text = line.trim_start_matches(' ');
+                let indent = ((line.len() - text.len()) / 2).min(MAX_INDENT);
+
+                if indent == 0 {
+                    result.push_str(&format!(r#"<div class="line">{text}</div>"#));
+                } else {
+                    result.push_str(&format!(
+                        r#"<div class="line indent-{indent}">{text}</div>"#
+                    ));
+                }
+            }
+            result.push_str("</div>");
+        }
+
+        result.push_str("</div>");
+        result
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_verse_builder() {
+        let verse = VerseBuilder::new()
+            .add_stanza(["First line", "    second <i>line</i>"])
+            .add_stanza(vec!["          deep".to_string()])
+            .build();
+
+        assert_eq!(
+            verse,
+            r#"<div class="verse"><div class="stanza"><div class="line">First line</div><div class="line indent-2">second <i>line</i></div></div><div class="stanza"><div class="line indent-3">deep</div></div></div>"#
+        );
+    }
+
+    #[test]
+    fn test_verse_builder_empty() {
+        assert_eq!(VerseBuilder::new().build(), r#"<div class="verse"></div>"#);
+    }
+}
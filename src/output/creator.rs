@@ -1,14 +1,15 @@
-use std::io::{Cursor, Write};
+use std::{io::Write, sync::Arc};
 
 use zip::{
-    CompressionMethod, ZipWriter,
-    write::{FileOptions, SimpleFileOptions},
+    CompressionMethod, DateTime, ZipWriter,
+    write::{FileOptions, SimpleFileOptions, StreamWriter},
 };
 
 use crate::{
-    epub::Epub,
+    epub::{Epub, PAGE_TEMPLATE_FILENAME, StdFileSystem, obfuscate_font},
     output::{
         file_content::{self, FileContent},
+        sink::Sink,
         xml,
     },
 };
@@ -24,22 +25,77 @@ pub enum ZipCompression {
     Stored,
 }
 
+/// The approximate size in bytes of the ZIP headers of an entry (local header, data descriptor and central
+/// directory record), besides its path twice.
+const ZIP_ENTRY_OVERHEAD: u64 = 30 + 16 + 46;
+
+/// Estimates the size in bytes of the final archive, **without reading any resource**.
+///
+/// The generated files (contents, `content.opf`, `toc.ncx`, stylesheet) are actually compressed
+/// in memory, while the resources (usually already compressed, like images and audio) are measured
+/// through their file metadata. Resources whose metadata cannot be read are not counted.
+///
+/// # Errors
+/// Returns any error produced while generating the files (e.g., an unknown citation key).
+pub(crate) fn estimate_size(epub: &Epub<'_>, compression: ZipCompression) -> crate::Result<u64> {
+    let mut generated = epub.clone();
+
+    let mut resources = epub
+        .itunes_artwork()
+        .cloned()
+        .into_iter()
+        .collect::<Vec<_>>();
+    resources.extend(generated.cover_image.take());
+    resources.extend(generated.cover_thumbnail.take());
+    resources.extend(generated.resources.take().into_iter().flatten());
+    resources.extend(
+        generated
+            .fallbacks
+            .take()
+            .into_iter()
+            .flatten()
+            .flat_map(|(image, fallback)| [image, fallback]),
+    );
+
+    generated.itunes_artwork = false;
+    generated.collect_errors = false;
+    generated.profile = None;
+
+    let mut buffer = Vec::new();
+    EpubFile::new(generated, &mut buffer, compression).create()?;
+
+    let resources = resources
+        .iter()
+        .filter_map(|resource| {
+            let size = resource.size_hint(epub.base_dir, epub.file_system())?;
+            let path_len = resource.filename().map_or(0, |filename| filename.len()) as u64 + 6;
+            Some(size + ZIP_ENTRY_OVERHEAD + 2 * path_len)
+        })
+        .sum::<u64>();
+
+    Ok(buffer.len() as u64 + resources)
+}
+
 /// A builder responsible for creating and writing all components of an EPUB book
 /// into a standard ZIP archive format.
 ///
 /// This struct manages the final serialization step, taking the high-level
 /// `Epub` data structure and writing all necessary files (`.opf`, `.ncx`, `.xhtml`, etc.)
 /// to an underlying writer.
+///
+/// The archive is streamed to the writer as every file is compressed, so a slow writer blocks the
+/// generation instead of piling up bytes.
 #[derive(Debug)]
-pub struct EpubFile<'a, W> {
+pub struct EpubFile<'a, W: Write> {
     /// The source data structure containing all metadata and content of the EPUB.
     epub: Epub<'a>,
     /// The file options (including compression method) used for writing files into the ZIP archive.
     options: FileOptions<'a, ()>,
-    /// The external writer where the final compressed EPUB bytes will be written to.
-    writer: W,
-    /// The internal ZIP writer, buffering the content before flushing to `self.writer`.
-    zip_writer: ZipWriter<Cursor<Vec<u8>>>,
+    /// The internal ZIP writer, streaming the archive to the external writer where the final compressed
+    /// EPUB bytes will be written to.
+    zip_writer: ZipWriter<StreamWriter<Sink<W>>>,
+    /// The `(path, SHA-256 digest)` of every written file, if the integrity manifest is enabled.
+    checksums: Option<Vec<(String, String)>>,
 }
 
 impl<'a, W> EpubFile<'a, W>
@@ -48,8 +104,8 @@ where
 {
     /// Creates a new `EpubFile` builder.
     ///
-    /// This sets up the internal ZIP writer and configures the file options
-    /// based on the chosen compression method.
+    /// This sets up the internal ZIP writer (streaming to `writer` and counting the bytes written) and
+    /// configures the file options based on the chosen compression method.
     ///
     /// # Arguments
     ///
@@ -62,13 +118,17 @@ where
             ZipCompression::Deflated => CompressionMethod::Deflated,
         };
 
+        let checksums = epub.integrity_manifest.then(Vec::new);
+
         Self {
             epub,
-            writer,
+            checksums,
+            // Entries get a fixed timestamp, so the same inputs generate the same archive
             options: SimpleFileOptions::default()
                 .compression_method(compression)
-                .unix_permissions(0o755),
-            zip_writer: ZipWriter::new(Cursor::new(Vec::new())),
+                .unix_permissions(0o755)
+                .last_modified_time(DateTime::default()),
+            zip_writer: ZipWriter::new_stream(Sink::new(writer)),
         }
     }
 
@@ -77,50 +137,146 @@ where
     ///
     /// The process involves:
     /// 1. Adding mandatory fixed files (`mimetype`, `container.xml`).
-    /// 2. Adding optional files (stylesheet, cover image, generic resources).
+    /// 2. Adding optional files (stylesheet, scripts, cover image, generic resources).
     /// 3. Generating and adding all content XHTML files.
-    /// 4. Generating, formatting, and adding the central XML files (`content.opf` and `toc.ncx`).
-    /// 5. Finalizing the internal ZIP archive and writing the resulting bytes to the
-    ///    external `writer`.
+    /// 4. Generating, formatting, and adding the central XML files (`content.opf` and `toc.ncx`),
+    ///    followed by the integrity manifest if enabled.
+    /// 5. Finalizing the internal ZIP archive, flushing the external `writer` and, if a size budget is set,
+    ///    checking the size of the written archive.
     ///
     /// # Returns
     ///
     /// Returns `crate::Result<()>` indicating success or failure in any step
     /// (file generation, XML formatting, or ZIP writing).
     pub fn create(mut self) -> crate::Result<()> {
+        self.epub.prepare()?;
+
+        if self.epub.collect_errors {
+            self.epub.check()?;
+        }
+
         // 1. Add mandatory files
         self.add_file(file_content::mimetype())?;
-        self.add_file(file_content::container())?;
+        let package_path = format!(
+            "{}/{}",
+            self.epub.content_dir(),
+            self.epub.package_filename()
+        );
+        self.add_file(file_content::container(&package_path))?;
         self.add_file(file_content::display_options())?;
 
-        // 2. Add optional files (stylesheet, cover image, resources)
+        // 2. Add optional files (stylesheet, scripts, cover image, resources, image fallbacks)
+        let base_dir = self.epub.base_dir;
+        let file_system = self
+            .epub
+            .file_system
+            .clone()
+            .unwrap_or_else(|| Arc::new(StdFileSystem));
+
         if let Some(stylesheet) = self.epub.stylesheet {
             self.add_file(FileContent::new("OEBPS/style.css", stylesheet))?;
         }
 
+        if let Some(page_template) = self.epub.page_template {
+            self.add_file(FileContent::new(
+                format!("OEBPS/{PAGE_TEMPLATE_FILENAME}"),
+                page_template,
+            ))?;
+        }
+
+        if let Some(scripts) = self.epub.scripts.take() {
+            for script in &scripts {
+                self.add_file(FileContent::new(
+                    format!("OEBPS/{}", script.filename),
+                    &script.source,
+                ))?;
+            }
+            self.epub.scripts = Some(scripts);
+        }
+
         if let Some(ref cover_image) = self.epub.cover_image {
-            self.add_file(cover_image.file_content()?)?;
+            let mut file_content = cover_image.file_content(base_dir, file_system.as_ref())?;
+            self.epub.process_resource(cover_image, &mut file_content)?;
+            self.add_file(file_content)?;
+        }
+
+        if let Some(ref cover_thumbnail) = self.epub.cover_thumbnail {
+            let mut file_content = cover_thumbnail.file_content(base_dir, file_system.as_ref())?;
+            self.epub
+                .process_resource(cover_thumbnail, &mut file_content)?;
+            self.add_file(file_content)?;
+        }
+
+        if let Some(artwork) = self.epub.itunes_artwork() {
+            let artwork = artwork.file_content(base_dir, file_system.as_ref())?;
+            self.add_file(FileContent::new("iTunesArtwork", artwork.bytes))?;
         }
 
-        if let Some(ref resources) = self.epub.resources {
-            let contents = resources
+        // Resources are read one at a time, right before being written
+        let obfuscation_key = self.epub.font_obfuscation_key()?;
+        if let Some(resources) = self.epub.resources.take() {
+            let mut obfuscated = Vec::new();
+            for resource in &resources {
+                let mut file_content = resource.file_content(base_dir, file_system.as_ref())?;
+                self.epub.process_resource(resource, &mut file_content)?;
+                if let Some(ref key) = obfuscation_key
+                    && obfuscate_font(resource, &mut file_content.bytes, key)
+                {
+                    obfuscated.push(self.epub.archive_path(&file_content.filepath).into_owned());
+                }
+                self.add_file(file_content)?;
+            }
+            if !obfuscated.is_empty() {
+                self.add_file(file_content::encryption(&obfuscated))?;
+            }
+            self.epub.resources = Some(resources);
+        }
+
+        for license_file in self.epub.font_license_files() {
+            self.add_file(license_file)?;
+        }
+
+        if let Some(fallbacks) = self.epub.fallbacks.take() {
+            for resource in fallbacks
                 .iter()
-                .map(|resource| resource.file_content())
-                .collect::<crate::Result<Vec<FileContent<String, Vec<u8>>>>>()?;
+                .flat_map(|(image, fallback)| [image, fallback])
+            {
+                let mut file_content = resource.file_content(base_dir, file_system.as_ref())?;
+                self.epub.process_resource(resource, &mut file_content)?;
+                self.add_file(file_content)?;
+            }
+            self.epub.fallbacks = Some(fallbacks);
+        }
 
-            self.add_files(contents)?;
+        if let Some(raw_files) = self.epub.raw_files.take() {
+            for raw_file in &raw_files {
+                self.write_file(raw_file.path().to_string(), raw_file.bytes())?;
+            }
+            self.epub.raw_files = Some(raw_files);
         }
 
-        // 3. Generate and add content XHTML files
-        if let Some(ref contents) = self.epub.contents {
+        // 3. Generate and write content XHTML files one at a time, reusing a single buffer
+        let mut spine_sizes = Vec::new();
+        if let Some(contents) = self.epub.contents.take() {
+            let head = self.epub.head_links();
             let mut file_number: usize = 0;
-            let mut file_contents: Vec<FileContent<String, String>> = Vec::new();
-            for content in contents {
-                let res = content.file_content(&mut file_number, self.epub.stylesheet.is_some())?;
-                file_contents.extend(res);
+            let mut buffer = Vec::new();
+            for content in &contents {
+                content.write_files(
+                    &mut file_number,
+                    &head,
+                    &mut buffer,
+                    &mut |filepath, bytes| {
+                        spine_sizes.push((
+                            self.epub.archive_path(&filepath).into_owned(),
+                            bytes.len() as u64,
+                        ));
+                        self.add_file(FileContent::new(filepath, bytes))
+                    },
+                )?;
             }
 
-            self.add_files(file_contents)?;
+            self.epub.contents = Some(contents);
         }
 
         // 4. Generate, format, and add OPF and NCX files
@@ -132,17 +288,18 @@ where
         toc_ncx.format(xml::format(&toc_ncx.bytes)?);
         self.add_file(toc_ncx)?;
 
-        // 5. Finalize ZIP and flush to external writer
-        let buffer = self.zip_writer.finish()?;
-        self.writer.write_all(&buffer.into_inner())?;
+        if let Some(checksums) = self.checksums.take() {
+            self.add_file(file_content::integrity_manifest(&checksums))?;
+        }
 
-        Ok(())
+        // 5. Finalize ZIP, flush the external writer and check the size budgets
+        let size_budget = self.epub.size_budget;
+        self.zip_writer.finish()?.into_inner().finish(|size| {
+            size_budget.map_or(Ok(()), |size_budget| size_budget.check(&spine_sizes, size))
+        })
     }
 
-    /// Adds a single `FileContent` item to the internal ZIP archive.
-    ///
-    /// This starts a new file entry in the ZIP using the configured compression
-    /// options and writes the file's content bytes.
+    /// Adds a single `FileContent` item to the internal ZIP archive, relocated to the content directory.
     ///
     /// # Arguments
     ///
@@ -152,25 +309,25 @@ where
         F: ToString,
         B: AsRef<[u8]>,
     {
-        self.zip_writer
-            .start_file(file_content.filepath.to_string(), self.options)?;
-        self.zip_writer.write_all(file_content.bytes.as_ref())?;
-        Ok(())
+        let filepath = file_content.filepath.to_string();
+        let filepath = self.epub.archive_path(&filepath).into_owned();
+        self.write_file(filepath, file_content.bytes.as_ref())
     }
 
-    /// Adds a vector of `FileContent` items to the internal ZIP archive.
-    ///
-    /// # Arguments
+    /// Writes a file to the internal ZIP archive at `filepath`, after running the file hooks.
     ///
-    /// * `file_contents`: A vector of file contents to add to the archive.
-    fn add_files<F, B>(&mut self, file_contents: Vec<FileContent<F, B>>) -> crate::Result<()>
-    where
-        F: ToString,
-        B: AsRef<[u8]>,
-    {
-        for fc in file_contents {
-            self.add_file(fc)?;
+    /// This starts a new file entry in the ZIP using the configured compression
+    /// options and writes the file's content bytes.
+    fn write_file(&mut self, filepath: String, bytes: &[u8]) -> crate::Result<()> {
+        let hooked = self.epub.hook_file(&filepath, bytes)?;
+        let bytes = hooked.as_deref().unwrap_or(bytes);
+
+        if let Some(ref mut checksums) = self.checksums {
+            checksums.push((filepath.clone(), file_content::sha256_hex(bytes)));
         }
+
+        self.zip_writer.start_file(filepath, self.options)?;
+        self.zip_writer.write_all(bytes)?;
         Ok(())
     }
 }
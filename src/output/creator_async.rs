@@ -1,14 +1,12 @@
-use std::io::Cursor;
-
-use async_zip::{Compression, ZipEntryBuilder, tokio::write::ZipFileWriter};
-use futures::future;
-use tokio::io::{AsyncWrite, AsyncWriteExt};
+use async_zip::{Compression, ZipDateTimeBuilder, ZipEntryBuilder, tokio::write::ZipFileWriter};
+use tokio::io::AsyncWrite;
 
 use crate::{
     ZipCompression,
-    epub::Epub,
+    epub::{Epub, PAGE_TEMPLATE_FILENAME, obfuscate_font},
     output::{
         file_content::{self, FileContent},
+        sink::Sink,
         xml,
     },
 };
@@ -18,15 +16,19 @@ use crate::{
 ///
 /// This struct is suitable for non-blocking I/O operations where the final
 /// EPUB archive is written to an asynchronous writer (`W`).
+///
+/// The archive is streamed to the writer as every file is compressed, so the generation awaits a slow
+/// writer instead of piling up bytes.
 pub struct EpubFile<'a, W> {
     /// The source data structure containing all metadata and content of the EPUB.
     epub: Epub<'a>,
-    /// The external asynchronous writer where the final compressed EPUB bytes will be written to.
-    writer: W,
-    /// The internal asynchronous ZIP writer, buffering the content before flushing.
-    zip_writer: ZipFileWriter<Cursor<Vec<u8>>>,
+    /// The internal asynchronous ZIP writer, streaming the archive to the external asynchronous writer
+    /// where the final compressed EPUB bytes will be written to.
+    zip_writer: ZipFileWriter<Sink<W>>,
     /// The configured compression method for the ZIP entries.
     compression: async_zip::Compression,
+    /// The `(path, SHA-256 digest)` of every written file, if the integrity manifest is enabled.
+    checksums: Option<Vec<(String, String)>>,
 }
 
 impl<'a, W> EpubFile<'a, W>
@@ -35,8 +37,8 @@ where
 {
     /// Creates a new asynchronous `EpubFile` builder.
     ///
-    /// This sets up the internal asynchronous ZIP writer and configures the
-    /// compression method to be used for most files (excluding `mimetype`, which is stored).
+    /// This sets up the internal asynchronous ZIP writer (streaming to `writer` and counting the bytes written) and
+    /// configures the compression method to be used for most files (excluding `mimetype`, which is stored).
     ///
     /// # Type Parameters
     ///
@@ -48,10 +50,12 @@ where
     /// * `writer`: The output asynchronous stream where the final EPUB bytes will be written.
     /// * `compression`: The default compression method to use for the files.
     pub fn new(epub: Epub<'a>, writer: W, compression: ZipCompression) -> EpubFile<'a, W> {
+        let checksums = epub.integrity_manifest.then(Vec::new);
+
         Self {
             epub,
-            writer,
-            zip_writer: ZipFileWriter::with_tokio(Cursor::new(Vec::new())),
+            checksums,
+            zip_writer: ZipFileWriter::with_tokio(Sink::new(writer)),
             compression: match compression {
                 ZipCompression::Stored => Compression::Stored,
                 ZipCompression::Deflated => Compression::Deflate,
@@ -62,8 +66,8 @@ where
     /// Asynchronously generates all necessary EPUB files, zips them, and writes the
     /// final archive to the output writer.
     ///
-    /// This method leverages asynchronous I/O and uses `future::try_join_all`
-    /// to concurrently load content from resources. It also uses the asynchronous
+    /// This method leverages asynchronous I/O, loading the resources one at a time right before
+    /// writing them (so a single one is held in memory). It also uses the asynchronous
     /// XML formatting function to ensure non-blocking operation.
     ///
     /// # Returns
@@ -71,45 +75,140 @@ where
     /// Returns `crate::Result<()>` indicating success or failure in any step
     /// (async file generation, XML formatting, or asynchronous ZIP writing).
     pub async fn create(mut self) -> crate::Result<()> {
+        self.epub.prepare()?;
+
+        if self.epub.collect_errors {
+            self.epub.check()?;
+        }
+
         self.add_file(file_content::mimetype()).await?;
-        self.add_file(file_content::container()).await?;
+        let package_path = format!(
+            "{}/{}",
+            self.epub.content_dir(),
+            self.epub.package_filename()
+        );
+        self.add_file(file_content::container(&package_path))
+            .await?;
         self.add_file(file_content::display_options()).await?;
 
+        let base_dir = self.epub.base_dir;
+        let file_system = self.epub.file_system.clone();
+
         if let Some(stylesheet) = self.epub.stylesheet {
             self.add_file(FileContent::new("OEBPS/style.css", stylesheet))
                 .await?;
         }
 
+        if let Some(page_template) = self.epub.page_template {
+            self.add_file(FileContent::new(
+                format!("OEBPS/{PAGE_TEMPLATE_FILENAME}"),
+                page_template,
+            ))
+            .await?;
+        }
+
+        if let Some(scripts) = self.epub.scripts.take() {
+            for script in &scripts {
+                self.add_file(FileContent::new(
+                    format!("OEBPS/{}", script.filename),
+                    &script.source,
+                ))
+                .await?;
+            }
+            self.epub.scripts = Some(scripts);
+        }
+
         if let Some(ref cover_image) = self.epub.cover_image {
-            self.add_file(cover_image.async_file_content().await?)
+            let mut file_content = cover_image
+                .async_file_content(base_dir, file_system.as_deref())
                 .await?;
+            self.epub.process_resource(cover_image, &mut file_content)?;
+            self.add_file(file_content).await?;
         }
 
-        // Concurrently load resources and add them
-        if let Some(ref resources) = self.epub.resources {
-            // Map resources to a vector of futures
-            let contents = resources
+        if let Some(ref cover_thumbnail) = self.epub.cover_thumbnail {
+            let mut file_content = cover_thumbnail
+                .async_file_content(base_dir, file_system.as_deref())
+                .await?;
+            self.epub
+                .process_resource(cover_thumbnail, &mut file_content)?;
+            self.add_file(file_content).await?;
+        }
+
+        if let Some(artwork) = self.epub.itunes_artwork() {
+            let artwork = artwork
+                .async_file_content(base_dir, file_system.as_deref())
+                .await?;
+            self.add_file(FileContent::new("iTunesArtwork", artwork.bytes))
+                .await?;
+        }
+
+        // Resources are loaded one at a time, right before being written
+        let obfuscation_key = self.epub.font_obfuscation_key()?;
+        if let Some(resources) = self.epub.resources.take() {
+            let mut obfuscated = Vec::new();
+            for resource in &resources {
+                let mut file_content = resource
+                    .async_file_content(base_dir, file_system.as_deref())
+                    .await?;
+                self.epub.process_resource(resource, &mut file_content)?;
+                if let Some(ref key) = obfuscation_key
+                    && obfuscate_font(resource, &mut file_content.bytes, key)
+                {
+                    obfuscated.push(self.epub.archive_path(&file_content.filepath).into_owned());
+                }
+                self.add_file(file_content).await?;
+            }
+            if !obfuscated.is_empty() {
+                self.add_file(file_content::encryption(&obfuscated)).await?;
+            }
+            self.epub.resources = Some(resources);
+        }
+
+        for license_file in self.epub.font_license_files() {
+            self.add_file(license_file).await?;
+        }
+
+        if let Some(fallbacks) = self.epub.fallbacks.take() {
+            for resource in fallbacks
                 .iter()
-                .map(|resource| resource.async_file_content())
-                .collect::<Vec<_>>();
+                .flat_map(|(image, fallback)| [image, fallback])
+            {
+                let mut file_content = resource
+                    .async_file_content(base_dir, file_system.as_deref())
+                    .await?;
+                self.epub.process_resource(resource, &mut file_content)?;
+                self.add_file(file_content).await?;
+            }
+            self.epub.fallbacks = Some(fallbacks);
+        }
 
-            // Wait for all resource futures to complete
-            let contents = future::try_join_all(contents).await?;
-            self.add_files(contents).await?;
+        if let Some(raw_files) = self.epub.raw_files.take() {
+            for raw_file in &raw_files {
+                self.write_file(raw_file.path().to_string(), raw_file.bytes())
+                    .await?;
+            }
+            self.epub.raw_files = Some(raw_files);
         }
 
         // Generate and add content XHTML files
-        if let Some(ref contents) = self.epub.contents {
+        let mut spine_sizes = Vec::new();
+        if let Some(contents) = self.epub.contents.take() {
+            let head = self.epub.head_links();
             let mut file_number: usize = 0;
-            let mut file_contents: Vec<FileContent<String, String>> = Vec::new();
-            for content in contents {
-                let res = content
-                    .async_file_content(&mut file_number, self.epub.stylesheet.is_some())
-                    .await?;
-                file_contents.extend(res);
+            for content in &contents {
+                // Every top-level content (and its subcontents) is written before generating the next one
+                let file_contents = content.async_file_content(&mut file_number, &head).await?;
+                spine_sizes.extend(file_contents.iter().map(|file_content| {
+                    (
+                        self.epub.archive_path(&file_content.filepath).into_owned(),
+                        file_content.bytes.len() as u64,
+                    )
+                }));
+                self.add_files(file_contents).await?;
             }
 
-            self.add_files(file_contents).await?;
+            self.epub.contents = Some(contents);
         }
 
         // Generate, format (async), and add OPF file
@@ -122,19 +221,24 @@ where
         toc_ncx.format(xml::async_format(toc_ncx.bytes.clone()).await?);
         self.add_file(toc_ncx).await?;
 
-        // Finalize the ZIP archive and write the internal buffer to the external writer
-        let compat_cursor = self.zip_writer.close().await?;
-        self.writer
-            .write_all(&compat_cursor.into_inner().into_inner())
-            .await?;
+        if let Some(checksums) = self.checksums.take() {
+            self.add_file(file_content::integrity_manifest(&checksums))
+                .await?;
+        }
 
-        Ok(())
+        // Finalize the ZIP archive, flush the external writer and check the size budgets
+        let size_budget = self.epub.size_budget;
+        let compat_sink = self.zip_writer.close().await?;
+        compat_sink
+            .into_inner()
+            .async_finish(|size| {
+                size_budget.map_or(Ok(()), |size_budget| size_budget.check(&spine_sizes, size))
+            })
+            .await
     }
 
-    /// Asynchronously adds a single `FileContent` item to the internal ZIP archive.
-    ///
-    /// Uses `ZipEntryBuilder` to configure the file and `write_entry_whole` to write
-    /// the entire content buffer in one asynchronous operation.
+    /// Asynchronously adds a single `FileContent` item to the internal ZIP archive, relocated to the
+    /// content directory.
     ///
     /// # Arguments
     ///
@@ -144,14 +248,31 @@ where
         F: Into<String>,
         B: AsRef<[u8]>,
     {
-        // Use the configured compression for all files added here
-        let builder = ZipEntryBuilder::new(file_content.filepath.into().into(), self.compression)
+        let filepath: String = file_content.filepath.into();
+        let filepath = self.epub.archive_path(&filepath).into_owned();
+        self.write_file(filepath, file_content.bytes.as_ref()).await
+    }
+
+    /// Asynchronously writes a file to the internal ZIP archive at `filepath`, after running the file hooks.
+    ///
+    /// Uses `ZipEntryBuilder` to configure the file and `write_entry_whole` to write
+    /// the entire content buffer in one asynchronous operation.
+    async fn write_file(&mut self, filepath: String, bytes: &[u8]) -> crate::Result<()> {
+        let hooked = self.epub.hook_file(&filepath, bytes)?;
+        let bytes = hooked.as_deref().unwrap_or(bytes);
+
+        if let Some(ref mut checksums) = self.checksums {
+            checksums.push((filepath.clone(), file_content::sha256_hex(bytes)));
+        }
+
+        // Use the configured compression for all files added here, with the fixed timestamp of the sync
+        // archive (1980-01-01 00:00:00), so the same inputs generate the same archive
+        let builder = ZipEntryBuilder::new(filepath.into(), self.compression)
             .unix_permissions(0o755)
+            .last_modification_date(ZipDateTimeBuilder::new().year(1980).month(1).day(1).build())
             .build();
 
-        self.zip_writer
-            .write_entry_whole(builder, file_content.bytes.as_ref())
-            .await?;
+        self.zip_writer.write_entry_whole(builder, bytes).await?;
         Ok(())
     }
 
@@ -1,4 +1,8 @@
-use crate::epub::{Content, ContentReference, Epub, ReferenceType};
+use sha2::{Digest, Sha256};
+
+use crate::epub::{
+    Content, ContentReference, Epub, PAGE_TEMPLATE_FILENAME, PAGE_TEMPLATE_MEDIA_TYPE, PlayOrders,
+};
 
 /// A generic struct representing a file within the EPUB archive.
 ///
@@ -39,18 +43,19 @@ where
 
 /// Creates a `FileContent` for the mandatory EPUB **container.xml** file.
 ///
-/// This file specifies the location of the OPF package document.
-pub fn container<'a>() -> FileContent<&'a str, &'a [u8]> {
+/// This file specifies the location of the OPF package document (`full_path`, e.g., `OEBPS/content.opf`).
+pub fn container<'a>(full_path: &str) -> FileContent<&'a str, String> {
     FileContent::new(
         "META-INF/container.xml",
-        r#"<?xml version="1.0" encoding="UTF-8"?>
+        format!(
+            r#"<?xml version="1.0" encoding="UTF-8"?>
 <container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
     <rootfiles>
-        <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+        <rootfile full-path="{full_path}" media-type="application/oebps-package+xml"/>
    </rootfiles>
 </container>
         "#
-        .as_bytes(),
+        ),
     )
 }
 
@@ -79,6 +84,56 @@ pub fn display_options<'a>() -> FileContent<&'a str, &'a [u8]> {
     )
 }
 
+/// Computes the lowercase hexadecimal **SHA-256** digest of the given bytes.
+pub fn sha256_hex(bytes: &[u8]) -> String {
+    Sha256::digest(bytes)
+        .iter()
+        .map(|byte| format!("{byte:02x}"))
+        .collect()
+}
+
+/// Creates a `FileContent` for the **META-INF/checksums.sha256** integrity manifest.
+///
+/// Each line holds the digest and the path of a file in the archive, using the
+/// `sha256sum` format (`<digest>  <path>`).
+///
+/// # Arguments
+///
+/// * `checksums`: A slice of `(path, digest)` tuples, in the order the files were written.
+pub fn integrity_manifest<'a>(checksums: &[(String, String)]) -> FileContent<&'a str, String> {
+    FileContent::new(
+        "META-INF/checksums.sha256",
+        checksums
+            .iter()
+            .map(|(path, digest)| format!("{digest}  {path}\n"))
+            .collect(),
+    )
+}
+
+/// Creates a `FileContent` for the **META-INF/encryption.xml** file, declaring the fonts obfuscated
+/// with the Adobe algorithm.
+///
+/// # Arguments
+///
+/// * `filepaths`: The paths of the obfuscated fonts inside the archive (e.g., "OEBPS/font.otf").
+pub fn encryption<'a>(filepaths: &[String]) -> FileContent<&'a str, String> {
+    let encrypted_data = filepaths
+        .iter()
+        .map(|filepath| {
+            format!(
+                r#"<enc:EncryptedData><enc:EncryptionMethod Algorithm="http://ns.adobe.com/pdf/enc#RC"/><enc:CipherData><enc:CipherReference URI="{filepath}"/></enc:CipherData></enc:EncryptedData>"#
+            )
+        })
+        .collect::<String>();
+
+    FileContent::new(
+        "META-INF/encryption.xml",
+        format!(
+            r#"<?xml version="1.0" encoding="UTF-8"?><encryption xmlns="urn:oasis:names:tc:opendocument:xmlns:container" xmlns:enc="http://www.w3.org/2001/04/xmlenc#">{encrypted_data}</encryption>"#
+        ),
+    )
+}
+
 /// A helper struct for efficiently building the content of XML files as a `String`.
 ///
 /// It wraps a single `String` and provides methods for appending various values,
@@ -141,14 +196,15 @@ impl ContentBuilder {
 /// # Returns
 ///
 /// Returns a `crate::Result` wrapping a `FileContent<String, String>` for
-/// "OEBPS/content.opf" with the generated XML content.
+/// the package document ("OEBPS/content.opf" by default) with the generated XML content.
 pub fn content_opf(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>> {
     let metadata = &epub.metadata;
+    let ids = epub.ids();
 
-    let mut content_builder = ContentBuilder(String::from(
+    let mut content_builder = ContentBuilder(
         r#"<?xml version="1.0" encoding="utf-8"?><package version="2.0" unique-identifier="BookId" xmlns="http://www.idpf.org/2007/opf">
-        <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">"#,
-    ));
+        <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">"#.to_string(),
+    );
 
     content_builder.add(metadata.title_as_metadata_xml());
     content_builder.add(metadata.language.as_metadata_xml());
@@ -156,63 +212,162 @@ pub fn content_opf(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>
     content_builder.add_optional(metadata.creator_as_metadata_xml());
     content_builder.add_optional(metadata.contributor_as_metadata_xml());
     content_builder.add_optional(metadata.publisher_as_metadata_xml());
+    content_builder.add_optional(metadata.imprint_as_metadata_xml());
     content_builder.add_optional(metadata.date_as_metadata_xml());
     content_builder.add_optional(metadata.subject_as_metadata_xml());
+    content_builder.add_optional(metadata.subjects_as_metadata_xml());
+    content_builder.add_optional(metadata.keywords_as_metadata_xml());
     content_builder.add_optional(metadata.description_as_metadata_xml());
+    content_builder.add_optional(metadata.rights_as_metadata_xml());
+    content_builder.add_optional(metadata.license_as_metadata_xml());
+    content_builder.add_optional(metadata.edition_as_metadata_xml());
+    content_builder.add_optional(metadata.print_edition_as_metadata_xml());
+    content_builder.add_optional(metadata.periodical_as_metadata_xml());
+    content_builder.add_optional(metadata.metas_as_metadata_xml());
+    content_builder.add_optional(epub.watermark_as_metadata_xml());
+    content_builder.add_optional(metadata.generator_as_metadata_xml());
+    content_builder.add_optional(epub.reading_time_as_metadata_xml());
+    content_builder.add_optional(epub.preview_as_metadata_xml());
+    content_builder.add_optional(epub.accessibility_as_metadata_xml());
     content_builder.add_optional(epub.cover_image_as_metadata_xml());
-    content_builder.add(
-        r#"</metadata><manifest><item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml" />"#,
-    );
+    content_builder.add_optional(epub.credits_as_metadata_xml());
+    content_builder.add(format!(
+        r#"</metadata><manifest><item id="{}" href="toc.ncx" media-type="application/x-dtbncx+xml" />"#,
+        ids.item_id("ncx")
+    ));
 
     content_builder.add_if_some(
-        r#"<item id="style.css" href="style.css" media-type="text/css"/>"#,
+        format!(
+            r#"<item id="{}" href="style.css" media-type="text/css"/>"#,
+            ids.item_id("style.css")
+        ),
         epub.stylesheet.as_ref(),
     );
 
+    content_builder.add_if_some(
+        format!(
+            r#"<item id="{}" href="{PAGE_TEMPLATE_FILENAME}" media-type="{PAGE_TEMPLATE_MEDIA_TYPE}"/>"#,
+            ids.item_id("page-template")
+        ),
+        epub.page_template.as_ref(),
+    );
+
+    for script in epub.scripts.iter().flatten() {
+        content_builder.add(script.as_manifest_xml(ids));
+    }
+
     content_builder.add_optional(epub.cover_image_as_manifest_xml());
+    content_builder.add_optional(
+        epub.cover_thumbnail
+            .as_ref()
+            .and_then(|cover_thumbnail| cover_thumbnail.as_manifest_xml(ids)),
+    );
 
     if let Some(ref resources) = epub.resources {
         for resource in resources {
-            content_builder.add_optional(resource.as_manifest_xml());
+            content_builder.add_optional(resource.as_manifest_xml(ids));
         }
     }
 
+    for (_, font_license) in epub.font_licenses.iter().flatten() {
+        content_builder.add_optional(font_license.as_manifest_xml(ids));
+    }
+
+    if let Some(ref fallbacks) = epub.fallbacks {
+        for (image, fallback) in fallbacks {
+            content_builder.add_optional(image.as_manifest_xml_with_fallback(fallback, ids));
+            content_builder.add_optional(fallback.as_manifest_xml(ids));
+        }
+    }
+
+    for raw_file in epub.raw_files.iter().flatten() {
+        content_builder.add_optional(raw_file.as_manifest_xml(epub.content_dir(), ids));
+    }
+
     create_content_chain(
         &mut 0,
         &mut content_builder,
         epub.contents.as_deref(),
-        |filename, _| {
+        |filename, content| {
+            if content.link {
+                return String::new();
+            }
             format!(
-                r#"<item id="{filename}" href="{filename}" media-type="application/xhtml+xml"/>"#
+                r#"<item id="{id}" href="{filename}" media-type="application/xhtml+xml"/>"#,
+                id = ids.item_id(&filename)
             )
         },
     )?;
 
-    content_builder.add(r#"</manifest><spine toc="ncx">"#);
+    content_builder.add(format!(
+        r#"</manifest><spine toc="{}">"#,
+        ids.item_id("ncx")
+    ));
 
-    create_content_chain(
+    // The spine follows the depth-first order of the tree, stably sorted by the spine order keys
+    let mut itemrefs = Vec::new();
+    collect_spine_items(
         &mut 0,
-        &mut content_builder,
+        0,
         epub.contents.as_deref(),
-        |filename, _| format!(r#"<itemref idref="{filename}"/>"#),
+        &mut itemrefs,
+        |filename, content| {
+            format!(
+                r#"<itemref idref="{idref}"{linear}/>"#,
+                idref = ids.item_id(&filename),
+                linear = if content.linear {
+                    ""
+                } else {
+                    r#" linear="no""#
+                }
+            )
+        },
     )?;
+    itemrefs.sort_by_key(|(spine_order, _)| *spine_order);
+    for (_, itemref) in itemrefs {
+        content_builder.add(itemref);
+    }
 
     content_builder.add(r#"</spine><guide>"#);
 
+    // When a content is marked as the start of reading, it is the only `text` reference
+    let has_start_reading = epub
+        .contents
+        .iter()
+        .flatten()
+        .any(|content| content.any(&|content| content.start_reading));
+
     create_content_chain(
         &mut 0,
         &mut content_builder,
         epub.contents.as_deref(),
-        |filename, reference_type| {
-            let (ref_type, title) = reference_type.type_and_title();
-            format!(r#"<reference type="{ref_type}" title="{title}" href="{filename}"/>"#,)
+        |filename, content| {
+            let (ref_type, _) = content.reference_type.type_and_title();
+            let reference = |ref_type: &str| {
+                format!(
+                    r#"<reference type="{ref_type}" title="{title}" href="{filename}"/>"#,
+                    title = content.title()
+                )
+            };
+
+            let references = match (ref_type, content.start_reading) {
+                ("text", false) if has_start_reading => String::new(),
+                ("text", _) | (_, false) => reference(ref_type),
+                (_, true) => reference(ref_type) + &reference("text"),
+            };
+
+            if content.preview {
+                references + &reference("other.preview")
+            } else {
+                references
+            }
         },
     )?;
 
     content_builder.add(r#"</guide></package>"#);
 
     Ok(FileContent::new(
-        "OEBPS/content.opf".to_string(),
+        format!("OEBPS/{}", epub.package_filename()),
         content_builder.build(),
     ))
 }
@@ -226,28 +381,31 @@ pub fn content_opf(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>
 /// * `file_number`: A mutable counter to assign unique filenames/IDs to content documents.
 /// * `cb`: A mutable reference to the `ContentBuilder` to append the generated XML.
 /// * `contents`: An `Option` containing a slice of the current level of `Content` to process.
-/// * `f`: A function pointer that takes the generated filename and its `ReferenceType` and
+/// * `f`: A function that takes the generated filename and its `Content` and
 ///   returns the specific XML element string to be added (e.g., a `<item>` tag).
 ///
 /// # Returns
 ///
 /// Returns `crate::Result<()>`, signaling an error if a content filename is invalid
 /// (not ending with `.xhtml`).
-fn create_content_chain(
+fn create_content_chain<F>(
     file_number: &mut usize,
     cb: &mut ContentBuilder,
     contents: Option<&[Content<'_>]>,
-    f: fn(String, &ReferenceType) -> String,
-) -> crate::Result {
+    f: F,
+) -> crate::Result
+where
+    F: Fn(String, &Content<'_>) -> String + Copy,
+{
     if let Some(contents) = contents {
         for con in contents {
             *file_number += 1;
             let filename = con.filename(*file_number).into_owned();
-            if !filename.ends_with(".xhtml") {
+            if !con.link && !filename.ends_with(".xhtml") {
                 return Err(crate::Error::ContentFilename(filename));
             }
 
-            cb.add(f(filename, &con.reference_type));
+            cb.add(f(filename, con));
 
             create_content_chain(file_number, cb, con.subcontents.as_deref(), f)?;
         }
@@ -255,11 +413,54 @@ fn create_content_chain(
     Ok(())
 }
 
+/// Recursively collects the `(spine order key, itemref)` of every content, in depth-first order.
+///
+/// Subcontents without a spine order key inherit `parent_order`, the key of their parent.
+/// Navigation-only entries (see [`crate::epub::ContentBuilder::link`]) are skipped.
+///
+/// # Errors
+/// Returns a [`crate::Error::ContentFilename`] if a content filename does not end with `.xhtml`.
+pub(crate) fn collect_spine_items<F>(
+    file_number: &mut usize,
+    parent_order: i32,
+    contents: Option<&[Content<'_>]>,
+    itemrefs: &mut Vec<(i32, String)>,
+    f: F,
+) -> crate::Result
+where
+    F: Fn(String, &Content<'_>) -> String + Copy,
+{
+    for content in contents.unwrap_or_default() {
+        *file_number += 1;
+        let filename = content.filename(*file_number).into_owned();
+        if !content.link && !filename.ends_with(".xhtml") {
+            return Err(crate::Error::ContentFilename(filename));
+        }
+
+        let spine_order = content.spine_order.unwrap_or(parent_order);
+        if !content.link {
+            itemrefs.push((spine_order, f(filename, content)));
+        }
+
+        collect_spine_items(
+            file_number,
+            spine_order,
+            content.subcontents.as_deref(),
+            itemrefs,
+            f,
+        )?;
+    }
+    Ok(())
+}
+
 /// Generates the **toc.ncx** (Navigation Control File for XML) file for the EPUB.
 ///
 /// This file defines the EPUB's table of contents, including the hierarchical
 /// structure of the book's sections and subsections (`navMap`).
 ///
+/// The NCX is mandatory in EPUB 2 (the only version generated), so it is always written and
+/// referenced from the `toc` attribute of the spine, whatever the target [`crate::epub::Profile`].
+///
 /// # Arguments
 ///
 /// * `epub`: A reference to the main `Epub` structure.
@@ -279,16 +480,39 @@ pub fn toc_ncx(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>> {
     content_builder.add(metadata.identifier.as_toc_xml());
     content_builder.add(epub.level_as_toc_xml());
 
-    content_builder.add(format!(r#"<meta name="dtb:totalPageCount" content="0"/><meta name="dtb:maxPageNumber" content="0"/></head>
-                        <docTitle><text>{}</text></docTitle><navMap>"#, metadata.title));
+    let mut page_targets = Vec::new();
+    if let Some(ref contents) = epub.contents {
+        collect_page_targets(&mut 0, contents, &mut page_targets);
+    }
 
-    content_builder.add_optional(
-        epub.contents
-            .as_ref()
-            .map(|contents| contents_to_nav_point(&mut 0, &mut 0, contents)),
-    );
+    let max_page_number = page_targets
+        .iter()
+        .filter_map(|(_, label)| label.parse::<usize>().ok())
+        .max()
+        .unwrap_or_default();
+
+    content_builder.add(format!(r#"<meta name="dtb:totalPageCount" content="{}"/><meta name="dtb:maxPageNumber" content="{max_page_number}"/></head>
+                        <docTitle><text>{}</text></docTitle><navMap>"#, page_targets.len(), metadata.title));
+
+    let play_orders = PlayOrders::new(epub, &mut page_targets)?;
+    let mut nav_point = 0;
+    content_builder.add_optional(epub.contents.as_ref().map(|contents| {
+        contents_to_nav_point(
+            &mut nav_point,
+            &play_orders,
+            epub.flat_toc,
+            &mut 0,
+            contents,
+        )
+    }));
 
-    content_builder.add(r#"</navMap></ncx>"#);
+    content_builder.add(r#"</navMap>"#);
+
+    if !page_targets.is_empty() {
+        content_builder.add(page_list(&play_orders, &page_targets));
+    }
+
+    content_builder.add(r#"</ncx>"#);
 
     Ok(FileContent::new(
         "OEBPS/toc.ncx".to_string(),
@@ -304,6 +528,7 @@ pub fn toc_ncx(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>> {
 /// # Arguments
 ///
 /// * `play_order`: A mutable counter used to generate the unique sequential `playOrder` attribute.
+/// * `flat`: Whether the subcontents are listed after their parent instead of nested in it.
 /// * `contents`: A slice of `Content` items at the current hierarchy level.
 ///
 /// # Returns
@@ -311,43 +536,104 @@ pub fn toc_ncx(epub: &Epub<'_>) -> crate::Result<FileContent<String, String>> {
 /// Returns an `Option<String>`: `Some(String)` containing the generated XML for the
 /// navigation points, or `None` if the input slice is empty.
 fn contents_to_nav_point(
-    play_order: &mut usize,
+    nav_point_number: &mut usize,
+    play_orders: &PlayOrders,
+    flat: bool,
     file_number: &mut usize,
     contents: &[Content<'_>],
 ) -> String {
     let mut result = String::new();
     for content in contents {
-        *play_order += 1;
-        let current_play_order = *play_order;
+        *nav_point_number += 1;
+        let current_nav_point = *nav_point_number;
 
         *file_number += 1;
         let filename = &content.filename(*file_number);
 
-        let nav_point = format!(
-            r#"<navPoint id="navPoint-{current_play_order}" playOrder="{current_play_order}">
-            <navLabel><text>{text}</text></navLabel>
-            <content src="{filename}"/>{content_references}{subs}</navPoint>"#,
-            text = content.title(),
-            content_references = content
-                .content_references
-                .as_ref()
-                .map(|content_references| content_references_to_nav_point(
-                    (current_play_order, filename),
-                    play_order,
+        let content_references = content
+            .content_references
+            .as_ref()
+            .map(|content_references| {
+                content_references_to_nav_point(
+                    (current_nav_point, filename),
+                    nav_point_number,
+                    play_orders,
                     "",
                     content_references,
-                    &mut 0
-                ))
-                .unwrap_or_default(),
-            subs = content
-                .subcontents
-                .as_ref()
-                .map(|s| contents_to_nav_point(play_order, file_number, s))
-                .unwrap_or_default(),
+                    &mut 0,
+                )
+            })
+            .unwrap_or_default();
+        let subs = content
+            .subcontents
+            .as_ref()
+            .map(|s| contents_to_nav_point(nav_point_number, play_orders, flat, file_number, s))
+            .unwrap_or_default();
+        // A flat navigation lists the subcontents right after their parent instead of inside it
+        let (nested, siblings) = if flat {
+            (String::new(), subs)
+        } else {
+            (subs, String::new())
+        };
+
+        result.push_str(&format!(
+            r#"<navPoint id="navPoint-{current_nav_point}" playOrder="{play_order}">
+            <navLabel><text>{text}</text></navLabel>
+            <content src="{filename}"/>{content_references}{nested}</navPoint>"#,
+            play_order = play_orders.get(filename),
+            text = content.title(),
+        ));
+        result.push_str(&siblings);
+    }
+
+    result
+}
+
+/// A recursive private helper function to collect the print page markers of every content,
+/// as `(src, label)` pairs (e.g., `("c01.xhtml#page-12", "12")`), in tree order.
+fn collect_page_targets(
+    file_number: &mut usize,
+    contents: &[Content<'_>],
+    page_targets: &mut Vec<(String, String)>,
+) {
+    for content in contents {
+        *file_number += 1;
+        let filename = content.filename(*file_number);
+
+        page_targets.extend(
+            content
+                .page_markers()
+                .into_iter()
+                .map(|(id, label)| (format!("{filename}#{id}"), label)),
         );
-        result.push_str(&nav_point);
+
+        if let Some(ref subcontents) = content.subcontents {
+            collect_page_targets(file_number, subcontents, page_targets);
+        }
+    }
+}
+
+/// Generates the `pageList` of the `toc.ncx`, numbered in reading order along with the `navMap`.
+///
+/// Pages labeled with a number are `normal` pages, while the rest (e.g., roman numerals) are `front` pages.
+fn page_list(play_orders: &PlayOrders, page_targets: &[(String, String)]) -> String {
+    let mut result = String::from(r#"<pageList><navLabel><text>Pages</text></navLabel>"#);
+
+    for (page_number, (src, label)) in page_targets.iter().enumerate() {
+        let (page_type, value) = match label.parse::<usize>() {
+            Ok(value) => ("normal", format!(r#" value="{value}""#)),
+            Err(_) => ("front", String::new()),
+        };
+
+        result.push_str(&format!(
+            r#"<pageTarget id="pageTarget-{}" type="{page_type}"{value} playOrder="{}">
+            <navLabel><text>{label}</text></navLabel><content src="{src}"/></pageTarget>"#,
+            page_number + 1,
+            play_orders.get(src),
+        ));
     }
 
+    result.push_str("</pageList>");
     result
 }
 
@@ -371,7 +657,8 @@ fn contents_to_nav_point(
 /// reference navigation points, or `None` if the input slice is empty.
 fn content_references_to_nav_point(
     current_xhtml: (usize, &str),
-    play_order: &mut usize,
+    nav_point_number: &mut usize,
+    play_orders: &PlayOrders,
     toc_index: &str,
     content_references: &[ContentReference],
     link_number: &mut usize,
@@ -390,22 +677,23 @@ fn content_references_to_nav_point(
         toc_number += 1;
         let current_toc = format!("{prefix}-{toc_number}");
 
-        *play_order += 1;
-        let current_play_order = *play_order;
+        *nav_point_number += 1;
+        let src = content_reference.reference_name(current_xhtml.1, current_link);
 
         let nav_point = format!(
-            r#"<navPoint id="navPoint-{xhtml_number}{current_toc}" playOrder="{current_play_order}">
+            r#"<navPoint id="navPoint-{xhtml_number}{current_toc}" playOrder="{play_order}">
             <navLabel><text>{text}</text></navLabel>
             <content src="{src}"/>{subcontent_references}</navPoint>"#,
             xhtml_number = current_xhtml.0,
+            play_order = play_orders.get(&src),
             text = content_reference.title,
-            src = content_reference.reference_name(current_xhtml.1, current_link),
             subcontent_references = content_reference
                 .subcontent_references
                 .as_ref()
                 .map(|subcontent_references| content_references_to_nav_point(
                     current_xhtml,
-                    play_order,
+                    nav_point_number,
+                    play_orders,
                     &format!("{current_toc}-"),
                     subcontent_references,
                     link_number,
@@ -420,16 +708,50 @@ fn content_references_to_nav_point(
 
 #[cfg(test)]
 mod tests {
-    use crate::epub::{
-        ContentBuilder, ContentReference, EpubBuilder, Identifier, MetadataBuilder, ReferenceType,
+    use crate::{
+        epub::{
+            ContentBuilder, ContentReference, EpubBuilder, Identifier, MetadataBuilder, PlayOrders,
+            ReferenceType,
+        },
+        markup::PageMarker,
     };
 
-    use super::{content_references_to_nav_point, contents_to_nav_point, toc_ncx};
+    use super::{
+        content_opf, content_references_to_nav_point, contents_to_nav_point, integrity_manifest,
+        sha256_hex, toc_ncx,
+    };
 
     fn cleaner(xml: String) -> String {
         xml.replace("\n", "").replace(" ".repeat(12).as_str(), "")
     }
 
+    #[test]
+    fn test_sha256_hex() {
+        assert_eq!(
+            sha256_hex(b"abc"),
+            "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+        );
+    }
+
+    #[test]
+    fn test_integrity_manifest() {
+        let checksums = vec![
+            ("mimetype".to_string(), sha256_hex(b"application/epub+zip")),
+            ("OEBPS/c01.xhtml".to_string(), "abcd".to_string()),
+        ];
+
+        let manifest = integrity_manifest(&checksums);
+
+        assert_eq!(manifest.filepath, "META-INF/checksums.sha256");
+        assert_eq!(
+            manifest.bytes,
+            format!(
+                "{}  mimetype\nabcd  OEBPS/c01.xhtml\n",
+                sha256_hex(b"application/epub+zip")
+            )
+        );
+    }
+
     #[test]
     fn test_toc_ncx_simple_content() {
         let mock_epub = EpubBuilder::new(
@@ -468,6 +790,216 @@ mod tests {
         assert!(content.ends_with(r#"</navMap></ncx>"#));
     }
 
+    #[test]
+    fn test_content_opf_non_linear() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Cover("Cover".to_string()))
+                    .linear(false)
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Chapter I".to_string()))
+                    .build(),
+            );
+
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(content.contains(
+            r#"<spine toc="ncx"><itemref idref="c01.xhtml" linear="no"/><itemref idref="c02.xhtml"/></spine>"#
+        ));
+    }
+
+    #[test]
+    fn test_content_opf_spine_order() {
+        let appendix = ContentBuilder::new(b"<body/>", ReferenceType::Text("Appendix".to_string()))
+            .spine_order(1)
+            .add_child(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Tables".to_string())).build(),
+            )
+            .build();
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part I".to_string()))
+                    .add_child(
+                        ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string()))
+                            .build(),
+                    )
+                    .add_child(appendix)
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part II".to_string())).build(),
+            );
+
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(content.contains(
+            r#"<spine toc="ncx"><itemref idref="c01.xhtml"/><itemref idref="c02.xhtml"/><itemref idref="c05.xhtml"/><itemref idref="c03.xhtml"/><itemref idref="c04.xhtml"/></spine>"#
+        ));
+    }
+
+    #[test]
+    fn test_content_opf_page_template() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .page_template(b"<ade:template/>");
+
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(content.contains(
+            r#"<item id="page-template" href="page-template.xpgt" media-type="application/vnd.adobe-page-template+xml"/>"#
+        ));
+        assert_eq!(
+            epub.0.head_links(),
+            r#"<link href="page-template.xpgt" rel="stylesheet" type="application/vnd.adobe-page-template+xml"/>"#
+        );
+    }
+
+    #[test]
+    fn test_content_opf_vendor_extensions() {
+        let epub = EpubBuilder::new(
+            MetadataBuilder::title("Title")
+                .add_meta("duokan-book-id", "123")
+                .build(),
+        )
+        .add_content(
+            ContentBuilder::new(b"<body/>", ReferenceType::Text("One".to_string())).build(),
+        );
+
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(content.contains(r#"<meta name="duokan-book-id" content="123"/>"#));
+        assert!(content.contains(
+            r#"<item id="c01.xhtml" href="c01.xhtml" media-type="application/xhtml+xml"/>"#
+        ));
+    }
+
+    #[test]
+    fn test_content_opf_start_reading() {
+        let chapter =
+            |title: &str| ContentBuilder::new(b"<body/>", ReferenceType::Text(title.to_string()));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("Half Title").build())
+            .add_content(chapter("Chapter I").build());
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(
+            content.contains(r#"<reference type="text" title="Half Title" href="c01.xhtml"/>"#)
+        );
+        assert!(content.contains(r#"<reference type="text" title="Chapter I" href="c02.xhtml"/>"#));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("Half Title").build())
+            .add_content(chapter("Chapter I").start_reading().build());
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(!content.contains("Half Title"));
+        assert!(content.contains(
+            r#"<guide><reference type="text" title="Chapter I" href="c02.xhtml"/></guide>"#
+        ));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Preface("Preface".to_string()))
+                    .start_reading()
+                    .build(),
+            )
+            .add_content(chapter("Chapter I").start_reading().build());
+        assert!(epub.validate().is_err());
+    }
+
+    #[test]
+    fn test_content_opf_preview() {
+        let chapter =
+            |title: &str| ContentBuilder::new(b"<body/>", ReferenceType::Text(title.to_string()));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("Chapter I").build())
+            .add_content(
+                chapter("Part II")
+                    .add_child(chapter("Chapter II").preview().build())
+                    .build(),
+            );
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(content.contains(r#"<meta name="preview" content="c03.xhtml"/>"#));
+        assert!(content.contains(
+            r#"<reference type="text" title="Chapter II" href="c03.xhtml"/><reference type="other.preview" title="Chapter II" href="c03.xhtml"/></guide>"#
+        ));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Title").build())
+            .add_content(chapter("Chapter I").build());
+        let content = cleaner(content_opf(&epub.0).unwrap().bytes);
+        assert!(!content.contains("preview"));
+
+        let epub = epub.add_content(
+            chapter("Chapter II")
+                .preview()
+                .add_child(chapter("Chapter III").preview().build())
+                .build(),
+        );
+        assert!(epub.validate().is_err());
+    }
+
+    #[test]
+    fn test_toc_ncx_page_list() {
+        let first = format!(
+            "<body><p>One{}</p><p>Two{}</p></body>",
+            PageMarker::new("ii"),
+            PageMarker::new("1")
+        );
+        let second = format!("<body>{}</body>", PageMarker::new("2"));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Paged Book").build())
+            .add_content(
+                ContentBuilder::new(
+                    first.as_bytes(),
+                    ReferenceType::Text("Chapter I".to_string()),
+                )
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::new(
+                    second.as_bytes(),
+                    ReferenceType::Text("Chapter II".to_string()),
+                )
+                .build(),
+            );
+
+        let content = cleaner(toc_ncx(&epub.0).unwrap().bytes);
+
+        assert!(content.contains(r#"<meta name="dtb:totalPageCount" content="3"/><meta name="dtb:maxPageNumber" content="2"/>"#));
+        assert!(content.contains(r#"</navMap><pageList><navLabel><text>Pages</text></navLabel>"#));
+        assert!(content.contains(r#"<pageTarget id="pageTarget-1" type="front" playOrder="2"><navLabel><text>ii</text></navLabel><content src="c01.xhtml#page-ii"/></pageTarget>"#));
+        assert!(content.contains(r#"<navPoint id="navPoint-2" playOrder="4">"#));
+        assert!(content.contains(r#"<pageTarget id="pageTarget-3" type="normal" value="2" playOrder="5"><navLabel><text>2</text></navLabel><content src="c02.xhtml#page-2"/></pageTarget>"#));
+        assert!(content.ends_with(r#"</pageList></ncx>"#));
+    }
+
+    #[test]
+    fn test_toc_ncx_page_list_spine_order() {
+        let chapter = format!("<body>{}</body>", PageMarker::new("1"));
+        let appendix = format!("<body>{}</body>", PageMarker::new("2"));
+
+        let epub = EpubBuilder::new(MetadataBuilder::title("Paged Book").build())
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part I".to_string()))
+                    .add_child(
+                        ContentBuilder::new(
+                            appendix.as_bytes(),
+                            ReferenceType::Text("Appendix".to_string()),
+                        )
+                        .spine_order(1)
+                        .build(),
+                    )
+                    .build(),
+            )
+            .add_content(
+                ContentBuilder::new(chapter.as_bytes(), ReferenceType::Text("One".to_string()))
+                    .build(),
+            );
+
+        let content = cleaner(toc_ncx(&epub.0).unwrap().bytes);
+        assert!(content.contains(r#"<pageTarget id="pageTarget-1" type="normal" value="1" playOrder="3"><navLabel><text>1</text></navLabel><content src="c03.xhtml#page-1"/></pageTarget>"#));
+        assert!(content.contains(r#"<pageTarget id="pageTarget-2" type="normal" value="2" playOrder="5"><navLabel><text>2</text></navLabel><content src="c02.xhtml#page-2"/></pageTarget>"#));
+
+        assert!(epub.create(&mut Vec::new()).is_ok());
+    }
+
     #[test]
     fn test_toc_ncx_no_content() {
         let mock_epub = EpubBuilder::new(MetadataBuilder::title("Empty Book").build());
@@ -516,11 +1048,14 @@ mod tests {
                 .build(),
             );
 
-        let mut play_order = 0;
+        let play_orders = PlayOrders::new(&mock_epub.0, &mut []).unwrap();
+        let mut nav_point = 0;
         let mut file_number = 0;
 
         let result = contents_to_nav_point(
-            &mut play_order,
+            &mut nav_point,
+            &play_orders,
+            false,
             &mut file_number,
             &mock_epub.0.contents.unwrap(),
         );
@@ -532,7 +1067,46 @@ mod tests {
         assert!(xml.contains(r#"<navPoint id="navPoint-3" playOrder="3"><navLabel><text>Section 1.2</text></navLabel><content src="c03.xhtml"/></navPoint>"#));
         assert!(xml.contains(r#"<navPoint id="navPoint-4" playOrder="4"><navLabel><text>Next Chapter</text></navLabel><content src="c04.xhtml"/></navPoint>"#));
 
-        assert_eq!(play_order, 4);
+        assert_eq!(nav_point, 4);
+    }
+
+    #[test]
+    fn test_toc_ncx_flat() {
+        let epub = EpubBuilder::new(MetadataBuilder::title("Flat Book").build())
+            .flat_toc(true)
+            .add_content(
+                ContentBuilder::new(
+                    br#"<body><h1>Part</h1><h2 id="id01">Intro</h2></body>"#,
+                    ReferenceType::Text("Part I".to_string()),
+                )
+                .add_content_reference(ContentReference::new("Intro"))
+                .add_child(
+                    ContentBuilder::new(b"<body/>", ReferenceType::Text("Chapter 1".to_string()))
+                        .add_child(
+                            ContentBuilder::new(
+                                b"<body/>",
+                                ReferenceType::Text("Section 1.1".to_string()),
+                            )
+                            .build(),
+                        )
+                        .build(),
+                )
+                .build(),
+            )
+            .add_content(
+                ContentBuilder::new(b"<body/>", ReferenceType::Text("Part II".to_string())).build(),
+            );
+
+        let content = cleaner(toc_ncx(&epub.0).unwrap().bytes);
+
+        assert!(content.contains(r#"<meta name="dtb:depth" content="2"/>"#));
+        assert!(content.contains(r#"<navPoint id="navPoint-1" playOrder="1"><navLabel><text>Part I</text></navLabel><content src="c01.xhtml"/><navPoint id="navPoint-1-1" playOrder="2"><navLabel><text>Intro</text></navLabel><content src="c01.xhtml#id01"/></navPoint></navPoint>"#));
+        assert!(content.contains(r#"</navPoint><navPoint id="navPoint-3" playOrder="3"><navLabel><text>Chapter 1</text></navLabel><content src="c02.xhtml"/></navPoint><navPoint id="navPoint-4" playOrder="4"><navLabel><text>Section 1.1</text></navLabel><content src="c03.xhtml"/></navPoint><navPoint id="navPoint-5" playOrder="5">"#));
+
+        let outline = epub.outline().unwrap();
+        assert_eq!(outline.navigation.len(), 4);
+        assert_eq!(outline.navigation[0].children.len(), 1);
+        assert_eq!(outline.spine.len(), 4);
     }
 
     #[test]
@@ -549,11 +1123,14 @@ mod tests {
             .build(),
         );
 
-        let mut play_order = 0;
+        let play_orders = PlayOrders::new(&mock_epub.0, &mut []).unwrap();
+        let mut nav_point = 0;
         let mut file_number = 0;
 
         let result = contents_to_nav_point(
-            &mut play_order,
+            &mut nav_point,
+            &play_orders,
+            false,
             &mut file_number,
             &mock_epub.0.contents.unwrap(),
         );
@@ -563,7 +1140,7 @@ mod tests {
         assert!(xml.contains(r#"<navPoint id="navPoint-1" playOrder="1"><navLabel><text>Chapter with Refs</text></navLabel><content src="c01.xhtml"/>"#));
         assert!(xml.contains(r#"<navPoint id="navPoint-1-1" playOrder="2"><navLabel><text>Ref A</text></navLabel><content src="c01.xhtml#id01"/></navPoint>"#));
         assert!(xml.contains(r#"<navPoint id="navPoint-1-2" playOrder="3"><navLabel><text>Ref B</text></navLabel><content src="c01.xhtml#id02"/></navPoint>"#));
-        assert_eq!(play_order, 3);
+        assert_eq!(nav_point, 3);
     }
 
     #[test]
@@ -576,12 +1153,18 @@ mod tests {
             ContentReference::new("Level 1 Ref 2").id("four"),
         ];
 
-        let mut play_order = 10;
+        let play_orders = ["#id01", "#id02", "#id03", "#four"]
+            .into_iter()
+            .enumerate()
+            .map(|(index, id)| (format!("some.xhtml{id}"), index + 11))
+            .collect();
+        let mut nav_point = 10;
         let mut link_number = 0;
 
         let result = content_references_to_nav_point(
             (5, "some.xhtml"),
-            &mut play_order,
+            &mut nav_point,
+            &play_orders,
             "",
             &content_references,
             &mut link_number,
@@ -593,7 +1176,7 @@ mod tests {
         assert!(xml.contains(r#"<navPoint id="navPoint-5-1-1" playOrder="12"><navLabel><text>Level 2 Ref 1</text></navLabel><content src="some.xhtml#id02"/>"#));
         assert!(xml.contains(r#"<navPoint id="navPoint-5-1-1-1" playOrder="13"><navLabel><text>Level 3 Ref 1</text></navLabel><content src="some.xhtml#id03"/></navPoint>"#));
         assert!(xml.contains(r#"<navPoint id="navPoint-5-2" playOrder="14"><navLabel><text>Level 1 Ref 2</text></navLabel><content src="some.xhtml#four"/></navPoint>"#));
-        assert_eq!(play_order, 14);
+        assert_eq!(nav_point, 14);
         assert_eq!(link_number, 4);
     }
 }
@@ -1,5 +1,6 @@
 pub mod creator;
 pub mod file_content;
+pub(crate) mod sink;
 pub mod xml;
 
 #[cfg(feature = "async")]
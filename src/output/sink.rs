@@ -0,0 +1,121 @@
+use std::io::Write;
+
+/// The destination of the ZIP archive while a book is written.
+///
+/// The archive is **streamed** to the external writer as every file is compressed, so its writes (and
+/// their backpressure) pace the generation and the archive is never held in memory. The bytes written
+/// are **counted**, so the size of the whole archive (i.e., for a size budget) is known once finished.
+#[derive(Debug)]
+pub struct Sink<W> {
+    /// The external writer.
+    writer: W,
+    /// The number of bytes written so far.
+    written: u64,
+}
+
+impl<W> Sink<W> {
+    /// Creates a sink streaming to `writer`.
+    pub fn new(writer: W) -> Self {
+        Self { writer, written: 0 }
+    }
+}
+
+impl<W: Write> Sink<W> {
+    /// Finishes the archive: flushes the external writer, then runs `check` with the size of the archive.
+    ///
+    /// # Errors
+    /// Returns any I/O error of the external writer, or the error of `check`.
+    pub fn finish<F>(mut self, check: F) -> crate::Result
+    where
+        F: FnOnce(u64) -> crate::Result,
+    {
+        self.writer.flush()?;
+        check(self.written)
+    }
+}
+
+impl<W: Write> Write for Sink<W> {
+    fn write(&mut self, buf: &[u8]) -> std::io::Result<usize> {
+        let written = self.writer.write(buf)?;
+        self.written += written as u64;
+        Ok(written)
+    }
+
+    fn flush(&mut self) -> std::io::Result<()> {
+        self.writer.flush()
+    }
+}
+
+#[cfg(feature = "async")]
+impl<W: tokio::io::AsyncWrite + Unpin> Sink<W> {
+    /// **Asynchronously** finishes the archive (see [`Sink::finish`]).
+    ///
+    /// # Errors
+    /// Returns any I/O error of the external writer, or the error of `check`.
+    pub async fn async_finish<F>(mut self, check: F) -> crate::Result
+    where
+        F: FnOnce(u64) -> crate::Result,
+    {
+        use tokio::io::AsyncWriteExt;
+
+        self.writer.flush().await?;
+        check(self.written)
+    }
+}
+
+#[cfg(feature = "async")]
+impl<W: tokio::io::AsyncWrite + Unpin> tokio::io::AsyncWrite for Sink<W> {
+    fn poll_write(
+        self: std::pin::Pin<&mut Self>,
+        cx: &mut std::task::Context<'_>,
+        buf: &[u8],
+    ) -> std::task::Poll<std::io::Result<usize>> {
+        let this = self.get_mut();
+        let poll = std::pin::Pin::new(&mut this.writer).poll_write(cx, buf);
+        if let std::task::Poll::Ready(Ok(written)) = poll {
+            this.written += written as u64;
+        }
+        poll
+    }
+
+    fn poll_flush(
+        self: std::pin::Pin<&mut Self>,
+        cx: &mut std::task::Context<'_>,
+    ) -> std::task::Poll<std::io::Result<()>> {
+        std::pin::Pin::new(&mut self.get_mut().writer).poll_flush(cx)
+    }
+
+    fn poll_shutdown(
+        self: std::pin::Pin<&mut Self>,
+        cx: &mut std::task::Context<'_>,
+    ) -> std::task::Poll<std::io::Result<()>> {
+        self.poll_flush(cx)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_sink() {
+        let mut output = Vec::new();
+        let mut sink = Sink::new(&mut output);
+        sink.write_all(b"PK").unwrap();
+        sink.write_all(b"\x03\x04").unwrap();
+        sink.finish(|size| {
+            assert_eq!(size, 4);
+            Ok(())
+        })
+        .unwrap();
+        assert_eq!(output, b"PK\x03\x04");
+
+        let mut output = Vec::new();
+        let mut sink = Sink::new(&mut output);
+        sink.write_all(b"PK").unwrap();
+        assert!(
+            sink.finish(|_| Err(crate::Error::Validation("too big".to_string())))
+                .is_err()
+        );
+    }
+}
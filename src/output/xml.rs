@@ -1,6 +1,6 @@
-use std::io::Cursor;
+use std::borrow::Cow;
 
-use quick_xml::{Reader, Writer, events::Event};
+use quick_xml::{Reader, Writer, escape::escape, events::Event};
 
 /// Formats an XML string, adding indentation and trimming text content.
 ///
@@ -24,26 +24,415 @@ use quick_xml::{Reader, Writer, events::Event};
 ///
 /// The primary error is `crate::Error::XmlParser` if the input XML is invalid.
 pub fn format(xml_data: &str) -> crate::Result<String> {
+    let mut buffer = Vec::new();
+    format_into(xml_data, &mut buffer)?;
+
+    Ok(String::from_utf8(buffer)?)
+}
+
+/// Formats an XML string like [`format`], writing the result into a reusable buffer.
+///
+/// The buffer is cleared before writing, so the same allocation can be reused
+/// across many documents (e.g., every chapter of a book) instead of allocating
+/// a new `String` for each of them.
+///
+/// # Arguments
+///
+/// * `xml_data`: The XML content to be formatted, as a string slice (`&str`).
+/// * `buffer`: The buffer that receives the formatted XML bytes.
+///
+/// # Errors
+///
+/// The primary error is `crate::Error::XmlParser` if the input XML is invalid.
+pub fn format_into(xml_data: &str, buffer: &mut Vec<u8>) -> crate::Result {
+    buffer.clear();
+
     let mut reader = Reader::from_str(xml_data);
     reader.config_mut().trim_text(true);
 
-    let mut writer = Writer::new_with_indent(Cursor::new(Vec::new()), b' ', 2);
+    let mut writer = Writer::new_with_indent(buffer, b' ', 2);
 
-    let mut buf = Vec::new();
     loop {
-        match reader.read_event_into(&mut buf) {
+        match reader.read_event() {
             Ok(Event::Eof) => break,
             Ok(event) => {
                 writer.write_event(event)?;
             }
             Err(e) => return Err(crate::Error::XmlParser(reader.buffer_position(), e)),
         }
-        buf.clear();
     }
 
-    let result = writer.into_inner().into_inner();
+    Ok(())
+}
+
+/// Extracts the text of the first `<h1>` or `<h2>` element of an XHTML string.
+///
+/// Nested tags are stripped and whitespace is collapsed. Entities are kept escaped,
+/// so the result can be written back into XML as is.
+///
+/// Returns `None` if there is no such heading or if it has no text.
+pub fn first_heading(xhtml: &str) -> Option<String> {
+    let (start, level) = ["h1", "h2"]
+        .into_iter()
+        .filter_map(|level| find_start_tag(xhtml, level).map(|start| (start, level)))
+        .min()?;
+
+    let content_start = start + xhtml[start..].find('>')? + 1;
+    let content_end = content_start + xhtml[content_start..].find(&format!("</{level}"))?;
+
+    let text = strip_tags(&xhtml[content_start..content_end])
+        .split_whitespace()
+        .collect::<Vec<_>>()
+        .join(" ");
+
+    (!text.is_empty()).then_some(text)
+}
+
+/// Finds the headings (`<h1>` to `<h6>`) of an XHTML string, as `(level, text)` pairs in document order.
+///
+/// The text is stripped of tags and its whitespace is collapsed. Empty headings are skipped.
+pub fn headings(xhtml: &str) -> Vec<(u8, String)> {
+    xhtml
+        .match_indices("<h")
+        .filter_map(|(start, _)| {
+            let level = xhtml.as_bytes().get(start + 2).copied()?;
+            if !(b'1'..=b'6').contains(&level)
+                || !xhtml[start + 3..].starts_with(|c: char| c == '>' || c.is_whitespace())
+            {
+                return None;
+            }
+
+            let content_start = start + xhtml[start..].find('>')? + 1;
+            let close = format!("</h{}", level as char);
+            let content_end = content_start + xhtml[content_start..].find(&close)?;
+            let text = strip_tags(&xhtml[content_start..content_end])
+                .split_whitespace()
+                .collect::<Vec<_>>()
+                .join(" ");
+            (!text.is_empty()).then_some((level - b'0', text))
+        })
+        .collect()
+}
+
+/// **Normalizes the heading levels** of an XHTML string: the first heading becomes an `<h1>` and
+/// no level is skipped (e.g., `h2, h4, h3` becomes `h1, h2, h2`), keeping the relative nesting of the headings.
+///
+/// Returns `None` if the levels are already normalized.
+pub fn normalize_headings(xhtml: &str) -> Option<String> {
+    // The (original, normalized) levels of the open sections
+    let mut sections: Vec<(u8, u8)> = Vec::new();
+    let mut normalized = String::with_capacity(xhtml.len());
+    let mut changed = false;
+    let mut rest = xhtml;
+
+    while let Some(position) = rest.find('<') {
+        let (before, tag) = rest.split_at(position);
+        normalized.push_str(before);
+
+        let closing = tag.starts_with("</");
+        let name_start = if closing { 2 } else { 1 };
+        let level = tag.as_bytes().get(name_start + 1).copied();
+        let is_heading = tag[name_start..].starts_with('h')
+            && level.is_some_and(|level| (b'1'..=b'6').contains(&level))
+            && tag[name_start + 2..]
+                .starts_with(|c: char| c == '>' || c == '/' || c.is_whitespace());
+
+        if !is_heading {
+            normalized.push('<');
+            rest = &tag[1..];
+            continue;
+        }
+
+        let level = level.unwrap_or(b'1') - b'0';
+        let new_level = if closing {
+            sections
+                .iter()
+                .rev()
+                .find(|(original, _)| *original == level)
+                .map_or(level, |(_, new_level)| *new_level)
+        } else {
+            while sections
+                .last()
+                .is_some_and(|(original, _)| *original >= level)
+            {
+                sections.pop();
+            }
+            let new_level = sections.last().map_or(1, |(_, new_level)| new_level + 1);
+            sections.push((level, new_level));
+            new_level
+        };
+
+        changed |= new_level != level;
+        normalized.push_str(&tag[..name_start]);
+        normalized.push_str(&format!("h{new_level}"));
+        rest = &tag[name_start + 2..];
+    }
+    normalized.push_str(rest);
+
+    changed.then_some(normalized)
+}
+
+/// Adds attributes to the first start tag named `name` (e.g., `body`) of an XHTML string.
+///
+/// Values are escaped. If any attribute uses the `epub:` prefix (e.g., `epub:type`),
+/// the `epub` namespace is declared on the same element.
+///
+/// Returns the text unchanged if there are no attributes or no such tag.
+pub fn add_attributes<'a>(
+    xhtml: &'a str,
+    name: &str,
+    attributes: &[(String, String)],
+) -> Cow<'a, str> {
+    let open = format!("<{name}");
+    let position = xhtml.match_indices(&open).find_map(|(start, _)| {
+        xhtml[start + open.len()..]
+            .starts_with(|c: char| c == '>' || c == '/' || c.is_whitespace())
+            .then_some(start + open.len())
+    });
+
+    let Some(position) = position.filter(|_| !attributes.is_empty()) else {
+        return Cow::Borrowed(xhtml);
+    };
+
+    let mut result = String::with_capacity(xhtml.len() + attributes.len() * 16);
+    result.push_str(&xhtml[..position]);
+
+    if attributes.iter().any(|(name, _)| name.starts_with("epub:")) {
+        result.push_str(r#" xmlns:epub="http://www.idpf.org/2007/ops""#);
+    }
+
+    for (name, value) in attributes {
+        result.push_str(&format!(r#" {name}="{}""#, escape(value)));
+    }
+
+    result.push_str(&xhtml[position..]);
+    Cow::Owned(result)
+}
+
+/// Rewrites the text nodes of an XHTML fragment, skipping tags and the text inside any of the `skipped` elements.
+///
+/// `rewrite` receives every text node and the output buffer, pushing the (possibly rewritten) text
+/// and returning `true` if it changed anything.
+///
+/// Returns `None` if nothing changed.
+pub fn rewrite_text<F>(xhtml: &str, skipped: &[&str], mut rewrite: F) -> Option<String>
+where
+    F: FnMut(&str, &mut String) -> bool,
+{
+    let mut result = String::with_capacity(xhtml.len());
+    let mut changed = false;
+    let mut skipped_depth = 0usize;
+    let mut rest = xhtml;
+
+    while !rest.is_empty() {
+        if rest.starts_with('<') {
+            let tag_len = rest.find('>').map_or(rest.len(), |end| end + 1);
+            let tag = &rest[..tag_len];
+            let name = tag
+                .trim_start_matches(['<', '/'])
+                .split(|c: char| c.is_whitespace() || c == '>' || c == '/')
+                .next()
+                .unwrap_or_default();
+
+            if skipped.contains(&name) && !tag.ends_with("/>") {
+                if tag.starts_with("</") {
+                    skipped_depth = skipped_depth.saturating_sub(1);
+                } else {
+                    skipped_depth += 1;
+                }
+            }
+
+            result.push_str(tag);
+            rest = &rest[tag_len..];
+            continue;
+        }
+
+        let text_len = rest.find('<').unwrap_or(rest.len());
+        let text = &rest[..text_len];
+        if skipped_depth == 0 {
+            changed |= rewrite(text, &mut result);
+        } else {
+            result.push_str(text);
+        }
+        rest = &rest[text_len..];
+    }
+
+    changed.then_some(result)
+}
+
+/// Counts the words of the text of an XHTML fragment, taking every tag as a word separator.
+pub fn word_count(xhtml: &str) -> usize {
+    xhtml
+        .split(['<', '>'])
+        .step_by(2)
+        .map(|text| text.split_whitespace().count())
+        .sum()
+}
+
+/// Inserts `markup` right after the closing tag of the first `<h1>`/`<h2>` of an XHTML string.
+///
+/// Returns `None` if there is no heading.
+pub fn insert_after_heading(xhtml: &str, markup: &str) -> Option<String> {
+    let (start, level) = ["h1", "h2"]
+        .into_iter()
+        .filter_map(|level| find_start_tag(xhtml, level).map(|start| (start, level)))
+        .min()?;
+
+    let closing_tag = format!("</{level}>");
+    let end = start + xhtml[start..].find(&closing_tag)? + closing_tag.len();
+
+    Some(format!("{}{markup}{}", &xhtml[..end], &xhtml[end..]))
+}
+
+/// Finds the position of the first whole-word occurrence of `word` in a text.
+pub fn find_word(text: &str, word: &str) -> Option<usize> {
+    text.match_indices(word)
+        .map(|(position, _)| position)
+        .find(|&position| {
+            let before = text[..position].chars().next_back();
+            let after = text[position + word.len()..].chars().next();
+            !before.is_some_and(char::is_alphanumeric) && !after.is_some_and(char::is_alphanumeric)
+        })
+}
+
+/// Finds the print page markers (elements with `epub:type="pagebreak"`) of an XHTML string.
+///
+/// Returns the `(id, label)` of every marker with an `id`, in document order. The label is
+/// taken from the `title` attribute, falling back to the `id`.
+pub fn page_markers(xhtml: &str) -> Vec<(String, String)> {
+    xhtml
+        .match_indices(r#"epub:type="pagebreak""#)
+        .filter_map(|(position, _)| {
+            let tag_start = xhtml[..position].rfind('<')?;
+            let tag_end = position + xhtml[position..].find('>')?;
+            let tag = &xhtml[tag_start..tag_end];
+
+            let id = attribute(tag, "id")?;
+            let label = attribute(tag, "title").unwrap_or(id);
+            Some((id.to_string(), label.to_string()))
+        })
+        .collect()
+}
+
+/// Finds the remote media of an XHTML string: the `src` with a URL scheme (`scheme://`) of every
+/// `<audio>`, `<video>`, `<source>` and `<iframe>` element, in document order.
+pub fn remote_media(xhtml: &str) -> Vec<&str> {
+    let mut urls = xhtml
+        .match_indices('<')
+        .filter_map(|(start, _)| {
+            let rest = &xhtml[start + 1..];
+            let name_len = rest.find(|c: char| c.is_whitespace() || c == '>' || c == '/')?;
+            if !matches!(&rest[..name_len], "audio" | "video" | "source" | "iframe") {
+                return None;
+            }
+            let tag = &xhtml[start..start + 1 + rest.find('>')?];
+            attribute(tag, "src").filter(|src| src.contains("://"))
+        })
+        .collect::<Vec<_>>();
+    urls.dedup();
+    urls
+}
+
+/// The HTML5 elements of EPUB 3 content documents that are not part of XHTML 1.1.
+const HTML5_ELEMENTS: [&str; 16] = [
+    "article",
+    "aside",
+    "audio",
+    "figcaption",
+    "figure",
+    "footer",
+    "header",
+    "iframe",
+    "main",
+    "mark",
+    "nav",
+    "section",
+    "source",
+    "time",
+    "track",
+    "video",
+];
+
+/// Finds the EPUB 3 markup of an XHTML string, not valid in the XHTML 1.1 of EPUB 2: its HTML5 elements
+/// (e.g., `<aside>`) followed by the `epub:type` and `role` semantic attributes.
+pub fn epub3_markup(xhtml: &str) -> Vec<String> {
+    let elements = HTML5_ELEMENTS
+        .iter()
+        .filter(|name| has_element(xhtml, name))
+        .map(|name| format!("<{name}>"));
+    let attributes = ["epub:type", "role"]
+        .iter()
+        .filter(|name| xhtml.contains(&format!(r#" {name}=""#)))
+        .map(|name| name.to_string());
+    elements.chain(attributes).collect()
+}
+
+/// Gets the `id` attributes of every element of an XHTML string, in document order.
+pub fn ids(xhtml: &str) -> Vec<&str> {
+    xhtml
+        .match_indices(r#" id=""#)
+        .filter_map(|(position, pattern)| {
+            let start = position + pattern.len();
+            let end = start + xhtml[start..].find('"')?;
+            Some(&xhtml[start..end])
+        })
+        .collect()
+}
+
+/// Checks whether an XHTML string has an element named `name` (e.g., `iframe`).
+pub fn has_element(xhtml: &str, name: &str) -> bool {
+    xhtml
+        .match_indices(&format!("<{name}"))
+        .any(|(position, open)| {
+            xhtml[position + open.len()..]
+                .starts_with(|c: char| c.is_whitespace() || c == '>' || c == '/')
+        })
+}
+
+/// Gets the value of the (double-quoted) attribute `name` of a tag.
+pub fn attribute<'a>(tag: &'a str, name: &str) -> Option<&'a str> {
+    let pattern = format!(r#" {name}=""#);
+    let start = tag.find(&pattern)? + pattern.len();
+    let end = start + tag[start..].find('"')?;
+    Some(&tag[start..end])
+}
+
+/// Finds the position of the first non self-closing start tag named `name`.
+fn find_start_tag(xhtml: &str, name: &str) -> Option<usize> {
+    let open = format!("<{name}");
+    let mut offset = 0;
+
+    while let Some(position) = xhtml[offset..].find(&open) {
+        let start = offset + position;
+        let rest = &xhtml[start + open.len()..];
+        let tag_end = rest.find('>')?;
+
+        if rest.starts_with(|c: char| c == '>' || c.is_whitespace())
+            && !rest[..tag_end].ends_with('/')
+        {
+            return Some(start);
+        }
+        offset = start + open.len();
+    }
+
+    None
+}
+
+/// Removes every tag from an XML fragment, keeping only its text.
+pub fn strip_tags(fragment: &str) -> String {
+    let mut text = String::with_capacity(fragment.len());
+    let mut in_tag = false;
 
-    Ok(String::from_utf8(result)?)
+    for c in fragment.chars() {
+        match c {
+            '<' => in_tag = true,
+            '>' if in_tag => in_tag = false,
+            _ if !in_tag => text.push(c),
+            _ => {}
+        }
+    }
+
+    text
 }
 
 /// Asynchronously formats an XML string by spawning the blocking
@@ -68,3 +457,194 @@ pub fn format(xml_data: &str) -> crate::Result<String> {
 pub async fn async_format(xml_data: String) -> crate::Result<String> {
     tokio::task::spawn_blocking(move || format(&xml_data)).await?
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_format_into_reuses_buffer() {
+        let mut buffer = b"previous content".to_vec();
+        format_into("<body><p>Text</p></body>", &mut buffer).unwrap();
+
+        assert_eq!(
+            String::from_utf8(buffer).unwrap(),
+            "<body>\n  <p>Text</p>\n</body>"
+        );
+    }
+
+    #[test]
+    fn test_add_attributes() {
+        let attributes = vec![
+            ("class".to_string(), "chapter".to_string()),
+            ("epub:type".to_string(), "bodymatter".to_string()),
+            ("title".to_string(), "A \"quoted\" & title".to_string()),
+        ];
+
+        assert_eq!(
+            add_attributes("<html><body><p/></body></html>", "body", &attributes),
+            r#"<html><body xmlns:epub="http://www.idpf.org/2007/ops" class="chapter" epub:type="bodymatter" title="A &quot;quoted&quot; &amp; title"><p/></body></html>"#
+        );
+        assert_eq!(
+            add_attributes(r#"<bodyx/><body id="b"/>"#, "body", &attributes[..1]),
+            r#"<bodyx/><body class="chapter" id="b"/>"#
+        );
+        assert!(matches!(
+            add_attributes("<body/>", "body", &[]),
+            Cow::Borrowed(_)
+        ));
+        assert!(matches!(
+            add_attributes("<p/>", "body", &attributes),
+            Cow::Borrowed(_)
+        ));
+    }
+
+    #[test]
+    fn test_rewrite_text() {
+        let rewritten = rewrite_text(
+            "<p>one <b>two</b></p><title>one</title>",
+            &["title"],
+            |text, result| {
+                result.push_str(&text.replace("one", "1"));
+                text.contains("one")
+            },
+        );
+        assert_eq!(rewritten.unwrap(), "<p>1 <b>two</b></p><title>one</title>");
+        assert!(
+            rewrite_text("<p>two</p>", &[], |text, result| {
+                result.push_str(text);
+                false
+            })
+            .is_none()
+        );
+    }
+
+    #[test]
+    fn test_word_count() {
+        assert_eq!(word_count("<h1>One two</h1><p>three\n<b>four</b></p>"), 4);
+        assert_eq!(word_count("<body/>"), 0);
+    }
+
+    #[test]
+    fn test_insert_after_heading() {
+        assert_eq!(
+            insert_after_heading(r#"<body><h2 id="a">Title</h2><p/></body>"#, "<hr/>").unwrap(),
+            r#"<body><h2 id="a">Title</h2><hr/><p/></body>"#
+        );
+        assert!(insert_after_heading("<body><p/></body>", "<hr/>").is_none());
+    }
+
+    #[test]
+    fn test_find_word() {
+        assert_eq!(find_word("Hamlets and Hamlet.", "Hamlet"), Some(12));
+        assert_eq!(find_word("Hamlets", "Hamlet"), None);
+    }
+
+    #[test]
+    fn test_page_markers() {
+        let xhtml = r#"<body><p>One<span epub:type="pagebreak" id="page-1" title="1"></span></p>
+            <span id="page-ii" epub:type="pagebreak"/><span epub:type="pagebreak"/></body>"#;
+
+        assert_eq!(
+            page_markers(xhtml),
+            vec![
+                ("page-1".to_string(), "1".to_string()),
+                ("page-ii".to_string(), "page-ii".to_string()),
+            ]
+        );
+        assert!(page_markers("<body/>").is_empty());
+    }
+
+    #[test]
+    fn test_headings() {
+        assert_eq!(
+            headings(
+                r#"<body><h1 class="t">The <em>Start</em></h1><header/><h2>Two</h2><h3/></body>"#
+            ),
+            vec![(1, "The Start".to_string()), (2, "Two".to_string())]
+        );
+    }
+
+    #[test]
+    fn test_normalize_headings() {
+        assert_eq!(
+            normalize_headings(
+                r#"<body><h2 class="t">A</h2><hr/><h4>B</h4><h3>C</h3><h2>D</h2><h5/></body>"#
+            )
+            .unwrap(),
+            r#"<body><h1 class="t">A</h1><hr/><h2>B</h2><h2>C</h2><h1>D</h1><h2/></body>"#
+        );
+        assert!(normalize_headings("<body><h1>A</h1><h2>B</h2><header/></body>").is_none());
+    }
+
+    #[test]
+    fn test_ids() {
+        assert_eq!(
+            ids(r#"<body id="top"><h2 class="a" id="s1">One</h2><p data-id="x">Text</p></body>"#),
+            vec!["top", "s1"]
+        );
+    }
+
+    #[test]
+    fn test_has_element() {
+        assert!(has_element(
+            r#"<body><iframe src="w.xhtml"/></body>"#,
+            "iframe"
+        ));
+        assert!(has_element("<body><script>run()</script></body>", "script"));
+        assert!(!has_element("<body><scripts/></body>", "script"));
+    }
+
+    #[test]
+    fn test_epub3_markup() {
+        assert_eq!(
+            epub3_markup(
+                r##"<body><p><a epub:type="noteref" href="#n1">1</a></p><aside id="n1" role="doc-footnote"/><video/></body>"##
+            ),
+            vec!["<aside>", "<video>", "epub:type", "role"]
+        );
+        assert!(
+            epub3_markup(
+                r#"<body><div class="figure"><p class="caption"/></div><headers/></body>"#
+            )
+            .is_empty()
+        );
+    }
+
+    #[test]
+    fn test_remote_media() {
+        let xhtml = r#"<body><audio src="https://cdn.example.com/a.mp3"/><video controls="controls">
+            <source src="https://cdn.example.com/v.mp4"/><source src="v.webm"/></video>
+            <img src="https://example.com/i.jpg"/><audio src="https://cdn.example.com/a.mp3"/></body>"#;
+
+        assert_eq!(
+            remote_media(xhtml),
+            vec![
+                "https://cdn.example.com/a.mp3",
+                "https://cdn.example.com/v.mp4",
+                "https://cdn.example.com/a.mp3"
+            ]
+        );
+        assert!(remote_media(r#"<body><audio src="a.mp3"/></body>"#).is_empty());
+    }
+
+    #[test]
+    fn test_first_heading() {
+        assert_eq!(
+            first_heading(
+                "<body><p>Intro</p><h1 class=\"title\">The <em>Sea</em>\n &amp; Sky</h1></body>"
+            ),
+            Some("The Sea &amp; Sky".to_string())
+        );
+        assert_eq!(
+            first_heading("<body><h2>Second</h2><h1>First</h1></body>"),
+            Some("Second".to_string())
+        );
+        assert_eq!(
+            first_heading("<body><h1/><header>No</header><h2>Heading</h2></body>"),
+            Some("Heading".to_string())
+        );
+        assert_eq!(first_heading("<body><h3>Deep</h3></body>"), None);
+        assert_eq!(first_heading("<body><h1> </h1></body>"), None);
+    }
+}
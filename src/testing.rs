@@ -0,0 +1,243 @@
+//! Helpers for golden-file testing of generated EPUB files.
+//!
+//! These functions build an EPUB into memory, read its entries back and normalize the
+//! volatile parts (random UUIDs, dates and the generator version), so downstream projects
+//! can compare the output against golden files committed to their repositories.
+//!
+//! This module is only compiled when the **`testing` feature** is enabled.
+
+use std::{
+    io::{Cursor, Read},
+    path::Path,
+};
+
+use zip::ZipArchive;
+
+use crate::epub::EpubBuilder;
+
+/// The environment variable that, when set, makes [`assert_golden`] (re)write the golden files.
+pub const UPDATE_GOLDEN_ENV: &str = "LIBER_UPDATE_GOLDEN";
+
+/// A single file read back from a generated EPUB archive.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Entry {
+    /// The path of the file inside the archive, e.g., "OEBPS/content.opf".
+    pub filepath: String,
+    /// The raw content of the file.
+    pub bytes: Vec<u8>,
+}
+
+impl Entry {
+    /// Returns the content as text, or `None` if it is not valid UTF-8.
+    pub fn text(&self) -> Option<&str> {
+        std::str::from_utf8(&self.bytes).ok()
+    }
+}
+
+/// Synchronously builds the EPUB into an in-memory buffer.
+///
+/// # Errors
+///
+/// Returns any error produced by [`EpubBuilder::create`].
+pub fn build_in_memory(epub_builder: EpubBuilder<'_>) -> crate::Result<Vec<u8>> {
+    let mut buffer = Vec::new();
+    epub_builder.create(&mut buffer)?;
+    Ok(buffer)
+}
+
+/// Reads every entry of an EPUB archive, in the order they were written.
+///
+/// # Errors
+///
+/// Returns an error if the bytes are not a valid ZIP archive or an entry cannot be read.
+pub fn read_entries(epub: &[u8]) -> crate::Result<Vec<Entry>> {
+    let mut archive = ZipArchive::new(Cursor::new(epub))?;
+    let mut entries = Vec::with_capacity(archive.len());
+
+    for index in 0..archive.len() {
+        let mut file = archive.by_index(index)?;
+        let filepath = file.name().to_string();
+        let mut bytes = Vec::new();
+        file.read_to_end(&mut bytes)?;
+        entries.push(Entry { filepath, bytes });
+    }
+
+    Ok(entries)
+}
+
+/// Finds an entry by its path inside the archive.
+pub fn find_entry<'e>(entries: &'e [Entry], filepath: &str) -> Option<&'e Entry> {
+    entries.iter().find(|entry| entry.filepath == filepath)
+}
+
+/// Replaces the volatile parts of a generated file with fixed placeholders.
+///
+/// * Every UUID (e.g., the default identifier) becomes `00000000-0000-0000-0000-000000000000`.
+/// * The content of `<dc:date>` elements becomes `0000-00-00`.
+/// * The generator version becomes `liber VERSION`.
+pub fn normalize(text: &str) -> String {
+    let text = normalize_uuids(text);
+    let text = replace_between(&text, "<dc:date", "</dc:date>", |element| {
+        element
+            .split_once('>')
+            .map(|(start, _)| format!("{start}>0000-00-00"))
+            .unwrap_or_else(|| element.to_string())
+    });
+    replace_between(&text, r#"content="liber "#, r#"""#, |_| {
+        "VERSION".to_string()
+    })
+}
+
+/// Compares a normalized text against the golden file at `golden_path`.
+///
+/// If the `LIBER_UPDATE_GOLDEN` environment variable is set, the golden file is (re)written
+/// instead, which is the usual way to create or update golden files.
+///
+/// # Panics
+///
+/// Panics with both contents if they differ, or if the golden file cannot be read or written.
+pub fn assert_golden<P: AsRef<Path>>(actual: &str, golden_path: P) {
+    let golden_path = golden_path.as_ref();
+    let actual = normalize(actual);
+
+    if std::env::var_os(UPDATE_GOLDEN_ENV).is_some() {
+        if let Some(parent) = golden_path.parent() {
+            std::fs::create_dir_all(parent).expect("Error creating golden file directory");
+        }
+        std::fs::write(golden_path, &actual).expect("Error writing golden file");
+        return;
+    }
+
+    let expected = std::fs::read_to_string(golden_path).unwrap_or_else(|e| {
+        panic!(
+            "Error reading golden file '{}': {e}. Set {UPDATE_GOLDEN_ENV} to create it",
+            golden_path.display()
+        )
+    });
+
+    assert_eq!(
+        actual,
+        expected,
+        "Content differs from golden file '{}'",
+        golden_path.display()
+    );
+}
+
+/// Replaces every UUID-shaped token (8-4-4-4-12 hexadecimal digits) with the nil UUID.
+fn normalize_uuids(text: &str) -> String {
+    const GROUPS: [usize; 5] = [8, 4, 4, 4, 12];
+    const LENGTH: usize = 36;
+
+    let is_uuid = |candidate: &[u8]| {
+        let mut position = 0;
+        GROUPS.iter().enumerate().all(|(index, group)| {
+            let hex = candidate[position..position + group]
+                .iter()
+                .all(u8::is_ascii_hexdigit);
+            position += group;
+            let separated = index == GROUPS.len() - 1 || candidate[position] == b'-';
+            position += 1;
+            hex && separated
+        })
+    };
+
+    let bytes = text.as_bytes();
+    let mut result = String::with_capacity(text.len());
+    let mut index = 0;
+
+    while index < bytes.len() {
+        if index + LENGTH <= bytes.len() && is_uuid(&bytes[index..index + LENGTH]) {
+            result.push_str("00000000-0000-0000-0000-000000000000");
+            index += LENGTH;
+        } else {
+            let ch = text[index..].chars().next().unwrap_or_default();
+            result.push(ch);
+            index += ch.len_utf8();
+        }
+    }
+
+    result
+}
+
+/// Replaces the text found between every `start` and the following `end` marker using `f`.
+///
+/// The `start` marker is kept and passed to `f` as part of the replaced section.
+fn replace_between<F>(text: &str, start: &str, end: &str, f: F) -> String
+where
+    F: Fn(&str) -> String,
+{
+    let mut result = String::with_capacity(text.len());
+    let mut rest = text;
+
+    while let Some(begin) = rest.find(start) {
+        let Some(finish) = rest[begin + start.len()..].find(end) else {
+            break;
+        };
+        let finish = begin + start.len() + finish;
+
+        result.push_str(&rest[..begin]);
+        result.push_str(start);
+        result.push_str(&f(&rest[begin + start.len()..finish]));
+        rest = &rest[finish..];
+        result.push_str(end);
+        rest = &rest[end.len()..];
+    }
+
+    result.push_str(rest);
+    result
+}
+
+#[cfg(test)]
+mod tests {
+    use tempfile::tempdir;
+
+    use super::*;
+    use crate::epub::{ContentBuilder, MetadataBuilder, ReferenceType};
+
+    #[test]
+    fn test_normalize() {
+        let text = r#"<dc:identifier id="BookId" opf:scheme="UUID">urn:uuid:67e55044-10b1-426f-9247-bb680e5fe0c8</dc:identifier><dc:date opf:event="publication">2025-10-16</dc:date><meta name="generator" content="liber 0.1.1"/>"#;
+
+        assert_eq!(
+            normalize(text),
+            r#"<dc:identifier id="BookId" opf:scheme="UUID">urn:uuid:00000000-0000-0000-0000-000000000000</dc:identifier><dc:date opf:event="publication">0000-00-00</dc:date><meta name="generator" content="liber VERSION"/>"#
+        );
+    }
+
+    #[test]
+    fn test_normalize_keeps_non_uuid_text() {
+        let text = "Chapter 1 — 12345678-abcd-ñ";
+        assert_eq!(normalize(text), text);
+    }
+
+    #[test]
+    fn test_build_and_read_entries() {
+        let epub_builder = EpubBuilder::new(MetadataBuilder::title("Golden").build()).add_content(
+            ContentBuilder::new(
+                "<body><h1>Chapter 1</h1></body>".as_bytes(),
+                ReferenceType::Text("Chapter 1".to_string()),
+            )
+            .build(),
+        );
+
+        let epub = build_in_memory(epub_builder).expect("Error building epub");
+        let entries = read_entries(&epub).expect("Error reading entries");
+
+        assert_eq!(entries[0].filepath, "mimetype");
+        assert_eq!(entries[0].text(), Some("application/epub+zip"));
+
+        let chapter = find_entry(&entries, "OEBPS/c01.xhtml").expect("Chapter not found");
+        assert!(chapter.text().unwrap().contains("<h1>Chapter 1</h1>"));
+        assert!(find_entry(&entries, "OEBPS/content.opf").is_some());
+    }
+
+    #[test]
+    fn test_assert_golden() {
+        let temp_dir = tempdir().expect("Error creating tempdir");
+        let golden_path = temp_dir.path().join("content.golden");
+        std::fs::write(&golden_path, "<dc:date>0000-00-00</dc:date>")
+            .expect("Error writing golden file");
+
+        assert_golden("<dc:date>2024-01-31</dc:date>", &golden_path);
+    }
+}